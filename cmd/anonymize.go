@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"pgithub.com/plasmatrip/pubscan/internal/anonymize"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/majors"
+	"pgithub.com/plasmatrip/pubscan/internal/publish"
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/stacks"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+	"pgithub.com/plasmatrip/pubscan/internal/toolchain"
+)
+
+// anonymizeReport returns a copy of rep with repo and team names hashed.
+// Spilled per-repo results are rewritten to detailsPath, keeping keep
+// previous versions of it.
+func anonymizeReport(rep report, reportPath, detailsPath string, keep int, a *anonymize.Anonymizer) (report, error) {
+	out := rep
+	out.Stats = a.Stats(rep.Stats)
+
+	if rep.ReposFile != "" {
+		f, err := createTemp(detailsPath)
+		if err != nil {
+			return report{}, err
+		}
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		var encErr error
+		err = readDetails(reportPath, rep.ReposFile, func(r stats.RepoResult) {
+			if encErr == nil {
+				encErr = enc.Encode(a.RepoResult(r))
+			}
+		})
+		if err == nil {
+			err = encErr
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return report{}, err
+		}
+		if err := commitTemp(f, detailsPath, keep); err != nil {
+			return report{}, err
+		}
+		out.ReposFile = detailsPath
+	}
+
+	out.MajorSplits = nil
+	for _, sp := range rep.MajorSplits {
+		lines := make([]majors.Line, len(sp.Lines))
+		copy(lines, sp.Lines)
+		for i := range lines {
+			lines[i].Repos = hashRepos(a, lines[i].Repos)
+		}
+		sp.Lines = lines
+		out.MajorSplits = append(out.MajorSplits, sp)
+	}
+
+	out.LintSets = nil
+	for _, ls := range rep.LintSets {
+		ls.Repos = hashRepos(a, ls.Repos)
+		out.LintSets = append(out.LintSets, ls)
+	}
+	if rep.Toolchains != nil {
+		tc := *rep.Toolchains
+		tc.Pins = anonymizeRows(a, tc.Pins)
+		tc.Matrix = anonymizeRows(a, tc.Matrix)
+		tc.Mismatches = anonymizeMismatches(a, tc.Mismatches)
+		tc.CIFlutter = anonymizeRows(a, tc.CIFlutter)
+		tc.CIDart = anonymizeRows(a, tc.CIDart)
+		tc.CIMismatches = anonymizeMismatches(a, tc.CIMismatches)
+		out.Toolchains = &tc
+	}
+
+	if rep.Codegen != nil {
+		cg := *rep.Codegen
+		cg.Stacks = nil
+		for _, u := range rep.Codegen.Stacks {
+			u.Repos = hashRepos(a, u.Repos)
+			cg.Stacks = append(cg.Stacks, u)
+		}
+		cg.Other.Repos = hashRepos(a, cg.Other.Repos)
+		out.Codegen = &cg
+	}
+
+	if rep.Stacks != nil {
+		sr := *rep.Stacks
+		sr.Categories, sr.Repos = nil, nil
+		for _, cu := range rep.Stacks.Categories {
+			list := make([]stacks.Usage, len(cu.Stacks))
+			for i, u := range cu.Stacks {
+				u.Repos = hashRepos(a, u.Repos)
+				list[i] = u
+			}
+			cu.Stacks = list
+			sr.Categories = append(sr.Categories, cu)
+		}
+		for _, rs := range rep.Stacks.Repos {
+			rs.Repo = a.Repo(rs.Repo)
+			sr.Repos = append(sr.Repos, rs)
+		}
+		out.Stacks = &sr
+	}
+
+	if rep.GitDeps != nil {
+		// Git URLs name orgs, so only the shape of the refs is kept.
+		gd := *rep.GitDeps
+		gd.Sources, gd.BranchPins = nil, nil
+		for _, src := range rep.GitDeps.Sources {
+			src.URL = a.GitURL(src.URL)
+			refs := make([]gitdeps.RefUsage, len(src.Refs))
+			for i, ru := range src.Refs {
+				ru.Ref = ""
+				ru.Repos = hashRepos(a, ru.Repos)
+				refs[i] = ru
+			}
+			src.Refs = refs
+			gd.Sources = append(gd.Sources, src)
+		}
+		for _, bp := range rep.GitDeps.BranchPins {
+			bp.Repo = a.Repo(bp.Repo)
+			bp.URL, bp.Ref = "", ""
+			gd.BranchPins = append(gd.BranchPins, bp)
+		}
+		gd.Forks = nil
+		for _, f := range rep.GitDeps.Forks {
+			f.URL = a.GitURL(f.URL)
+			f.Repos = hashRepos(a, f.Repos)
+			gd.Forks = append(gd.Forks, f)
+		}
+		out.GitDeps = &gd
+	}
+
+	if rep.Hosted != nil {
+		// Private package servers name the company.
+		hs := *rep.Hosted
+		hs.Servers, hs.Allowlist, hs.Untrusted = nil, nil, nil
+		for _, s := range rep.Hosted.Servers {
+			s.URL = a.GitURL(s.URL)
+			s.Repos = hashRepos(a, s.Repos)
+			hs.Servers = append(hs.Servers, s)
+		}
+		for _, u := range rep.Hosted.Allowlist {
+			hs.Allowlist = append(hs.Allowlist, a.GitURL(u))
+		}
+		for _, u := range rep.Hosted.Untrusted {
+			u.Repo, u.URL = a.Repo(u.Repo), a.GitURL(u.URL)
+			hs.Untrusted = append(hs.Untrusted, u)
+		}
+		out.Hosted = &hs
+	}
+
+	out.InputOverlaps = nil
+	for _, o := range rep.InputOverlaps {
+		// Inputs name local paths, like the flags.
+		out.InputOverlaps = append(out.InputOverlaps, repolist.Overlap{Repo: a.Repo(o.Repo)})
+	}
+
+	if rep.Meta != nil {
+		// Flags name local paths and hosts; the hash still matches runs.
+		m := *rep.Meta
+		m.Flags = nil
+		m.Providers = map[string]string{}
+		for name, u := range rep.Meta.Providers {
+			if u != defaultProviders[name] {
+				u = a.GitURL(u)
+			}
+			m.Providers[name] = u
+		}
+		m.APIUsage = anonymizeUsage(rep.Meta.APIUsage, a)
+		out.Meta = &m
+	}
+
+	if rep.Updates != nil {
+		up := *rep.Updates
+		up.Uncovered = nil
+		for _, r := range rep.Updates.Uncovered {
+			r.Repo = a.Repo(r.Repo)
+			up.Uncovered = append(up.Uncovered, r)
+		}
+		out.Updates = &up
+	}
+
+	if rep.LockDrift != nil {
+		ld := *rep.LockDrift
+		ld.Repos = nil
+		for _, r := range rep.LockDrift.Repos {
+			r.Repo = a.Repo(r.Repo)
+			ld.Repos = append(ld.Repos, r)
+		}
+		out.LockDrift = &ld
+	}
+
+	if rep.Integrity != nil {
+		in := *rep.Integrity
+		in.Mismatches = nil
+		for _, m := range rep.Integrity.Mismatches {
+			m.Repo = a.Repo(m.Repo)
+			in.Mismatches = append(in.Mismatches, m)
+		}
+		out.Integrity = &in
+	}
+
+	if rep.Transitive != nil {
+		tr := *rep.Transitive
+		tr.Largest = nil
+		for _, c := range rep.Transitive.Largest {
+			c.Repo = a.Repo(c.Repo)
+			tr.Largest = append(tr.Largest, c)
+		}
+		tr.Redundant = nil
+		for _, r := range rep.Transitive.Redundant {
+			r.Repo = a.Repo(r.Repo)
+			tr.Redundant = append(tr.Redundant, r)
+		}
+		out.Transitive = &tr
+	}
+
+	if rep.Imports != nil {
+		im := *rep.Imports
+		im.Repos = nil
+		for _, r := range rep.Imports.Repos {
+			r.Repo = a.Repo(r.Repo)
+			im.Repos = append(im.Repos, r)
+		}
+		im.BarelyUsed = nil
+		for _, u := range rep.Imports.BarelyUsed {
+			u.Repo = a.Repo(u.Repo)
+			im.BarelyUsed = append(im.BarelyUsed, u)
+		}
+		out.Imports = &im
+	}
+
+	if rep.Flavors != nil {
+		fl := *rep.Flavors
+		fl.Repos = nil
+		for _, r := range rep.Flavors.Repos {
+			r.Repo = a.Repo(r.Repo)
+			fl.Repos = append(fl.Repos, r)
+		}
+		out.Flavors = &fl
+	}
+
+	if rep.Tech != nil {
+		tc := *rep.Tech
+		tc.Repos = nil
+		for _, r := range rep.Tech.Repos {
+			r.Repo = a.Repo(r.Repo)
+			tc.Repos = append(tc.Repos, r)
+		}
+		out.Tech = &tc
+	}
+
+	if rep.Publishing != nil {
+		// Internal server URLs name the company; the kind says enough.
+		pb := *rep.Publishing
+		hash := func(list []publish.Package) []publish.Package {
+			var out []publish.Package
+			for _, p := range list {
+				p.Repo = a.Repo(p.Repo)
+				if p.Kind == publish.KindInternal {
+					p.PublishTo = ""
+				}
+				out = append(out, p)
+			}
+			return out
+		}
+		pb.Internal, pb.Public, pb.Unguarded = hash(pb.Internal), hash(pb.Public), hash(pb.Unguarded)
+		pb.Versions = nil
+		for _, vc := range rep.Publishing.Versions {
+			vc.Repo, vc.Error = a.Repo(vc.Repo), ""
+			pb.Versions = append(pb.Versions, vc)
+		}
+		if m := rep.Publishing.Metadata; m != nil {
+			md := *m
+			md.Packages = nil
+			for _, c := range m.Packages {
+				c.Repo = a.Repo(c.Repo)
+				md.Packages = append(md.Packages, c)
+			}
+			pb.Metadata = &md
+		}
+		out.Publishing = &pb
+	}
+
+	out.Risk = nil
+	for _, r := range rep.Risk {
+		r.Repo = a.Repo(r.Repo)
+		out.Risk = append(out.Risk, r)
+	}
+	// Findings quote fork and package server URLs; they get the hashes of
+	// the sections above.
+	urls := reportURLs(rep)
+	scrub := func(f findings.Finding) findings.Finding {
+		f.Message = a.Text(f.Message, f.Repo, urls)
+		f.Evidence = a.Text(f.Evidence, f.Repo, urls)
+		if f.Rule == findings.RuleScanFailed {
+			f.Evidence = a.Error(f.Evidence, f.Repo)
+		}
+		f.Repo = a.Repo(f.Repo)
+		f.ID = f.Fingerprint()
+		return f
+	}
+	out.Findings = nil
+	for _, f := range rep.Findings {
+		out.Findings = append(out.Findings, scrub(f))
+	}
+	out.GateViolations = nil
+	for _, v := range rep.GateViolations {
+		v.Detail = a.Text(v.Detail, v.Repo, urls)
+		if v.Gate == gate.ScanFailed {
+			v.Detail = a.Error(v.Detail, v.Repo)
+		}
+		v.Repo = a.Repo(v.Repo)
+		out.GateViolations = append(out.GateViolations, v)
+	}
+	out.Suppressed = nil
+	for _, f := range rep.Suppressed {
+		f.Finding = scrub(f.Finding)
+		out.Suppressed = append(out.Suppressed, f)
+	}
+	return out, nil
+}
+
+// reportURLs returns the git and package server URLs of rep.
+func reportURLs(rep report) []string {
+	var urls []string
+	if rep.GitDeps != nil {
+		for _, src := range rep.GitDeps.Sources {
+			urls = append(urls, src.URL)
+		}
+		for _, f := range rep.GitDeps.Forks {
+			urls = append(urls, f.URL)
+		}
+	}
+	if rep.Hosted != nil {
+		for _, s := range rep.Hosted.Servers {
+			urls = append(urls, s.URL)
+		}
+		urls = append(urls, rep.Hosted.Allowlist...)
+		for _, u := range rep.Hosted.Untrusted {
+			urls = append(urls, u.URL)
+		}
+	}
+	return urls
+}
+
+// anonymizeUsage hashes the hosts that requests were counted under because
+// no provider was registered for them.
+func anonymizeUsage(u *quota.Usage, a *anonymize.Anonymizer) *quota.Usage {
+	if u == nil {
+		return nil
+	}
+	out := *u
+	out.Providers = make([]quota.ProviderUsage, len(u.Providers))
+	for i, p := range u.Providers {
+		if !meteredProviders[p.Provider] {
+			p.Provider = a.GitURL(p.Provider)
+		}
+		out.Providers[i] = p
+	}
+	return &out
+}
+
+func hashRepos(a *anonymize.Anonymizer, repos []string) []string {
+	if repos == nil {
+		return nil
+	}
+	out := make([]string, len(repos))
+	for i, r := range repos {
+		out[i] = a.Repo(r)
+	}
+	return out
+}
+
+func anonymizeRows(a *anonymize.Anonymizer, rows []toolchain.Row) []toolchain.Row {
+	var out []toolchain.Row
+	for _, r := range rows {
+		r.Repos = hashRepos(a, r.Repos)
+		out = append(out, r)
+	}
+	return out
+}
+
+func anonymizeMismatches(a *anonymize.Anonymizer, list []toolchain.Mismatch) []toolchain.Mismatch {
+	var out []toolchain.Mismatch
+	for _, m := range list {
+		m.Repo = a.Repo(m.Repo)
+		out = append(out, m)
+	}
+	return out
+}
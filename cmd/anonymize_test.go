@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/anonymize"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/hosted"
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+)
+
+func TestAnonymizeReportFindings(t *testing.T) {
+	fork := "https://github.com/acme/http"
+	tests := []struct {
+		name string
+		rep  report
+	}{
+		{"fork", report{
+			GitDeps:  &gitdeps.Report{Forks: []gitdeps.Fork{{Package: "http", URL: fork, Upstream: "https://github.com/dart-lang/http"}}},
+			Findings: []findings.Finding{{Rule: "fork", Repo: "acme/app", Message: "http is used from the fork " + fork + " instead of pub.dev", Evidence: "git source " + fork}},
+		}},
+		{"hosted server", report{
+			Hosted:   &hosted.Report{Servers: []hosted.Server{{URL: "pub.acme.internal"}}},
+			Findings: []findings.Finding{{Rule: "hosted", Repo: "acme/app", Message: "http is fetched from pub.acme.internal, which is not an allowed package server"}},
+		}},
+		{"suppressed", report{
+			Suppressed: []findings.Suppressed{{Finding: findings.Finding{Rule: "fork", Repo: "acme/app", Evidence: "git source " + fork}, Reason: "patched"}},
+		}},
+		{"scan failed", report{
+			Findings: []findings.Finding{{Rule: findings.RuleScanFailed, Repo: "acme/app", Evidence: "token has no access to acme"}},
+		}},
+		{"API usage", report{
+			Meta: &reportMeta{APIUsage: &quota.Usage{Providers: []quota.ProviderUsage{{Provider: "github", Requests: 3}, {Provider: "artifacts.acme.internal", Requests: 1}}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := anonymizeReport(tt.rep, "", "", 0, anonymize.New("salt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "acme") {
+				t.Errorf("anonymized report names the org: %s", data)
+			}
+		})
+	}
+}
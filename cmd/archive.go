@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// archiveRecord is the commit.json written next to archived pubspecs.
+// Files are paths in the repo.
+type archiveRecord struct {
+	Repo       string    `json:"repo"`
+	Ref        string    `json:"ref"`
+	Commit     string    `json:"commit,omitempty"`
+	Files      []string  `json:"files"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// pubspecArchive saves the pubspecs and lockfiles a scan fetches under
+// dir/owner/repo/path, with the commit they were read at, for analyses
+// that need the raw files.
+type pubspecArchive struct {
+	dir string
+}
+
+func newPubspecArchive(dir string) (*pubspecArchive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &pubspecArchive{dir: dir}, nil
+}
+
+// save writes files, keyed by their path in the repo, and the commit.json
+// of the entry directory dir.
+func (a *pubspecArchive) save(name, dir string, prov *stats.Provenance, files map[string]string) error {
+	owner, repo, _ := strings.Cut(name, "/")
+	root := filepath.Join(a.dir, owner, repo)
+	rec := archiveRecord{Repo: name, Ref: prov.Ref, Commit: prov.Commit, ArchivedAt: time.Now().UTC()}
+
+	for p, content := range files {
+		clean := path.Clean("/" + p)
+		target := filepath.Join(root, filepath.FromSlash(clean))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return err
+		}
+		rec.Files = append(rec.Files, strings.TrimPrefix(clean, "/"))
+	}
+	sort.Strings(rec.Files)
+	data, _ := json.MarshalIndent(rec, "", "  ")
+	target := filepath.Join(root, filepath.FromSlash(path.Clean("/"+dir)), "commit.json")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, append(data, '\n'), 0644)
+}
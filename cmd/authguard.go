@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+)
+
+// authGuard stops API calls that are bound to fail once the token has been
+// rejected: everywhere for bad credentials, per organization for SAML SSO.
+// Missing fine-grained permissions are per repo and not remembered.
+type authGuard struct {
+	mu    sync.Mutex
+	fatal *github.AuthError
+	orgs  map[string]*github.AuthError
+}
+
+func newAuthGuard() *authGuard {
+	return &authGuard{orgs: map[string]*github.AuthError{}}
+}
+
+// blocked returns the earlier error that applies to owner with the given
+// credentials, or nil.
+func (g *authGuard) blocked(owner, credentials string) *github.AuthError {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.fatal != nil && credentials == "" {
+		return g.fatal
+	}
+	return g.orgs[owner+"|"+credentials]
+}
+
+// record remembers err if it is an AuthError and prints the guidance the
+// first time it blocks something. It returns the AuthError, or nil.
+func (g *authGuard) record(owner, credentials string, err error) *github.AuthError {
+	var ae *github.AuthError
+	if !errors.As(err, &ae) {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case ae.Kind == github.AuthBadCredentials && credentials == "":
+		if g.fatal == nil {
+			g.fatal = ae
+			fmt.Printf("❌ %v. Skipping the remaining repositories.\n", ae)
+		}
+	case ae.Kind == github.AuthBadCredentials, ae.Kind == github.AuthSAML:
+		key := owner + "|" + credentials
+		if g.orgs[key] == nil {
+			g.orgs[key] = ae
+			fmt.Printf("❌ %s: %v. Skipping its other repositories.\n", owner, ae)
+		}
+	}
+	return ae
+}
+
+// failed reports whether the token was rejected outright.
+func (g *authGuard) failed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.fatal != nil
+}
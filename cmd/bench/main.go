@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/bench"
+)
+
+// pgs-bench times scans of a synthetic fleet served by a local stand-in for
+// the GitHub, pub.dev and OSV APIs, and compares them with an earlier
+// result. It is a development tool, kept out of the pubscan binary.
+func main() {
+	fs := flag.NewFlagSet("pgs-bench", flag.ExitOnError)
+	pgsPath := fs.String("pgs", "bin/pubscan", "The pubscan binary to benchmark")
+	repos := fs.Int("repos", 200, "Number of synthetic repos")
+	deps := fs.Int("deps", 15, "Hosted dependencies per repo")
+	packages := fs.Int("packages", 100, "Number of distinct packages the dependencies are drawn from")
+	versions := fs.Int("versions", 10, "Published versions per package")
+	latency := fs.Duration("latency", 0, "Delay added to every API response")
+	runs := fs.Int("runs", 3, "Number of scans to take the median of")
+	outPath := fs.String("out", "", "Path to write the result to as JSON")
+	baselinePath := fs.String("baseline", "", "Result of an earlier run to compare with")
+	tolerance := fs.Float64("tolerance", 0.2, "Growth of wall time and allocations over the baseline allowed, 0.2 for 20%")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  go build -o bin/pubscan ./cmd
+  go run ./cmd/bench [options] [-- scan options]
+
+Options:
+  --pgs        The pubscan binary to benchmark (default: bin/pubscan)
+  --repos      Number of synthetic repos (default: 200)
+  --deps       Hosted dependencies per repo (default: 15)
+  --packages   Number of distinct packages the dependencies are drawn from (default: 100)
+  --versions   Published versions per package (default: 10)
+  --latency    Delay added to every API response, e.g. 20ms (default: none)
+  --runs       Number of scans to take the median of (default: 3)
+  --out        Path to write the result to as JSON
+  --baseline   Result of an earlier run to compare with
+  --tolerance  Growth of wall time and allocations over the baseline allowed
+               (default: 0.2, for 20%)
+
+The scan options after -- are passed on, e.g. -- --lockfile --dep-graph --risk.
+Exit status is 1 when a metric regressed against the baseline: median wall time
+or allocations above the tolerance, or more requests to any endpoint.`)
+	}
+	fs.Parse(os.Args[1:])
+	if *repos < 1 || *deps < 1 || *packages < *deps || *versions < 1 || *runs < 1 {
+		fmt.Println("--repos, --deps, --versions and --runs must be at least 1, and --packages at least --deps")
+		os.Exit(2)
+	}
+	cfg := bench.Config{Repos: *repos, Deps: *deps, Packages: *packages, Versions: *versions, Latency: *latency}
+	result := bench.Result{Config: cfg, Flags: fs.Args(), Requests: map[string]int{}}
+
+	var baseline *bench.Result
+	if *baselinePath != "" {
+		b, err := bench.Load(*baselinePath)
+		if err != nil {
+			fmt.Printf("Failed to read baseline: %v\n", err)
+			os.Exit(2)
+		}
+		if why := bench.Comparable(b, result); why != "" {
+			fmt.Printf("Cannot compare with %s: %s\n", *baselinePath, why)
+			os.Exit(2)
+		}
+		baseline = &b
+	}
+
+	pgs, err := exec.LookPath(*pgsPath)
+	if err == nil {
+		pgs, err = filepath.Abs(pgs)
+	}
+	if err != nil {
+		fmt.Printf("Failed to find the pubscan binary: %v\n", err)
+		os.Exit(2)
+	}
+	work, err := os.MkdirTemp("", "pubscan-bench-")
+	if err != nil {
+		fmt.Printf("Failed to create bench directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(work)
+
+	srv := bench.NewServer(cfg)
+	listPath := filepath.Join(work, "repos.txt")
+	if err := os.WriteFile(listPath, []byte(strings.Join(srv.Repos(), "\n")+"\n"), 0644); err != nil {
+		fmt.Printf("Failed to write %s: %v\n", listPath, err)
+		return
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Printf("Failed to start the synthetic API server: %v\n", err)
+		return
+	}
+	defer ln.Close()
+	go http.Serve(ln, srv)
+	apiURL := "http://" + ln.Addr().String()
+
+	fmt.Printf("Benchmarking scans of %s...\n", cfg)
+	for i := 1; i <= *runs; i++ {
+		run, err := benchRun(pgs, apiURL, listPath, filepath.Join(work, fmt.Sprintf("run-%d", i)), fs.Args())
+		if err != nil {
+			fmt.Printf("Run %d failed: %v\n", i, err)
+			os.Exit(1)
+		}
+		requests := srv.Requests()
+		for _, n := range requests {
+			run.Requests += n
+		}
+		result.Requests = requests
+		result.Runs = append(result.Runs, run)
+		fmt.Printf("  run %d: %s, %s allocated in %d objects, %d requests\n", i, run.Wall.Round(time.Millisecond), formatBytes(run.AllocBytes), run.Mallocs, run.Requests)
+	}
+	result.Summarize()
+	m := result.Median
+	fmt.Printf("Median: %s, %s allocated in %d objects, %d requests\n", m.Wall.Round(time.Millisecond), formatBytes(m.AllocBytes), m.Mallocs, m.Requests)
+	endpoints := make([]string, 0, len(result.Requests))
+	for e := range result.Requests {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	for _, e := range endpoints {
+		fmt.Printf("  %-20s %6d\n", e, result.Requests[e])
+	}
+
+	if *outPath != "" {
+		if err := bench.Save(*outPath, result); err != nil {
+			fmt.Printf("Failed to write %s: %v\n", *outPath, err)
+			os.Exit(2)
+		}
+		fmt.Printf("Result written to %s\n", *outPath)
+	}
+	if baseline == nil {
+		return
+	}
+	regressions := bench.Compare(*baseline, result, *tolerance)
+	if len(regressions) == 0 {
+		fmt.Printf("✅ No regressions against %s\n", *baselinePath)
+		return
+	}
+	fmt.Printf("❌ %d regressions against %s:\n", len(regressions), *baselinePath)
+	for _, r := range regressions {
+		fmt.Printf("   %s\n", r)
+	}
+	os.Exit(1)
+}
+
+// benchRun scans the synthetic fleet once, in a directory of its own so
+// no enrichment cache or .env carries over, and measures it. The scan sees
+// none of the caller's environment, such as PUBSCAN_* settings or caches.
+// The rate limits of pub.dev and OSV are lifted; scan options can set them
+// again.
+func benchRun(pgs, apiURL, listPath, dir string, scanArgs []string) (bench.Run, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return bench.Run{}, err
+	}
+	reportPath := filepath.Join(dir, "report.json")
+	args := []string{"--repos", listPath, "--out", reportPath,
+		"--api-url", apiURL, "--pubdev-url", apiURL, "--osv-url", apiURL,
+		"--rps-pubdev", "0", "--rps-osv", "0"}
+	args = append(args, scanArgs...)
+
+	logPath := filepath.Join(dir, "scan.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return bench.Run{}, err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(pgs, args...)
+	cmd.Dir = dir
+	cmd.Env = benchEnv(dir)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	start := time.Now()
+	runErr := cmd.Run()
+	wall := time.Since(start)
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		if out, err := os.ReadFile(logPath); err == nil {
+			os.Stdout.Write(out)
+		}
+		return bench.Run{}, fmt.Errorf("no report written: %v", runErr)
+	}
+	var rep struct {
+		Meta *struct {
+			Runtime *struct {
+				AllocBytes uint64 `json:"alloc_bytes"`
+				Mallocs    uint64 `json:"mallocs"`
+			} `json:"runtime"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return bench.Run{}, err
+	}
+	if rep.Meta == nil || rep.Meta.Runtime == nil {
+		return bench.Run{}, fmt.Errorf("the report has no runtime usage")
+	}
+	return bench.Run{Wall: wall, AllocBytes: rep.Meta.Runtime.AllocBytes, Mallocs: rep.Meta.Runtime.Mallocs}, nil
+}
+
+// benchEnv is the environment of a scan in dir: the PATH, for signing
+// tools, and a home, cache and temporary directory in dir.
+func benchEnv(dir string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"XDG_CACHE_HOME=" + filepath.Join(dir, "cache"),
+		"TMPDIR=" + dir,
+		"GITHUB_TOKEN=bench",
+	}
+}
+
+// formatBytes formats a byte count in MB.
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/bundle"
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// runBundle implements the `pubscan bundle` commands, which move a scan
+// with everything it read to another machine.
+func runBundle(args []string) {
+	usage := `Usage:
+  pgs bundle export --out audit.tar.gz [options] report.json
+  pgs bundle import --dir audit bundle.tar.gz
+  pgs bundle replay [options] bundle.tar.gz`
+	if len(args) == 0 {
+		fmt.Println(usage)
+		return
+	}
+	switch args[0] {
+	case "export":
+		runBundleExport(args[1:])
+	case "import":
+		runBundleImport(args[1:])
+	case "replay":
+		runBundleReplay(args[1:])
+	default:
+		fmt.Println(usage)
+	}
+}
+
+// runBundleExport implements `pubscan bundle export`, archiving a report
+// with the repo files captured by --capture-dir and the enrichment cache.
+func runBundleExport(args []string) {
+	fs := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	outPath := fs.String("out", "", "Path of the bundle (.tar, .tar.gz or .tar.zst)")
+	reposPath := fs.String("repos", "", "Repos list or manifest of the scan, if not the one in the report's metadata")
+	captureDir := fs.String("capture-dir", "", "Repo files captured by the scan with --capture-dir")
+	cacheDir := fs.String("cache-dir", "", "Enrichment cache of the scan")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs bundle export --out audit.tar.gz [options] report.json
+
+Options:
+  --out        Path of the bundle (.tar, .tar.gz or .tar.zst)
+  --repos      Repos list or manifest of the scan, if not the one in the report's metadata
+  --capture-dir
+               Repo files captured by the scan with --capture-dir
+  --cache-dir  Enrichment cache of the scan
+
+The bundle holds the report, its per-repo file, the input files of the
+scan (repos list, rules, suppressions, baseline, taxonomy, internal
+packages, popular packages), the fetched pubspecs, lockfiles and other repo files, and the
+cached pub.dev and OSV responses, so every report can be derived from it
+offline.`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *outPath == "" || fs.NArg() != 1 {
+		fmt.Println("Missing required arguments. Use pgs bundle export --help for usage.")
+		return
+	}
+	reportPath := fs.Arg(0)
+	data, err := readReport(reportPath)
+	if err != nil {
+		fmt.Printf("Failed to read report: %v\n", err)
+		return
+	}
+	var s stats.Stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		fmt.Printf("Failed to parse report: %v\n", err)
+		return
+	}
+
+	m := bundle.Manifest{Version: version, Created: time.Now().UTC(), Report: filepath.Base(reportPath), Inputs: map[string]string{}}
+	sources := []bundle.Source{{Name: m.Report, Path: reportPath}}
+	if s.ReposFile != "" {
+		details := s.ReposFile
+		if _, err := os.Stat(details); os.IsNotExist(err) && !filepath.IsAbs(details) {
+			details = filepath.Join(filepath.Dir(reportPath), filepath.Base(details))
+		}
+		m.Details = filepath.Base(details)
+		sources = append(sources, bundle.Source{Name: m.Details, Path: details})
+	}
+	flags := reportFlags(data)
+	if *reposPath != "" {
+		flags["repos"] = *reposPath
+	}
+	for _, name := range bundleInputs {
+		p := flags[name]
+		if p == "" {
+			continue
+		}
+		if info, err := os.Stat(p); err != nil || info.IsDir() {
+			fmt.Printf("⚠️  --%s %s is not a local file and is not included\n", name, p)
+			continue
+		}
+		m.Inputs[name] = path.Join(bundle.InputsDir, name+filepath.Ext(p))
+		sources = append(sources, bundle.Source{Name: m.Inputs[name], Path: p})
+	}
+	for _, d := range []struct {
+		flag, path, name string
+		set              *bool
+	}{{"--capture-dir", *captureDir, bundle.ReposDir, &m.Repos}, {"--cache-dir", *cacheDir, bundle.CacheDir, &m.Cache}} {
+		if d.path == "" {
+			continue
+		}
+		if info, err := os.Stat(d.path); err != nil || !info.IsDir() {
+			fmt.Printf("%s %s is not a directory\n", d.flag, d.path)
+			return
+		}
+		*d.set = true
+		sources = append(sources, bundle.Source{Name: d.name, Path: d.path})
+	}
+	if !m.Repos {
+		fmt.Println("⚠️  No --capture-dir given: the bundle cannot reproduce the scan itself")
+	}
+
+	err = writeOutput(context.Background(), *outPath, 0, nil, func(w io.Writer) error {
+		return bundle.Write(w, m, sources)
+	})
+	if err != nil {
+		fmt.Printf("Failed to write bundle: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved to %s\n", *outPath)
+}
+
+// runBundleImport implements `pubscan bundle import`, unpacking a bundle
+// into a directory for offline runs.
+func runBundleImport(args []string) {
+	fs := flag.NewFlagSet("bundle import", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory to unpack the bundle into")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs bundle import --dir audit bundle.tar.gz
+
+Options:
+  --dir        Directory to unpack the bundle into`)
+	}
+	parseFlags(fs, args)
+	if *dir == "" || fs.NArg() != 1 {
+		fmt.Println("Missing required arguments. Use pgs bundle import --help for usage.")
+		return
+	}
+	bundlePath := fs.Arg(0)
+	m, err := extractBundle(bundlePath, *dir)
+	if err != nil {
+		fmt.Printf("Failed to import bundle: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Imported %d files of a pubscan %s bundle from %s into %s\n", m.Files, m.Version, m.Created.Format(time.RFC3339), *dir)
+	fmt.Printf("  report: %s\n", filepath.Join(*dir, m.Report))
+	if m.Repos && m.Inputs["repos"] != "" {
+		cmd := fmt.Sprintf("pgs --offline --local-repos %s --repos %s", filepath.Join(*dir, bundle.ReposDir), filepath.Join(*dir, m.Inputs["repos"]))
+		if m.Cache {
+			cmd += " --cache-dir " + filepath.Join(*dir, bundle.CacheDir)
+		}
+		fmt.Printf("  rescan: %s --out rescan.json\n", cmd)
+		fmt.Printf("  replay: pgs bundle replay %s\n", bundlePath)
+	}
+}
+
+// extractBundle unpacks the bundle at path into dir, decompressing it as
+// its extension says.
+func extractBundle(path, dir string) (bundle.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return bundle.Manifest{}, err
+	}
+	defer f.Close()
+	format, _ := compress.ForPath(path)
+	r, err := compress.NewReader(context.Background(), f, format)
+	if err != nil {
+		return bundle.Manifest{}, err
+	}
+	defer r.Close()
+	return bundle.Extract(r, dir)
+}
+
+// bundleInputs are the scan options naming input files, which bundles
+// carry so scans can be replayed with them.
+var bundleInputs = []string{"repos", "taxonomy", "internal-packages", "rules", "suppressions", "baseline", "popular-packages"}
+
+// reportFlags returns the scan options recorded in a report's metadata.
+func reportFlags(data []byte) map[string]string {
+	var r struct {
+		Meta *reportMeta `json:"meta"`
+	}
+	out := map[string]string{}
+	if json.Unmarshal(data, &r) == nil && r.Meta != nil {
+		for k, v := range r.Meta.Flags {
+			out[k] = v
+		}
+	}
+	return out
+}
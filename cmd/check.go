@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/httpcache"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/rules"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// runCheck implements `pubscan check`, analyzing a single repo or local
+// checkout with the same enrichment and policies as a fleet scan and
+// printing the findings for CI logs.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	envPath := fs.String("env", "", "Path to .env file containing GITHUB_TOKEN (remote repos only)")
+	localPath := fs.String("path", "", "Check the pubspec.yaml in this directory instead of a GitHub repo")
+	mainDeps := fs.Bool("maindeps", false, "Only check main dependencies")
+	apiURL := fs.String("api-url", github.DefaultBaseURL, "GitHub API base URL (for GitHub Enterprise Server)")
+	pubdevURL := fs.String("pubdev-url", pubdev.DefaultBaseURL, "pub.dev API base URL")
+	osvURL := fs.String("osv-url", osv.DefaultBaseURL, "OSV API base URL")
+	staleMonths := fs.Int("stale-months", 24, "Months since the latest release after which a package counts as stale")
+	suppressionsPath := fs.String("suppressions", "", "YAML file of findings to waive, with reason and expiry")
+	baselinePath := fs.String("baseline", "", "Baseline file of known findings that do not fail the check")
+	writeBaselinePath := fs.String("write-baseline", "", "Path to record the findings of this check as a baseline")
+	acceptBaseline := fs.Bool("accept-baseline", false, "Treat the findings recorded by --write-baseline as baseline findings in this check, so they do not fail it")
+	rulesPath := fs.String("rules", "", "YAML file of custom rules written as CEL expressions over the repo")
+	failOn := fs.String("fail-on", "", "Fail if a finding has this severity or higher: critical, high, medium, low, info")
+	maxDeps := fs.Int("max-deps-per-repo", gate.Disabled, "Fail if the repo declares more main dependencies than this")
+	maxOverrides := fs.Int("max-overrides", gate.Disabled, "Fail if the repo declares more dependency overrides than this")
+	maxUnbounded := fs.Int("max-unbounded", gate.Disabled, "Fail if the repo has more constraints without upper bound than this")
+	maxMutable := fs.Int("max-mutable-refs", gate.Disabled, "Fail if the repo has more git dependencies on a branch instead of a tag or commit than this")
+	debugHTTP := fs.Bool("debug-http", false, "Log sanitized metadata of every HTTP request and response")
+	rpsGitHub := fs.Float64("rps-github", 0, "Maximum GitHub API requests per second (0: unlimited)")
+	cacheDir := fs.String("cache-dir", "", "Directory to cache pub.dev and OSV responses in across runs")
+	userAgentFlag := fs.String("user-agent", userAgent(), "User-Agent header sent with every request")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs check [options] owner/repo
+  pgs check [options] --path .
+
+Options:
+  --env        Path to .env file containing GITHUB_TOKEN (remote repos only)
+  --path       Check the pubspec.yaml in this directory instead of a GitHub repo
+  --maindeps   Only check main dependencies
+  --api-url    GitHub API base URL (default: https://api.github.com)
+  --pubdev-url pub.dev API base URL (default: https://pub.dev)
+  --osv-url    OSV API base URL (default: https://api.osv.dev)
+  --stale-months
+               Months since the latest release after which a package counts as stale (default: 24)
+  --suppressions
+               YAML file of findings to waive, with reason and expiry
+  --baseline   Baseline file of known findings that do not fail the check
+  --write-baseline
+               Path to record the findings of this check as a baseline
+  --accept-baseline
+               Treat the findings recorded by --write-baseline as baseline findings in this check, so they do not fail it
+  --rules      YAML file of custom rules written as CEL expressions over the repo
+  --fail-on    Fail if a finding has this severity or higher: critical, high, medium, low, info
+  --max-deps-per-repo, --max-overrides, --max-unbounded, --max-mutable-refs
+               Quality gates, as for a fleet scan
+  --debug-http Log sanitized metadata of every HTTP request and response
+  --rps-github Maximum GitHub API requests per second (default: unlimited)
+  --cache-dir  Directory to cache pub.dev and OSV responses in across runs
+  --user-agent User-Agent header sent with every request
+
+Exit status is 1 when a gate or --fail-on is violated, 2 when the repo could
+not be checked, e.g. it could not be read or its pubspec.yaml not parsed, and
+0 otherwise.`)
+	}
+	parseFlags(fs, args)
+	if err := loadConfig(fs, envPath); err != nil {
+		fmt.Println(err)
+		exit(2)
+	}
+	debugTransport.Debug = *debugHTTP
+	agentTransport.agent = *userAgentFlag
+	limitEnrichment(*pubdevURL, *osvURL)
+	if *rpsGitHub < 0 {
+		fmt.Println("--rps-github must not be negative")
+		exit(2)
+	}
+	limitRate(*apiURL, *rpsGitHub)
+	if *cacheDir != "" {
+		c, err := httpcache.New(*cacheDir)
+		if err != nil {
+			fmt.Printf("Failed to create cache directory: %v\n", err)
+			exit(2)
+		}
+		enrichCache = c
+	}
+
+	if (*localPath == "") == (fs.NArg() != 1) {
+		fmt.Println("Give either owner/repo or --path. Use pgs check --help for usage.")
+		exit(2)
+	}
+	if *failOn != "" && !findings.ValidSeverity(*failOn) {
+		fmt.Printf("Unknown severity %q\n", *failOn)
+		exit(2)
+	}
+	var customRules *rules.Set
+	if *rulesPath != "" {
+		var err error
+		if customRules, err = rules.Load(*rulesPath); err != nil {
+			fmt.Printf("Failed to read rules file: %v\n", err)
+			exit(2)
+		}
+	}
+	var baseline *findings.Baseline
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = findings.LoadBaseline(*baselinePath); err != nil {
+			fmt.Printf("Failed to read baseline: %v\n", err)
+			exit(2)
+		}
+	}
+	var suppressions []findings.Suppression
+	if *suppressionsPath != "" {
+		var err error
+		if suppressions, err = findings.LoadSuppressions(*suppressionsPath); err != nil {
+			fmt.Printf("Failed to read suppressions file: %v\n", err)
+			exit(2)
+		}
+	}
+
+	ctx := context.Background()
+	var res stats.RepoResult
+	if *localPath != "" {
+		var err error
+		if res, err = checkLocal(*localPath, *mainDeps); err != nil {
+			fmt.Printf("Failed to read pubspec.yaml: %v\n", err)
+			exit(2)
+		}
+	} else {
+		token := githubToken()
+		if token == "" {
+			fmt.Println("GITHUB_TOKEN not found in the environment or .env file")
+			exit(2)
+		}
+		// The same transports as a fleet scan: rate limits and Retry-After,
+		// the User-Agent and --debug-http.
+		client := github.NewClient(rateLimiter.Client(10*time.Second), token)
+		client.BaseURL = strings.TrimSuffix(*apiURL, "/")
+		res = scanRepo(ctx, client, repolist.Entry{Name: fs.Arg(0)}, scanOptions{mainDeps: *mainDeps, provider: "github"})
+		if res.Error != "" {
+			fmt.Printf("Failed to check %s: %s\n", fs.Arg(0), res.Error)
+			exit(2)
+		}
+	}
+
+	pd := newPubDev(*pubdevURL)
+	ov := newOSV(*osvURL)
+	limits := gate.Limits{MaxDeps: *maxDeps, MaxOverrides: *maxOverrides, MaxUnbounded: *maxUnbounded, MaxMutableRefs: *maxMutable}
+	extra := findings.FromGate(limits.Check(res))
+	if customRules != nil {
+		extra = append(extra, customRules.Check(res)...)
+		printRuleErrors(customRules)
+	}
+	list, suppressed, err := checkFindings(ctx, res, enrich.New(pd), ov, time.Duration(*staleMonths)*30*24*time.Hour, extra, suppressions)
+	if err != nil {
+		fmt.Printf("Failed to check dependencies: %v\n", err)
+		exit(2)
+	}
+	if baseline != nil {
+		baseline.Apply(list)
+	}
+	if *writeBaselinePath != "" {
+		if err := writeJSON(ctx, *writeBaselinePath, 0, nil, findings.NewBaseline(list, time.Now())); err != nil {
+			fmt.Printf("Failed to write baseline: %v\n", err)
+			exit(2)
+		}
+		if *acceptBaseline {
+			for i := range list {
+				list[i].Baseline = true
+			}
+		}
+	}
+
+	failed := printCheck(res, list, suppressed, *failOn)
+	if failed {
+		exit(1)
+	}
+}
+
+// checkFindings computes the findings of one repo, adds extra ones such as
+// gate findings and applies the suppressions. Expired suppressions are
+// printed.
+func checkFindings(ctx context.Context, res stats.RepoResult, en *enrich.Enricher, ov *osv.Client, staleAfter time.Duration, extra []findings.Finding, suppressions []findings.Suppression) ([]findings.Finding, []findings.Suppressed, error) {
+	ranked, err := assessRisk(ctx, stats.Stats{Repos: []stats.RepoResult{res}}, "", en, ov, risk.DefaultWeights, staleAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := append(findings.FromRisk(ranked), extra...)
+	if suppressions == nil {
+		return list, nil, nil
+	}
+	list, suppressed, expired := findings.Suppress(list, suppressions, time.Now())
+	for _, s := range expired {
+		fmt.Printf("⚠️  Suppression for %s %s %s expired on %s\n", s.Repo, s.Rule, s.Package, s.Expires)
+	}
+	return list, suppressed, nil
+}
+
+// printRuleErrors warns about custom rules that could not be evaluated.
+func printRuleErrors(set *rules.Set) {
+	for _, e := range set.Errors() {
+		fmt.Printf("⚠️  Rule %s failed on %d repos, first %s: %v\n", e.Rule, e.Repos, e.Repo, e.Err)
+	}
+}
+
+// checkLocal reads the pubspec of a local checkout. The repo is named after
+// the directory.
+func checkLocal(dir string, mainDeps bool) (stats.RepoResult, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return stats.RepoResult{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(abs, "pubspec.yaml"))
+	if err != nil {
+		return stats.RepoResult{}, err
+	}
+	ps, err := pubspec.Parse(string(data))
+	if err != nil {
+		return stats.RepoResult{}, err
+	}
+	res := stats.RepoResult{Repo: filepath.Base(abs)}
+	manifest.Apply(&res, manifest.FromPubspec(ps), mainDeps)
+	return res, nil
+}
+
+// printCheck writes the findings of one repo, with the remediation hint of
+// each, and reports whether the check failed.
+func printCheck(res stats.RepoResult, list []findings.Finding, suppressed []findings.Suppressed, failOn string) bool {
+	fmt.Printf("%s: %d dependencies, %d dev dependencies, %d overrides\n",
+		res.Repo, len(res.Dependencies), len(res.DevDependencies), len(res.DependencyOverrides))
+	findings.SortBySeverity(list)
+	for _, f := range list {
+		msg := f.Message
+		if f.Baseline {
+			msg += " (baseline)"
+		}
+		fmt.Printf("  %-8s %-24s %s\n", strings.ToUpper(f.Severity), f.Rule, msg)
+		if f.Remediation != "" {
+			fmt.Printf("  %-8s %-24s → %s\n", "", "", f.Remediation)
+		}
+	}
+	for _, s := range suppressed {
+		fmt.Printf("  %-8s %-24s %s (suppressed: %s)\n", "-", s.Rule, s.Message, s.Reason)
+	}
+
+	failing := len(findings.Failing(list, failOn))
+	switch {
+	case failing > 0:
+		fmt.Printf("❌ %d findings, %d failing the check\n", len(list), failing)
+		return true
+	case len(list) == 0:
+		fmt.Println("✅ No findings")
+	default:
+		fmt.Printf("✅ %d findings, none failing the check\n", len(list))
+	}
+	return false
+}
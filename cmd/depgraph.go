@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/depgraph"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// depResolver looks up the dependencies of locked packages on pub.dev. A
+// package's listing holds the pubspecs of all its versions, so it is
+// fetched once per package and cached for every repo.
+type depResolver struct {
+	client *pubdev.Client
+
+	mu           sync.Mutex
+	cache        map[string]map[string][]string
+	wait         map[string]chan struct{}
+	hits, misses int
+}
+
+func newDepResolver(client *pubdev.Client) *depResolver {
+	return &depResolver{client: client, cache: map[string]map[string][]string{}, wait: map[string]chan struct{}{}}
+}
+
+// deps returns the dependencies of the package at version. Packages that
+// are not hosted, e.g. sdk or private ones, have none.
+func (d *depResolver) deps(ctx context.Context, name, version string) []string {
+	d.mu.Lock()
+	if versions, done := d.cache[name]; done {
+		d.hits++
+		d.mu.Unlock()
+		return versions[version]
+	}
+	if ch, busy := d.wait[name]; busy {
+		d.hits++
+		d.mu.Unlock()
+		<-ch
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.cache[name][version]
+	}
+	d.misses++
+	ch := make(chan struct{})
+	d.wait[name] = ch
+	d.mu.Unlock()
+
+	versions := map[string][]string{}
+	pkg, err := d.client.Package(ctx, name)
+	switch {
+	case err == nil:
+		for _, rel := range pkg.Versions {
+			section, _ := rel.Pubspec["dependencies"].(map[string]interface{})
+			versions[rel.Version] = pubspec.Names(section)
+		}
+	case !errors.Is(err, pubdev.ErrNotFound):
+		fmt.Printf("Error fetching dependencies of %s: %v\n", name, err)
+	}
+
+	d.mu.Lock()
+	d.cache[name] = versions
+	delete(d.wait, name)
+	d.mu.Unlock()
+	close(ch)
+	return versions[version]
+}
+
+// resolveGraph records which direct dependencies pull in each locked
+// package of res and how deep its dependency graph is.
+func resolveGraph(ctx context.Context, d *depResolver, res *stats.RepoResult) {
+	var direct []string
+	for name := range res.Constraints {
+		direct = append(direct, name)
+	}
+	sort.Strings(direct)
+	pulledBy, depth := depgraph.Analyze(res.Locked, direct, func(name, version string) []string {
+		return d.deps(ctx, name, version)
+	})
+	if len(pulledBy) > 0 {
+		res.PulledBy = pulledBy
+	}
+	res.LockDepth = depth
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/depsubmit"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// submitDependencies submits the dependencies of every scanned repo to
+// GitHub's dependency graph, at the commit they were scanned at, so
+// Dependabot alerts cover packages GitHub does not detect itself.
+func submitDependencies(ctx context.Context, client *github.Client, s stats.Stats, reportPath string, started time.Time) error {
+	jobID := strconv.FormatInt(started.Unix(), 10)
+	var submitted, failed int
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error != "" {
+			return
+		}
+		name, dir, _ := strings.Cut(r.Repo, ":")
+		owner, repo, _ := strings.Cut(name, "/")
+		file := "pubspec.yaml"
+		if r.Ecosystem != "" && r.Ecosystem != manifest.Pub {
+			if p, err := manifest.Lookup(r.Ecosystem); err == nil {
+				file = p.Files()[0]
+			}
+		}
+		file = path.Join(dir, file)
+
+		// The commit scanned, or for reports without provenance the
+		// branch's head now.
+		var sha string
+		var err error
+		if r.Provenance != nil {
+			sha = r.Provenance.Commit
+		}
+		if sha == "" {
+			sha, err = client.CommitSHA(ctx, owner, repo, r.Branch)
+		}
+		if err != nil {
+			fmt.Printf("Failed to submit dependencies of %s: %v\n", r.Repo, err)
+			failed++
+			return
+		}
+		data, _ := json.Marshal(depsubmit.Build(r, sha, file, jobID, version, time.Now()))
+		if err := client.SubmitDependencies(ctx, owner, repo, data); err != nil {
+			fmt.Printf("Failed to submit dependencies of %s: %v\n", r.Repo, err)
+			failed++
+			return
+		}
+		submitted++
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Dependencies of %d repos submitted to the GitHub dependency graph\n", submitted)
+	if failed > 0 {
+		return fmt.Errorf("%d submission(s) failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// forEachRepo calls fn for every per-repo result of a report, whether kept
+// inline or spilled to a repos file next to reportPath.
+func forEachRepo(s stats.Stats, reportPath string, fn func(stats.RepoResult)) error {
+	for _, r := range s.Repos {
+		fn(r)
+	}
+	if s.ReposFile != "" {
+		return readDetails(reportPath, s.ReposFile, fn)
+	}
+	return nil
+}
+
+// readDetails streams an NDJSON per-repo file written by --low-memory. A
+// relative path that does not exist is resolved against the report's
+// directory. Compressed files (.gz, .zst) are decompressed.
+func readDetails(reportPath, detailsPath string, fn func(stats.RepoResult)) error {
+	f, err := os.Open(detailsPath)
+	if os.IsNotExist(err) && !filepath.IsAbs(detailsPath) {
+		f, err = os.Open(filepath.Join(filepath.Dir(reportPath), filepath.Base(detailsPath)))
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format, _ := compress.ForPath(detailsPath)
+	r, err := compress.NewReader(context.Background(), f, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var r stats.RepoResult
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return err
+		}
+		fn(r)
+	}
+	return sc.Err()
+}
+
+// readReport reads a JSON report, decompressing .gz and .zst files.
+func readReport(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	format, _ := compress.ForPath(path)
+	r, err := compress.NewReader(context.Background(), f, format)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	return data, err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/httpcache"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+)
+
+// enrichCache answers pub.dev and OSV requests from disk when --cache-dir
+// is set.
+var enrichCache *httpcache.Cache
+
+// How long cached responses of the enrichment APIs stay fresh; advisories
+// change more often than package listings.
+var (
+	pubdevTTL = 24 * time.Hour
+	osvTTL    = 6 * time.Hour
+)
+
+// enrichmentClient returns a rate-limited client that answers from the
+// enrichment cache within ttl.
+func enrichmentClient(timeout, ttl time.Duration) *http.Client {
+	c := rateLimiter.Client(timeout)
+	if enrichCache != nil {
+		c.Transport = enrichCache.Wrap(c.Transport, ttl)
+	}
+	return c
+}
+
+func newPubDev(baseURL string) *pubdev.Client {
+	pd := pubdev.NewClient(enrichmentClient(10*time.Second, pubdevTTL))
+	pd.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return pd
+}
+
+func newOSV(baseURL string) *osv.Client {
+	ov := osv.NewClient(enrichmentClient(30*time.Second, osvTTL))
+	ov.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return ov
+}
+
+// networkFlags name the options that only work with network access, so
+// --offline rejects them up front instead of failing mid-run.
+var networkFlags = []string{"backstage-url", "weight-by", "check-published", "submit-dependencies",
+	"defectdojo-url", "jira-url", "dtrack-url", "bigquery-table", "clickhouse-url"}
+
+// checkOffline validates an --offline run: repos come from local checkouts
+// and no option may need the network.
+func checkOffline(fs *flag.FlagSet, localRepos string, refresh bool) error {
+	if localRepos == "" {
+		return errors.New("--offline needs --local-repos to read the repos from")
+	}
+	if refresh {
+		return errors.New("--refresh-enrichment cannot be used with --offline")
+	}
+	var used []string
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range networkFlags {
+			if f.Name == name {
+				used = append(used, "--"+name)
+			}
+		}
+	})
+	if len(used) > 0 {
+		return fmt.Errorf("--offline cannot be used with %s", strings.Join(used, ", "))
+	}
+	return nil
+}
+
+// offlineTransport fails every request, so an --offline run never reaches
+// the network, e.g. for a remote repos list or an uncached response.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("offline: %s %s needs the network", req.Method, req.URL.Redacted())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestCheckOffline(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		environ    map[string]string
+		localRepos string
+		wantErr    string
+	}{
+		{name: "local repos", localRepos: "checkouts"},
+		{name: "no local repos", wantErr: "--offline needs --local-repos"},
+		{name: "local flag", args: []string{"--min", "2"}, localRepos: "checkouts"},
+		{name: "network flag", args: []string{"--jira-url", "https://jira.example.com"}, localRepos: "checkouts", wantErr: "cannot be used with --jira-url"},
+		{name: "network flag from environment", environ: map[string]string{"PUBSCAN_JIRA_URL": "https://jira.example.com"}, localRepos: "checkouts", wantErr: "cannot be used with --jira-url"},
+		{name: "several network flags", args: []string{"--weight-by", "stars"}, environ: map[string]string{"PUBSCAN_DTRACK_URL": "https://dtrack.example.com"}, localRepos: "checkouts", wantErr: "cannot be used with --dtrack-url, --weight-by"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.environ {
+				t.Setenv(name, value)
+			}
+			fs := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			fs.Int("min", 1, "")
+			for _, name := range networkFlags {
+				fs.String(name, "", "")
+			}
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyEnv(fs); err != nil {
+				t.Fatal(err)
+			}
+			err := checkOffline(fs, tt.localRepos, false)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOfflineRejectsNetworkFlagFromEnvironment(t *testing.T) {
+	stdout, _, _ := runPGS(t, []string{"PUBSCAN_JIRA_URL=https://jira.example.com"},
+		"--offline", "--local-repos", t.TempDir(), "--repos", writeFile(t, "repos.txt", "acme/app\n"), "--out", "stats.json")
+	if !strings.Contains(stdout, "--offline cannot be used with --jira-url") {
+		t.Errorf("--jira-url from the environment not rejected:\n%s", stdout)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"pgithub.com/plasmatrip/pubscan/internal/redact"
+)
+
+// envPrefix is the prefix of the environment variables that set flags, e.g.
+// PUBSCAN_REPOS for --repos and PUBSCAN_MAX_OVERRIDES for --max-overrides.
+const envPrefix = "PUBSCAN_"
+
+// envName returns the environment variable of a flag.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig fills the flags of fs that were not given on the command line
+// from PUBSCAN_* variables, then loads the .env file named by envPath, which
+// may itself come from PUBSCAN_ENV. Flags win over the environment, and the
+// environment wins over the .env file. A missing .env file is ignored and an
+// unreadable one only warned about; only one that does not parse fails.
+func loadConfig(fs *flag.FlagSet, envPath *string) error {
+	if err := applyEnv(fs); err != nil {
+		return err
+	}
+	if envPath == nil || *envPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(*envPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		fmt.Printf("⚠️  Ignoring %s: %v\n", *envPath, err)
+		return nil
+	}
+	vars, err := godotenv.Unmarshal(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *envPath, err)
+	}
+	// Variables that are already set are kept, so this only picks up
+	// PUBSCAN_* entries of the file itself.
+	for name, value := range vars {
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, value)
+		}
+	}
+	redact.FromEnv()
+	return applyEnv(fs)
+}
+
+// applyEnv sets every flag of fs that was not given on the command line and
+// has a PUBSCAN_* variable. They are set through fs, so fs.Visit sees them
+// like flags given on the command line, e.g. for the run metadata and the
+// --offline checks.
+func applyEnv(fs *flag.FlagSet) error {
+	given := map[string]bool{"help": true}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || err != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", v, envName(f.Name), e)
+			}
+		}
+	})
+	return err
+}
+
+// githubToken returns PUBSCAN_TOKEN, or GITHUB_TOKEN when it is unset.
+func githubToken() string {
+	if token := os.Getenv("PUBSCAN_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
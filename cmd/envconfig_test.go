@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		environ string
+		wantErr string
+		want    string
+	}{
+		{name: "missing", path: filepath.Join(dir, "missing.env"), want: "default"},
+		{name: "unreadable", path: dir, want: "default"},
+		{name: "malformed", content: "PUBSCAN_REPOS=\"unterminated\n", wantErr: "failed to parse"},
+		{name: "sets flag", content: "PUBSCAN_REPOS=from-file\n", want: "from-file"},
+		{name: "environment wins", content: "PUBSCAN_REPOS=from-file\n", environ: "from-env", want: "from-env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = filepath.Join(dir, tt.name+".env")
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The file's variables are set for good; clear them afterwards.
+			t.Setenv("PUBSCAN_REPOS", tt.environ)
+			if tt.environ == "" {
+				os.Unsetenv("PUBSCAN_REPOS")
+			}
+
+			fs := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			repos := fs.String("repos", "default", "")
+			err := loadConfig(fs, &path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+					t.Fatalf("error %v, want %q naming %s", err, tt.wantErr, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *repos != tt.want {
+				t.Errorf("--repos is %q, want %q", *repos, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/bigquery"
+	"pgithub.com/plasmatrip/pubscan/internal/clickhouse"
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+	"pgithub.com/plasmatrip/pubscan/internal/facts"
+	"pgithub.com/plasmatrip/pubscan/internal/parquet"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// factColumns is the Parquet schema of the fact table.
+var factColumns = func() []parquet.Column {
+	cols := make([]parquet.Column, len(facts.Fields))
+	for i, name := range facts.Fields {
+		cols[i] = parquet.Column{Name: name, Type: parquet.String}
+	}
+	cols[0].Type = parquet.Date
+	cols[len(cols)-1].Optional = true
+	return cols
+}()
+
+// writeParquet exports the fact table of a report, one row per repo and
+// declared dependency, as a Parquet file written like the report.
+func writeParquet(ctx context.Context, s stats.Stats, reportPath, path string, date time.Time, keep int, enc *encrypt.Age) error {
+	if enc != nil {
+		path += encrypt.Ext
+	}
+	rows := 0
+	err := writeOutput(ctx, path, keep, enc, func(w io.Writer) error {
+		pw, err := parquet.NewWriter(w, factColumns)
+		if err != nil {
+			return err
+		}
+		var writeErr error
+		if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+			for _, row := range facts.Rows(r, date) {
+				if writeErr == nil {
+					writeErr = pw.Write(row.Values())
+					rows++
+				}
+			}
+		}); err != nil {
+			return err
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+		return pw.Close()
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fact table with %d rows saved to %s\n", rows, path)
+	return nil
+}
+
+// factSchema is the BigQuery schema of the fact table.
+var factSchema = func() []bigquery.Field {
+	fields := make([]bigquery.Field, len(facts.Fields))
+	for i, name := range facts.Fields {
+		fields[i] = bigquery.Field{Name: name, Type: "STRING", Mode: "REQUIRED"}
+	}
+	fields[0].Type = "DATE"
+	fields[len(fields)-1].Mode = "NULLABLE"
+	return fields
+}()
+
+// newBigQuery returns a client authenticated with BIGQUERY_TOKEN or the
+// service account key at GOOGLE_APPLICATION_CREDENTIALS.
+func newBigQuery(baseURL string) (*bigquery.Client, error) {
+	bq := &bigquery.Client{
+		HTTP:    &http.Client{Timeout: 60 * time.Second},
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Token:   os.Getenv("BIGQUERY_TOKEN"),
+	}
+	if bq.Token != "" {
+		return bq, nil
+	}
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return nil, fmt.Errorf("neither BIGQUERY_TOKEN nor GOOGLE_APPLICATION_CREDENTIALS is set")
+	}
+	var err error
+	bq.Account, err = bigquery.LoadServiceAccount(path)
+	return bq, err
+}
+
+// exportBigQuery streams the fact table of a report into a BigQuery table
+// partitioned by scan date, creating the table or adding missing columns
+// first. It returns the number of rows.
+func exportBigQuery(ctx context.Context, s stats.Stats, reportPath string, bq *bigquery.Client, t bigquery.Table, date time.Time) (int, error) {
+	if err := bq.EnsureTable(ctx, t, factSchema, facts.Fields[0]); err != nil {
+		return 0, err
+	}
+	var batch []bigquery.Row
+	rows := 0
+	var insertErr error
+	flush := func() {
+		if insertErr == nil && len(batch) > 0 {
+			insertErr = bq.Insert(ctx, t, batch)
+			rows += len(batch)
+		}
+		batch = batch[:0]
+	}
+	day := date.UTC().Format("2006-01-02")
+	if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		for _, row := range facts.Rows(r, date) {
+			batch = append(batch, bigquery.Row{InsertID: bigquery.InsertID(day, row.Repo, row.Ecosystem, row.Section, row.Package), JSON: row})
+		}
+		if len(batch) >= bigquery.BatchSize {
+			flush()
+		}
+	}); err != nil {
+		return rows, err
+	}
+	flush()
+	return rows, insertErr
+}
+
+// factsDDL creates the ClickHouse fact table. Rescans of the same day
+// replace their rows when parts are merged.
+const factsDDL = `CREATE TABLE IF NOT EXISTS %s (
+    scan_date  Date,
+    repo       String,
+    branch     String,
+    ecosystem  LowCardinality(String),
+    package    String,
+    purl       String,
+    section    LowCardinality(String),
+    "constraint" String,
+    source     LowCardinality(String),
+    version    Nullable(String)
+) ENGINE = ReplacingMergeTree
+PARTITION BY toYYYYMM(scan_date)
+ORDER BY (ecosystem, package, repo, section, scan_date)`
+
+// exportClickHouse inserts the fact table of a report into a ClickHouse
+// table, creating it as factsDDL first. It returns the number of rows.
+func exportClickHouse(ctx context.Context, s stats.Stats, reportPath string, ch *clickhouse.Client, table string, date time.Time) (int, error) {
+	if err := ch.Exec(ctx, fmt.Sprintf(factsDDL, table)); err != nil {
+		return 0, err
+	}
+	var batch []interface{}
+	rows := 0
+	var insertErr error
+	flush := func() {
+		if insertErr == nil && len(batch) > 0 {
+			insertErr = ch.Insert(ctx, table, batch)
+			rows += len(batch)
+		}
+		batch = batch[:0]
+	}
+	if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		for _, row := range facts.Rows(r, date) {
+			batch = append(batch, row)
+		}
+		if len(batch) >= clickhouse.BatchSize {
+			flush()
+		}
+	}); err != nil {
+		return rows, err
+	}
+	flush()
+	return rows, insertErr
+}
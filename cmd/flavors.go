@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path"
+	"slices"
+	"sort"
+
+	"pgithub.com/plasmatrip/pubscan/internal/flavors"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+)
+
+// detectFlavors returns the flavors of the Flutter app in dir and where they
+// were found: the flavorizr section of the pubspec or a flavorizr.yaml, and
+// main_<flavor>.dart entrypoints in lib/.
+func detectFlavors(ctx context.Context, client *github.Client, owner, repo, ref, dir, pubspecContent string) ([]string, []string, error) {
+	var names, sources []string
+	found := flavors.Flavorizr(pubspecContent, false)
+	if len(found) == 0 {
+		content, err := client.File(ctx, owner, repo, ref, path.Join(dir, flavors.ConfigFile))
+		switch {
+		case err == nil:
+			found = flavors.Flavorizr(content, true)
+		case !errors.Is(err, github.ErrNotFound):
+			return nil, nil, err
+		}
+	}
+	if len(found) > 0 {
+		names, sources = append(names, found...), append(sources, flavors.SourceFlavorizr)
+	}
+
+	lib, err := client.Dir(ctx, owner, repo, ref, path.Join(dir, "lib"))
+	if err != nil && !errors.Is(err, github.ErrNotFound) {
+		return nil, nil, err
+	}
+	if found := flavors.Entrypoints(lib); len(found) > 0 {
+		names, sources = append(names, found...), append(sources, flavors.SourceEntrypoints)
+	}
+	sort.Strings(names)
+	return slices.Compact(names), sources, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+)
+
+// findForks looks up the pub.dev namesakes of the packages used from git
+// and records the sources that are forks of them.
+func findForks(ctx context.Context, pd *pubdev.Client, rep *gitdeps.Report) {
+	upstreams := map[string]string{}
+	for _, src := range rep.Sources {
+		for _, name := range src.Packages {
+			if _, done := upstreams[name]; done {
+				continue
+			}
+			upstreams[name] = ""
+			pkg, err := pd.Package(ctx, name)
+			if errors.Is(err, pubdev.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Error fetching pub.dev metadata for %s: %v\n", name, err)
+				continue
+			}
+			// The repository is where a package is developed; the
+			// homepage is often a website, which names no org.
+			for _, key := range []string{"repository", "homepage"} {
+				u, _ := pkg.Latest.Pubspec[key].(string)
+				if parts := strings.SplitN(gitdeps.Normalize(u), "/", 4); len(parts) >= 3 {
+					// Drop /tree/<branch>/<dir> of monorepo packages.
+					upstreams[name] = strings.Join(parts[:3], "/")
+					break
+				}
+			}
+		}
+	}
+	rep.FindForks(func(name string) string { return upstreams[name] })
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// gitPackage is the name and version a git dependency declares in its own
+// pubspec.
+type gitPackage struct {
+	name, version string
+	err           error
+}
+
+// gitResolver looks up the pubspecs of git dependencies hosted on the
+// scanned GitHub instance. Lookups are cached, since many repos usually
+// point at the same few internal packages.
+type gitResolver struct {
+	client *github.Client
+	host   string
+
+	mu           sync.Mutex
+	cache        map[string]*gitPackage
+	wait         map[string]chan struct{}
+	hits, misses int
+}
+
+func newGitResolver(client *github.Client) *gitResolver {
+	host := "github.com"
+	if u, err := url.Parse(client.BaseURL); err == nil && u.Host != "api.github.com" {
+		host = strings.ToLower(u.Hostname())
+	}
+	return &gitResolver{client: client, host: host, cache: map[string]*gitPackage{}, wait: map[string]chan struct{}{}}
+}
+
+// resolve returns the package at the git source. ok is false for sources on
+// other hosts, which cannot be fetched with the GitHub token.
+func (g *gitResolver) resolve(ctx context.Context, rawURL, ref, dir string) (pkg gitPackage, ok bool) {
+	owner, repo, ok := g.ownerRepo(rawURL)
+	if !ok {
+		return gitPackage{}, false
+	}
+	key := strings.ToLower(owner+"/"+repo) + "@" + ref + ":" + dir
+
+	g.mu.Lock()
+	if p, done := g.cache[key]; done {
+		g.hits++
+		g.mu.Unlock()
+		return *p, true
+	}
+	if ch, busy := g.wait[key]; busy {
+		g.hits++
+		g.mu.Unlock()
+		<-ch
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return *g.cache[key], true
+	}
+	g.misses++
+	ch := make(chan struct{})
+	g.wait[key] = ch
+	g.mu.Unlock()
+
+	p := &gitPackage{}
+	content, err := g.client.File(ctx, owner, repo, ref, path.Join(dir, "pubspec.yaml"))
+	if err != nil {
+		p.err = err
+	} else if ps, err := pubspec.Parse(content); err != nil {
+		p.err = err
+	} else {
+		p.name, p.version = ps.Name, ps.Version
+	}
+
+	g.mu.Lock()
+	g.cache[key] = p
+	delete(g.wait, key)
+	g.mu.Unlock()
+	close(ch)
+	return *p, true
+}
+
+func (g *gitResolver) ownerRepo(rawURL string) (string, string, bool) {
+	host, rest, _ := strings.Cut(gitdeps.Normalize(rawURL), "/")
+	if host != g.host {
+		return "", "", false
+	}
+	owner, repo, ok := strings.Cut(rest, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", false
+	}
+	return owner, repo, true
+}
+
+// resolveGitDeps records the versions of the git dependencies of res and
+// renames entries whose declared name differs from the package name in the
+// source pubspec, so they are counted together with the hosted package.
+func resolveGitDeps(ctx context.Context, g *gitResolver, res *stats.RepoResult, ps pubspec.Pubspec) {
+	renames := map[string]string{}
+	for _, section := range []map[string]interface{}{ps.Dependencies, ps.DevDependencies, ps.DependencyOverrides} {
+		for name, v := range section {
+			gitURL, ref, dir, ok := pubspec.GitSource(v)
+			if !ok {
+				continue
+			}
+			pkg, ok := g.resolve(ctx, gitURL, ref, dir)
+			if !ok {
+				continue
+			}
+			if pkg.err != nil {
+				fmt.Printf("Error resolving git dependency %s of %s: %v\n", name, res.Repo, pkg.err)
+				continue
+			}
+			if pkg.name == "" {
+				continue
+			}
+			if res.GitVersions == nil {
+				res.GitVersions = map[string]string{}
+			}
+			res.GitVersions[pkg.name] = pkg.version
+			if pkg.name != name {
+				renames[name] = pkg.name
+			}
+		}
+	}
+	if len(renames) == 0 {
+		return
+	}
+	for _, list := range []*[]string{&res.Dependencies, &res.DevDependencies, &res.DependencyOverrides} {
+		for i, name := range *list {
+			if to, ok := renames[name]; ok {
+				(*list)[i] = to
+			}
+		}
+		sort.Strings(*list)
+		*list = slices.Compact(*list)
+	}
+	for from, to := range renames {
+		if c, ok := res.Constraints[from]; ok {
+			delete(res.Constraints, from)
+			res.Constraints[to] = c
+		}
+		fmt.Printf("%s: git dependency %s is package %s\n", res.Repo, from, to)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/imports"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+)
+
+// scanImports lists the repo tree, fetches the Dart sources of the package
+// at the entry path, outside its excluded directories, and counts their
+// package imports. It reads at most
+// imports.MaxFiles files and returns how many it read.
+func scanImports(ctx context.Context, client *github.Client, owner, repo, ref string, entry repolist.Entry) (map[string]int, int, error) {
+	tree, truncated, err := client.Tree(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	if truncated {
+		fmt.Printf("⚠️  %s/%s: tree too large to list completely, imports may be missing\n", owner, repo)
+	}
+	counts, files := map[string]int{}, 0
+	for _, e := range tree {
+		if e.Type != "blob" || !imports.IsSource(entry.Path, e.Path) || entry.Excludes(e.Path) {
+			continue
+		}
+		if files == imports.MaxFiles {
+			fmt.Printf("⚠️  %s/%s: more than %d Dart files, the rest are not scanned\n", owner, repo, imports.MaxFiles)
+			break
+		}
+		content, err := client.Blob(ctx, owner, repo, e.SHA)
+		if err != nil {
+			return nil, 0, err
+		}
+		if client.OnFetch != nil {
+			client.OnFetch(e.Path)
+		}
+		files++
+		for name, n := range imports.Parse(content) {
+			counts[name] += n
+		}
+	}
+	return counts, files, nil
+}
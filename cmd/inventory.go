@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/inventory"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/stacks"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// writeInventory exports one row per repo and dependency to path, looking
+// the licenses of pub packages up on pub.dev. With enc the inventory is
+// written encrypted to path.age.
+func writeInventory(ctx context.Context, s stats.Stats, reportPath, path, format string, mapping inventory.Mapping, tax stacks.Taxonomy, en *enrich.Enricher, enc *encrypt.Age) error {
+	names := map[string]bool{}
+	if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if purl.Type(r.Ecosystem) != purl.Pub {
+			return
+		}
+		for _, name := range inventory.Packages(r) {
+			names[name] = true
+		}
+	}); err != nil {
+		return err
+	}
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	licenses := map[string][]string{}
+	for name, info := range en.Packages(ctx, list, workers) {
+		licenses[purl.For("", name)] = info.Licenses
+	}
+
+	if enc != nil {
+		path += encrypt.Ext
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var out io.WriteCloser = f
+	if enc != nil {
+		if out, err = enc.Writer(ctx, f); err != nil {
+			return err
+		}
+	}
+	iw, err := inventory.NewWriter(out, mapping, format)
+	if err != nil {
+		return err
+	}
+	iw.CategoryOf = tax.CategoryOf
+	var writeErr error
+	if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if writeErr == nil {
+			writeErr = iw.WriteRepo(r, licenses)
+		}
+	}); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	rows, err := iw.Close()
+	if err != nil {
+		return err
+	}
+	if enc != nil {
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Inventory with %d rows saved to %s\n", rows, path)
+	return nil
+}
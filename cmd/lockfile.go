@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/lockfile"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// openLockfile opens the pubspec.lock next to the pubspec in dir and
+// returns its path. Packages of a pub workspace are locked at the repo
+// root, which is tried when dir has no lockfile. It returns
+// github.ErrNotFound when the repo commits no lockfile; reading one over
+// max bytes fails with github.ErrTooLarge when max is set.
+func openLockfile(ctx context.Context, client *github.Client, owner, repo, ref, dir string, max int64) (string, io.ReadCloser, error) {
+	file := path.Join(dir, lockfile.File)
+	body, err := client.OpenFile(ctx, owner, repo, ref, file, max)
+	if errors.Is(err, github.ErrNotFound) && dir != "" && dir != "." {
+		file = lockfile.File
+		body, err = client.OpenFile(ctx, owner, repo, ref, file, max)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return file, body, nil
+}
+
+// decodeLockfile decodes a lockfile as it is read from body. With keep, the
+// raw text is returned as well, e.g. for the archive; otherwise it is not
+// held in memory.
+func decodeLockfile(body io.Reader, limits lockfile.Limits, keep bool) (map[string]lockfile.Package, string, error) {
+	var raw strings.Builder
+	if keep {
+		body = io.TeeReader(body, &raw)
+	}
+	pkgs, err := lockfile.Decode(body, limits)
+	if err == nil {
+		// Decode stops after the packages: section; the rest is read too,
+		// for the raw text and so the connection can be reused.
+		_, err = io.Copy(io.Discard, body)
+	}
+	return pkgs, raw.String(), err
+}
+
+// checkHashes compares the archive hashes the scanned lockfiles record with
+// the ones pub.dev advertises for the locked versions. Each package is
+// looked up once.
+func checkHashes(ctx context.Context, s stats.Stats, reportPath string, pd *pubdev.Client) (*lockfile.Integrity, error) {
+	type locked struct{ repo, name, version, sha string }
+	var list []locked
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error != "" {
+			return
+		}
+		for name, sha := range r.LockedSHA256 {
+			list = append(list, locked{r.Repo, name, r.Locked[name], sha})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	published := map[string]map[string]string{} // package -> version -> sha256
+	in := &lockfile.Integrity{}
+	for _, l := range list {
+		hashes, ok := published[l.name]
+		if !ok {
+			pkg, err := pd.Package(ctx, l.name)
+			switch {
+			case errors.Is(err, pubdev.ErrNotFound):
+			case err != nil:
+				return nil, err
+			default:
+				hashes = map[string]string{}
+				for _, rel := range pkg.Versions {
+					hashes[rel.Version] = strings.ToLower(rel.ArchiveSHA256)
+				}
+			}
+			published[l.name] = hashes
+		}
+		in.Checked++
+		if want, ok := hashes[l.version]; !ok || want != l.sha {
+			in.Mismatches = append(in.Mismatches, lockfile.HashMismatch{Repo: l.repo, Package: l.name, Version: l.version, Locked: l.sha, Published: want})
+		}
+	}
+	in.Sort()
+	return in, nil
+}
+
+// lockedBehind finds, per repo with a lockfile, the hosted direct
+// dependencies locked behind a newer release their constraint allows, and
+// counts the hosted direct dependencies each locks. Like outdatedDeps it
+// reads the latest versions from the enricher risk scoring filled.
+func lockedBehind(ctx context.Context, s stats.Stats, reportPath string, en *enrich.Enricher) (map[string][]lockfile.Behind, map[string]int, error) {
+	behind, direct := map[string][]lockfile.Behind{}, map[string]int{}
+	type dep struct{ repo, name, constraint, locked string }
+	var deps []dep
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error != "" || r.Locked == nil {
+			return
+		}
+		for name, c := range lockfile.Hosted(r) {
+			deps = append(deps, dep{r.Repo, name, c, r.Locked[name]})
+			direct[r.Repo]++
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range deps {
+		info, err := en.Package(ctx, d.name)
+		if err != nil || info.NotFound || info.Latest == "" {
+			continue
+		}
+		if lockfile.IsBehind(d.constraint, d.locked, info.Latest) {
+			behind[d.repo] = append(behind[d.repo], lockfile.Behind{Package: d.name, Locked: d.locked, Latest: info.Latest})
+		}
+	}
+	return behind, direct, nil
+}
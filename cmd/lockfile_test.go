@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/lockfile"
+)
+
+func TestDecodeLockfile(t *testing.T) {
+	const lock = "packages:\n  meta:\n    source: hosted\n    version: \"1.9.1\"\nsdks:\n  dart: \">=3.0.0 <4.0.0\"\n"
+	tests := []struct {
+		name    string
+		keep    bool
+		limits  lockfile.Limits
+		wantRaw string
+		wantErr bool
+	}{
+		{name: "streamed", keep: false},
+		{name: "kept for the archive", keep: true, wantRaw: lock},
+		{name: "over the limit", keep: true, limits: lockfile.Limits{MaxBytes: 20}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(lock)
+			pkgs, raw, err := decodeLockfile(body, tt.limits, tt.keep)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pkgs["meta"].Version != "1.9.1" {
+				t.Errorf("packages %+v", pkgs)
+			}
+			if raw != tt.wantRaw {
+				t.Errorf("raw %q, want %q", raw, tt.wantRaw)
+			}
+			if body.Len() != 0 {
+				t.Errorf("%d bytes left unread", body.Len())
+			}
+		})
+	}
+}
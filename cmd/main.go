@@ -1,267 +1,2051 @@
 package main
 
 import (
+	"bufio"
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
-	"sort"
+	"path"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/joho/godotenv"
-	"gopkg.in/yaml.v3"
+	"pgithub.com/plasmatrip/pubscan/internal/adoption"
+	"pgithub.com/plasmatrip/pubscan/internal/anonymize"
+	"pgithub.com/plasmatrip/pubscan/internal/backstage"
+	"pgithub.com/plasmatrip/pubscan/internal/bigquery"
+	"pgithub.com/plasmatrip/pubscan/internal/clickhouse"
+	"pgithub.com/plasmatrip/pubscan/internal/codeowners"
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/defectdojo"
+	"pgithub.com/plasmatrip/pubscan/internal/depcount"
+	"pgithub.com/plasmatrip/pubscan/internal/depgraph"
+	"pgithub.com/plasmatrip/pubscan/internal/dtrack"
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/flavors"
+	"pgithub.com/plasmatrip/pubscan/internal/funding"
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/hosted"
+	"pgithub.com/plasmatrip/pubscan/internal/httpcache"
+	"pgithub.com/plasmatrip/pubscan/internal/imports"
+	"pgithub.com/plasmatrip/pubscan/internal/inventory"
+	"pgithub.com/plasmatrip/pubscan/internal/jira"
+	"pgithub.com/plasmatrip/pubscan/internal/l10n"
+	"pgithub.com/plasmatrip/pubscan/internal/lints"
+	"pgithub.com/plasmatrip/pubscan/internal/lockfile"
+	"pgithub.com/plasmatrip/pubscan/internal/majors"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/native"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/publish"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+	"pgithub.com/plasmatrip/pubscan/internal/ratelimit"
+	"pgithub.com/plasmatrip/pubscan/internal/redact"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/rules"
+	"pgithub.com/plasmatrip/pubscan/internal/stacks"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+	"pgithub.com/plasmatrip/pubscan/internal/toolchain"
+	"pgithub.com/plasmatrip/pubscan/internal/typosquat"
+	"pgithub.com/plasmatrip/pubscan/internal/updates"
 )
 
-// --- Structures ---
-
-type Branch struct {
-	Name   string `json:"name"`
-	Commit struct {
-		Commit struct {
-			Author struct {
-				Date time.Time `json:"date"`
-			} `json:"author"`
-		} `json:"commit"`
-	} `json:"commit"`
-}
+const workers = 5
 
-type FileContent struct {
-	Content string `json:"content"`
-}
+// report is the JSON written by a scan: the aggregated statistics plus the
+// sections of the fleet-wide analyses.
+type report struct {
+	stats.Stats
+	MajorSplits []majors.Split        `json:"major_splits,omitempty"`
+	Risk        []risk.RepoRisk       `json:"risk,omitempty"`
+	Findings    []findings.Finding    `json:"findings,omitempty"`
+	LintSets    []lints.Set           `json:"lint_sets,omitempty"`
+	Toolchains  *toolchain.Report     `json:"toolchains,omitempty"`
+	Codegen     *stacks.CodegenReport `json:"codegen,omitempty"`
+	Stacks      *stacks.StackReport   `json:"stack_report,omitempty"`
+	GitDeps     *gitdeps.Report       `json:"git_dependencies,omitempty"`
+	Hosted      *hosted.Report        `json:"hosted_servers,omitempty"`
+	DepCounts   *depcount.Report      `json:"dependency_counts,omitempty"`
+	Publishing  *publish.Report       `json:"publishing,omitempty"`
+	Updates     *updates.Report       `json:"dependency_updates,omitempty"`
+	LockDrift   *lockfile.Report      `json:"lockfile_drift,omitempty"`
+	Integrity   *lockfile.Integrity   `json:"lockfile_integrity,omitempty"`
+	Transitive  *depgraph.Report      `json:"transitive_dependencies,omitempty"`
+	Imports     *imports.Report       `json:"imports,omitempty"`
+	Flavors     *flavors.Report       `json:"flavors,omitempty"`
+	Native      *native.Report        `json:"native_dependencies,omitempty"`
+	Tech        *manifest.Composition `json:"technologies,omitempty"`
 
-type Pubspec struct {
-	Dependencies        map[string]interface{} `yaml:"dependencies"`
-	DevDependencies     map[string]interface{} `yaml:"dev_dependencies"`
-	DependencyOverrides map[string]interface{} `yaml:"dependency_overrides"`
-}
+	Suppressed     []findings.Suppressed `json:"suppressed_findings,omitempty"`
+	GateViolations []gate.Violation      `json:"gate_violations,omitempty"`
+
+	// InputOverlaps are the repos listed more than once by the inputs,
+	// which were scanned once.
+	InputOverlaps []repolist.Overlap `json:"input_overlaps,omitempty"`
 
-type Stats struct {
-	Dependencies        []map[string]interface{} `json:"dependencies"`
-	DevDependencies     []map[string]interface{} `json:"dev_dependencies"`
-	DependencyOverrides []map[string]interface{} `json:"dependency_overrides"`
+	Meta *reportMeta `json:"meta,omitempty"`
 }
 
-// --- Core logic ---
+// --- Main logic ---
 
-func getLatestBranch(ctx context.Context, client *http.Client, owner, repo, token string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/branches", owner, repo)
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "token "+token)
+// debugTransport wraps every HTTP client without its own transport, masking
+// credentials in transport errors; --debug-http turns on request logging.
+var debugTransport = &redact.Transport{Base: http.DefaultTransport}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+// agentTransport sets the User-Agent of every HTTP client without its own
+// transport; --user-agent replaces the default.
+var agentTransport = &userAgentTransport{base: debugTransport, agent: userAgent()}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to get branches: %s (%s)", resp.Status, string(body))
-	}
+// rateLimiter throttles the API clients per host and retries the requests
+// hosts reject with 429 or Retry-After.
+var rateLimiter = ratelimit.New()
 
-	var branches []Branch
-	if err := json.NewDecoder(resp.Body).Decode(&branches); err != nil {
-		return "", err
-	}
-	if len(branches) == 0 {
-		return "", fmt.Errorf("no branches found")
-	}
+// Default request rates per second of the enrichment APIs; GitHub is not
+// limited unless --rps-github is set.
+const (
+	pubdevRate = 10
+	osvRate    = 5
+)
 
-	sort.Slice(branches, func(i, j int) bool {
-		return branches[i].Commit.Commit.Author.Date.After(branches[j].Commit.Commit.Author.Date)
-	})
+// limitRate allows rps requests per second to the host of baseURL, in
+// bursts of up to one second's worth. 0 removes the limit.
+func limitRate(baseURL string, rps float64) {
+	rateLimiter.Limit(baseURL, rps, int(math.Ceil(rps)))
+}
 
-	return branches[0].Name, nil
+// limitEnrichment sets the default pub.dev and OSV rates for the given
+// endpoints.
+func limitEnrichment(pubdevURL, osvURL string) {
+	limitRate(pubdevURL, pubdevRate)
+	limitRate(osvURL, osvRate)
 }
 
-func getPubspec(ctx context.Context, client *http.Client, owner, repo, branch, token string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/pubspec.yaml?ref=%s", owner, repo, branch)
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "token "+token)
+// clock is the time scans judge staleness and expiry against; --as-of
+// fixes it to repeat past scans.
+var clock = time.Now
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+// apiMeter counts the requests of every HTTP client per provider.
+var apiMeter = quota.NewMeter()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch pubspec.yaml from %s/%s (%s)", owner, repo, resp.Status)
-	}
+// flushOutput writes pending redacted output; see exit.
+var flushOutput = func() {}
 
-	var file FileContent
-	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
-		return "", err
-	}
+// exit flushes the output before exiting with code, since os.Exit skips
+// deferred calls.
+func exit(code int) {
+	flushOutput()
+	os.Exit(code)
+}
 
-	data, err := base64.StdEncoding.DecodeString(file.Content)
-	if err != nil {
-		return "", err
+// parseFlags parses args as flag.ExitOnError would, but exits through exit
+// so that the usage and the error are flushed: 0 for -h, 2 for bad flags.
+// Flag sets must use flag.ContinueOnError.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		exit(0)
+	} else if err != nil {
+		exit(2)
 	}
-	return string(data), nil
 }
 
-func parsePubspec(content string) Pubspec {
-	var ps Pubspec
-	_ = yaml.Unmarshal([]byte(content), &ps)
-	return ps
-}
+func main() {
+	// Everything printed goes through the redaction filter, so tokens
+	// quoted in errors never reach logs.
+	redact.FromEnv()
+	stdoutIsTerminal = isTerminal(os.Stdout)
+	flushStdout, flushStderr := redact.Stdout(), redact.Stderr()
+	flushOutput = func() {
+		flushStdout()
+		flushStderr()
+	}
+	defer flushOutput()
+	http.DefaultTransport = apiMeter.Wrap(agentTransport)
 
-// --- Main logic ---
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		case "repos":
+			runRepos(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "bundle":
+			runBundle(os.Args[2:])
+			return
+		case "version":
+			runVersion()
+			return
+		}
+	}
 
-func main() {
 	envPath := flag.String("env", "", "Path to .env file containing GITHUB_TOKEN")
-	reposPath := flag.String("repos", "", "Path to file with list of GitHub repositories")
+	reposPath := flag.String("repos", "", "Paths or http(s) URLs of lists of GitHub repositories, comma-separated, - for stdin (auth header in REPOS_AUTH_HEADER)")
 	outPath := flag.String("out", "", "Path to output JSON file")
+	outFormat := flag.String("format", "json", "Output format: json for the report, or ndjson to stream per-repo results")
+	keep := flag.Int("keep", 0, "Number of previous reports to keep as .bak files")
+	encryptTo := flag.String("encrypt-to", "", "Comma-separated age recipients to encrypt the report, per-repo file and inventory for")
+	checksum := flag.Bool("checksum", false, "Write a SHA-256 checksum file next to the report")
+	signTool := flag.String("sign", "", "Sign the report with cosign or minisign (key path in SIGNING_KEY)")
 	minUsage := flag.Int("min", 1, "Minimum usage count for package to be included in statistics")
 	helpFlag := flag.Bool("help", false, "Show usage help")
+	versionFlag := flag.Bool("version", false, "Print the version and build information")
 	mainDeps := flag.Bool("maindeps", false, "Only count main dependencies")
-	flag.Parse()
+	ecosystem := flag.String("ecosystem", manifest.Pub, "Manifest to scan: pub, npm, go, cargo, pypi, auto to detect it per repo, or all to scan every manifest in each repo")
+	otherManifests := flag.Bool("other-manifests", false, "List the manifests of other ecosystems in repos without a pubspec")
+	includeExamples := flag.Bool("include-examples", false, "With --ecosystem all, also scan the manifests in example and test fixture directories")
+	verbose := flag.Bool("verbose", false, "Print more detail on each repo, such as the example manifests --ecosystem all skips")
+	submodules := flag.Bool("submodules", false, "Also scan the repos included as git submodules, under the labels of the including repo")
+	lowMemory := flag.Bool("low-memory", false, "Stream the repos file and spill per-repo results to disk")
+	apiURL := flag.String("api-url", github.DefaultBaseURL, "GitHub API base URL (for GitHub Enterprise Server)")
+	reposFormat := flag.String("repos-format", "", "Repos file format: text or csv (default: detected from extension)")
+	backstageURL := flag.String("backstage-url", "", "Read repos from a Backstage catalog, in addition to --repos (token in BACKSTAGE_TOKEN)")
+	backstageTags := flag.String("backstage-tags", "dart,flutter", "Comma-separated component tags to select from the Backstage catalog")
+	backstageDocs := flag.String("backstage-docs-dir", "", "Directory to write a TechDocs dependencies page per Backstage component")
+	weightColumn := flag.String("weight-column", "", "CSV column holding a weight per repo")
+	weightBy := flag.String("weight-by", "", "Weight repos by GitHub metadata: stars or topics")
+	weightTiers := flag.String("weight-tiers", "", "Topic weights for --weight-by topics, e.g. tier-1=10,prototype=0.1")
+	reposColumn := flag.String("repos-column", "", "CSV column holding owner/repo (default: auto-detect)")
+	shardFlag := flag.String("shard", "", "Only scan shard K of N (format: K/N)")
+	resolveGit := flag.Bool("resolve-git", false, "Fetch the pubspecs of git dependencies to confirm their package names and versions")
+	forksFlag := flag.Bool("forks", false, "Flag git dependencies on forks of pub.dev packages")
+	hostedAllowlist := flag.String("hosted-allowlist", "", "Comma-separated package servers hosted: dependencies may use besides pub.dev; others are flagged")
+	typosquats := flag.Bool("typosquats", false, "Flag dependencies whose names look like popular pub.dev packages")
+	popularPackages := flag.String("popular-packages", "", "File of package names to compare against with --typosquats, in addition to the built-in list")
+	typosquatAllow := flag.String("typosquat-allow", "", "Comma-separated package names --typosquats never flags")
+	taxonomyPath := flag.String("taxonomy", "", "YAML file extending the package-to-category taxonomy")
+	flutterPins := flag.Bool("flutter-pins", false, "Collect Flutter versions pinned by FVM or .tool-versions")
+	verifyHashes := flag.Bool("verify-hashes", false, "Check the archive hashes recorded in pubspec.lock against pub.dev (implies --lockfile)")
+	maxLockMB := flag.Int("max-lockfile-mb", lockfile.DefaultMaxBytes>>20, "Largest pubspec.lock read, in MB (0: unlimited)")
+	maxLockPackages := flag.Int("max-lockfile-packages", lockfile.DefaultMaxPackages, "Most packages read from a pubspec.lock (0: unlimited)")
+	lockfileFlag := flag.Bool("lockfile", false, "Fetch pubspec.lock and report versions that drifted from the pubspec constraints")
+	depGraph := flag.Bool("dep-graph", false, "Resolve the lockfile dependency graph on pub.dev for fan-in and depth metrics (implies --lockfile)")
+	importsFlag := flag.Bool("imports", false, "Scan the Dart imports under lib/ and bin/ for unused and undeclared dependencies (one request per file)")
+	flavorsFlag := flag.Bool("flavors", false, "Detect build flavors from flutter_flavorizr configs and main_<flavor>.dart entrypoints")
+	nativeFlag := flag.Bool("native", false, "Inventory Android (build.gradle) and iOS (Podfile.lock) dependencies of Flutter apps")
+	workflowsFlag := flag.Bool("workflows", false, "Collect the Flutter and Dart versions GitHub Actions workflows install")
+	updateBots := flag.Bool("update-bots", false, "Report which repos lack Dependabot or Renovate updates for pub dependencies")
+	lintsFlag := flag.Bool("lints", false, "Report the lint rule sets each repo's analysis_options.yaml uses")
+	codeOwners := flag.Bool("codeowners", false, "Attribute repos to teams from their CODEOWNERS file")
+	teamsDir := flag.String("teams-dir", "", "Directory to write one JSON report per team (implies --codeowners)")
+	internalPkgs := flag.String("internal-packages", "", "YAML file listing internal packages to report adoption for")
+	adoptionOut := flag.String("adoption-out", "", "Path to adoption report (default: <out>.adoption.json)")
+	fundingOut := flag.String("funding-out", "", "Path to write funding links of the most-used community packages")
+	fundingTop := flag.Int("funding-top", 50, "Number of community packages in the funding report")
+	fundingNotes := flag.String("funding-notes", "", "Path to write Markdown outreach notes for the packages of the funding report")
+	pubdevURL := flag.String("pubdev-url", pubdev.DefaultBaseURL, "pub.dev API base URL")
+	riskFlag := flag.Bool("risk", false, "Score and rank repos by dependency risk (queries pub.dev and OSV)")
+	riskWeights := flag.String("risk-weights", "", "Risk signal weights, e.g. vulnerable=10,discontinued=5,unbounded=2,stale=1,overrides=1")
+	staleMonths := flag.Int("stale-months", 24, "Months since the latest release after which a package counts as stale")
+	asOf := flag.String("as-of", "", "Judge staleness and suppression expiry as of this RFC 3339 time instead of now")
+	cacheDir := flag.String("cache-dir", "", "Directory to cache pub.dev and OSV responses in across runs")
+	pubdevTTLFlag := flag.Duration("pubdev-ttl", pubdevTTL, "How long cached pub.dev responses are reused")
+	osvTTLFlag := flag.Duration("osv-ttl", osvTTL, "How long cached OSV responses are reused")
+	refreshEnrichment := flag.Bool("refresh-enrichment", false, "Fetch pub.dev and OSV data again instead of using the cache")
+	offline := flag.Bool("offline", false, "Make no network calls: read repos from --local-repos and enrichment data from --cache-dir")
+	localRepos := flag.String("local-repos", "", "Directory of repo checkouts (owner/repo) to scan instead of the GitHub API")
+	captureDir := flag.String("capture-dir", "", "Directory to save the fetched repo files to, for pgs bundle export")
+	archivePubspecs := flag.String("archive-pubspecs", "", "Directory to save every fetched pubspec.yaml and pubspec.lock to, with the commit they were read at")
+	osvURL := flag.String("osv-url", osv.DefaultBaseURL, "OSV API base URL")
+	ddURL := flag.String("defectdojo-url", "", "DefectDojo URL to export findings to (token in DEFECTDOJO_TOKEN; implies --risk)")
+	ddProductType := flag.String("defectdojo-product-type", "pubscan", "DefectDojo product type for auto-created products")
+	ddProduct := flag.String("defectdojo-product", "pubscan", "DefectDojo product name; may contain {repo}, {owner}, {name}")
+	jiraURL := flag.String("jira-url", "", "Jira URL to open tickets for repos with violations (JIRA_TOKEN, optional JIRA_USER; implies --risk)")
+	jiraProject := flag.String("jira-project", "", "Jira project key for tickets")
+	jiraIssueType := flag.String("jira-issue-type", "Bug", "Jira issue type for tickets")
+	jiraMinSeverity := flag.String("jira-min-severity", findings.SeverityMedium, "Lowest finding severity that opens a ticket")
+	maxDeps := flag.Int("max-deps-per-repo", gate.Disabled, "Fail if a repo declares more main dependencies than this")
+	maxOverrides := flag.Int("max-overrides", gate.Disabled, "Fail if a repo declares more dependency overrides than this")
+	maxUnbounded := flag.Int("max-unbounded", gate.Disabled, "Fail if a repo has more constraints without upper bound than this")
+	maxMutable := flag.Int("max-mutable-refs", gate.Disabled, "Fail if a repo has more git dependencies on a branch instead of a tag or commit than this")
+	suppressionsPath := flag.String("suppressions", "", "YAML file of findings to waive, with reason and expiry (implies --risk)")
+	baselinePath := flag.String("baseline", "", "Baseline file of known findings that do not fail the run")
+	writeBaselinePath := flag.String("write-baseline", "", "Path to record the findings of this run as a baseline")
+	acceptBaseline := flag.Bool("accept-baseline", false, "Treat the findings recorded by --write-baseline as baseline findings in this run, so they do not fail it")
+	rulesPath := flag.String("rules", "", "YAML file of custom rules written as CEL expressions over each repo")
+	failOn := flag.String("fail-on", "", "Fail if a finding has this severity or higher: critical, high, medium, low, info")
+	anonymizeFlag := flag.Bool("anonymize", false, "Hash repo and team names in the report (salt in ANONYMIZE_SALT)")
+	bqTable := flag.String("bigquery-table", "", "BigQuery table (project.dataset.table) to stream the fact table into (credentials in GOOGLE_APPLICATION_CREDENTIALS or BIGQUERY_TOKEN)")
+	bqURL := flag.String("bigquery-url", bigquery.DefaultBaseURL, "BigQuery API base URL")
+	chURL := flag.String("clickhouse-url", "", "ClickHouse HTTP URL to insert the fact table into (CLICKHOUSE_USER, CLICKHOUSE_PASSWORD)")
+	chTable := flag.String("clickhouse-table", "pubscan_facts", "ClickHouse table for the fact table, table or database.table")
+	parquetOut := flag.String("parquet-out", "", "Path to write the per-repo, per-dependency fact table as Parquet")
+	planOut := flag.String("plan-out", "", "Path to write a remediation plan grouped by package and team (.md or .csv)")
+	inventoryOut := flag.String("inventory-out", "", "Path to write a flat repo/package inventory for asset systems")
+	inventoryFormat := flag.String("inventory-format", inventory.FormatJSON, "Inventory format: json or tfvars")
+	inventoryFields := flag.String("inventory-fields", "", "Inventory field mapping, e.g. repo=ci_name,package=component")
+	sbomDir := flag.String("sbom-dir", "", "Directory to write a CycloneDX SBOM per repo")
+	dtURL := flag.String("dtrack-url", "", "Dependency-Track URL to upload SBOMs to (API key in DTRACK_API_KEY)")
+	submitDeps := flag.Bool("submit-dependencies", false, "Submit each repo's dependencies to the GitHub dependency graph (token needs contents write access)")
+	ddEngagement := flag.String("defectdojo-engagement", "Dependency scan", "DefectDojo engagement name; may contain {repo}, {owner}, {name}")
+	debugHTTP := flag.Bool("debug-http", false, "Log sanitized metadata of every HTTP request and response")
+	resolveWorkers := flag.Int("resolve-workers", workers, "Number of repos opened on GitHub at a time")
+	fetchWorkers := flag.Int("fetch-workers", workers, "Number of repos whose files are fetched and parsed at a time")
+	enrichWorkers := flag.Int("enrich-workers", workers, "Number of repos whose git dependencies and dependency graph are resolved at a time")
+	rpsGitHub := flag.Float64("rps-github", 0, "Maximum GitHub API requests per second (0: unlimited)")
+	rpsPubDev := flag.Float64("rps-pubdev", pubdevRate, "Maximum pub.dev API requests per second (0: unlimited)")
+	rpsOSV := flag.Float64("rps-osv", osvRate, "Maximum OSV API requests per second (0: unlimited)")
+	rpsBackstage := flag.Float64("rps-backstage", 0, "Maximum Backstage API requests per second (0: unlimited)")
+	langFlag := flag.String("lang", l10n.Default, "Language of the summary and TechDocs pages: "+strings.Join(l10n.Languages(), ", "))
+	noColor := flag.Bool("no-color", false, "Print the summary without colors (also with NO_COLOR set or when stdout is not a terminal)")
+	userAgentFlag := flag.String("user-agent", userAgent(), "User-Agent header sent with every request")
+	githubAPIVersion := flag.String("github-api-version", github.DefaultAPIVersion, "GitHub REST API version sent as X-GitHub-Api-Version")
+	lenient := flag.Bool("lenient", false, "Repair slightly broken pubspecs instead of reporting them as errors")
+	checkPublishedFlag := flag.Bool("check-published", false, "Compare internal packages with the latest version on their publish_to server (token in PUB_SERVER_TOKEN)")
+	packageMetadata := flag.Bool("package-metadata", false, "Check the pub.dev metadata of publishable packages: description, topics, links, screenshots and example")
+	validatePubspec := flag.Bool("validate-pubspec", false, "Check pubspecs against the pubspec schema and report violations as findings")
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	parseFlags(flag.CommandLine, os.Args[1:])
 
+	// Gate violations fail the run. Registered first, the exit runs after
+	// every other deferred cleanup.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			exit(exitCode)
+		}
+	}()
+
+	if *versionFlag {
+		runVersion()
+		return
+	}
 	if *helpFlag {
 		fmt.Println(`Usage:
   pgs --env .env --repos repos.txt --out stats.json [--min N]
+  pgs merge --out merged.json stats-1.json stats-2.json ...
+  pgs serve --report stats.json [--addr :8080]
+  pgs check owner/repo | --path .
+  pgs watch --local .
+  pgs repos validate --repos repos.yaml [--live]
+  pgs report --quarter 2026Q3 --out rollup.md reports/*.json
+  pgs simulate --package dio --to 6.0.0 stats.json
+  pgs bundle export --out audit.tar.gz --capture-dir captured stats.json
+  pgs bundle import --dir audit audit.tar.gz
+  pgs version
 
 Options:
-  --env      Path to .env file containing GITHUB_TOKEN
-  --repos    Path to file with GitHub repositories (format: owner/repo per line)
-  --out      Path to output JSON file
-  --min      Minimum number of package usages to include in stats (default: 1)
-  --maindeps Only count main dependencies
-  --help     Show this help message`)
+  --env        Path to .env file containing GITHUB_TOKEN (flags can also be set as PUBSCAN_* variables)
+  --repos      Paths or http(s) URLs of GitHub repositories lists, comma-separated, - for stdin
+               (format: owner/repo per line; auth header in REPOS_AUTH_HEADER)
+  --out        Path to output JSON file, compressed when it ends in .gz or .zst; may
+               contain {date}, {time}, {org} and {ext}, missing directories are created
+  --format     Output format: json (default) writes the report at the end, ndjson
+               streams one line per repo to --out as it is scanned (- for stdout)
+  --keep       Number of previous reports to keep as .bak files (default: 0)
+  --encrypt-to Comma-separated age recipients (age1... or SSH public keys); the report,
+               its per-repo file and the inventory are written encrypted as .age files
+  --checksum   Write a SHA-256 checksum file (report.json.sha256) next to the report
+  --sign       Sign the report with cosign or minisign (.sig or .minisig file); private
+               key path in SIGNING_KEY, minisign key password in MINISIGN_PASSWORD
+  --min        Minimum number of package usages to include in stats (default: 1)
+  --maindeps   Only count main dependencies
+  --ecosystem  Manifest to scan: pub (pubspec.yaml), npm (package.json), go (go.mod),
+               cargo (Cargo.toml), pypi (pyproject.toml or requirements.txt), auto
+               to detect it per repo, or all to scan every manifest in each repo's
+               tree (default: pub)
+  --other-manifests
+               List the manifests of other ecosystems in repos without a pubspec
+  --include-examples
+               With --ecosystem all, also scan the manifests in example/,
+               test/fixtures/ and similar directories, skipped by default
+  --verbose    Print more detail on each repo, such as the skipped example manifests
+  --submodules Also scan the GitHub repos listed in each repo's .gitmodules, at the
+               pinned commits, under the labels of the including repo
+  --low-memory Stream the repos file and write per-repo results to <out>.repos.ndjson
+  --api-url    GitHub API base URL (default: https://api.github.com)
+  --repos-format
+               Repos file format: text or csv (default: detected from extension)
+  --backstage-url
+               Read repos from a Backstage catalog, in addition to --repos (token in BACKSTAGE_TOKEN)
+  --backstage-tags
+               Component tags to select (default: dart,flutter)
+  --backstage-docs-dir
+               Directory to write a TechDocs dependencies page per component
+  --repos-column
+               CSV column holding owner/repo (default: repo_name, repo.name, full_name, ...)
+  --weight-column
+               CSV column holding a weight per repo
+  --weight-by  Weight repos by GitHub metadata: stars or topics
+  --weight-tiers
+               Topic weights for --weight-by topics, e.g. tier-1=10,prototype=0.1
+  --shard      Only scan shard K of N, e.g. 3/10
+  --resolve-git
+               Fetch the pubspecs of git dependencies to confirm their package names and versions
+  --forks      Flag git dependencies on forks of pub.dev packages
+  --typosquats Flag dependencies whose names look like popular pub.dev packages,
+               such as http_ or htpp
+  --popular-packages
+               File of package names, one per line, to compare against with
+               --typosquats in addition to the built-in list
+  --typosquat-allow
+               Comma-separated package names --typosquats never flags
+  --hosted-allowlist
+               Comma-separated hosts or URLs of the package servers hosted:
+               dependencies may use besides pub.dev; the others are flagged
+  --taxonomy   YAML file extending the package-to-category taxonomy
+  --flutter-pins
+               Collect Flutter versions pinned by FVM or .tool-versions
+  --lockfile   Fetch pubspec.lock and report versions that drifted from the pubspec constraints
+               (with --risk, also lockfiles far behind what the constraints allow)
+  --max-lockfile-mb, --max-lockfile-packages
+               Skip lockfiles over this many MB or packages, 0 for unlimited
+               (defaults: 32, 20000)
+  --verify-hashes
+               Check the archive hashes recorded in pubspec.lock against the ones
+               pub.dev advertises and flag mismatches (implies --lockfile)
+  --dep-graph  Resolve the lockfile dependency graph on pub.dev for fan-in and depth metrics (implies --lockfile)
+  --imports    Scan the Dart imports under lib/ and bin/ for unused and undeclared dependencies (one request per file)
+  --flavors    Detect build flavors from flutter_flavorizr configs and main_<flavor>.dart entrypoints
+  --native     Inventory Android (build.gradle) and iOS (Podfile.lock) dependencies of Flutter apps
+  --workflows  Collect the Flutter and Dart versions GitHub Actions workflows install
+  --update-bots
+               Report which repos lack Dependabot or Renovate updates for pub dependencies
+               (with --risk, compared with how outdated their dependencies are)
+  --lints      Report the lint rule sets each repo's analysis_options.yaml uses
+  --codeowners Attribute repos to teams from their CODEOWNERS file
+  --teams-dir  Directory to write one JSON report per team (implies --codeowners)
+  --internal-packages
+               YAML file listing internal packages to report adoption for
+  --adoption-out
+               Path to adoption report (default: <out>.adoption.json)
+  --funding-out
+               Path to write funding links of the most-used community packages
+  --funding-top
+               Number of community packages in the funding report (default: 50)
+  --funding-notes
+               Path to write Markdown outreach notes for the packages of the funding report
+  --pubdev-url Pub.dev API base URL (default: https://pub.dev)
+  --risk       Score and rank repos by dependency risk (queries pub.dev and OSV)
+  --risk-weights
+               Signal weights (default: vulnerable=10,discontinued=5,unbounded=2,stale=1,overrides=1)
+  --stale-months
+               Months since the latest release after which a package is stale (default: 24)
+  --as-of      Judge staleness and suppression expiry as of this RFC 3339 time instead of now
+  --osv-url    OSV API base URL (default: https://api.osv.dev)
+  --cache-dir  Directory to cache pub.dev and OSV responses in across runs
+  --pubdev-ttl How long cached pub.dev responses are reused (default: 24h)
+  --osv-ttl    How long cached OSV responses are reused (default: 6h)
+  --refresh-enrichment
+               Fetch pub.dev and OSV data again instead of using the cache
+  --offline    Make no network calls: read repos from --local-repos and enrichment data from --cache-dir
+  --local-repos
+               Directory of repo checkouts (owner/repo) to scan instead of the GitHub API
+  --capture-dir
+               Directory to save the fetched repo files to, for pgs bundle export
+  --archive-pubspecs
+               Directory to save every fetched pubspec.yaml and pubspec.lock to, with the commit they were read at
+  --defectdojo-url
+               DefectDojo URL to export findings to (token in DEFECTDOJO_TOKEN; implies --risk)
+  --jira-url   Jira URL to open tickets for repos with violations (JIRA_TOKEN, optional JIRA_USER; implies --risk)
+  --jira-project
+               Jira project key for tickets
+  --jira-issue-type
+               Jira issue type for tickets (default: Bug)
+  --jira-min-severity
+               Lowest finding severity that opens a ticket (default: medium)
+  --max-deps-per-repo
+               Fail if a repo declares more main dependencies than this
+  --max-overrides
+               Fail if a repo declares more dependency overrides than this
+  --max-unbounded
+               Fail if a repo has more constraints without upper bound than this
+  --max-mutable-refs
+               Fail if a repo has more git dependencies on a branch instead of a tag or commit than this
+  --suppressions
+               YAML file of findings to waive, with reason and expiry (implies --risk)
+  --baseline   Baseline file of known findings that do not fail the run
+  --write-baseline
+               Path to record the findings of this run as a baseline
+  --accept-baseline
+               Treat the findings recorded by --write-baseline as baseline findings in this run, so they do not fail it
+  --rules      YAML file of custom rules written as CEL expressions over each repo
+  --fail-on    Fail if a finding has this severity or higher: critical, high, medium, low, info
+  --anonymize  Hash repo and team names in the report for external sharing (salt in ANONYMIZE_SALT)
+  --plan-out   Path to write a remediation plan grouped by package and team (.md or .csv)
+  --inventory-out
+               Path to write a flat repo/package inventory for asset systems
+  --inventory-format
+               Inventory format: json or tfvars (default: json)
+  --inventory-fields
+               Field mapping, e.g. repo=ci_name,package=component (default: all fields)
+  --parquet-out
+               Path to write the per-repo, per-dependency fact table as Parquet
+  --bigquery-table
+               BigQuery table (project.dataset.table) to stream the fact table into;
+               credentials in GOOGLE_APPLICATION_CREDENTIALS or BIGQUERY_TOKEN
+  --bigquery-url
+               BigQuery API base URL (default: https://bigquery.googleapis.com)
+  --clickhouse-url
+               ClickHouse HTTP URL to insert the fact table into (CLICKHOUSE_USER,
+               CLICKHOUSE_PASSWORD)
+  --clickhouse-table
+               ClickHouse table, table or database.table (default: pubscan_facts)
+  --sbom-dir   Directory to write a CycloneDX SBOM per repo
+  --dtrack-url Dependency-Track URL to upload SBOMs to (API key in DTRACK_API_KEY)
+  --submit-dependencies
+               Submit each repo's dependencies to the GitHub dependency graph (token needs
+               contents write access)
+  --defectdojo-product-type
+               Product type for auto-created products (default: pubscan)
+  --defectdojo-product
+               Product name, may contain {repo}, {owner}, {name} (default: pubscan)
+  --defectdojo-engagement
+               Engagement name, may contain {repo}, {owner}, {name} (default: Dependency scan)
+  --debug-http Log sanitized metadata of every HTTP request and response
+  --lenient    Repair slightly broken pubspecs instead of reporting them as errors
+  --check-published
+               Compare internal packages with the latest version on their publish_to server
+               (token in PUB_SERVER_TOKEN)
+  --validate-pubspec
+               Check pubspecs against the pubspec schema and report violations as findings
+  --package-metadata
+               Check the pub.dev metadata of publishable packages: description, topics,
+               links, screenshots and example
+  --resolve-workers, --fetch-workers, --enrich-workers
+               Number of repos each scan stage works on at a time: opening repos,
+               fetching and parsing their files, resolving git dependencies and the
+               dependency graph (default: 5 each)
+  --rps-github, --rps-pubdev, --rps-osv, --rps-backstage
+               Maximum requests per second to each API, 0 for unlimited
+               (defaults: unlimited, 10, 5, unlimited)
+  --lang       Language of the summary and TechDocs pages: de, en, ru (default: en)
+  --no-color   Print the summary without colors (also with NO_COLOR set or when stdout is not a terminal)
+  --user-agent User-Agent header sent with every request
+               (default: pubscan/<version> (+https://github.com/plasmatrip/pubscan))
+  --github-api-version
+               GitHub REST API version sent as X-GitHub-Api-Version (default: 2022-11-28)
+  --version    Print the version and build information
+  --help       Show this help message`)
 		return
 	}
 
-	if *envPath == "" || *reposPath == "" || *outPath == "" {
+	if err := loadConfig(flag.CommandLine, envPath); err != nil {
+		fmt.Println(err)
+		exitCode = 2
+		return
+	}
+	debugTransport.Debug = *debugHTTP
+	agentTransport.agent = *userAgentFlag
+	tr, err := l10n.New(*langFlag)
+	if err != nil {
+		fmt.Println(err)
+		exitCode = 2
+		return
+	}
+	if (*reposPath == "" && *backstageURL == "") || *outPath == "" {
 		fmt.Println("Missing required arguments. Use --help for usage.")
+		exitCode = 2
 		return
 	}
 
-	_ = godotenv.Load(*envPath)
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		fmt.Println("GITHUB_TOKEN not found in .env file")
+	if *offline {
+		if err := checkOffline(flag.CommandLine, *localRepos, *refreshEnrichment); err != nil {
+			fmt.Println(err)
+			exitCode = 2
+			return
+		}
+		debugTransport.Base = offlineTransport{}
+	}
+
+	token := githubToken()
+	if token == "" && *localRepos == "" {
+		fmt.Println("GITHUB_TOKEN not found in the environment or .env file")
+		exitCode = 2
 		return
 	}
 
-	file, err := os.ReadFile(*reposPath)
+	signer, err := newSigner(*signTool)
 	if err != nil {
-		fmt.Printf("Failed to read repos file: %v\n", err)
+		fmt.Println(err)
+		exitCode = 2
+		return
+	}
+	var enc *encrypt.Age
+	if *encryptTo != "" {
+		if enc, err = encrypt.NewAge(*encryptTo); err != nil {
+			fmt.Println(err)
+			exitCode = 2
+			return
+		}
+	}
+	switch *outFormat {
+	case "json":
+	case "ndjson":
+		if format, _ := compress.ForPath(*outPath); format != "" || enc != nil {
+			fmt.Println("--format ndjson cannot be compressed or encrypted")
+			exitCode = 2
+			return
+		}
+	default:
+		fmt.Printf("Unknown output format %q (want json or ndjson)\n", *outFormat)
+		exitCode = 2
 		return
 	}
 
-	repos := strings.Fields(strings.TrimSpace(string(file)))
-	if len(repos) == 0 {
-		fmt.Println("No repositories found in the file.")
+	if *asOf != "" {
+		t, err := time.Parse(time.RFC3339, *asOf)
+		if err != nil {
+			fmt.Printf("Invalid --as-of time: %v\n", err)
+			exitCode = 2
+			return
+		}
+		clock = func() time.Time { return t }
+	}
+	if *failOn != "" && !findings.ValidSeverity(*failOn) {
+		fmt.Printf("Unknown severity %q\n", *failOn)
+		exitCode = 2
+		return
+	}
+	var customRules *rules.Set
+	if *rulesPath != "" {
+		if customRules, err = rules.Load(*rulesPath); err != nil {
+			fmt.Printf("Failed to read rules file: %v\n", err)
+			exitCode = 2
+			return
+		}
+	}
+	var baseline *findings.Baseline
+	if *baselinePath != "" {
+		if baseline, err = findings.LoadBaseline(*baselinePath); err != nil {
+			fmt.Printf("Failed to read baseline: %v\n", err)
+			exitCode = 2
+			return
+		}
+	}
+
+	if *cacheDir != "" {
+		c, err := httpcache.New(*cacheDir)
+		if err != nil {
+			fmt.Printf("Failed to create cache directory: %v\n", err)
+			exitCode = 2
+			return
+		}
+		c.Refresh = *refreshEnrichment
+		c.Offline = *offline
+		enrichCache = c
+		pubdevTTL, osvTTL = *pubdevTTLFlag, *osvTTLFlag
+	}
+
+	if *maxLockMB < 0 || *maxLockPackages < 0 {
+		fmt.Println("--max-lockfile-mb and --max-lockfile-packages must not be negative")
+		exitCode = 2
+		return
+	}
+	if *resolveWorkers < 1 || *fetchWorkers < 1 || *enrichWorkers < 1 {
+		fmt.Println("--resolve-workers, --fetch-workers and --enrich-workers must be at least 1")
+		exitCode = 2
 		return
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	var shard repolist.Shard
+	if *shardFlag != "" {
+		var err error
+		if shard, err = repolist.ParseShard(*shardFlag); err != nil {
+			fmt.Println(err)
+			exitCode = 2
+			return
+		}
+	}
 	ctx := context.Background()
-	mu := sync.Mutex{}
-
-	type counter struct {
-		deps      map[string]int
-		devDeps   map[string]int
-		overrides map[string]int
-	}
-	stats := counter{
-		deps:      map[string]int{},
-		devDeps:   map[string]int{},
-		overrides: map[string]int{},
-	}
-
-	sem := make(chan struct{}, 5)
-	var wg sync.WaitGroup
-	for i, full := range repos {
-		wg.Add(1)
-		go func(i int, full string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			fmt.Printf("[%d/%d] Processing %s...\n", i+1, len(repos), full)
-			parts := strings.Split(full, "/")
-			if len(parts) != 2 {
-				fmt.Printf("Invalid repo format: %s\n", full)
-				return
+	started := time.Now()
+	apiMeter.Register("github", *apiURL)
+	apiMeter.Register("pub.dev", *pubdevURL)
+	apiMeter.Register("osv", *osvURL)
+	for _, l := range []struct {
+		url string
+		rps float64
+	}{{*apiURL, *rpsGitHub}, {*pubdevURL, *rpsPubDev}, {*osvURL, *rpsOSV}, {*backstageURL, *rpsBackstage}} {
+		if l.rps < 0 {
+			fmt.Println("--rps-* rates must not be negative")
+			exitCode = 2
+			return
+		}
+		limitRate(l.url, l.rps)
+	}
+	apiMeter.Register("backstage", *backstageURL)
+	apiMeter.Register("dependency-track", *dtURL)
+	apiMeter.Register("defectdojo", *ddURL)
+	apiMeter.Register("jira", *jiraURL)
+	if *bqTable != "" {
+		apiMeter.Register("bigquery", *bqURL)
+	}
+	apiMeter.Register("clickhouse", *chURL)
+	var reposInputs []string
+	if *reposPath != "" {
+		reposInputs = strings.Split(*reposPath, ",")
+	}
+	for _, p := range reposInputs {
+		if repolist.IsURL(p) {
+			apiMeter.Register("repos list", p)
+		}
+	}
+
+	// Inputs are read in order: the Backstage catalog, then every repos
+	// list.
+	type reposInput struct {
+		name string
+		read func(emit func(repolist.Entry) error) error
+	}
+	var inputs []reposInput
+	var components []backstage.Component
+	if *backstageURL != "" {
+		bs := &backstage.Client{
+			HTTP:    rateLimiter.Client(30 * time.Second),
+			BaseURL: strings.TrimSuffix(*backstageURL, "/"),
+			Token:   os.Getenv("BACKSTAGE_TOKEN"),
+		}
+		var skipped []string
+		var err error
+		components, skipped, err = bs.Components(ctx, strings.Split(*backstageTags, ","))
+		if err != nil {
+			fmt.Printf("Failed to read Backstage catalog: %v\n", err)
+			exitCode = 2
+			return
+		}
+		for _, ref := range skipped {
+			fmt.Printf("Skipping component %s: no %s annotation\n", ref, backstage.SlugAnnotation)
+		}
+		inputs = append(inputs, reposInput{name: "backstage", read: func(emit func(repolist.Entry) error) error {
+			// Components of one repo are not overlaps.
+			seen := map[string]bool{}
+			for _, c := range components {
+				name := repolist.Normalize(c.Repo)
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				if err := emit(repolist.Entry{Name: name}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}})
+	}
+	// No overall timeout: in low-memory mode a long list is read while
+	// the scan runs.
+	listClient := &http.Client{Transport: apiMeter.Wrap(&userAgentTransport{base: &redact.Transport{
+		Base:  &http.Transport{Proxy: http.ProxyFromEnvironment, ResponseHeaderTimeout: 30 * time.Second},
+		Debug: *debugHTTP,
+	}, agent: *userAgentFlag})}
+	cols := repolist.Options{Column: *reposColumn, WeightColumn: *weightColumn}
+	for _, p := range reposInputs {
+		f, err := repolist.Open(ctx, listClient, p, os.Getenv("REPOS_AUTH_HEADER"))
+		if err != nil {
+			fmt.Printf("Failed to read repos file %s: %v\n", redact.String(p), err)
+			exitCode = 2
+			return
+		}
+		defer f.Close()
+		format := repolist.DetectFormat(p, *reposFormat)
+		name := redact.String(p)
+		if p == repolist.Stdin {
+			name = "stdin"
+		}
+		inputs = append(inputs, reposInput{name: name, read: func(emit func(repolist.Entry) error) error {
+			if err := repolist.Read(f, format, cols, emit); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
 			}
-			owner, repo := parts[0], parts[1]
+			return nil
+		}})
+	}
+	// Entries naming a repo and path already read, by the same input or
+	// another, are scanned once; names are normalized by the readers.
+	dedup := repolist.NewDedup()
+	readRepos := func(emit func(repolist.Entry) error) error {
+		for _, in := range inputs {
+			err := in.read(func(e repolist.Entry) error {
+				if !shard.Includes(e.Name) {
+					return nil
+				}
+				e.Input = in.name
+				if first, dup := dedup.Add(in.name, e); dup {
+					at := in.name
+					if e.Line > 0 {
+						at = fmt.Sprintf("%s:%d", in.name, e.Line)
+					}
+					fmt.Printf("Skipping duplicate %s at %s, first listed at %s\n", e.ID(), at, first)
+					return nil
+				}
+				return emit(e)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-			branch, err := getLatestBranch(ctx, client, owner, repo, token)
+	// In low-memory mode the repos file is consumed while the scan runs, so
+	// the total is unknown up front.
+	repoCh := make(chan repolist.Entry)
+	total := 0
+	var listed []string
+	if *lowMemory {
+		go func() {
+			defer close(repoCh)
+			err := readRepos(func(e repolist.Entry) error {
+				repoCh <- e
+				return nil
+			})
 			if err != nil {
-				fmt.Printf("Error getting branch for %s: %v\n", full, err)
-				return
+				fmt.Printf("Failed to read repos file: %v\n", err)
+			}
+		}()
+	} else {
+		var repos []repolist.Entry
+		err := readRepos(func(e repolist.Entry) error {
+			repos = append(repos, e)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Failed to read repos file: %v\n", err)
+			exitCode = 2
+			return
+		}
+		if len(repos) == 0 {
+			if shard.Count > 1 {
+				fmt.Printf("No repositories found in shard %s.\n", shard)
+			} else {
+				fmt.Println("No repositories found in the file.")
+			}
+			return
+		}
+		total = len(repos)
+		for _, r := range repos {
+			listed = append(listed, r.ID())
+		}
+		go func() {
+			defer close(repoCh)
+			for _, r := range repos {
+				repoCh <- r
+			}
+		}()
+	}
+
+	opts := scanOptions{
+		mainDeps:   *mainDeps,
+		codeOwners: *codeOwners || *teamsDir != "",
+		lints:      *lintsFlag,
+		updates:    *updateBots,
+		pins:       *flutterPins,
+		workflows:  *workflowsFlag,
+		lockfile:   *lockfileFlag || *depGraph || *verifyHashes,
+		hashes:     *verifyHashes,
+		lockLimits: lockfile.Limits{MaxBytes: int64(*maxLockMB) << 20, MaxPackages: *maxLockPackages},
+		imports:    *importsFlag,
+		flavors:    *flavorsFlag,
+		native:     *nativeFlag,
+		lenient:    *lenient,
+		validate:   *validatePubspec,
+		metadata:   *packageMetadata,
+		others:     *otherManifests,
+		submodules: *submodules,
+		examples:   *includeExamples,
+		verbose:    *verbose,
+		auth:       newAuthGuard(),
+		provider:   "github",
+	}
+	if *weightBy != "" {
+		var err error
+		if opts.weights, err = newRepoWeights(*weightBy, *weightTiers); err != nil {
+			fmt.Println(err)
+			exitCode = 2
+			return
+		}
+	}
+
+	agg := stats.NewAggregator()
+	agg.TrackTeams = opts.codeOwners
+
+	var tracker *adoption.Tracker
+	if *internalPkgs != "" {
+		var err error
+		if tracker, err = adoption.Load(*internalPkgs); err != nil {
+			fmt.Printf("Failed to read internal packages file: %v\n", err)
+			exitCode = 2
+			return
+		}
+		agg.Use(tracker)
+	}
+
+	majorSplits := majors.NewTracker()
+	majorSplits.CountOnly = *lowMemory
+	agg.Use(majorSplits)
+	updateCoverage := updates.NewTracker()
+	agg.Use(updateCoverage)
+	lockDrift := lockfile.NewTracker()
+	agg.Use(lockDrift)
+	transitive := depgraph.NewTracker()
+	agg.Use(transitive)
+	importUsage := imports.NewTracker()
+	agg.Use(importUsage)
+	flavored := flavors.NewTracker()
+	agg.Use(flavored)
+	natives := native.NewTracker()
+	agg.Use(natives)
+	tech := manifest.NewTracker()
+	agg.Use(tech)
+	lintSets := lints.NewTracker()
+	lintSets.CountOnly = *lowMemory
+	agg.Use(lintSets)
+	toolchains := toolchain.NewTracker()
+	toolchains.CountOnly = *lowMemory
+	agg.Use(toolchains)
+	codegen := stacks.NewCodegenTracker()
+	codegen.CountOnly = *lowMemory
+	agg.Use(codegen)
+	taxonomy := stacks.DefaultTaxonomy()
+	if *taxonomyPath != "" {
+		var err error
+		if taxonomy, err = stacks.LoadTaxonomy(*taxonomyPath); err != nil {
+			fmt.Printf("Failed to read taxonomy file: %v\n", err)
+			exitCode = 2
+			return
+		}
+	}
+	classifier := stacks.NewClassifier(taxonomy.Categories)
+	classifier.CountOnly = *lowMemory
+	agg.Use(classifier)
+	gitDeps := gitdeps.NewTracker()
+	gitDeps.CountOnly = *lowMemory
+	agg.Use(gitDeps)
+	hostedServers := hosted.NewTracker(strings.Split(*hostedAllowlist, ","))
+	hostedServers.CountOnly = *lowMemory
+	agg.Use(hostedServers)
+	depCounts := depcount.NewTracker()
+	agg.Use(depCounts)
+	publishing := publish.NewTracker()
+	agg.Use(publishing)
+
+	var anon *anonymize.Anonymizer
+	if *anonymizeFlag {
+		salt := os.Getenv("ANONYMIZE_SALT")
+		if salt == "" {
+			fmt.Println("ANONYMIZE_SALT not found in the environment or .env file")
+			exitCode = 2
+			return
+		}
+		anon = anonymize.New(salt)
+	}
+
+	// The stream's name is fixed before the scan, so {org} is taken from
+	// the repos list, which is unknown up front in low-memory mode.
+	var stream *resultStream
+	if *outFormat == "ndjson" {
+		*outPath = expandOut(*outPath, started, ownersOf(listed))
+		var err error
+		if stream, err = openStream(*outPath, *keep, anon); err != nil {
+			fmt.Printf("Failed to create %s: %v\n", *outPath, err)
+			return
+		}
+		defer stream.Close()
+		if *outPath == "-" {
+			// Progress and summaries go to stderr, out of the way of the
+			// stream; it is redacted as well.
+			os.Stdout = os.Stderr
+		}
+		agg.Use(stream)
+	}
+
+	// With --anonymize the real per-repo results are spilled to a temporary
+	// file for the integrations below, and only the hashed copy is kept.
+	var detailsPath, spillPath string
+	var spill *bufio.Writer
+	if *lowMemory {
+		// The report's name may depend on the scanned repos, so results are
+		// spilled to a temporary file next to it and moved once it is known.
+		// Results to be encrypted stay out of the output directory.
+		dir := fixedDir(*outPath)
+		if enc != nil {
+			dir = os.TempDir()
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Failed to create %s: %v\n", dir, err)
+			return
+		}
+		df, err := os.CreateTemp(dir, ".pubscan-*.ndjson")
+		if err != nil {
+			fmt.Printf("Failed to create spill file in %s: %v\n", dir, err)
+			return
+		}
+		spillPath = df.Name()
+		defer os.Remove(spillPath)
+		defer df.Close()
+		df.Chmod(0644)
+		spill = bufio.NewWriter(df)
+		agg.SpillTo(spill)
+	}
+
+	client := github.NewClient(rateLimiter.Client(10*time.Second), token)
+	client.BaseURL = strings.TrimSuffix(*apiURL, "/")
+	client.APIVersion = *githubAPIVersion
+	if *localRepos != "" {
+		client.HTTP = &http.Client{Transport: github.LocalTransport{Dir: *localRepos}}
+		opts.provider = "local"
+	}
+	if *captureDir != "" {
+		client.HTTP.Transport = &github.CaptureTransport{Base: client.HTTP.Transport, Dir: *captureDir}
+	}
+	if *resolveGit {
+		opts.git = newGitResolver(client)
+	}
+	if *archivePubspecs != "" {
+		if opts.archive, err = newPubspecArchive(*archivePubspecs); err != nil {
+			fmt.Printf("Failed to create %s: %v\n", *archivePubspecs, err)
+			return
+		}
+	}
+	switch {
+	case *ecosystem == manifest.Auto:
+		opts.detect = true
+	case *ecosystem == manifest.All:
+		opts.all = true
+	case *ecosystem != manifest.Pub:
+		p, err := manifest.Lookup(*ecosystem)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		opts.parser = p
+	}
+	if *depGraph {
+		pd := newPubDev(*pubdevURL)
+		opts.graph = newDepResolver(pd)
+	}
+
+	var (
+		seqMu  sync.Mutex
+		seq    int
+		owners = map[string]bool{}
+	)
+	pipeline := &scanPipeline{
+		client:  client,
+		opts:    opts,
+		workers: stageWorkers{resolve: *resolveWorkers, fetch: *fetchWorkers, enrich: *enrichWorkers},
+		dedup:   dedup,
+		start: func(entry repolist.Entry) {
+			full := entry.ID()
+			seqMu.Lock()
+			seq++
+			n := seq
+			owner, _, _ := strings.Cut(full, "/")
+			owners[owner] = true
+			seqMu.Unlock()
+			if total > 0 {
+				fmt.Printf("[%d/%d] Processing %s...\n", n, total, full)
+			} else {
+				fmt.Printf("[%d] Processing %s...\n", n, full)
+			}
+		},
+		add: func(res stats.RepoResult) {
+			// Errors end up in the report; some quote response bodies.
+			res.Error = redact.String(res.Error)
+			if err := agg.Add(res); err != nil {
+				fmt.Printf("Failed to write details for %s: %v\n", res.Repo, err)
 			}
+		},
+	}
+	pipeline.run(ctx, repoCh)
+	if spill != nil {
+		if err := spill.Flush(); err != nil {
+			fmt.Printf("Failed to write %s: %v\n", spillPath, err)
+			return
+		}
+	}
+
+	if seq == 0 {
+		fmt.Println("No repositories found in the file.")
+		return
+	}
+	if opts.auth.failed() {
+		exitCode = 1
+	}
+	if opts.git != nil {
+		apiMeter.Cache("git dependencies", opts.git.hits, opts.git.misses)
+	}
+	if opts.graph != nil {
+		apiMeter.Cache("dependency graph", opts.graph.hits, opts.graph.misses)
+	}
+
+	finalStats := report{
+		Stats:       agg.Stats(*minUsage),
+		MajorSplits: majorSplits.Splits(),
+		LintSets:    lintSets.Sets(),
+		Toolchains:  toolchains.Report(),
+		Codegen:     codegen.Report(),
+		Stacks:      classifier.Report(),
+		GitDeps:     gitDeps.Report(),
+		Hosted:      hostedServers.Report(),
+		DepCounts:   depCounts.Report(),
+		Publishing:  publishing.Report(),
+		Updates:     updateCoverage.Report(),
+		LockDrift:   lockDrift.Report(),
+		Transitive:  transitive.Report(),
+		Imports:     importUsage.Report(),
+		Flavors:     flavored.Report(),
+		Native:      natives.Report(),
+		Tech:        tech.Report(),
+	}
+	finalStats.InputOverlaps = dedup.Overlaps()
+	finalStats.ReposFile = spillPath
+	if shard.Count > 1 {
+		finalStats.Shard = shard.String()
+	}
+
+	if *dtURL != "" && os.Getenv("DTRACK_API_KEY") == "" {
+		fmt.Println("DTRACK_API_KEY not found in the environment or .env file")
+		return
+	}
+
+	mapping, err := inventory.ParseMapping(*inventoryFields)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var suppressions []findings.Suppression
+	if *suppressionsPath != "" {
+		*riskFlag = true
+		if suppressions, err = findings.LoadSuppressions(*suppressionsPath); err != nil {
+			fmt.Printf("Failed to read suppressions file: %v\n", err)
+			return
+		}
+	}
 
-			content, err := getPubspec(ctx, client, owner, repo, branch, token)
+	if *jiraURL != "" {
+		*riskFlag = true
+		if *jiraProject == "" || os.Getenv("JIRA_TOKEN") == "" {
+			fmt.Println("--jira-project and JIRA_TOKEN in the .env file are required for Jira tickets")
+			return
+		}
+		if !findings.ValidSeverity(*jiraMinSeverity) {
+			fmt.Printf("Unknown severity %q\n", *jiraMinSeverity)
+			return
+		}
+	}
+
+	if *ddURL != "" {
+		*riskFlag = true
+		if os.Getenv("DEFECTDOJO_TOKEN") == "" {
+			fmt.Println("DEFECTDOJO_TOKEN not found in the environment or .env file")
+			return
+		}
+	}
+
+	if *riskFlag {
+		weights, err := risk.ParseWeights(*riskWeights)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		pd := newPubDev(*pubdevURL)
+		ov := newOSV(*osvURL)
+
+		fmt.Println("Scoring repository risk...")
+		en := enrich.New(pd)
+		ranked, err := assessRisk(ctx, finalStats.Stats, *outPath, en, ov, weights, time.Duration(*staleMonths)*30*24*time.Hour)
+		hits, misses := en.CacheStats()
+		apiMeter.Cache("pub.dev", hits, misses)
+		if err != nil {
+			fmt.Printf("Failed to score risk: %v\n", err)
+			return
+		}
+		if finalStats.Updates != nil {
+			outdated, covered, err := outdatedDeps(ctx, finalStats.Stats, *outPath, en)
 			if err != nil {
-				fmt.Printf("Error fetching pubspec.yaml for %s: %v\n", full, err)
+				fmt.Printf("Failed to count outdated dependencies: %v\n", err)
 				return
 			}
-
-			ps := parsePubspec(content)
-			mu.Lock()
-			for k := range ps.Dependencies {
-				stats.deps[k]++
+			finalStats.Updates.SetOutdated(outdated, covered)
+		}
+		if finalStats.LockDrift != nil {
+			behind, direct, err := lockedBehind(ctx, finalStats.Stats, *outPath, en)
+			if err != nil {
+				fmt.Printf("Failed to compare lockfiles with pub.dev: %v\n", err)
+				return
 			}
-			if !*mainDeps {
-				for k := range ps.DevDependencies {
-					stats.devDeps[k]++
-				}
-				for k := range ps.DependencyOverrides {
-					stats.overrides[k]++
+			finalStats.LockDrift.SetBehind(behind, direct)
+		}
+		finalStats.Risk = ranked
+		finalStats.Findings = findings.FromRisk(ranked)
+	}
+	if *checkPublishedFlag && finalStats.Publishing != nil && len(finalStats.Publishing.Internal) > 0 {
+		checkPublished(ctx, finalStats.Publishing)
+	}
+	if *verifyHashes {
+		integrity, err := checkHashes(ctx, finalStats.Stats, *outPath, newPubDev(*pubdevURL))
+		if err != nil {
+			fmt.Printf("Failed to check lockfile hashes: %v\n", err)
+			return
+		}
+		finalStats.Integrity = integrity
+		finalStats.Findings = append(finalStats.Findings, findings.FromIntegrity(integrity.Mismatches)...)
+	}
+	var checks []findings.RepoCheck
+	schemaViolations, suspects := 0, 0
+	if *validatePubspec {
+		checks = append(checks, func(r stats.RepoResult) []findings.Finding {
+			schemaViolations += len(r.SchemaViolations)
+			return findings.SchemaCheck(r)
+		})
+	}
+	limits := gate.Limits{MaxDeps: *maxDeps, MaxOverrides: *maxOverrides, MaxUnbounded: *maxUnbounded, MaxMutableRefs: *maxMutable}
+	if limits.Enabled() {
+		checks = append(checks, func(r stats.RepoResult) []findings.Finding {
+			violations := limits.Check(r)
+			finalStats.GateViolations = append(finalStats.GateViolations, violations...)
+			return findings.FromGate(violations)
+		})
+	}
+	if customRules != nil {
+		checks = append(checks, customRules.Check)
+	}
+	if *forksFlag && finalStats.GitDeps != nil {
+		pd := newPubDev(*pubdevURL)
+		findForks(ctx, pd, finalStats.GitDeps)
+		if forks := finalStats.GitDeps.Forks; len(forks) > 0 {
+			checks = append(checks, findings.ForkCheck(forks))
+		}
+	}
+	if hs := finalStats.Hosted; hs != nil && len(hs.Allowlist) > 0 {
+		checks = append(checks, findings.HostedCheck(hs.Allowlist))
+	}
+	if *typosquats {
+		popular := typosquat.Popular()
+		if *popularPackages != "" {
+			more, err := typosquat.LoadList(*popularPackages)
+			if err != nil {
+				fmt.Printf("Failed to read popular packages file: %v\n", err)
+				exitCode = 2
+				return
+			}
+			popular = append(popular, more...)
+		}
+		check := findings.TyposquatCheck(typosquat.NewChecker(popular, strings.Split(*typosquatAllow, ",")))
+		checks = append(checks, func(r stats.RepoResult) []findings.Finding {
+			found := check(r)
+			suspects += len(found)
+			return found
+		})
+	}
+	if len(checks) > 0 {
+		err := forEachRepo(finalStats.Stats, *outPath, func(r stats.RepoResult) {
+			finalStats.Findings = append(finalStats.Findings, findings.RunChecks(r, checks)...)
+		})
+		if err != nil {
+			fmt.Printf("Failed to check repos: %v\n", err)
+			return
+		}
+		findings.Sort(finalStats.Findings)
+	}
+	if customRules != nil {
+		printRuleErrors(customRules)
+	}
+	if *validatePubspec {
+		fmt.Printf("Found %d pubspec schema violations\n", schemaViolations)
+	}
+	if suspects > 0 {
+		fmt.Printf("⚠️  %d dependencies look like popular pub.dev packages and may be typosquats\n", suspects)
+	}
+	if suppressions != nil {
+		kept, suppressed, expired := findings.Suppress(finalStats.Findings, suppressions, clock())
+		finalStats.Findings, finalStats.Suppressed = kept, suppressed
+		for _, s := range expired {
+			fmt.Printf("⚠️  Suppression for %s %s %s expired on %s; its findings are reported again\n", s.Repo, s.Rule, s.Package, s.Expires)
+		}
+		fmt.Printf("Suppressed %d findings\n", len(suppressed))
+	}
+	if baseline != nil {
+		fresh, fixed := baseline.Apply(finalStats.Findings)
+		fmt.Printf("%d new findings, %d in the baseline; %d baseline findings fixed\n", fresh, len(finalStats.Findings)-fresh, fixed)
+	}
+	if *writeBaselinePath != "" {
+		if err := writeJSON(ctx, *writeBaselinePath, 0, nil, findings.NewBaseline(finalStats.Findings, clock())); err != nil {
+			fmt.Printf("Failed to write baseline: %v\n", err)
+			return
+		}
+		if *acceptBaseline {
+			for i := range finalStats.Findings {
+				finalStats.Findings[i].Baseline = true
+			}
+		}
+		fmt.Printf("Recorded %d findings in %s\n", len(finalStats.Findings), *writeBaselinePath)
+	}
+
+	if *ddURL != "" {
+		dd := &defectdojo.Client{
+			HTTP:    &http.Client{Timeout: 60 * time.Second},
+			BaseURL: strings.TrimSuffix(*ddURL, "/"),
+			Token:   os.Getenv("DEFECTDOJO_TOKEN"),
+		}
+		mapping := defectdojo.Mapping{
+			ProductType: *ddProductType,
+			Product:     *ddProduct,
+			Engagement:  *ddEngagement,
+			TestTitle:   "pubscan",
+		}
+		n, err := dd.Export(ctx, mapping, finalStats.Findings)
+		if err != nil {
+			fmt.Printf("Failed to export findings to DefectDojo: %v\n", err)
+		} else {
+			fmt.Printf("Exported %d findings to DefectDojo (%d products)\n", len(finalStats.Findings), n)
+		}
+	}
+
+	settings := flagSettings(flag.CommandLine)
+	finalStats.Meta = &reportMeta{
+		Version:    version,
+		Commit:     commit,
+		BuildDate:  buildDate,
+		StartedAt:  started.UTC().Format(time.RFC3339),
+		FinishedAt: time.Now().UTC().Format(time.RFC3339),
+		Providers:  map[string]string{"github": *apiURL, "pub.dev": *pubdevURL, "osv": *osvURL},
+		Repos:      seq,
+		Flags:      settings,
+		ConfigHash: configHash(settings),
+		APIUsage:   apiMeter.Usage(),
+		Runtime:    readRuntimeUsage(),
+	}
+	var sealed []string
+	if stream != nil {
+		if err := stream.Close(); err != nil {
+			fmt.Printf("Failed to write %s: %v\n", *outPath, err)
+			return
+		}
+		fmt.Printf("✅ Stats collected successfully (min usage %d)\n", *minUsage)
+		if *outPath != "-" {
+			fmt.Printf("Streamed %d per-repo results to %s\n", stream.n, *outPath)
+			sealed = []string{*outPath}
+		}
+	} else {
+		*outPath = expandOut(*outPath, started, owners)
+		if err := os.MkdirAll(filepath.Dir(*outPath), 0755); err != nil {
+			fmt.Printf("Failed to create %s: %v\n", filepath.Dir(*outPath), err)
+			return
+		}
+		// Compressed or encrypted per-repo results are written from the spill
+		// file; plain ones are moved into place.
+		format, _ := compress.ForPath(*outPath)
+		encoded := enc != nil || format != ""
+		if *lowMemory {
+			detailsPath = outStem(*outPath) + ".repos.ndjson"
+			if anon == nil && !encoded {
+				if err := replaceFile(spillPath, detailsPath, *keep); err != nil {
+					fmt.Printf("Failed to move per-repo results to %s: %v\n", detailsPath, err)
+					return
 				}
+				finalStats.ReposFile = detailsPath
+			}
+		}
+		written := finalStats
+		if anon != nil {
+			anonDetails := detailsPath
+			if encoded {
+				anonDetails = spillPath + ".anon"
+				defer os.Remove(anonDetails)
+			}
+			if written, err = anonymizeReport(finalStats, *outPath, anonDetails, *keep, anon); err != nil {
+				fmt.Printf("Failed to anonymize report: %v\n", err)
+				return
+			}
+		}
+		if encoded && written.ReposFile != "" {
+			detailsPath += compress.Ext(format)
+			if enc != nil {
+				detailsPath += encrypt.Ext
+			}
+			if err := copyOutput(ctx, written.ReposFile, detailsPath, *keep, enc); err != nil {
+				fmt.Printf("Failed to write per-repo results: %v\n", err)
+				return
+			}
+			written.ReposFile = detailsPath
+		}
+		reportFile := *outPath
+		if enc != nil {
+			reportFile += encrypt.Ext
+		}
+		if err := writeJSON(ctx, reportFile, *keep, enc, written); err != nil {
+			fmt.Printf("Failed to write JSON: %v\n", err)
+			return
+		}
+
+		fmt.Printf("✅ Stats collected successfully (min usage %d)\n", *minUsage)
+		fmt.Printf("Saved to %s\n", reportFile)
+		if detailsPath != "" {
+			fmt.Printf("Per-repo results saved to %s\n", detailsPath)
+		}
+		sealed = []string{reportFile}
+		if detailsPath != "" {
+			sealed = append(sealed, detailsPath)
+		}
+	}
+	if err := sealFiles(ctx, sealed, *checksum, signer); err != nil {
+		fmt.Printf("Failed to seal report: %v\n", err)
+		return
+	}
+	if failing := findings.Failing(finalStats.Findings, *failOn); len(failing) > 0 {
+		fmt.Printf("❌ %d failing findings:\n", len(failing))
+		for _, f := range failing {
+			fmt.Printf("  %s: %s %s\n", f.Repo, f.Rule, f.Message)
+		}
+		exitCode = 1
+	} else if limits.Enabled() || *failOn != "" {
+		fmt.Println("✅ Quality gates passed")
+	}
+	if dc := finalStats.DepCounts; dc != nil {
+		fmt.Printf("Dependencies per repo: p50 %d, p90 %d, max %d\n", dc.Dependencies.P50, dc.Dependencies.P90, dc.Dependencies.Max)
+	}
+	if gd := finalStats.GitDeps; gd != nil && gd.BranchCount > 0 {
+		fmt.Printf("⚠️  %d of %d git dependencies follow a branch instead of a tag or commit\n", gd.BranchCount, gd.BranchCount+gd.PinnedCount)
+	}
+	if gd := finalStats.GitDeps; gd != nil && len(gd.Forks) > 0 {
+		fmt.Printf("⚠️  %d git sources are forks of pub.dev packages\n", len(gd.Forks))
+	}
+	if in := finalStats.Integrity; in != nil && in.Checked > 0 {
+		if len(in.Mismatches) > 0 {
+			fmt.Printf("⚠️  %d of %d locked packages do not match the archive hashes on pub.dev\n", len(in.Mismatches), in.Checked)
+		} else {
+			fmt.Printf("✅ %d locked package hashes match pub.dev\n", in.Checked)
+		}
+	}
+	if hs := finalStats.Hosted; hs != nil && hs.UntrustedCount > 0 {
+		fmt.Printf("⚠️  %d dependencies are fetched from package servers not on the allowlist\n", hs.UntrustedCount)
+	}
+	if pb := finalStats.Publishing; pb != nil && len(pb.Unguarded) > 0 {
+		fmt.Printf("⚠️  %d apps have no publish_to: none and could be published to pub.dev by accident\n", len(pb.Unguarded))
+	}
+	if pb := finalStats.Publishing; pb != nil && pb.Metadata != nil {
+		incomplete := 0
+		for _, c := range pb.Metadata.Packages {
+			if len(c.Missing) > 0 {
+				incomplete++
 			}
-			mu.Unlock()
-		}(i, full)
+		}
+		fmt.Printf("Package metadata: %d of %d packages incomplete\n", incomplete, len(pb.Metadata.Packages))
+	}
+	if tc := finalStats.Toolchains; tc != nil && len(tc.CIMismatches) > 0 {
+		fmt.Printf("⚠️  %d repos test on a Flutter version their pubspec does not allow\n", len(tc.CIMismatches))
 	}
+	if ld := finalStats.LockDrift; ld != nil {
+		violating := 0
+		for _, r := range ld.Repos {
+			if len(r.Violations) > 0 {
+				violating++
+			}
+		}
+		fmt.Printf("Lockfiles: %d of %d violate their pubspec constraints, %d stale, %d repos without pubspec.lock\n", violating, ld.Locked, ld.Stale, ld.Missing)
+	}
+	if up := finalStats.Updates; up != nil && len(up.Uncovered) > 0 {
+		fmt.Printf("⚠️  %d repos have no automated updates for pub dependencies\n", len(up.Uncovered))
+	}
+	printSummary(finalStats, *outPath, palette(useColor(*noColor)), tr)
 
-	wg.Wait()
+	if tracker != nil {
+		path := *adoptionOut
+		if path == "" {
+			path = outStem(*outPath) + ".adoption.json"
+		}
+		if enc != nil {
+			path += encrypt.Ext
+		}
+		rep := tracker.Report()
+		if err := writeJSON(ctx, path, *keep, enc, rep); err != nil {
+			fmt.Printf("Failed to write adoption report: %v\n", err)
+			return
+		}
+		for _, p := range rep.Packages {
+			fmt.Printf("  %s: %.1f%% adoption (%d of %d expected repos)\n", p.Name, p.Percent, p.Expected-len(p.Missing), p.Expected)
+		}
+		fmt.Printf("Adoption report saved to %s\n", path)
+	}
 
-	buildSortedList := func(m map[string]int) []map[string]interface{} {
-		type entry struct {
-			Name  string
-			Count int
+	if *jiraURL != "" {
+		jc := &jira.Client{
+			HTTP:      &http.Client{Timeout: 30 * time.Second},
+			BaseURL:   strings.TrimSuffix(*jiraURL, "/"),
+			User:      os.Getenv("JIRA_USER"),
+			Token:     os.Getenv("JIRA_TOKEN"),
+			Project:   *jiraProject,
+			IssueType: *jiraIssueType,
 		}
-		var list []entry
-		for k, v := range m {
-			if v >= *minUsage {
-				list = append(list, entry{Name: k, Count: v})
+		byRepo := findings.ByRepo(findings.AtLeast(finalStats.Findings, *jiraMinSeverity))
+		var clean []string
+		for _, r := range finalStats.Risk {
+			if _, ok := byRepo[r.Repo]; !ok {
+				clean = append(clean, r.Repo)
 			}
 		}
-		sort.Slice(list, func(i, j int) bool {
-			return list[i].Count < list[j].Count
+		res, err := jc.Sync(ctx, byRepo, clean)
+		if err != nil {
+			fmt.Printf("Failed to sync Jira tickets: %v\n", err)
+		}
+		fmt.Printf("Jira: %d created, %d updated, %d unchanged, %d resolved\n", res.Created, res.Updated, res.Unchanged, res.Resolved)
+	}
+
+	if *backstageDocs != "" && len(components) > 0 {
+		byRepo := map[string][]backstage.Component{}
+		for _, c := range components {
+			byRepo[c.Repo] = append(byRepo[c.Repo], c)
+		}
+		now, written := clock(), 0
+		err := forEachRepo(finalStats.Stats, *outPath, func(r stats.RepoResult) {
+			if r.Error != "" {
+				return
+			}
+			for _, c := range byRepo[r.Repo] {
+				if err := backstage.WriteDocs(*backstageDocs, c, backstage.DocsPage(c, r, now, tr)); err != nil {
+					fmt.Printf("Failed to write TechDocs page for %s/%s: %v\n", c.Namespace, c.Name, err)
+					continue
+				}
+				written++
+			}
 		})
-		var result []map[string]interface{}
-		for _, e := range list {
-			result = append(result, map[string]interface{}{
-				"name":  e.Name,
-				"count": e.Count,
-				"url":   fmt.Sprintf("https://pub.dev/packages/%s", e.Name),
-			})
+		if err != nil {
+			fmt.Printf("Failed to read per-repo results: %v\n", err)
+		}
+		fmt.Printf("%d TechDocs pages saved to %s\n", written, *backstageDocs)
+	}
+
+	if *planOut != "" {
+		if err := writePlan(ctx, finalStats.Stats, *outPath, finalStats.Findings, *planOut, enc); err != nil {
+			fmt.Printf("Failed to write remediation plan: %v\n", err)
+		}
+	}
+
+	if *inventoryOut != "" {
+		pd := newPubDev(*pubdevURL)
+		en := enrich.New(pd)
+		en.Licenses = true
+		if err := writeInventory(ctx, finalStats.Stats, *outPath, *inventoryOut, *inventoryFormat, mapping, taxonomy, en, enc); err != nil {
+			fmt.Printf("Failed to write inventory: %v\n", err)
+		}
+		hits, misses := en.CacheStats()
+		apiMeter.Cache("pub.dev", hits, misses)
+	}
+
+	if *parquetOut != "" {
+		if err := writeParquet(ctx, finalStats.Stats, *outPath, *parquetOut, started, *keep, enc); err != nil {
+			fmt.Printf("Failed to write fact table: %v\n", err)
+		}
+	}
+
+	if *bqTable != "" {
+		t, err := bigquery.ParseTable(*bqTable)
+		var bq *bigquery.Client
+		if err == nil {
+			bq, err = newBigQuery(*bqURL)
+		}
+		var rows int
+		if err == nil {
+			rows, err = exportBigQuery(ctx, finalStats.Stats, *outPath, bq, t, started)
+		}
+		if err != nil {
+			fmt.Printf("Failed to export to BigQuery: %v\n", err)
+		} else {
+			fmt.Printf("Streamed %d rows to BigQuery table %s\n", rows, t)
+		}
+	}
+
+	if *chURL != "" {
+		ch := &clickhouse.Client{
+			HTTP:     &http.Client{Timeout: 5 * time.Minute},
+			BaseURL:  strings.TrimSuffix(*chURL, "/"),
+			User:     os.Getenv("CLICKHOUSE_USER"),
+			Password: os.Getenv("CLICKHOUSE_PASSWORD"),
+		}
+		rows, err := 0, clickhouse.CheckTable(*chTable)
+		if err == nil {
+			rows, err = exportClickHouse(ctx, finalStats.Stats, *outPath, ch, *chTable, started)
+		}
+		if err != nil {
+			fmt.Printf("Failed to export to ClickHouse: %v\n", err)
+		} else {
+			fmt.Printf("Inserted %d rows into ClickHouse table %s\n", rows, *chTable)
 		}
-		return result
 	}
 
-	finalStats := Stats{
-		Dependencies:        buildSortedList(stats.deps),
-		DevDependencies:     buildSortedList(stats.devDeps),
-		DependencyOverrides: buildSortedList(stats.overrides),
+	if *sbomDir != "" || *dtURL != "" {
+		var dt *dtrack.Client
+		if *dtURL != "" {
+			dt = &dtrack.Client{
+				HTTP:    &http.Client{Timeout: 60 * time.Second},
+				BaseURL: strings.TrimSuffix(*dtURL, "/"),
+				APIKey:  os.Getenv("DTRACK_API_KEY"),
+			}
+		}
+		if err := writeSBOMs(ctx, finalStats.Stats, *outPath, *sbomDir, dt); err != nil {
+			fmt.Printf("SBOM export incomplete: %v\n", err)
+		}
 	}
 
-	data, _ := json.MarshalIndent(finalStats, "", "  ")
-	if err := os.WriteFile(*outPath, data, 0644); err != nil {
-		fmt.Printf("Failed to write JSON: %v\n", err)
+	if *submitDeps {
+		if err := submitDependencies(ctx, client, finalStats.Stats, *outPath, started); err != nil {
+			fmt.Printf("Dependency submission incomplete: %v\n", err)
+		}
+	}
+
+	if *fundingOut != "" {
+		pd := newPubDev(*pubdevURL)
+		fmt.Println("Collecting funding links from pub.dev...")
+		rep := funding.Build(ctx, pd, finalStats.Stats, *fundingTop, workers)
+		path := *fundingOut
+		if enc != nil {
+			path += encrypt.Ext
+		}
+		if err := writeJSON(ctx, path, *keep, enc, rep); err != nil {
+			fmt.Printf("Failed to write funding report: %v\n", err)
+			return
+		}
+		fmt.Printf("Funding report saved to %s\n", path)
+		if *fundingNotes != "" {
+			notes := *fundingNotes
+			if enc != nil {
+				notes += encrypt.Ext
+			}
+			err := writeOutput(ctx, notes, *keep, enc, func(w io.Writer) error { return funding.WriteNotes(w, rep) })
+			if err != nil {
+				fmt.Printf("Failed to write funding notes: %v\n", err)
+				return
+			}
+			fmt.Printf("Funding notes saved to %s\n", notes)
+		}
+	}
+
+	if *teamsDir != "" {
+		if err := writeTeamReports(ctx, *teamsDir, finalStats.Teams, *keep, enc); err != nil {
+			fmt.Printf("Failed to write team reports: %v\n", err)
+			return
+		}
+		fmt.Printf("Team reports saved to %s\n", *teamsDir)
+	}
+	if enrichCache != nil {
+		hits, misses := enrichCache.Stats()
+		apiMeter.Cache("enrichment cache", hits, misses)
+	}
+	printUsage(apiMeter.Usage())
+}
+
+// writeTeamReports writes each team's section as its own file so it can be
+// routed to that team, e.g. @acme/mobile becomes acme-mobile.json. They are
+// written like the report, keeping keep previous versions and encrypted to
+// enc when it is set.
+func writeTeamReports(ctx context.Context, dir string, teams []stats.TeamStats, keep int, enc *encrypt.Age) error {
+	for _, t := range teams {
+		name := strings.Trim(strings.NewReplacer("@", "", "/", "-", "(", "", ")", "").Replace(t.Team), "-")
+		path := filepath.Join(dir, name+".json")
+		if enc != nil {
+			path += encrypt.Ext
+		}
+		if err := writeJSON(ctx, path, keep, enc, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type scanOptions struct {
+	mainDeps   bool
+	codeOwners bool
+	lints      bool
+	updates    bool
+	pins       bool
+	workflows  bool
+	lockfile   bool
+	hashes     bool
+	lockLimits lockfile.Limits
+	imports    bool
+	flavors    bool
+	native     bool
+	lenient    bool
+	validate   bool
+	metadata   bool
+
+	// weights derives repo weights from GitHub metadata when set; weights
+	// given in the repos file take precedence.
+	weights *repoWeights
+
+	// git resolves git dependencies to their own pubspec names when set.
+	git *gitResolver
+
+	// graph resolves the dependencies of locked packages when set.
+	graph *depResolver
+
+	// deferred collects the parsed pubspecs when set, leaving the git and
+	// graph resolution to the enrich stage of the scan pipeline.
+	deferred deferred
+
+	// others lists the entry directory of repos without a pubspec for the
+	// manifests of other ecosystems.
+	others bool
+
+	// submodules also scans the repos a repo includes as git submodules.
+	submodules bool
+
+	// parser reads the manifests of the ecosystem set with --ecosystem;
+	// nil scans pubspecs.
+	parser manifest.Parser
+
+	// detect selects the parser of each repo from its manifests.
+	detect bool
+
+	// all scans every manifest in the tree of each repo.
+	all bool
+
+	// examples includes the manifests in example and fixture directories
+	// when all is set.
+	examples bool
+
+	// verbose prints more detail on each repo.
+	verbose bool
+
+	// file is the manifest the parser reads, when already found.
+	file string
+
+	// auth skips repos whose token was already rejected when set.
+	auth *authGuard
+
+	// provider names where repo files come from in the provenance of
+	// results: "github", or "local" with --local-repos.
+	provider string
+
+	// archive saves the fetched pubspecs and lockfiles when set.
+	archive *pubspecArchive
+}
+
+// authKind returns the AuthError kind of err, or "", and records it with
+// the guard when there is one.
+func authKind(g *authGuard, owner, credentials string, err error) string {
+	if g != nil {
+		if ae := g.record(owner, credentials, err); ae != nil {
+			return ae.Kind
+		}
+		return ""
+	}
+	var ae *github.AuthError
+	if errors.As(err, &ae) {
+		return ae.Kind
+	}
+	return ""
+}
+
+// scanRepo fetches and parses the pubspec of a single owner/repo entry.
+// Failures are reported on stdout and recorded in the result.
+func scanRepo(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions) stats.RepoResult {
+	client, res, ok := openRepo(ctx, client, &entry, opts)
+	if !ok {
+		return res
+	}
+	return scanOpened(ctx, client, entry, opts, res)
+}
+
+// openRepo starts the result of an entry: it picks the client for the
+// entry's credentials and resolves the branch, commit and weight. The
+// entry of a renamed repo is changed to the new name. On failure it
+// returns false with the error recorded in the result.
+func openRepo(ctx context.Context, client *github.Client, entry *repolist.Entry, opts scanOptions) (*github.Client, stats.RepoResult, bool) {
+	full := entry.ID()
+	res := stats.RepoResult{Repo: full, Weight: entry.Weight, Labels: entry.Labels}
+
+	parts := strings.Split(entry.Name, "/")
+	if len(parts) != 2 {
+		fmt.Printf("Invalid repo format: %s\n", full)
+		res.Error = "invalid repo format"
+		return client, res, false
+	}
+	owner, repo := parts[0], parts[1]
+
+	c := *client
+	if entry.Credentials != "" {
+		token := os.Getenv(entry.Credentials)
+		if token == "" {
+			fmt.Printf("Credentials %s for %s not found in the environment\n", entry.Credentials, full)
+			res.Error = "credentials not found"
+			return client, res, false
+		}
+		c.Token = token
+	}
+	// GitHub redirects the requests for a renamed repo to its new name.
+	var moved bool
+	c.OnRedirect = func(string) { moved = true }
+	client = &c
+
+	if opts.auth != nil {
+		if ae := opts.auth.blocked(owner, entry.Credentials); ae != nil {
+			res.Error = ae.Error()
+			res.AuthError = ae.Kind
+			return client, res, false
+		}
+	}
+
+	branch := entry.Ref
+	if branch == "" {
+		var err error
+		if branch, err = client.LatestBranch(ctx, owner, repo); err != nil {
+			fmt.Printf("Error getting branch for %s: %v\n", full, err)
+			res.Error = err.Error()
+			res.AuthError = authKind(opts.auth, owner, entry.Credentials, err)
+			return client, res, false
+		}
+	}
+	res.Branch = branch
+	res.Provenance = &stats.Provenance{Provider: opts.provider, Ref: branch}
+	if sha, err := client.CommitSHA(ctx, owner, repo, branch); err != nil {
+		fmt.Printf("⚠️  Commit of %s at %s not resolved, reading the files at the branch: %v\n", full, branch, err)
+	} else {
+		res.Provenance.Commit = sha
+	}
+	client.OnRedirect = nil
+	if moved {
+		renameEntry(ctx, client, entry, &res)
+	}
+
+	if res.Weight == 0 && opts.weights != nil {
+		w, err := opts.weights.weight(ctx, client, owner, repo)
+		if err != nil {
+			fmt.Printf("Error getting weight for %s: %v\n", full, err)
+		} else {
+			res.Weight = w
+		}
+	}
+	return client, res, true
+}
+
+// renameEntry looks up the new name of a repo whose requests were
+// redirected and records it in the entry and result, warning that the
+// repos list is stale.
+func renameEntry(ctx context.Context, client *github.Client, entry *repolist.Entry, res *stats.RepoResult) {
+	owner, repo, _ := strings.Cut(entry.Name, "/")
+	r, err := client.Repo(ctx, owner, repo)
+	if err != nil {
+		fmt.Printf("Error looking up the new name of %s: %v\n", res.Repo, err)
 		return
 	}
+	name := repolist.Normalize(r.FullName)
+	if name == entry.Name || strings.Count(name, "/") != 1 {
+		return
+	}
+	fmt.Printf("⚠️  %s was renamed to %s; update the repos list\n", entry.Name, name)
+	res.RenamedFrom = entry.Name
+	entry.Name = name
+	res.Repo = entry.ID()
+}
 
-	fmt.Printf("✅ Stats collected successfully (min usage %d)\n", *minUsage)
-	fmt.Printf("Saved to %s\n", *outPath)
+// scanOpened scans the manifest of an entry in a repo opened by openRepo.
+func scanOpened(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions, res stats.RepoResult) stats.RepoResult {
+	full := res.Repo
+	owner, repo, _ := strings.Cut(entry.Name, "/")
+	ref := scanRef(res)
+
+	// Entries of a monorepo share the commit but not the files read.
+	fetched := newFetchLog()
+	c := *client
+	c.OnFetch = fetched.add
+	client = &c
+	prov := *res.Provenance
+	res.Provenance = &prov
+	defer func() { prov.Files = fetched.list() }()
+
+	if opts.parser != nil || opts.detect {
+		p, file, err := selectManifest(ctx, client, owner, repo, ref, entry, opts)
+		if err != nil {
+			fmt.Printf("Error finding manifest for %s: %v\n", full, err)
+			res.Error = err.Error()
+			res.AuthError = authKind(opts.auth, owner, entry.Credentials, err)
+			return res
+		}
+		res.Ecosystem = p.Ecosystem()
+		if p.Ecosystem() != manifest.Pub {
+			scanManifest(ctx, client, owner, repo, ref, entry, p, file, opts, &res)
+			return res
+		}
+	}
+
+	content, err := client.File(ctx, owner, repo, ref, path.Join(entry.Path, "pubspec.yaml"))
+	if errors.Is(err, github.ErrNotFound) {
+		classifyNotDart(ctx, client, owner, repo, ref, entry, opts, &res)
+		return res
+	}
+	if err != nil {
+		fmt.Printf("Error fetching pubspec.yaml for %s: %v\n", full, err)
+		res.Error = err.Error()
+		res.AuthError = authKind(opts.auth, owner, entry.Credentials, err)
+		return res
+	}
+	archived := map[string]string{path.Join(entry.Path, "pubspec.yaml"): content}
+	if opts.archive != nil {
+		defer func() {
+			if err := opts.archive.save(entry.Name, entry.Path, &prov, archived); err != nil {
+				fmt.Printf("Failed to archive the pubspec of %s: %v\n", full, err)
+			}
+		}()
+	}
+
+	ps, err := pubspec.Parse(content)
+	if err != nil && opts.lenient {
+		ps, res.ParseWarnings = pubspec.ParseLenient(content)
+		res.ParseWarnings = append([]string{err.Error()}, res.ParseWarnings...)
+		fmt.Printf("⚠️  %s parsed leniently after: %v\n", full, err)
+	} else if err != nil {
+		fmt.Printf("Error parsing pubspec.yaml for %s: %v\n", full, err)
+		res.Error = err.Error()
+		return res
+	}
+	manifest.Apply(&res, manifest.FromPubspec(ps), opts.mainDeps)
+	if opts.validate {
+		res.SchemaViolations = pubspec.Validate(content)
+	}
+	if opts.metadata && ps.Name != "" && publish.KindOf(ps.PublishTo) != publish.KindPrivate {
+		res.Metadata = pubspec.ParseMetadata(content)
+		if res.Metadata != nil {
+			example, err := client.Exists(ctx, owner, repo, ref, path.Join(entry.Path, "example"))
+			if err != nil {
+				fmt.Printf("Error looking up example for %s: %v\n", full, err)
+			}
+			res.Metadata.HasExample = example
+		}
+	}
+
+	if opts.deferred != nil {
+		opts.deferred[res.Repo] = ps
+	} else if opts.git != nil {
+		resolveGitDeps(ctx, opts.git, &res, ps)
+	}
+
+	if opts.codeOwners {
+		co, err := client.CodeOwners(ctx, owner, repo, ref)
+		switch {
+		case err == nil:
+			res.Owners = codeowners.Parse(co).Owners(path.Join(entry.Path, "pubspec.yaml"))
+		case !errors.Is(err, github.ErrNotFound):
+			fmt.Printf("Error fetching CODEOWNERS for %s: %v\n", full, err)
+		}
+	}
+
+	if opts.pins {
+		file, pin, err := client.FlutterPin(ctx, owner, repo, ref, toolchain.PinFiles)
+		switch {
+		case err == nil:
+			res.FlutterPin = toolchain.ParsePin(file, pin)
+		case !errors.Is(err, github.ErrNotFound):
+			fmt.Printf("Error fetching Flutter version pin for %s: %v\n", full, err)
+		}
+	}
+
+	if opts.lockfile {
+		file, body, err := openLockfile(ctx, client, owner, repo, ref, entry.Path, opts.lockLimits.MaxBytes)
+		var pkgs map[string]lockfile.Package
+		if err == nil {
+			var lock string
+			pkgs, lock, err = decodeLockfile(body, opts.lockLimits, opts.archive != nil)
+			body.Close()
+			if opts.archive != nil && err == nil {
+				archived[file] = lock
+			}
+		}
+		switch {
+		case err == nil:
+			res.Locked = lockfile.Versions(pkgs)
+			if opts.hashes {
+				res.LockedSHA256 = lockfile.PubDevHashes(pkgs)
+			}
+			if opts.graph != nil && opts.deferred == nil {
+				resolveGraph(ctx, opts.graph, &res)
+			}
+		case errors.Is(err, github.ErrNotFound):
+			res.LockMissing = true
+		case errors.Is(err, github.ErrTooLarge) || errors.Is(err, lockfile.ErrTooLarge):
+			fmt.Printf("⚠️  Skipping the pubspec.lock of %s, over --max-lockfile-mb or --max-lockfile-packages: %v\n", full, err)
+		default:
+			fmt.Printf("Error fetching pubspec.lock for %s: %v\n", full, err)
+		}
+	}
+
+	if opts.imports {
+		counts, files, err := scanImports(ctx, client, owner, repo, ref, entry)
+		switch {
+		case err != nil:
+			fmt.Printf("Error scanning Dart imports for %s: %v\n", full, err)
+		case files > 0:
+			res.Imports, res.ImportFiles = counts, files
+		}
+	}
+
+	if opts.flavors {
+		names, sources, err := detectFlavors(ctx, client, owner, repo, ref, entry.Path, content)
+		if err != nil {
+			fmt.Printf("Error detecting flavors for %s: %v\n", full, err)
+		} else {
+			res.Flavors, res.FlavorSources = names, sources
+		}
+	}
+
+	if opts.native {
+		deps, err := nativeDeps(ctx, client, owner, repo, ref, entry.Path)
+		if err != nil {
+			fmt.Printf("Error fetching native dependencies for %s: %v\n", full, err)
+		} else {
+			res.Native = deps
+		}
+	}
+
+	if opts.workflows {
+		ci, err := ciVersions(ctx, client, owner, repo, ref)
+		if err != nil {
+			fmt.Printf("Error fetching GitHub Actions workflows for %s: %v\n", full, err)
+		} else {
+			res.CIFlutter, res.CIDart = ci.Flutter, ci.Dart
+		}
+	}
+
+	if opts.updates {
+		pub, configured, err := detectUpdates(ctx, client, owner, repo, ref, entry.Path)
+		if err != nil {
+			fmt.Printf("Error fetching dependency update configs for %s: %v\n", full, err)
+		} else {
+			res.PubUpdates, res.UpdateConfigs = pub, configured
+		}
+	}
+
+	if opts.lints {
+		ao, err := client.AnalysisOptions(ctx, owner, repo, ref)
+		switch {
+		case err == nil:
+			if res.LintSets, err = lints.Parse(ao); err != nil {
+				fmt.Printf("Error parsing analysis_options.yaml for %s: %v\n", full, err)
+			}
+		case errors.Is(err, github.ErrNotFound):
+			res.LintSets = []string{lints.None}
+		default:
+			fmt.Printf("Error fetching analysis_options.yaml for %s: %v\n", full, err)
+		}
+	}
+	return res
 }
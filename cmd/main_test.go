@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mainEnv makes the test binary run main instead of the tests, so a test
+// can run pgs as a subprocess.
+const mainEnv = "PGS_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(mainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runPGS runs main with args and env in a temporary directory and returns
+// its stdout, stderr and exit status.
+func runPGS(t *testing.T, env []string, args ...string) (string, string, int) {
+	t.Helper()
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append([]string{mainEnv + "=1", "PATH=" + os.Getenv("PATH"), "HOME=" + dir, "XDG_CACHE_HOME=" + filepath.Join(dir, "cache")}, env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// writeFile writes content to a file in a temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStreamToStdoutRedactsStderr(t *testing.T) {
+	const token = "ghp_secretsecretsecretsecret123"
+	const owner = "ghp_abcdefghijklmnopqrstuvwx"
+	repos := writeFile(t, "repos.txt", owner+"/app\n")
+
+	stdout, stderr, _ := runPGS(t, []string{"GITHUB_TOKEN=" + token},
+		"--repos", repos, "--api-url", "http://127.0.0.1:1/"+token, "--format", "ndjson", "--out", "-")
+	if !strings.Contains(stderr, "Processing") {
+		t.Fatalf("no progress on stderr:\n%s", stderr)
+	}
+	for name, out := range map[string]string{"stdout": stdout, "stderr": stderr} {
+		for _, secret := range []string{token, owner} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s prints %s:\n%s", name, secret, out)
+			}
+		}
+	}
+}
+
+func TestCheckExitStatus(t *testing.T) {
+	ok := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ok, "pubspec.yaml"), []byte("name: app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := t.TempDir()
+	if err := os.WriteFile(filepath.Join(invalid, "pubspec.yaml"), []byte("name: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unreachable := []string{"--pubdev-url", "http://127.0.0.1:1", "--osv-url", "http://127.0.0.1:1"}
+
+	tests := []struct {
+		name string
+		env  []string
+		args []string
+		want int
+	}{
+		{"no findings", nil, []string{"--path", ok}, 0},
+		{"missing pubspec", nil, []string{"--path", t.TempDir()}, 2},
+		{"invalid pubspec", nil, []string{"--path", invalid}, 2},
+		{"unreadable repo", []string{"GITHUB_TOKEN=x"}, []string{"--api-url", "http://127.0.0.1:1", "owner/repo"}, 2},
+		{"no token", nil, []string{"owner/repo"}, 2},
+		{"missing baseline", nil, []string{"--path", ok, "--baseline", filepath.Join(ok, "none.json")}, 2},
+		{"unknown severity", nil, []string{"--path", ok, "--fail-on", "fatal"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"check"}, unreachable...)
+			stdout, _, code := runPGS(t, tt.env, append(args, tt.args...)...)
+			if code != tt.want {
+				t.Errorf("exit status %d, want %d:\n%s", code, tt.want, stdout)
+			}
+		})
+	}
+}
+
+func TestGateExitStatus(t *testing.T) {
+	baseline := filepath.Join(t.TempDir(), "baseline.json")
+	deps := "name: app\ndependencies:\n  http: ^1.0.0\n"
+	tests := []struct {
+		name    string
+		pubspec string
+		args    []string
+		want    int
+	}{
+		{"within limits", "name: app\n", []string{"--max-deps-per-repo", "0"}, 0},
+		{"scan failed", "name: [\n", []string{"--max-deps-per-repo", "0"}, 1},
+		{"scan failed without gates", "name: [\n", nil, 0},
+		{"over the limit", deps, []string{"--max-deps-per-repo", "0"}, 1},
+		{"baseline written", deps, []string{"--max-deps-per-repo", "0", "--write-baseline", baseline}, 1},
+		{"baseline accepted", deps, []string{"--max-deps-per-repo", "0", "--write-baseline", baseline, "--accept-baseline"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repos, "acme", "app"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(repos, "acme", "app", "pubspec.yaml"), []byte(tt.pubspec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			list := writeFile(t, "repos.txt", "acme/app\n")
+			args := append([]string{"--local-repos", repos, "--repos", list, "--out", filepath.Join(repos, "stats.json")}, tt.args...)
+			stdout, _, code := runPGS(t, nil, args...)
+			if code != tt.want {
+				t.Errorf("exit status %d, want %d:\n%s", code, tt.want, stdout)
+			}
+		})
+	}
+}
+
+func TestFlagErrorsArePrinted(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"--bogus"}, "flag provided but not defined: -bogus", 2},
+		{[]string{"-h"}, "Usage", 0},
+		{[]string{"check", "--bogus"}, "flag provided but not defined: -bogus", 2},
+		{[]string{"check", "--help"}, "Usage", 0},
+		{[]string{"merge", "--bogus"}, "flag provided but not defined: -bogus", 2},
+		{[]string{"bundle", "export", "--bogus"}, "flag provided but not defined: -bogus", 2},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			stdout, stderr, code := runPGS(t, nil, tt.args...)
+			if !strings.Contains(stdout+stderr, tt.want) || code != tt.code {
+				t.Errorf("exit status %d, want %d with %q:\n%s%s", code, tt.code, tt.want, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestConfigErrorExitStatus(t *testing.T) {
+	misspelled := writeFile(t, "rules.yaml", "rules:\n  - id: deps\n    expr: dependncies.size() > 0\n")
+	repos := writeFile(t, "repos.txt", "acme/app\n")
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing rules file", []string{"--rules", "/nonexistent", "--fail-on", "low"}, "Failed to read rules file"},
+		{"misspelled rule variable", []string{"--rules", misspelled}, `undeclared reference to "dependncies"`},
+		{"unknown severity", []string{"--fail-on", "fatal"}, `Unknown severity "fatal"`},
+		{"missing baseline", []string{"--baseline", "/nonexistent"}, "Failed to read baseline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--local-repos", t.TempDir(), "--repos", repos, "--out", filepath.Join(t.TempDir(), "stats.json")}, tt.args...)
+			stdout, _, code := runPGS(t, nil, args...)
+			if code != 2 || !strings.Contains(stdout, tt.want) {
+				t.Errorf("exit status %d, want 2 with %q:\n%s", code, tt.want, stdout)
+			}
+		})
+	}
+}
+
+func TestCheckRemoteTransport(t *testing.T) {
+	var mu sync.Mutex
+	var agents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		agents = append(agents, r.UserAgent())
+		if len(agents) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	stdout, _, code := runPGS(t, []string{"GITHUB_TOKEN=x"}, "check", "--api-url", srv.URL, "--user-agent", "ci-check", "acme/app")
+	if code != 2 || !strings.Contains(stdout, "Failed to check acme/app: ") {
+		t.Errorf("exit status %d, want 2 and the error:\n%s", code, stdout)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(agents) != 2 || agents[1] != "ci-check" {
+		t.Errorf("requests with User-Agents %q, want the 429 retried with ci-check", agents)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// selectManifest returns the parser and manifest file for a repo: those of
+// the ecosystem set with --ecosystem, or with --ecosystem auto the ones
+// detected next to the entry path. Parsers with a single manifest need no
+// lookup.
+func selectManifest(ctx context.Context, client *github.Client, owner, repo, ref string, entry repolist.Entry, opts scanOptions) (manifest.Parser, string, error) {
+	if opts.file != "" {
+		return opts.parser, opts.file, nil
+	}
+	if !opts.detect && len(opts.parser.Files()) == 1 {
+		return opts.parser, opts.parser.Files()[0], nil
+	}
+	names, err := client.Dir(ctx, owner, repo, ref, entry.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.detect {
+		p, file, ok := manifest.Detect(names)
+		if !ok {
+			return nil, "", fmt.Errorf("no manifest found in %s/%s", owner, path.Join(repo, entry.Path))
+		}
+		return p, file, nil
+	}
+	file, ok := manifest.Find(opts.parser, names)
+	if !ok {
+		return nil, "", fmt.Errorf("no %s manifest found in %s/%s", opts.parser.Ecosystem(), owner, path.Join(repo, entry.Path))
+	}
+	return opts.parser, file, nil
+}
+
+// scanEntry scans an entry, or with --ecosystem all every manifest found
+// in the repo's tree below the entry path. Results of manifests outside the
+// entry path are named after their directory, like entries with a path.
+// With --submodules the results of the repo's submodules follow.
+func scanEntry(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions) []stats.RepoResult {
+	client, res, ok := openRepo(ctx, client, &entry, opts)
+	if !ok {
+		return []stats.RepoResult{res}
+	}
+	return scanOpenedEntry(ctx, client, entry, opts, res)
+}
+
+// scanOpenedEntry is scanEntry for an entry opened by openRepo.
+func scanOpenedEntry(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions, res stats.RepoResult) []stats.RepoResult {
+	var out []stats.RepoResult
+	if opts.all {
+		out = scanTree(ctx, client, entry, opts, res)
+	} else {
+		out = []stats.RepoResult{scanOpened(ctx, client, entry, opts, res)}
+	}
+	if opts.submodules {
+		out = append(out, scanSubmodules(ctx, client, entry, opts, res)...)
+	}
+	return out
+}
+
+// scanTree scans every manifest in the tree of an opened repo below the
+// entry path.
+func scanTree(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions, res stats.RepoResult) []stats.RepoResult {
+	owner, repo, _ := strings.Cut(entry.Name, "/")
+	tree, truncated, err := client.Tree(ctx, owner, repo, scanRef(res))
+	if err != nil {
+		fmt.Printf("Error listing the tree of %s: %v\n", res.Repo, err)
+		res.Error = err.Error()
+		res.AuthError = authKind(opts.auth, owner, entry.Credentials, err)
+		return []stats.RepoResult{res}
+	}
+	if truncated {
+		fmt.Printf("⚠️  Tree of %s is truncated; manifests may be missing\n", res.Repo)
+	}
+	var paths []string
+	for _, e := range tree {
+		if e.Type == "blob" {
+			paths = append(paths, e.Path)
+		}
+	}
+	found, examples := manifest.Discover(paths, entry.Path, entry.Exclude, opts.examples)
+	if opts.verbose && len(examples) > 0 {
+		fmt.Printf("Skipping %d example and fixture manifest directories in %s: %s\n", len(examples), res.Repo, strings.Join(examples, ", "))
+	}
+	if len(found) == 0 {
+		res.Error = fmt.Sprintf("no manifest found in %s", res.Repo)
+		fmt.Printf("Error finding manifest for %s: %s\n", res.Repo, res.Error)
+		return []stats.RepoResult{res}
+	}
+	out := make([]stats.RepoResult, 0, len(found))
+	for _, f := range found {
+		sub := entry
+		sub.Path = f.Dir
+		r := res
+		r.Repo = sub.ID()
+		o := opts
+		o.parser, o.file = f.Parser, f.File
+		out = append(out, scanOpened(ctx, client, sub, o, r))
+	}
+	return out
+}
+
+// scanManifest reads the manifest of another ecosystem than pub into res.
+// The pub-specific analyses do not apply to it.
+func scanManifest(ctx context.Context, client *github.Client, owner, repo, ref string, entry repolist.Entry, p manifest.Parser, file string, opts scanOptions, res *stats.RepoResult) {
+	content, err := client.File(ctx, owner, repo, ref, path.Join(entry.Path, file))
+	if err != nil {
+		fmt.Printf("Error fetching %s for %s: %v\n", file, res.Repo, err)
+		res.Error = err.Error()
+		res.AuthError = authKind(opts.auth, owner, entry.Credentials, err)
+		return
+	}
+	m, err := p.Parse(file, content)
+	if err != nil {
+		fmt.Printf("Error parsing %s for %s: %v\n", file, res.Repo, err)
+		res.Error = err.Error()
+		return
+	}
+	manifest.Apply(res, m, opts.mainDeps)
+}
+
+// classifyNotDart records an entry without a pubspec as not a Dart project,
+// and with --other-manifests what it holds instead. Failures to list the
+// directory only leave the manifests out.
+func classifyNotDart(ctx context.Context, client *github.Client, owner, repo, ref string, entry repolist.Entry, opts scanOptions, res *stats.RepoResult) {
+	res.NotDart = true
+	res.Error = fmt.Sprintf("no pubspec.yaml in %s/%s", owner, path.Join(repo, entry.Path))
+	fmt.Printf("Skipping %s: not a Dart project (no pubspec.yaml)\n", res.Repo)
+	if !opts.others {
+		return
+	}
+	names, err := client.Dir(ctx, owner, repo, ref, entry.Path)
+	if err != nil {
+		fmt.Printf("Error listing %s for other manifests: %v\n", res.Repo, err)
+		return
+	}
+	res.OtherManifests = manifest.Others(names)
+}
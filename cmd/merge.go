@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/depcount"
+	"pgithub.com/plasmatrip/pubscan/internal/depgraph"
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/flavors"
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/hosted"
+	"pgithub.com/plasmatrip/pubscan/internal/imports"
+	"pgithub.com/plasmatrip/pubscan/internal/lints"
+	"pgithub.com/plasmatrip/pubscan/internal/lockfile"
+	"pgithub.com/plasmatrip/pubscan/internal/majors"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/native"
+	"pgithub.com/plasmatrip/pubscan/internal/publish"
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stacks"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+	"pgithub.com/plasmatrip/pubscan/internal/toolchain"
+	"pgithub.com/plasmatrip/pubscan/internal/updates"
+)
+
+// runMerge implements `pubscan merge`, combining several stats reports
+// produced by sharded or per-provider runs.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	outPath := fs.String("out", "", "Path to merged output JSON file")
+	keep := fs.Int("keep", 0, "Number of previous merged reports to keep as .bak files")
+	encryptTo := fs.String("encrypt-to", "", "Comma-separated age recipients to encrypt the merged report for")
+	checksum := fs.Bool("checksum", false, "Write a SHA-256 checksum file next to the merged report")
+	signTool := fs.String("sign", "", "Sign the merged report with cosign or minisign (key path in SIGNING_KEY)")
+	minUsage := fs.Int("min", 1, "Minimum usage count for package to be included in statistics")
+	taxonomyPath := fs.String("taxonomy", "", "YAML file extending the package-to-category taxonomy")
+	hostedAllowlist := fs.String("hosted-allowlist", "", "Comma-separated package servers hosted: dependencies may use besides pub.dev; others are flagged")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs merge --out merged.json [--min N] stats-1.json stats-2.json ...
+
+Options:
+  --out        Path to merged output JSON file; may contain {date}, {time},
+               {org} and {ext}, missing directories are created
+  --keep       Number of previous merged reports to keep as .bak files (default: 0)
+  --encrypt-to Comma-separated age recipients; the merged report is written as a .age file
+  --checksum   Write a SHA-256 checksum file next to the merged report
+  --sign       Sign the merged report with cosign or minisign (key path in SIGNING_KEY)
+  --min        Minimum number of package usages to include in stats (default: 1)
+  --taxonomy   YAML file extending the package-to-category taxonomy
+  --hosted-allowlist
+               Comma-separated hosts or URLs of the package servers hosted:
+               dependencies may use besides pub.dev; the others are flagged`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *outPath == "" || fs.NArg() == 0 {
+		fmt.Println("Missing required arguments. Use pgs merge --help for usage.")
+		return
+	}
+
+	signer, err := newSigner(*signTool)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	var enc *encrypt.Age
+	if *encryptTo != "" {
+		if enc, err = encrypt.NewAge(*encryptTo); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	started := time.Now()
+	m := stats.NewMerger()
+	majorSplits := majors.NewTracker()
+	lintSets := lints.NewTracker()
+	toolchains := toolchain.NewTracker()
+	codegen := stacks.NewCodegenTracker()
+	taxonomy := stacks.DefaultTaxonomy()
+	if *taxonomyPath != "" {
+		var err error
+		if taxonomy, err = stacks.LoadTaxonomy(*taxonomyPath); err != nil {
+			fmt.Printf("Failed to read taxonomy file: %v\n", err)
+			return
+		}
+	}
+	classifier := stacks.NewClassifier(taxonomy.Categories)
+	gitDeps := gitdeps.NewTracker()
+	hostedServers := hosted.NewTracker(strings.Split(*hostedAllowlist, ","))
+	depCounts := depcount.NewTracker()
+	publishing := publish.NewTracker()
+	updateCoverage := updates.NewTracker()
+	lockDrift := lockfile.NewTracker()
+	transitive := depgraph.NewTracker()
+	importUsage := imports.NewTracker()
+	flavored := flavors.NewTracker()
+	natives := native.NewTracker()
+	tech := manifest.NewTracker()
+	m.Use(majorSplits, lintSets, toolchains, codegen, classifier, gitDeps, hostedServers, depCounts, publishing, updateCoverage, lockDrift, transitive, importUsage, flavored, natives, tech)
+
+	// The reports are read first, so the merger knows all inputs before
+	// their repos arrive; per-repo results are read again afterwards.
+	var sections mergedSections
+	var reposFiles []string
+	var inline []bool
+	spilled := false
+	for _, path := range fs.Args() {
+		rep, err := readMergeInput(path)
+		if err != nil {
+			fmt.Printf("Failed to read %s: %v\n", path, err)
+			return
+		}
+		detailed := rep.Repos != nil || rep.ReposFile != ""
+		reposFiles = append(reposFiles, rep.ReposFile)
+		inline = append(inline, rep.Repos != nil)
+		spilled = spilled || rep.ReposFile != ""
+		rep.Repos = nil
+		m.AddReport(path, rep.Stats, detailed)
+		sections.add(rep)
+	}
+
+	// Shards of --low-memory runs spill their results, and so does the
+	// merge: the merged results go to a temporary file, moved once the
+	// report's name is known.
+	var spillPath string
+	var spill *bufio.Writer
+	if spilled {
+		dir := fixedDir(*outPath)
+		if enc != nil {
+			dir = os.TempDir()
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Failed to create %s: %v\n", dir, err)
+			return
+		}
+		df, err := os.CreateTemp(dir, ".pubscan-*.ndjson")
+		if err != nil {
+			fmt.Printf("Failed to create spill file in %s: %v\n", dir, err)
+			return
+		}
+		spillPath = df.Name()
+		defer os.Remove(spillPath)
+		defer df.Close()
+		df.Chmod(0644)
+		spill = bufio.NewWriter(df)
+		m.SpillTo(spill)
+	}
+
+	owners := map[string]bool{}
+	for i, path := range fs.Args() {
+		var addErr error
+		add := func(r stats.RepoResult) {
+			if addErr == nil {
+				owner, _, _ := strings.Cut(r.Repo, "/")
+				owners[owner] = true
+				addErr = m.AddRepo(path, r)
+			}
+		}
+		var err error
+		if reposFiles[i] != "" {
+			err = readDetails(path, reposFiles[i], add)
+		} else if inline[i] {
+			err = readInlineRepos(path, add)
+		}
+		if err == nil {
+			err = addErr
+		}
+		if err != nil {
+			fmt.Printf("Failed to read per-repo results of %s: %v\n", path, err)
+			return
+		}
+	}
+
+	mergedStats, conflicts, err := m.Result(*minUsage)
+	if err == nil && spill != nil {
+		err = spill.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to write %s: %v\n", spillPath, err)
+		return
+	}
+	merged := report{
+		Stats:       mergedStats,
+		MajorSplits: majorSplits.Splits(),
+		LintSets:    lintSets.Sets(),
+		Toolchains:  toolchains.Report(),
+		Codegen:     codegen.Report(),
+		Stacks:      classifier.Report(),
+		GitDeps:     gitDeps.Report(),
+		Hosted:      hostedServers.Report(),
+		DepCounts:   depCounts.Report(),
+		Publishing:  publishing.Report(),
+		Updates:     updateCoverage.Report(),
+		LockDrift:   lockDrift.Report(),
+		Transitive:  transitive.Report(),
+		Imports:     importUsage.Report(),
+		Flavors:     flavored.Report(),
+		Native:      natives.Report(),
+		Tech:        tech.Report(),
+	}
+	sections.result(&merged, m)
+	merged.Meta = mergeMeta(sections.metas, m.Repos())
+	for _, c := range conflicts {
+		if c.Repo != "" {
+			fmt.Printf("⚠️  %s: %s (%v)\n", c.Repo, c.Reason, c.Inputs)
+		} else {
+			fmt.Printf("⚠️  %s\n", c.Reason)
+		}
+	}
+
+	*outPath = expandOut(*outPath, started, owners)
+	if err := os.MkdirAll(filepath.Dir(*outPath), 0755); err != nil {
+		fmt.Printf("Failed to create %s: %v\n", filepath.Dir(*outPath), err)
+		return
+	}
+	var detailsPath string
+	if spillPath != "" {
+		// Compressed or encrypted per-repo results are written from the
+		// spill file; plain ones are moved into place.
+		format, _ := compress.ForPath(*outPath)
+		detailsPath = outStem(*outPath) + ".repos.ndjson"
+		if enc != nil || format != "" {
+			detailsPath += compress.Ext(format)
+			if enc != nil {
+				detailsPath += encrypt.Ext
+			}
+			err = copyOutput(context.Background(), spillPath, detailsPath, *keep, enc)
+		} else {
+			err = replaceFile(spillPath, detailsPath, *keep)
+		}
+		if err != nil {
+			fmt.Printf("Failed to write per-repo results: %v\n", err)
+			return
+		}
+		merged.ReposFile = detailsPath
+	}
+	if enc != nil {
+		*outPath += encrypt.Ext
+	}
+	if err := writeJSON(context.Background(), *outPath, *keep, enc, merged); err != nil {
+		fmt.Printf("Failed to write JSON: %v\n", err)
+		return
+	}
+	sealed := []string{*outPath}
+	fmt.Printf("✅ Merged %d reports (%d repos, %d conflicts)\n", fs.NArg(), m.Repos(), len(conflicts))
+	fmt.Printf("Saved to %s\n", *outPath)
+	if detailsPath != "" {
+		fmt.Printf("Per-repo results saved to %s\n", detailsPath)
+		sealed = append(sealed, detailsPath)
+	}
+	if err := sealFiles(context.Background(), sealed, *checksum, signer); err != nil {
+		fmt.Printf("Failed to seal report: %v\n", err)
+	}
+}
+
+// readMergeInput reads the report at path; its per-repo results are read
+// separately.
+func readMergeInput(path string) (report, error) {
+	data, err := readReport(path)
+	if err != nil {
+		return report{}, err
+	}
+	var rep report
+	err = json.Unmarshal(data, &rep)
+	return rep, err
+}
+
+// readInlineRepos calls fn for each per-repo result in the report at path.
+func readInlineRepos(path string, fn func(stats.RepoResult)) error {
+	data, err := readReport(path)
+	if err != nil {
+		return err
+	}
+	var s struct {
+		Repos []stats.RepoResult `json:"repos"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for _, r := range s.Repos {
+		fn(r)
+	}
+	return nil
+}
+
+// mergedSections collects the findings, risk, gate violations and metadata
+// of the inputs. A finding, risk entry or violation reported by several
+// inputs is kept once.
+type mergedSections struct {
+	findings   []findings.Finding
+	suppressed []findings.Suppressed
+	risk       []risk.RepoRisk
+	violations []gate.Violation
+	metas      []*reportMeta
+	seen       map[string]bool
+}
+
+func (s *mergedSections) add(rep report) {
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	first := func(key string) bool {
+		if s.seen[key] {
+			return false
+		}
+		s.seen[key] = true
+		return true
+	}
+	for _, f := range rep.Findings {
+		f.ID = f.Fingerprint()
+		if first("finding " + f.ID) {
+			s.findings = append(s.findings, f)
+		}
+	}
+	for _, f := range rep.Suppressed {
+		f.ID = f.Fingerprint()
+		if first("suppressed " + f.ID) {
+			s.suppressed = append(s.suppressed, f)
+		}
+	}
+	for _, r := range rep.Risk {
+		if first("risk " + strings.ToLower(r.Repo) + " " + r.Ecosystem) {
+			s.risk = append(s.risk, r)
+		}
+	}
+	for _, v := range rep.GateViolations {
+		if first("gate " + strings.ToLower(v.Repo) + " " + v.Gate) {
+			s.violations = append(s.violations, v)
+		}
+	}
+	if rep.Meta != nil {
+		s.metas = append(s.metas, rep.Meta)
+	}
+}
+
+// result fills in the merged sections of out. Failed scans that another
+// input scanned successfully are dropped with their findings.
+func (s *mergedSections) result(out *report, m *stats.Merger) {
+	for _, f := range s.findings {
+		if f.Rule != findings.RuleScanFailed || m.Failed(f.Repo) {
+			out.Findings = append(out.Findings, f)
+		}
+	}
+	findings.SortBySeverity(out.Findings)
+	out.Suppressed = s.suppressed
+	out.Risk = s.risk
+	risk.Rank(out.Risk)
+	for _, v := range s.violations {
+		if v.Gate != gate.ScanFailed || m.Failed(v.Repo) {
+			out.GateViolations = append(out.GateViolations, v)
+		}
+	}
+}
+
+// mergeMeta describes the merge: the build that wrote it, the time span of
+// the inputs' scans, their providers and summed API usage. The flags are
+// kept when all inputs were scanned with the same configuration.
+func mergeMeta(metas []*reportMeta, repos int) *reportMeta {
+	if len(metas) == 0 {
+		return nil
+	}
+	out := &reportMeta{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		Providers: map[string]string{},
+		Repos:     repos,
+	}
+	sameConfig := true
+	var usages []*quota.Usage
+	for _, m := range metas {
+		if out.StartedAt == "" || m.StartedAt < out.StartedAt {
+			out.StartedAt = m.StartedAt
+		}
+		if m.FinishedAt > out.FinishedAt {
+			out.FinishedAt = m.FinishedAt
+		}
+		for name, u := range m.Providers {
+			if _, ok := out.Providers[name]; !ok {
+				out.Providers[name] = u
+			}
+		}
+		sameConfig = sameConfig && m.ConfigHash == metas[0].ConfigHash
+		usages = append(usages, m.APIUsage)
+	}
+	if sameConfig {
+		out.Flags, out.ConfigHash = metas[0].Flags, metas[0].ConfigHash
+	}
+	out.APIUsage = quota.Sum(usages...)
+	return out
+}
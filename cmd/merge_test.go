@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeSections(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.repos.ndjson": `{"repo":"acme/app","dependencies":["http"]}` + "\n" + `{"repo":"acme/web","error":"timeout"}` + "\n",
+		"a.json": `{"dependencies":[{"name":"http","count":1}],"repos_file":"a.repos.ndjson",
+			"findings":[{"rule":"discontinued-dependency","severity":"medium","repo":"acme/app","package":"http","message":"http is discontinued"},
+				{"rule":"scan-failed","severity":"high","repo":"acme/web","message":"could not be scanned"}],
+			"gate_violations":[{"repo":"acme/web","gate":"scan-failed","value":0,"limit":0,"detail":"timeout"}],
+			"risk":[{"repo":"acme/app","score":1,"signals":{}}],
+			"meta":{"version":"1","started_at":"2026-07-01T10:00:00Z","finished_at":"2026-07-01T10:05:00Z","providers":{"github":"https://api.github.com"},"repos":2,"config_hash":"x",
+				"api_usage":{"providers":[{"provider":"github","requests":3}]}}}`,
+		"b.json": `{"repos":[{"repo":"acme/web","dependencies":["dio"]}],
+			"findings":[{"rule":"discontinued-dependency","severity":"medium","repo":"ACME/app","package":"http","message":"http is discontinued"}],
+			"risk":[{"repo":"acme/app","score":1,"signals":{}}],
+			"meta":{"version":"1","started_at":"2026-07-01T09:00:00Z","finished_at":"2026-07-01T09:30:00Z","providers":{"github":"https://api.github.com"},"repos":1,"config_hash":"y",
+				"api_usage":{"providers":[{"provider":"github","requests":2}]}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "merged.json")
+	stdout, _, code := runPGS(t, nil, "merge", "--out", out, filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"))
+	if code != 0 {
+		t.Fatalf("exit status %d:\n%s", code, stdout)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged report
+	if err := json.Unmarshal(data, &merged); err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Findings) != 1 || merged.Findings[0].Rule != "discontinued-dependency" {
+		t.Errorf("findings %+v, want the discontinued package once", merged.Findings)
+	}
+	if len(merged.GateViolations) != 0 {
+		t.Errorf("gate violations %+v of a repo scanned by another input", merged.GateViolations)
+	}
+	if len(merged.Risk) != 1 {
+		t.Errorf("risk %+v, want one repo", merged.Risk)
+	}
+	if m := merged.Meta; m == nil || m.StartedAt != "2026-07-01T09:00:00Z" || m.FinishedAt != "2026-07-01T10:05:00Z" || m.Repos != 2 || m.ConfigHash != "" ||
+		m.APIUsage == nil || m.APIUsage.Providers[0].Requests != 5 {
+		t.Errorf("meta %+v", m)
+	}
+
+	if merged.Repos != nil || merged.ReposFile != filepath.Join(dir, "merged.repos.ndjson") {
+		t.Fatalf("repos %v, repos file %q; want the results spilled", merged.Repos, merged.ReposFile)
+	}
+	details, err := os.ReadFile(merged.ReposFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(details)), "\n")
+	if len(lines) != 2 || strings.Contains(string(details), "timeout") {
+		t.Errorf("per-repo results %q", lines)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"runtime"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+	"pgithub.com/plasmatrip/pubscan/internal/redact"
+)
+
+// defaultProviders are the public API endpoints, which anonymized reports
+// keep.
+var defaultProviders = map[string]string{
+	"github":  github.DefaultBaseURL,
+	"pub.dev": pubdev.DefaultBaseURL,
+	"osv":     osv.DefaultBaseURL,
+}
+
+// meteredProviders are the names registered with apiMeter. Requests to
+// other hosts are counted under the host, which anonymized reports hash.
+var meteredProviders = map[string]bool{
+	"github": true, "pub.dev": true, "osv": true, "backstage": true,
+	"dependency-track": true, "defectdojo": true, "jira": true,
+	"bigquery": true, "clickhouse": true, "repos list": true, "pub servers": true,
+}
+
+// reportMeta describes the scan that produced a report, so a report file
+// can be traced back to its inputs and the build that wrote it.
+type reportMeta struct {
+	Version    string            `json:"version"`
+	Commit     string            `json:"commit,omitempty"`
+	BuildDate  string            `json:"build_date,omitempty"`
+	StartedAt  string            `json:"started_at"`
+	FinishedAt string            `json:"finished_at"`
+	Providers  map[string]string `json:"providers"`
+	Repos      int               `json:"repos"`
+
+	// Flags are the options set on the command line or from the
+	// environment; ConfigHash identifies them, so runs with the same
+	// configuration can be matched.
+	Flags      map[string]string `json:"flags,omitempty"`
+	ConfigHash string            `json:"config_hash"`
+
+	// APIUsage counts the requests sent until the report was written.
+	APIUsage *quota.Usage `json:"api_usage,omitempty"`
+
+	// Runtime is the memory the scan allocated until then.
+	Runtime *runtimeUsage `json:"runtime,omitempty"`
+}
+
+// runtimeUsage is the allocation count of the process: the bytes and
+// objects allocated in total, the garbage collections, and the memory
+// obtained from the OS.
+type runtimeUsage struct {
+	AllocBytes uint64 `json:"alloc_bytes"`
+	Mallocs    uint64 `json:"mallocs"`
+	GCCycles   uint32 `json:"gc_cycles"`
+	SysBytes   uint64 `json:"sys_bytes"`
+}
+
+func readRuntimeUsage() *runtimeUsage {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return &runtimeUsage{AllocBytes: m.TotalAlloc, Mallocs: m.Mallocs, GCCycles: m.NumGC, SysBytes: m.Sys}
+}
+
+// flagSettings returns the flags of fs that were set, with credentials in
+// their values masked.
+func flagSettings(fs *flag.FlagSet) map[string]string {
+	out := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		out[f.Name] = redact.String(f.Value.String())
+	})
+	return out
+}
+
+// configHash is a short hash of the flag settings, independent of their
+// order.
+func configHash(settings map[string]string) string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + "=" + settings[name] + "\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])[:16]
+}
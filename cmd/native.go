@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/native"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// nativeDeps reads the Gradle build and the Podfile.lock, or the Podfile,
+// of the Flutter app in dir. Apps without either platform have no native
+// dependencies for it.
+func nativeDeps(ctx context.Context, client *github.Client, owner, repo, ref, dir string) ([]stats.NativeDep, error) {
+	var out []stats.NativeDep
+	first := func(files []string, parse func(file, content string) []stats.NativeDep) error {
+		for _, file := range files {
+			content, err := client.File(ctx, owner, repo, ref, path.Join(dir, file))
+			if errors.Is(err, github.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			out = append(out, parse(file, content)...)
+			return nil
+		}
+		return nil
+	}
+	if err := first(native.GradleFiles, func(_, c string) []stats.NativeDep { return native.ParseGradle(c) }); err != nil {
+		return nil, err
+	}
+	if err := first(native.PodFiles, native.ParsePods); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
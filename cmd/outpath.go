@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+)
+
+// expandOut fills in the placeholders of an --out template: {date} and
+// {time} of the run's start in UTC, {org} for the owner all scanned repos
+// share ("multi" when they span several), and {ext} for the report's
+// extension. Paths without placeholders are returned unchanged.
+func expandOut(tmpl string, started time.Time, owners map[string]bool) string {
+	org := "multi"
+	if len(owners) == 1 {
+		for o := range owners {
+			org = o
+		}
+	}
+	at := started.UTC()
+	return strings.NewReplacer(
+		"{date}", at.Format("2006-01-02"),
+		"{time}", at.Format("150405"),
+		"{org}", org,
+		"{ext}", "json",
+	).Replace(tmpl)
+}
+
+// ownersOf returns the owners of the given repos, e.g. of "acme/app:pkg/a".
+func ownersOf(repos []string) map[string]bool {
+	owners := map[string]bool{}
+	for _, r := range repos {
+		owner, _, _ := strings.Cut(r, "/")
+		owners[owner] = true
+	}
+	return owners
+}
+
+// fixedDir returns the leading directories of an --out template that
+// contain no placeholder, where files can be created before the template
+// is expanded.
+func fixedDir(tmpl string) string {
+	dir := filepath.Dir(tmpl)
+	for strings.Contains(dir, "{") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// writeOutput writes what fill produces to path, creating missing parent
+// directories. The output is compressed as the extension of path says
+// (.gz, .zst) and encrypted when enc is set, in which case path ends in
+// .age. It goes to a temporary file that replaces path once complete, so
+// readers never see a partial report.
+func writeOutput(ctx context.Context, path string, keep int, enc *encrypt.Age, fill func(io.Writer) error) error {
+	f, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(ctx, f, path, enc, fill); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return commitTemp(f, path, keep)
+}
+
+// writeJSON writes v as indented JSON with writeOutput.
+func writeJSON(ctx context.Context, path string, keep int, enc *encrypt.Age, v interface{}) error {
+	return writeOutput(ctx, path, keep, enc, func(w io.Writer) error {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	})
+}
+
+// copyOutput writes the file from to path with writeOutput.
+func copyOutput(ctx context.Context, from, path string, keep int, enc *encrypt.Age) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeOutput(ctx, path, keep, enc, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// encode runs fill through the compressor and encryptor path asks for.
+func encode(ctx context.Context, f io.Writer, path string, enc *encrypt.Age, fill func(io.Writer) error) error {
+	var closers []io.Closer
+	closeAll := func(err error) error {
+		for i := len(closers) - 1; i >= 0; i-- {
+			if cerr := closers[i].Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+	w := f
+	if enc != nil {
+		ew, err := enc.Writer(ctx, w)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, ew)
+		w = ew
+	}
+	format, _ := compress.ForPath(strings.TrimSuffix(path, encrypt.Ext))
+	cw, err := compress.NewWriter(ctx, w, format)
+	if err != nil {
+		return closeAll(err)
+	}
+	closers = append(closers, cw)
+	bw := bufio.NewWriter(cw)
+	err = fill(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	return closeAll(err)
+}
+
+// outStem returns path without its compression and format extensions,
+// the base of the files derived from a report.
+func outStem(path string) string {
+	_, base := compress.ForPath(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// createTemp creates a temporary file in the directory of path, which
+// commitTemp moves into place.
+func createTemp(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+}
+
+// commitTemp syncs and closes a file from createTemp and renames it to
+// path, keeping up to keep previous versions of path (see backup).
+func commitTemp(f *os.File, path string, keep int) error {
+	err := f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = replaceFile(f.Name(), path, keep)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// replaceFile renames from to path, keeping up to keep previous versions
+// of path (see backup).
+func replaceFile(from, path string, keep int) error {
+	if err := backup(path, keep); err != nil {
+		return err
+	}
+	return os.Rename(from, path)
+}
+
+// backup keeps the current file at path as path.bak before it is replaced,
+// shifting older copies to path.bak.2 ... path.bak.N so that keep copies
+// remain. The copy is a hard link where possible; path itself stays in
+// place until the rename that replaces it.
+func backup(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	name := func(i int) string {
+		if i == 1 {
+			return path + ".bak"
+		}
+		return path + ".bak." + strconv.Itoa(i)
+	}
+	os.Remove(name(keep))
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(name(i), name(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Link(path, name(1)); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name(1), data, 0644)
+}
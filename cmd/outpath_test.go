@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputKeep(t *testing.T) {
+	tests := []struct {
+		name   string
+		keep   int
+		writes []string
+		want   map[string]string
+	}{
+		{"no backups", 0, []string{"1", "2"}, map[string]string{"report.json": "2"}},
+		{"first write", 2, []string{"1"}, map[string]string{"report.json": "1"}},
+		{"one backup", 1, []string{"1", "2", "3"}, map[string]string{"report.json": "3", "report.json.bak": "2"}},
+		{"rotated", 3, []string{"1", "2", "3", "4", "5"}, map[string]string{
+			"report.json": "5", "report.json.bak": "4", "report.json.bak.2": "3", "report.json.bak.3": "2",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "report.json")
+			for _, content := range tt.writes {
+				err := writeOutput(context.Background(), path, tt.keep, nil, func(w io.Writer) error {
+					_, err := io.WriteString(w, content)
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := readDir(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOutputFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fill func(io.Writer) error
+	}{
+		{"fails before writing", func(io.Writer) error { return errors.New("interrupted") }},
+		{"fails halfway", func(w io.Writer) error {
+			io.WriteString(w, `{"partial": `)
+			return errors.New("interrupted")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "report.json")
+			if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeOutput(context.Background(), path, 1, nil, tt.fill); err == nil {
+				t.Fatal("no error")
+			}
+			if got, want := readDir(t, dir), map[string]string{"report.json": "old"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("files %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteOutputCreatesDirs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "2026", "07", "report.json")
+	if err := writeJSON(context.Background(), path, 0, nil, map[string]int{"repos": 2}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  \"repos\": 2\n}\n" {
+		t.Errorf("content %q", data)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0644 {
+		t.Errorf("mode %v, want 0644", fi.Mode().Perm())
+	}
+}
+
+// readDir returns the files in dir and their contents.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]string{}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[e.Name()] = string(data)
+	}
+	return out
+}
+
+func TestExpandOut(t *testing.T) {
+	started := time.Date(2026, 7, 1, 23, 30, 5, 0, time.FixedZone("EDT", -4*3600))
+	tests := []struct {
+		tmpl   string
+		repos  []string
+		want   string
+		wantIn string
+	}{
+		{"report.json", []string{"acme/app"}, "report.json", "."},
+		{"out/{org}/{date}-{time}.{ext}", []string{"acme/app", "acme/web:pkg/a"}, "out/acme/2026-07-02-033005.json", "out"},
+		{"out/{org}.json", []string{"acme/app", "other/lib"}, "out/multi.json", "out"},
+		{"reports/{date}/{org}/scan.json", nil, "reports/2026-07-02/multi/scan.json", "reports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := expandOut(tt.tmpl, started, ownersOf(tt.repos)); got != tt.want {
+				t.Errorf("expandOut = %q, want %q", got, tt.want)
+			}
+			if got := fixedDir(tt.tmpl); got != tt.wantIn {
+				t.Errorf("fixedDir = %q, want %q", got, tt.wantIn)
+			}
+		})
+	}
+}
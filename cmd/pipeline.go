@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// stageWorkers is the number of workers of each scan stage.
+type stageWorkers struct {
+	resolve, fetch, enrich int
+}
+
+// deferred collects the pubspecs the fetch stage parsed, by result ID, for
+// the enrich stage to resolve their git dependencies and dependency graph.
+type deferred map[string]pubspec.Pubspec
+
+// scanJob is a listed entry on its way through the scan stages.
+type scanJob struct {
+	entry   repolist.Entry
+	client  *github.Client
+	opened  stats.RepoResult
+	ok      bool
+	results []stats.RepoResult
+	parsed  deferred
+}
+
+// scanPipeline scans the entries of a repos list in stages, each with its
+// own workers: resolve opens the repo (branch, commit, rename, weight),
+// fetch reads and parses its manifests and the files the analyses need,
+// enrich resolves git dependencies and the lockfile's dependency graph,
+// and a single aggregator hands the results to add. Stages are connected
+// by channels holding as many jobs as the next stage has workers, so a
+// stage that falls behind holds up the ones before it instead of queueing
+// repos in memory.
+type scanPipeline struct {
+	client  *github.Client
+	opts    scanOptions
+	workers stageWorkers
+	dedup   *repolist.Dedup
+
+	// start is called as an entry enters the resolve stage.
+	start func(repolist.Entry)
+	// add receives every result, from the aggregator only.
+	add func(stats.RepoResult)
+}
+
+// run scans the entries of in and returns once every result was added.
+func (p *scanPipeline) run(ctx context.Context, in <-chan repolist.Entry) {
+	resolved := make(chan *scanJob, p.workers.fetch)
+	fetched := make(chan *scanJob, p.workers.enrich)
+	enriched := make(chan *scanJob, 1)
+
+	runStage(p.workers.resolve, func() {
+		for entry := range in {
+			if j := p.resolve(ctx, entry); j != nil {
+				resolved <- j
+			}
+		}
+	}, func() { close(resolved) })
+	runStage(p.workers.fetch, func() {
+		for j := range resolved {
+			p.fetch(ctx, j)
+			fetched <- j
+		}
+	}, func() { close(fetched) })
+	runStage(p.workers.enrich, func() {
+		for j := range fetched {
+			p.enrich(ctx, j)
+			enriched <- j
+		}
+	}, func() { close(enriched) })
+
+	for j := range enriched {
+		p.aggregate(j)
+	}
+}
+
+// runStage runs work on n goroutines and calls done once all returned.
+func runStage(n int, work func(), done func()) {
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		done()
+	}()
+}
+
+// resolve opens the repo of an entry. It returns nil for a renamed repo
+// whose new name is listed too, which is scanned once, under that name.
+func (p *scanPipeline) resolve(ctx context.Context, entry repolist.Entry) *scanJob {
+	p.start(entry)
+	j := &scanJob{entry: entry}
+	j.client, j.opened, j.ok = openRepo(ctx, p.client, &j.entry, p.opts)
+	if j.ok && j.opened.RenamedFrom != "" {
+		if first, dup := p.dedup.Add(entry.Input, j.entry); dup {
+			fmt.Printf("Skipping %s: renamed to %s, which is listed at %s\n", entry.ID(), j.entry.ID(), first)
+			return nil
+		}
+	}
+	return j
+}
+
+// fetch scans the manifests of an opened repo, leaving the enrichment of
+// the results to the enrich stage.
+func (p *scanPipeline) fetch(ctx context.Context, j *scanJob) {
+	if !j.ok {
+		j.results = []stats.RepoResult{j.opened}
+		return
+	}
+	o := p.opts
+	if o.git != nil || o.graph != nil {
+		o.deferred = deferred{}
+	}
+	j.results = scanOpenedEntry(ctx, j.client, j.entry, o, j.opened)
+	j.parsed = o.deferred
+}
+
+// enrich resolves what the fetch stage left to it for the results of a
+// job.
+func (p *scanPipeline) enrich(ctx context.Context, j *scanJob) {
+	for i := range j.results {
+		if ps, ok := j.parsed[j.results[i].Repo]; ok {
+			enrichResult(ctx, p.opts, &j.results[i], ps)
+		}
+	}
+}
+
+// aggregate adds the results of a job. A submodule listed too, or included
+// by another repo, is counted once.
+func (p *scanPipeline) aggregate(j *scanJob) {
+	for _, res := range j.results {
+		if res.SubmoduleOf != "" {
+			name, dir, _ := strings.Cut(res.Repo, ":")
+			if first, dup := p.dedup.Add(j.entry.Input, repolist.Entry{Name: name, Path: dir, Line: j.entry.Line}); dup {
+				fmt.Printf("Skipping submodule %s of %s, which is listed at %s\n", res.Repo, res.SubmoduleOf, first)
+				continue
+			}
+		}
+		p.add(res)
+	}
+}
+
+// enrichResult resolves the git dependencies of the pubspec of res and the
+// dependency graph of its lockfile, as far as opts asks for them. Git
+// dependencies go first: they may rename the direct dependencies the graph
+// starts from.
+func enrichResult(ctx context.Context, opts scanOptions, res *stats.RepoResult, ps pubspec.Pubspec) {
+	if opts.git != nil {
+		resolveGitDeps(ctx, opts.git, res, ps)
+	}
+	if opts.graph != nil && res.Locked != nil {
+		resolveGraph(ctx, opts.graph, res)
+	}
+}
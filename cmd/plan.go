@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/compress"
+	"pgithub.com/plasmatrip/pubscan/internal/encrypt"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/remediation"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// writePlan writes the remediation plan of list to path, as CSV for .csv
+// paths and as Markdown otherwise. Teams come from the repos' CODEOWNERS.
+func writePlan(ctx context.Context, s stats.Stats, reportPath string, list []findings.Finding, path string, enc *encrypt.Age) error {
+	owners := map[string][]string{}
+	if err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if len(r.Owners) > 0 {
+			owners[r.Repo] = r.Owners
+		}
+	}); err != nil {
+		return err
+	}
+	plan := remediation.Build(list, owners)
+	_, base := compress.ForPath(path)
+	render := remediation.WriteMarkdown
+	if strings.EqualFold(filepath.Ext(base), ".csv") {
+		render = remediation.WriteCSV
+	}
+	if enc != nil {
+		path += encrypt.Ext
+	}
+	if err := writeOutput(ctx, path, 0, enc, func(w io.Writer) error { return render(w, plan) }); err != nil {
+		return err
+	}
+	fmt.Printf("Remediation plan with %d items for %d teams saved to %s\n", len(plan.Items), len(plan.Teams), path)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// fetchLog collects the files read for one result, as the OnFetch hook
+// of its client.
+type fetchLog struct {
+	mu    sync.Mutex
+	files map[string]time.Time
+}
+
+func newFetchLog() *fetchLog {
+	return &fetchLog{files: map[string]time.Time{}}
+}
+
+// add records p at the first time it was fetched.
+func (l *fetchLog) add(p string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.files[p]; !ok {
+		l.files[p] = time.Now().UTC()
+	}
+}
+
+// list returns the recorded files sorted by path.
+func (l *fetchLog) list() []stats.FetchedFile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]stats.FetchedFile, 0, len(l.files))
+	for p, at := range l.files {
+		out = append(out, stats.FetchedFile{Path: p, FetchedAt: at})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// scanRef is the ref the files of a result are read at: the commit its
+// branch resolved to, so every file comes from the same one, or the branch
+// when it was not resolved.
+func scanRef(res stats.RepoResult) string {
+	if res.Provenance != nil && res.Provenance.Commit != "" {
+		return res.Provenance.Commit
+	}
+	return res.Branch
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/publish"
+)
+
+// checkPublished looks up every internal package on the server its
+// publish_to names and records how the declared version compares to the
+// latest published one. PUB_SERVER_TOKEN is sent to the servers when set.
+func checkPublished(ctx context.Context, rep *publish.Report) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		ch      = make(chan publish.Package)
+		clients = map[string]*pubdev.Client{}
+	)
+	client := func(server string) *pubdev.Client {
+		mu.Lock()
+		defer mu.Unlock()
+		if c := clients[server]; c != nil {
+			return c
+		}
+		c := pubdev.NewClient(rateLimiter.Client(10 * time.Second))
+		c.BaseURL = strings.TrimSuffix(server, "/")
+		apiMeter.Register("pub servers", server)
+		c.Token = os.Getenv("PUB_SERVER_TOKEN")
+		clients[server] = c
+		return c
+	}
+
+	rep.Versions = make([]publish.VersionCheck, 0, len(rep.Internal))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ch {
+				vc := publish.VersionCheck{Repo: p.Repo, Name: p.Name, Declared: p.Version}
+				pkg, err := client(p.PublishTo).Package(ctx, p.Name)
+				switch {
+				case errors.Is(err, pubdev.ErrNotFound):
+					vc.Status = publish.StatusNotPublished
+				case err != nil:
+					vc.Status, vc.Error = publish.StatusError, err.Error()
+				default:
+					vc.Published = pkg.Latest.Version
+					vc.Status = publish.Compare(p.Version, vc.Published)
+				}
+				mu.Lock()
+				rep.Versions = append(rep.Versions, vc)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range rep.Internal {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+	sort.Slice(rep.Versions, func(i, j int) bool { return rep.Versions[i].Repo < rep.Versions[j].Repo })
+
+	counts := map[string]int{}
+	for _, vc := range rep.Versions {
+		counts[vc.Status]++
+	}
+	fmt.Printf("Internal packages: %d published, %d with unpublished changes, %d behind the server, %d never published, %d failed\n",
+		counts[publish.StatusPublished], counts[publish.StatusUnpublished], counts[publish.StatusBehind],
+		counts[publish.StatusNotPublished], counts[publish.StatusError])
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"pgithub.com/plasmatrip/pubscan/internal/bundle"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// replayDropped are scan options a replay leaves out: where the inputs and
+// outputs were and how the network was used. The replay sets its own.
+var replayDropped = map[string]bool{
+	"env": true, "out": true, "format": true, "keep": true, "encrypt-to": true, "checksum": true, "sign": true,
+	"cache-dir": true, "pubdev-ttl": true, "osv-ttl": true, "refresh-enrichment": true,
+	"offline": true, "local-repos": true, "capture-dir": true, "as-of": true,
+	"debug-http": true, "user-agent": true, "rps-github": true, "rps-pubdev": true, "rps-osv": true, "rps-backstage": true,
+}
+
+// replayOutputs are the options naming derived outputs; a replay writes
+// them next to its report instead.
+var replayOutputs = []string{"adoption-out", "funding-out", "funding-notes", "plan-out", "inventory-out",
+	"parquet-out", "sbom-dir", "teams-dir", "write-baseline"}
+
+// runBundleReplay implements `pubscan bundle replay`, repeating the scan of
+// a bundle offline with the options and clock of the original run and
+// comparing the result with the bundled report.
+func runBundleReplay(args []string) {
+	fs := flag.NewFlagSet("bundle replay", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory to unpack the bundle and write the replay to (default: a temporary one, removed afterwards)")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs bundle replay [--dir replay] bundle.tar.gz
+
+Options:
+  --dir        Directory to unpack the bundle and write the replay to
+               (default: a temporary one, removed afterwards)
+
+The scan is repeated with --offline from the bundled repo files and
+enrichment cache, with the options recorded in the report and staleness
+judged as of the original run. Exit status is 1 when the replayed report
+differs from the bundled one.`)
+	}
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fmt.Println("Missing required arguments. Use pgs bundle replay --help for usage.")
+		return
+	}
+	work := *dir
+	if work == "" {
+		tmp, err := os.MkdirTemp("", "pubscan-replay-")
+		if err != nil {
+			fmt.Printf("Failed to create replay directory: %v\n", err)
+			return
+		}
+		defer os.RemoveAll(tmp)
+		work = tmp
+	}
+	m, err := extractBundle(fs.Arg(0), work)
+	if err != nil {
+		fmt.Printf("Failed to import bundle: %v\n", err)
+		return
+	}
+	if !m.Repos || m.Inputs["repos"] == "" {
+		fmt.Println("The bundle has no captured repo files or repos list to replay the scan from")
+		return
+	}
+	reportPath := filepath.Join(work, m.Report)
+	data, err := readReport(reportPath)
+	if err != nil {
+		fmt.Printf("Failed to read report: %v\n", err)
+		return
+	}
+	var rm struct {
+		Meta *reportMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &rm); err != nil || rm.Meta == nil {
+		fmt.Println("The bundled report has no metadata to replay the scan with")
+		return
+	}
+
+	outDir := filepath.Join(work, "replay")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		fmt.Printf("Failed to create %s: %v\n", outDir, err)
+		return
+	}
+	replayPath := filepath.Join(outDir, "report.json")
+	scanArgs, skipped := replayArgs(rm.Meta, m, work, outDir)
+	scanArgs = append(scanArgs, "--out", replayPath)
+	for _, name := range skipped {
+		fmt.Printf("⚠️  --%s needs the network and is not replayed\n", name)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Printf("Failed to find the pubscan binary: %v\n", err)
+		return
+	}
+	logPath := filepath.Join(outDir, "scan.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		fmt.Printf("Failed to create %s: %v\n", logPath, err)
+		return
+	}
+	cmd := exec.Command(self, scanArgs...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	// Gate failures exit with 1 in the replay as in the original run;
+	// only a missing report means the replay failed.
+	runErr := cmd.Run()
+	logFile.Close()
+	if _, err := os.Stat(replayPath); err != nil {
+		fmt.Printf("Replay failed: %v\n", runErr)
+		if *dir != "" {
+			fmt.Printf("See %s\n", logPath)
+		} else if out, err := os.ReadFile(logPath); err == nil {
+			os.Stdout.Write(out)
+		}
+		exit(1)
+	}
+
+	want, err := reportSnapshot(reportPath)
+	if err != nil {
+		fmt.Printf("Failed to read the bundled report: %v\n", err)
+		return
+	}
+	got, err := reportSnapshot(replayPath)
+	if err != nil {
+		fmt.Printf("Failed to read the replayed report: %v\n", err)
+		return
+	}
+	diffs := bundle.Diff(want, got)
+	if len(diffs) == 0 {
+		fmt.Printf("✅ Replay of %d repos matches the bundled report\n", len(want.Repos))
+		if *dir != "" {
+			fmt.Printf("Replayed outputs are in %s\n", outDir)
+		}
+		return
+	}
+	fmt.Printf("❌ Replay differs from the bundled report in %d places:\n", len(diffs))
+	for _, d := range diffs {
+		fmt.Printf("   %s\n", d)
+	}
+	if *dir != "" {
+		fmt.Printf("Replayed outputs are in %s\n", outDir)
+	}
+	exit(1)
+}
+
+// replayArgs rebuilds the command line of the original scan for an offline
+// run from the unpacked bundle in dir. It returns the options that need the
+// network, which are left out.
+func replayArgs(meta *reportMeta, m bundle.Manifest, dir, outDir string) ([]string, []string) {
+	outputs := map[string]bool{}
+	for _, name := range replayOutputs {
+		outputs[name] = true
+	}
+	network := map[string]bool{}
+	for _, name := range networkFlags {
+		network[name] = true
+	}
+
+	names := make([]string, 0, len(meta.Flags))
+	for name := range meta.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var args, skipped []string
+	for _, name := range names {
+		value := meta.Flags[name]
+		switch {
+		case replayDropped[name]:
+			continue
+		case network[name]:
+			skipped = append(skipped, name)
+			continue
+		case m.Inputs[name] != "":
+			value = filepath.Join(dir, filepath.FromSlash(m.Inputs[name]))
+		case outputs[name]:
+			value = filepath.Join(outDir, filepath.Base(value))
+		}
+		args = append(args, "--"+name+"="+value)
+	}
+	args = append(args, "--offline", "--local-repos", filepath.Join(dir, bundle.ReposDir))
+	if m.Cache {
+		args = append(args, "--cache-dir", filepath.Join(dir, bundle.CacheDir))
+	}
+	if meta.StartedAt != "" {
+		args = append(args, "--as-of", meta.StartedAt)
+	}
+	return args, skipped
+}
+
+// reportSnapshot reads a report for bundle.Diff. The metadata, and the
+// provider and fetch times of the provenance, differ between runs by design
+// and are left out.
+func reportSnapshot(path string) (bundle.Snapshot, error) {
+	snap := bundle.Snapshot{Sections: map[string]json.RawMessage{}, Repos: map[string]json.RawMessage{}}
+	data, err := readReport(path)
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap.Sections); err != nil {
+		return snap, err
+	}
+	for _, k := range []string{"meta", "repos", "repos_file"} {
+		delete(snap.Sections, k)
+	}
+	var s stats.Stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		return snap, err
+	}
+	err = forEachRepo(s, path, func(r stats.RepoResult) {
+		if r.Provenance != nil {
+			p := *r.Provenance
+			p.Provider, p.Files = "", nil
+			for _, f := range r.Provenance.Files {
+				p.Files = append(p.Files, stats.FetchedFile{Path: f.Path})
+			}
+			r.Provenance = &p
+		}
+		snap.Repos[r.Repo], _ = json.Marshal(r)
+	})
+	return snap, err
+}
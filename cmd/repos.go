@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+)
+
+// runRepos implements the `pubscan repos` commands.
+func runRepos(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Println(`Usage:
+  pgs repos validate [options]`)
+		return
+	}
+	runReposValidate(args[1:])
+}
+
+// runReposValidate implements `pubscan repos validate`, checking a repos
+// list or manifest before an expensive scan.
+func runReposValidate(args []string) {
+	fs := flag.NewFlagSet("repos validate", flag.ContinueOnError)
+	envPath := fs.String("env", "", "Path to .env file containing GITHUB_TOKEN (with --live)")
+	reposPath := fs.String("repos", "", "Path or http(s) URL of the repos list or manifest")
+	reposFormat := fs.String("repos-format", "", "Repos file format: text, csv, json or yaml (default: detected from extension)")
+	reposColumn := fs.String("repos-column", "", "CSV column holding owner/repo (default: auto-detect)")
+	weightColumn := fs.String("weight-column", "", "CSV column holding a weight per repo")
+	live := fs.Bool("live", false, "Also check that every repo exists and is readable with the token")
+	apiURL := fs.String("api-url", github.DefaultBaseURL, "GitHub API base URL (for GitHub Enterprise Server)")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs repos validate --repos repos.yaml [--live --env .env]
+
+Options:
+  --repos      Path or http(s) URL of the repos list or manifest
+  --repos-format
+               Repos file format: text, csv, json or yaml (default: detected from extension)
+  --repos-column
+               CSV column holding owner/repo (default: auto-detect)
+  --weight-column
+               CSV column holding a weight per repo
+  --live       Also check that every repo exists and is readable with the token
+  --env        Path to .env file containing GITHUB_TOKEN (with --live)
+  --api-url    GitHub API base URL (default: https://api.github.com)
+
+Exit status is 1 when problems are found, 0 otherwise.`)
+	}
+	parseFlags(fs, args)
+	if err := loadConfig(fs, envPath); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *reposPath == "" {
+		fmt.Println("Missing required arguments. Use pgs repos validate --help for usage.")
+		return
+	}
+
+	ctx := context.Background()
+	f, err := repolist.Open(ctx, &http.Client{Timeout: 30 * time.Second}, *reposPath, os.Getenv("REPOS_AUTH_HEADER"))
+	if err != nil {
+		fmt.Printf("Failed to read repos file: %v\n", err)
+		return
+	}
+	defer f.Close()
+	format := repolist.DetectFormat(*reposPath, *reposFormat)
+	entries, problems, err := repolist.Validate(f, format, repolist.Options{Column: *reposColumn, WeightColumn: *weightColumn})
+	if err != nil {
+		fmt.Printf("Failed to read repos file: %v\n", err)
+		return
+	}
+
+	if *live {
+		token := githubToken()
+		if token == "" {
+			fmt.Println("GITHUB_TOKEN not found in the environment or .env file")
+			return
+		}
+		client := github.NewClient(&http.Client{Timeout: 10 * time.Second}, token)
+		client.BaseURL = strings.TrimSuffix(*apiURL, "/")
+		problems = append(problems, checkReachable(ctx, client, entries)...)
+		repolist.SortProblems(problems)
+	}
+
+	// path:line: message, the form editors and CI annotations pick up.
+	for _, p := range problems {
+		if p.Line > 0 {
+			fmt.Printf("%s:%d: %s\n", *reposPath, p.Line, p.Message)
+		} else {
+			fmt.Printf("%s: %s\n", *reposPath, p.Message)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("❌ %d problems\n", len(problems))
+		exit(1)
+	}
+	fmt.Printf("✅ %d entries OK\n", len(entries))
+}
+
+// checkReachable looks up every repo once and reports the ones that cannot
+// be read, using the credentials of their entry.
+func checkReachable(ctx context.Context, client *github.Client, entries []repolist.Entry) []repolist.Problem {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		problems []repolist.Problem
+		ch       = make(chan repolist.Entry)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range ch {
+				c := client
+				if e.Credentials != "" {
+					token := os.Getenv(e.Credentials)
+					if token == "" {
+						mu.Lock()
+						problems = append(problems, repolist.Problem{Line: e.Line, Repo: e.Name, Message: fmt.Sprintf("credentials %s not found in the environment", e.Credentials)})
+						mu.Unlock()
+						continue
+					}
+					cc := *client
+					cc.Token = token
+					c = &cc
+				}
+				owner, repo, _ := strings.Cut(e.Name, "/")
+				if _, err := c.Repo(ctx, owner, repo); err != nil {
+					mu.Lock()
+					problems = append(problems, repolist.Problem{Line: e.Line, Repo: e.Name, Message: fmt.Sprintf("%s is unreachable: %v", e.Name, err)})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if !seen[e.Name] {
+			seen[e.Name] = true
+			ch <- e
+		}
+	}
+	close(ch)
+	wg.Wait()
+	return problems
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// assessRisk enriches every hosted dependency of the scanned repos and
+// returns the repos ranked by risk score. Advisories are looked up for
+// every ecosystem, pub.dev data for pub packages only. Per-repo results are read twice,
+// which keeps it usable with --low-memory.
+func assessRisk(ctx context.Context, s stats.Stats, reportPath string, en *enrich.Enricher, ov *osv.Client, w risk.Weights, staleAfter time.Duration) ([]risk.RepoRisk, error) {
+	names := map[string]bool{}
+	queries := map[osv.Query]bool{}
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		for name, c := range r.Constraints {
+			if strings.Contains(c, ":") {
+				continue
+			}
+			if purl.Type(r.Ecosystem) == purl.Pub {
+				names[name] = true
+			}
+			if v, ok := risk.LowerBound(c); ok {
+				queries[osv.Query{PURL: purl.For(r.Ecosystem, name), Version: v}] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	qs := make([]osv.Query, 0, len(queries))
+	for q := range queries {
+		qs = append(qs, q)
+	}
+
+	in := risk.Input{
+		Packages:   en.Packages(ctx, list, workers),
+		StaleAfter: staleAfter,
+		Now:        clock(),
+	}
+	if in.Vulns, err = ov.QueryBatch(ctx, qs); err != nil {
+		return nil, err
+	}
+
+	var ranked []risk.RepoRisk
+	err = forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error == "" {
+			ranked = append(ranked, risk.Assess(r, in, w))
+		}
+	})
+	risk.Rank(ranked)
+	return ranked, err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/rollup"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// runReport implements `pubscan report`, rolling up stored scan reports
+// into a quarterly summary for readers who do not want the raw JSON.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	quarter := fs.String("quarter", "", "Quarter to roll up, e.g. 2026Q3 (default: the quarter of the latest report)")
+	format := fs.String("format", "", "Output format: md or html (default: from --out, else md)")
+	outPath := fs.String("out", "", "Path to write the rollup to (default: stdout)")
+	tmplPath := fs.String("template", "", "Go template to render the rollup with instead of the built-in one")
+	top := fs.Int("top", 10, "Number of top risk repositories to list")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs report [options] reports/*.json
+
+Options:
+  --quarter    Quarter to roll up, e.g. 2026Q3 (default: the quarter of the latest report)
+  --format     Output format: md or html (default: from --out, else md)
+  --out        Path to write the rollup to (default: stdout)
+  --template   Go template to render the rollup with instead of the built-in one
+  --top        Number of top risk repositories to list (default: 10)`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if fs.NArg() == 0 {
+		fmt.Println("Missing report files. Use pgs report --help for usage.")
+		return
+	}
+	if *format == "" {
+		*format = "md"
+		if ext := strings.ToLower(filepath.Ext(*outPath)); ext == ".html" || ext == ".htm" {
+			*format = "html"
+		}
+	}
+
+	var snaps []rollup.Snapshot
+	for _, path := range fs.Args() {
+		snap, err := readSnapshot(path)
+		if err != nil {
+			fmt.Printf("Failed to read %s: %v\n", path, err)
+			return
+		}
+		snaps = append(snaps, snap)
+	}
+	if *quarter == "" {
+		latest := snaps[0].Date
+		for _, s := range snaps {
+			if s.Date.After(latest) {
+				latest = s.Date
+			}
+		}
+		*quarter = rollup.QuarterOf(latest)
+	}
+	r, err := rollup.Build(snaps, *quarter, *top)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *outPath == "" {
+		if err := rollup.Render(os.Stdout, r, *format, *tmplPath); err != nil {
+			fmt.Printf("Failed to render rollup: %v\n", err)
+		}
+		return
+	}
+	err = writeOutput(context.Background(), *outPath, 0, nil, func(w io.Writer) error {
+		return rollup.Render(w, r, *format, *tmplPath)
+	})
+	if err != nil {
+		fmt.Printf("Failed to write rollup: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Rolled up %d reports for %s\n", r.Scans, r.Quarter)
+	fmt.Printf("Saved to %s\n", *outPath)
+}
+
+// readSnapshot reads the parts of a stored report a rollup needs. Reports
+// without metadata are dated by their modification time.
+func readSnapshot(path string) (rollup.Snapshot, error) {
+	data, err := readReport(path)
+	if err != nil {
+		return rollup.Snapshot{}, err
+	}
+	var rep report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return rollup.Snapshot{}, err
+	}
+	snap := rollup.Snapshot{Packages: rep.Combined, Risk: rep.Risk}
+	if rep.Meta != nil {
+		snap.Repos = rep.Meta.Repos
+		snap.Date, err = time.Parse(time.RFC3339, rep.Meta.StartedAt)
+		if err != nil {
+			return rollup.Snapshot{}, fmt.Errorf("invalid started_at: %v", err)
+		}
+		return snap, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return rollup.Snapshot{}, err
+	}
+	snap.Date = fi.ModTime()
+	err = forEachRepo(rep.Stats, path, func(stats.RepoResult) { snap.Repos++ })
+	return snap, err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pgithub.com/plasmatrip/pubscan/internal/dtrack"
+	"pgithub.com/plasmatrip/pubscan/internal/sbom"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// writeSBOMs generates a CycloneDX SBOM for every scanned repo, writes it to
+// dir when set and uploads it to Dependency-Track when dt is not nil.
+func writeSBOMs(ctx context.Context, s stats.Stats, reportPath, dir string, dt *dtrack.Client) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	now := clock()
+	var written, uploaded, failed int
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error != "" {
+			return
+		}
+		data, _ := json.MarshalIndent(sbom.Build(r, now), "", "  ")
+		if dir != "" {
+			if err := os.WriteFile(filepath.Join(dir, sbom.FileName(r.Repo)), data, 0644); err != nil {
+				fmt.Printf("Failed to write SBOM for %s: %v\n", r.Repo, err)
+				failed++
+				return
+			}
+			written++
+		}
+		if dt != nil {
+			if err := dt.UploadBOM(ctx, r.Repo, r.Branch, data); err != nil {
+				fmt.Printf("Failed to upload SBOM for %s: %v\n", r.Repo, err)
+				failed++
+				return
+			}
+			uploaded++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		fmt.Printf("%d SBOMs saved to %s\n", written, dir)
+	}
+	if dt != nil {
+		fmt.Printf("%d SBOMs uploaded to Dependency-Track\n", uploaded)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d SBOM(s) failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/adoption"
+	"pgithub.com/plasmatrip/pubscan/internal/badge"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// runServe implements `pubscan serve`, serving usage and adoption badges
+// from the latest scan report.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	reportPath := fs.String("report", "", "Path to the scan report JSON")
+	adoptionPath := fs.String("adoption", "", "Path to the adoption report (default: <report>.adoption.json if present)")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs serve --report stats.json [--adoption stats.adoption.json] [--addr :8080]
+
+Options:
+  --report     Path to the scan report JSON
+  --adoption   Path to the adoption report (default: <report>.adoption.json if present)
+  --addr       Address to listen on (default: :8080)
+
+Endpoints:
+  /badge/<package>.svg                   "used by N repos" badge
+  /badge/<package>.svg?metric=adoption   adoption percentage badge
+  /badge/<package>.json                  shields.io endpoint JSON (same metrics)`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *reportPath == "" {
+		fmt.Println("Missing required arguments. Use pgs serve --help for usage.")
+		return
+	}
+	if *adoptionPath == "" {
+		*adoptionPath = strings.TrimSuffix(*reportPath, ".json") + ".adoption.json"
+	}
+
+	src := &badgeSource{reportPath: *reportPath, adoptionPath: *adoptionPath}
+	if err := src.reload(); err != nil {
+		fmt.Printf("Failed to load report: %v\n", err)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/badge/", src.serveBadge)
+	fmt.Printf("Serving badges for %s on %s\n", *reportPath, *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Printf("Server stopped: %v\n", err)
+	}
+}
+
+// badgeSource holds the usage counts of the latest report and reloads them
+// when the report file changes.
+type badgeSource struct {
+	reportPath, adoptionPath string
+
+	mu       sync.Mutex
+	modTime  time.Time
+	usage    map[string]int
+	adoption map[string]float64
+}
+
+func (b *badgeSource) reload() error {
+	fi, err := os.Stat(b.reportPath)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if fi.ModTime().Equal(b.modTime) && b.usage != nil {
+		return nil
+	}
+
+	data, err := readReport(b.reportPath)
+	if err != nil {
+		return err
+	}
+	var s stats.Stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	// Per-repo results are not cut off by --min, so prefer them over the
+	// combined counts.
+	usage := map[string]int{}
+	switch {
+	case s.Repos != nil || s.ReposFile != "":
+		err = forEachRepo(s, b.reportPath, func(r stats.RepoResult) {
+			for _, name := range stats.PackageNames(r) {
+				usage[name]++
+			}
+		})
+		if err != nil {
+			return err
+		}
+	case len(s.Combined) > 0:
+		for _, p := range s.Combined {
+			usage[p.Name] = p.Count
+		}
+	default:
+		for _, list := range [][]stats.PackageStat{s.Dependencies, s.DevDependencies, s.DependencyOverrides} {
+			for _, p := range list {
+				usage[p.Name] = max(usage[p.Name], p.Count)
+			}
+		}
+	}
+
+	adoptionPct := map[string]float64{}
+	if data, err := os.ReadFile(b.adoptionPath); err == nil {
+		var ar adoption.Report
+		if err := json.Unmarshal(data, &ar); err != nil {
+			return fmt.Errorf("parse %s: %w", b.adoptionPath, err)
+		}
+		for _, p := range ar.Packages {
+			adoptionPct[p.Name] = p.Percent
+		}
+	}
+
+	b.modTime, b.usage, b.adoption = fi.ModTime(), usage, adoptionPct
+	return nil
+}
+
+func (b *badgeSource) serveBadge(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/badge/")
+	asJSON := strings.HasSuffix(name, ".json")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".svg")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if err := b.reload(); err != nil {
+		fmt.Printf("Failed to reload report: %v\n", err)
+	}
+
+	b.mu.Lock()
+	var bd badge.Badge
+	switch r.URL.Query().Get("metric") {
+	case "", "usage":
+		bd = badge.Usage(b.usage[name])
+	case "adoption":
+		if pct, ok := b.adoption[name]; ok {
+			bd = badge.Adoption(pct)
+		} else {
+			bd = badge.Unknown("adoption")
+		}
+	default:
+		b.mu.Unlock()
+		http.Error(w, "unknown metric", http.StatusBadRequest)
+		return
+	}
+	b.mu.Unlock()
+
+	w.Header().Set("Cache-Control", "max-age=300")
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bd.Endpoint())
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	fmt.Fprint(w, bd.SVG())
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/signing"
+)
+
+// newSigner returns the signer for --sign, with the key from SIGNING_KEY,
+// or nil when signing is off.
+func newSigner(tool string) (*signing.Signer, error) {
+	if tool == "" {
+		return nil, nil
+	}
+	if !slices.Contains(signing.Tools, tool) {
+		return nil, fmt.Errorf("unknown --sign tool %q (want %s)", tool, strings.Join(signing.Tools, " or "))
+	}
+	s := &signing.Signer{Tool: tool, Key: os.Getenv("SIGNING_KEY"), Password: os.Getenv("MINISIGN_PASSWORD")}
+	if s.Key == "" {
+		return nil, fmt.Errorf("--sign %s needs the private key path in SIGNING_KEY", tool)
+	}
+	return s, nil
+}
+
+// sealFiles writes a checksum and a signature, as requested, next to each
+// of the written report files.
+func sealFiles(ctx context.Context, paths []string, checksum bool, signer *signing.Signer) error {
+	for _, path := range paths {
+		if checksum {
+			out, err := signing.Checksum(path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Checksum saved to %s\n", out)
+		}
+		if signer != nil {
+			out, err := signer.Sign(ctx, path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Signature saved to %s\n", out)
+		}
+	}
+	return nil
+}
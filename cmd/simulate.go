@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/simulate"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// runSimulate implements `pubscan simulate`, showing from a stored report
+// which repos could take a new version of a package before an upgrade
+// campaign starts.
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	pkgName := fs.String("package", "", "Package to upgrade")
+	to := fs.String("to", "", "Version to upgrade the package to")
+	pubdevURL := fs.String("pubdev-url", pubdev.DefaultBaseURL, "pub.dev API base URL")
+	outPath := fs.String("out", "", "Path to write the simulation to as JSON")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs simulate --package dio --to 6.0.0 [options] report.json
+
+Options:
+  --package    Package to upgrade
+  --to         Version to upgrade the package to
+  --pubdev-url pub.dev API base URL (default: https://pub.dev)
+  --out        Path to write the simulation to as JSON
+
+Reports scanned with --lockfile give the most accurate results.`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *pkgName == "" || *to == "" || fs.NArg() != 1 {
+		fmt.Println("Give --package, --to and a report file. Use pgs simulate --help for usage.")
+		return
+	}
+	version, err := semver.ParseVersion(*to)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	limitRate(*pubdevURL, pubdevRate)
+
+	reportPath := fs.Arg(0)
+	data, err := readReport(reportPath)
+	if err != nil {
+		fmt.Printf("Failed to read report: %v\n", err)
+		return
+	}
+	var s stats.Stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		fmt.Printf("Failed to parse report: %v\n", err)
+		return
+	}
+
+	pd := newPubDev(*pubdevURL)
+	idx := &releaseIndex{ctx: context.Background(), client: pd, cache: map[string][]pubdev.Release{}}
+	target := simulate.Target{Package: *pkgName, Version: version}
+	published := false
+	for _, rel := range idx.releases(*pkgName) {
+		if rel.Version == version.String() {
+			published = true
+			deps, _ := rel.Pubspec["dependencies"].(map[string]interface{})
+			target.Dependencies = pubspec.Constraints(deps)
+			target.Environment = environment(rel.Pubspec)
+		}
+	}
+	if !published {
+		fmt.Printf("⚠️  %s %s is not published, its own constraints are not checked\n", *pkgName, version)
+	}
+
+	var repos []simulate.Repo
+	err = forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if res, ok := simulate.Check(r, target, idx); ok {
+			repos = append(repos, res)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Failed to read per-repo results: %v\n", err)
+		return
+	}
+	rep := simulate.Build(target, published, repos)
+	printSimulation(rep)
+
+	if *outPath != "" {
+		if err := writeJSON(context.Background(), *outPath, 0, nil, rep); err != nil {
+			fmt.Printf("Failed to write simulation: %v\n", err)
+			return
+		}
+		fmt.Printf("Saved to %s\n", *outPath)
+	}
+}
+
+func printSimulation(rep simulate.Report) {
+	fmt.Printf("%s %s: %d repos accept it as-is, %d need a constraint bump, %d have conflicts\n",
+		rep.Package, rep.Version, rep.Counts[simulate.Accepts], rep.Counts[simulate.NeedsBump], rep.Counts[simulate.Conflict])
+	for _, r := range rep.Repos {
+		switch r.Status {
+		case simulate.Accepts:
+			fmt.Printf("✅ %s (%s)\n", r.Repo, r.Constraint)
+		case simulate.NeedsBump:
+			fmt.Printf("⬆️  %s: %s → %s\n", r.Repo, r.Constraint, r.Suggested)
+		case simulate.NotHosted:
+			fmt.Printf("➖ %s: not a hosted dependency (%s)\n", r.Repo, r.Constraint)
+		case simulate.Conflict:
+			line := fmt.Sprintf("❌ %s (%s)", r.Repo, r.Constraint)
+			if r.Suggested != "" {
+				line += " → " + r.Suggested
+			}
+			fmt.Println(line)
+			for _, c := range r.Clashes {
+				fmt.Printf("   %s\n", c)
+			}
+		}
+	}
+}
+
+// environment returns the sdk and flutter constraints of a published
+// pubspec.
+func environment(ps map[string]interface{}) map[string]string {
+	env, _ := ps["environment"].(map[string]interface{})
+	out := map[string]string{}
+	for k, v := range env {
+		if v != nil {
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+// releaseIndex is a simulate.Index over pub.dev. Each package listing is
+// fetched once.
+type releaseIndex struct {
+	ctx    context.Context
+	client *pubdev.Client
+	cache  map[string][]pubdev.Release
+}
+
+func (x *releaseIndex) releases(name string) []pubdev.Release {
+	if rels, done := x.cache[name]; done {
+		return rels
+	}
+	var rels []pubdev.Release
+	pkg, err := x.client.Package(x.ctx, name)
+	switch {
+	case err == nil:
+		rels = pkg.Versions
+	case !errors.Is(err, pubdev.ErrNotFound):
+		fmt.Printf("Error fetching releases of %s: %v\n", name, err)
+	}
+	x.cache[name] = rels
+	return rels
+}
+
+func (x *releaseIndex) Requires(name, version string) map[string]string {
+	for _, rel := range x.releases(name) {
+		if rel.Version == version {
+			deps, _ := rel.Pubspec["dependencies"].(map[string]interface{})
+			return pubspec.Constraints(deps)
+		}
+	}
+	return nil
+}
+
+func (x *releaseIndex) Newest(name string, r semver.Range) string {
+	var best *semver.Version
+	for _, rel := range x.releases(name) {
+		v, err := semver.ParseVersion(rel.Version)
+		if err != nil || v.Pre != "" || !r.Allows(v) {
+			continue
+		}
+		if best == nil || v.Compare(*best) > 0 {
+			best = &v
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.String()
+}
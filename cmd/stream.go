@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/anonymize"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// resultStream writes each per-repo result as one NDJSON line as soon as
+// the repo is scanned (--format ndjson), so consumers can process a long
+// scan while it runs.
+type resultStream struct {
+	mu   sync.Mutex
+	w    io.Writer
+	f    *os.File
+	anon *anonymize.Anonymizer
+	n    int
+	err  error
+}
+
+// openStream creates the stream at path, or on stdout for "-", keeping up
+// to keep previous versions of the file.
+func openStream(path string, keep int, anon *anonymize.Anonymizer) (*resultStream, error) {
+	if path == "-" {
+		return &resultStream{w: os.Stdout, anon: anon}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := backup(path, keep); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &resultStream{w: f, f: f, anon: anon}, nil
+}
+
+// Add writes r unbuffered, so every finished line is visible to readers.
+func (s *resultStream) Add(r stats.RepoResult) {
+	if s.anon != nil {
+		r = s.anon.RepoResult(r)
+	}
+	data, err := json.Marshal(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if err == nil {
+		_, err = s.w.Write(append(data, '\n'))
+	}
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.n++
+}
+
+// Close returns the first write error.
+func (s *resultStream) Close() error {
+	if s.f != nil {
+		if err := s.f.Close(); err != nil && s.err == nil {
+			s.err = err
+		}
+		s.f = nil
+	}
+	return s.err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/gitmodules"
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// scanSubmodules scans the GitHub repos the .gitmodules of an opened repo
+// includes at or below the entry path, outside its excluded directories,
+// each at the commit it is pinned to. Their results carry the labels of
+// the entry and name the repo in SubmoduleOf; submodules without a pubspec
+// are left out. Submodules of submodules are not followed.
+func scanSubmodules(ctx context.Context, client *github.Client, entry repolist.Entry, opts scanOptions, parent stats.RepoResult) []stats.RepoResult {
+	owner, repo, _ := strings.Cut(entry.Name, "/")
+	ref := scanRef(parent)
+	content, err := client.File(ctx, owner, repo, ref, gitmodules.File)
+	if err != nil {
+		if !errors.Is(err, github.ErrNotFound) {
+			fmt.Printf("Error reading submodules of %s: %v\n", parent.Repo, err)
+		}
+		return nil
+	}
+
+	o := opts
+	o.submodules = false
+	var out []stats.RepoResult
+	for _, sm := range gitmodules.Parse(content) {
+		if entry.Path != "" && sm.Path != entry.Path && !strings.HasPrefix(sm.Path, entry.Path+"/") || entry.Excludes(sm.Path) {
+			continue
+		}
+		name, ok := gitmodules.Repo(entry.Name, sm.URL)
+		if !ok {
+			fmt.Printf("⚠️  Submodule %s of %s is not on GitHub, skipping it: %s\n", sm.Path, parent.Repo, sm.URL)
+			continue
+		}
+		sub := repolist.Entry{
+			Name:        name,
+			Labels:      entry.Labels,
+			Credentials: entry.Credentials,
+			Input:       entry.Input,
+			Line:        entry.Line,
+		}
+		if sub.Ref, err = client.Submodule(ctx, owner, repo, ref, sm.Path); err != nil {
+			fmt.Printf("⚠️  Commit of submodule %s in %s not resolved, scanning its latest branch: %v\n", sm.Path, parent.Repo, err)
+		}
+		fmt.Printf("Scanning submodule %s of %s...\n", name, parent.Repo)
+		for _, res := range scanEntry(ctx, client, sub, o) {
+			if res.NotDart {
+				continue
+			}
+			res.SubmoduleOf = parent.Repo
+			out = append(out, res)
+		}
+	}
+	return out
+}
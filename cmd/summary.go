@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/l10n"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// summaryTop is the number of packages and failures the summary lists.
+const summaryTop = 10
+
+// stdoutIsTerminal is set at startup, before stdout is replaced by the
+// redaction pipe.
+var stdoutIsTerminal bool
+
+// isTerminal reports whether f is a character device rather than a file or
+// pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor reports whether the summary may be colored: stdout is a
+// terminal and neither --no-color nor NO_COLOR asks otherwise.
+func useColor(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && stdoutIsTerminal
+}
+
+// palette wraps text in ANSI colors when enabled.
+type palette bool
+
+func (p palette) wrap(code, s string) string {
+	if !p {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p palette) bold(s string) string   { return p.wrap("1", s) }
+func (p palette) red(s string) string    { return p.wrap("31", s) }
+func (p palette) yellow(s string) string { return p.wrap("33", s) }
+func (p palette) green(s string) string  { return p.wrap("32", s) }
+func (p palette) dim(s string) string    { return p.wrap("2", s) }
+
+// severity colors a finding severity name by how urgent it is.
+func (p palette) severity(sev, name string) string {
+	switch sev {
+	case findings.SeverityCritical, findings.SeverityHigh:
+		return p.red(name)
+	case findings.SeverityMedium:
+		return p.yellow(name)
+	}
+	return p.dim(name)
+}
+
+// printSummary writes what the scan found — failed repos, with those that
+// are not Dart projects only counted, the most used packages, findings and
+// gate violations — so the JSON need not be opened to know how a run went.
+func printSummary(rep report, reportPath string, p palette, tr *l10n.Printer) {
+	var scanned, notDart int
+	var failed []stats.RepoResult
+	err := forEachRepo(rep.Stats, reportPath, func(r stats.RepoResult) {
+		scanned++
+		switch {
+		case r.NotDart:
+			notDart++
+		case r.Error != "":
+			failed = append(failed, r)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Failed to read per-repo results: %v\n", err)
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(p.bold(tr.T("summary.title")))
+	status := p.green(tr.T("summary.scanned", scanned))
+	if len(failed) > 0 {
+		status += ", " + p.red(tr.T("summary.failed", len(failed)))
+	}
+	if notDart > 0 {
+		status += ", " + p.dim(tr.T("summary.not_dart", notDart))
+	}
+	fmt.Printf("  %s\n", tr.T("summary.repos", status))
+	for i, r := range failed {
+		if i == summaryTop {
+			fmt.Printf("    %s\n", p.dim(tr.T("summary.more", len(failed)-summaryTop)))
+			break
+		}
+		fmt.Printf("    %s %s\n", p.red("✗ "+r.Repo), p.dim(firstLine(r.Error)))
+	}
+
+	if n := len(rep.Combined); n > 0 {
+		fmt.Printf("  %s\n", tr.T("summary.top"))
+		for i := n - 1; i >= 0 && i >= n-summaryTop; i-- {
+			pkg := rep.Combined[i]
+			fmt.Printf("    %-32s %s\n", pkg.Name, p.dim(tr.N("summary.repo_count", pkg.Count)))
+		}
+	}
+
+	if tc := rep.Tech; tc != nil && len(tc.Ecosystems) > 1 {
+		fmt.Printf("  %s\n", tr.T("summary.technologies", mix(tc.Combinations)))
+	}
+
+	if len(rep.Findings) > 0 {
+		counts := map[string]int{}
+		for _, f := range rep.Findings {
+			counts[f.Severity]++
+		}
+		var parts []string
+		for _, sev := range findings.Severities {
+			if counts[sev] > 0 {
+				parts = append(parts, tr.T("summary.severity_count", counts[sev], p.severity(sev, tr.T("severity."+sev))))
+			}
+		}
+		fmt.Printf("  %s\n", tr.T("summary.findings", strings.Join(parts, ", ")))
+	}
+	if n := len(rep.GateViolations); n > 0 {
+		fmt.Printf("  %s\n", tr.T("summary.gates", p.red(tr.N("summary.violations", n))))
+	}
+	fmt.Println()
+}
+
+// mix lists the repos per combination of ecosystems, most common first.
+func mix(combinations map[string]int) string {
+	names := make([]string, 0, len(combinations))
+	for name := range combinations {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if combinations[names[i]] != combinations[names[j]] {
+			return combinations[names[i]] > combinations[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, combinations[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstLine shortens an error to its first line.
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) > 120 {
+		s = s[:117] + "..."
+	}
+	return s
+}
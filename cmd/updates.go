@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+	"pgithub.com/plasmatrip/pubscan/internal/updates"
+)
+
+// detectUpdates finds the Dependabot and Renovate configs of a repo and
+// returns the tools that update the pub dependencies in dir, or
+// updates.None, and the tools configured at all. The repo root and .github
+// are listed first, so only configs that exist are fetched.
+func detectUpdates(ctx context.Context, client *github.Client, owner, repo, ref, dir string) ([]string, []string, error) {
+	present := map[string]bool{}
+	for _, d := range []string{"", ".github"} {
+		names, err := client.Dir(ctx, owner, repo, ref, d)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range names {
+			present[path.Join(d, name)] = true
+		}
+	}
+
+	var pub, configured []string
+	check := func(tool string, files []string, covers func(string) (bool, error)) error {
+		for _, file := range files {
+			if !present[file] {
+				continue
+			}
+			configured = append(configured, tool)
+			content, err := client.File(ctx, owner, repo, ref, file)
+			if err != nil {
+				return err
+			}
+			ok, err := covers(content)
+			if err != nil {
+				return err
+			}
+			if ok {
+				pub = append(pub, tool)
+			}
+			return nil
+		}
+		return nil
+	}
+	err := check(updates.ToolDependabot, updates.DependabotFiles, func(c string) (bool, error) {
+		return updates.DependabotCoversPub(c, dir)
+	})
+	if err == nil {
+		err = check(updates.ToolRenovate, updates.RenovateFiles, func(c string) (bool, error) {
+			return updates.RenovateCoversPub(c), nil
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pub) == 0 {
+		pub = []string{updates.None}
+	}
+	return pub, configured, nil
+}
+
+// outdatedDeps counts, per checked repo, the hosted dependencies whose
+// constraint excludes the latest version on pub.dev. The enricher already
+// holds the packages after risk scoring, so no requests are made for them.
+func outdatedDeps(ctx context.Context, s stats.Stats, reportPath string, en *enrich.Enricher) (map[string]int, map[string]bool, error) {
+	outdated, covered := map[string]int{}, map[string]bool{}
+	type dep struct{ repo, name, constraint string }
+	var deps []dep
+	err := forEachRepo(s, reportPath, func(r stats.RepoResult) {
+		if r.Error != "" || len(r.PubUpdates) == 0 {
+			return
+		}
+		outdated[r.Repo] = 0
+		covered[r.Repo] = r.PubUpdates[0] != updates.None
+		for name, c := range r.Constraints {
+			if !strings.Contains(c, ":") {
+				deps = append(deps, dep{r.Repo, name, c})
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range deps {
+		info, err := en.Package(ctx, d.name)
+		if err != nil || info.NotFound || info.Latest == "" {
+			continue
+		}
+		rng, err1 := semver.ParseConstraint(d.constraint)
+		latest, err2 := semver.ParseVersion(info.Latest)
+		if err1 == nil && err2 == nil && !rng.Allows(latest) {
+			outdated[d.repo]++
+		}
+	}
+	return outdated, covered, nil
+}
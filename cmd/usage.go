@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"pgithub.com/plasmatrip/pubscan/internal/quota"
+)
+
+// printUsage writes the API requests of the run per provider, with the
+// remaining quota where the provider reports it, and the cache hit rates.
+func printUsage(u *quota.Usage) {
+	if u == nil {
+		return
+	}
+	fmt.Println("API requests:")
+	for _, p := range u.Providers {
+		line := fmt.Sprintf("  %-18s %6d", p.Provider, p.Requests)
+		if p.Errors > 0 {
+			line += fmt.Sprintf(", %d failed", p.Errors)
+		}
+		if p.RateLimited > 0 {
+			line += fmt.Sprintf(", %d rate limited", p.RateLimited)
+		}
+		if p.Remaining != nil {
+			line += fmt.Sprintf(" (%d of %d remaining", *p.Remaining, p.Limit)
+			if p.Reset != nil {
+				line += ", resets " + p.Reset.Local().Format("15:04")
+			}
+			line += ")"
+		}
+		fmt.Println(line)
+	}
+	if len(u.Caches) > 0 {
+		fmt.Println("Cache hit rates:")
+		for _, c := range u.Caches {
+			fmt.Printf("  %-18s %5.1f%% (%d of %d)\n", c.Cache, 100*c.HitRate, c.Hits, c.Hits+c.Misses)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/osv"
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+)
+
+// watchedFiles are the files whose changes trigger a new analysis.
+var watchedFiles = []string{"pubspec.yaml", "pubspec.lock"}
+
+// fileState is what a change is detected from.
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+// runWatch implements `pubscan watch`, re-checking a local checkout whenever
+// its pubspec changes and printing the findings that appeared or went away.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	localPath := fs.String("local", ".", "Directory of the pubspec.yaml to watch")
+	mainDeps := fs.Bool("maindeps", false, "Only check main dependencies")
+	pubdevURL := fs.String("pubdev-url", pubdev.DefaultBaseURL, "pub.dev API base URL")
+	osvURL := fs.String("osv-url", osv.DefaultBaseURL, "OSV API base URL")
+	staleMonths := fs.Int("stale-months", 24, "Months since the latest release after which a package counts as stale")
+	suppressionsPath := fs.String("suppressions", "", "YAML file of findings to waive, with reason and expiry")
+	interval := fs.Duration("interval", time.Second, "How often to look for changes")
+	fs.Usage = func() {
+		fmt.Println(`Usage:
+  pgs watch [options] --local .
+
+Options:
+  --local      Directory of the pubspec.yaml to watch (default: .)
+  --maindeps   Only check main dependencies
+  --pubdev-url pub.dev API base URL (default: https://pub.dev)
+  --osv-url    OSV API base URL (default: https://api.osv.dev)
+  --stale-months
+               Months since the latest release after which a package counts as stale (default: 24)
+  --suppressions
+               YAML file of findings to waive, with reason and expiry
+  --interval   How often to look for changes (default: 1s)
+
+The checkout is analyzed on start and again whenever pubspec.yaml or
+pubspec.lock changes. Stop with Ctrl+C.`)
+	}
+	parseFlags(fs, args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var suppressions []findings.Suppression
+	if *suppressionsPath != "" {
+		var err error
+		if suppressions, err = findings.LoadSuppressions(*suppressionsPath); err != nil {
+			fmt.Printf("Failed to read suppressions file: %v\n", err)
+			return
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	limitEnrichment(*pubdevURL, *osvURL)
+	pd := newPubDev(*pubdevURL)
+	ov := newOSV(*osvURL)
+	// The enricher caches package info, so later runs only look up packages
+	// that were added in the meantime.
+	en := enrich.New(pd)
+	staleAfter := time.Duration(*staleMonths) * 30 * 24 * time.Hour
+
+	var (
+		prev    map[string]findings.Finding
+		state   = map[string]fileState{}
+		ticker  = time.NewTicker(*interval)
+		started bool
+	)
+	defer ticker.Stop()
+	for {
+		if changed := watchChanges(*localPath, state); !started || len(changed) > 0 {
+			if started {
+				fmt.Printf("\n[%s] %s changed\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+			}
+			started = true
+			if next, ok := watchCheck(ctx, *localPath, *mainDeps, en, ov, staleAfter, suppressions, prev); ok {
+				prev = next
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchChanges updates state and returns the watched files that changed
+// since the last call.
+func watchChanges(dir string, state map[string]fileState) []string {
+	var changed []string
+	for _, name := range watchedFiles {
+		var cur fileState
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			cur = fileState{mod: info.ModTime(), size: info.Size()}
+		}
+		if old, ok := state[name]; ok && old != cur {
+			changed = append(changed, name)
+		}
+		state[name] = cur
+	}
+	return changed
+}
+
+// watchCheck analyzes the checkout once. The first run prints every finding,
+// later runs only the difference to prev. It returns the current findings by
+// fingerprint, or false when the analysis failed and prev should be kept.
+func watchCheck(ctx context.Context, dir string, mainDeps bool, en *enrich.Enricher, ov *osv.Client, staleAfter time.Duration, suppressions []findings.Suppression, prev map[string]findings.Finding) (map[string]findings.Finding, bool) {
+	res, err := checkLocal(dir, mainDeps)
+	if err != nil {
+		fmt.Printf("Failed to read pubspec.yaml: %v\n", err)
+		return nil, false
+	}
+	list, suppressed, err := checkFindings(ctx, res, en, ov, staleAfter, nil, suppressions)
+	if err != nil {
+		fmt.Printf("Failed to check dependencies: %v\n", err)
+		return nil, false
+	}
+	cur := make(map[string]findings.Finding, len(list))
+	for _, f := range list {
+		cur[f.Fingerprint()] = f
+	}
+	if prev == nil {
+		printCheck(res, list, suppressed, "")
+		return cur, true
+	}
+
+	var added, resolved []findings.Finding
+	for _, f := range list {
+		if _, ok := prev[f.Fingerprint()]; !ok {
+			added = append(added, f)
+		}
+	}
+	for key, f := range prev {
+		if _, ok := cur[key]; !ok {
+			resolved = append(resolved, f)
+		}
+	}
+	findings.SortBySeverity(added)
+	findings.SortBySeverity(resolved)
+	for _, f := range added {
+		fmt.Printf("  + %-8s %-24s %s\n", strings.ToUpper(f.Severity), f.Rule, f.Message)
+	}
+	for _, f := range resolved {
+		fmt.Printf("  - %-8s %-24s %s\n", strings.ToUpper(f.Severity), f.Rule, f.Message)
+	}
+	fmt.Printf("%s: %d findings (%d new, %d resolved)\n", res.Repo, len(list), len(added), len(resolved))
+	return cur, true
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+)
+
+// repoWeights derives repo weights from GitHub metadata: 1 + stars, or the
+// highest weight among the repo's topics listed in tiers.
+type repoWeights struct {
+	by    string
+	tiers map[string]float64
+}
+
+func newRepoWeights(by, tiers string) (*repoWeights, error) {
+	w := &repoWeights{by: by, tiers: map[string]float64{}}
+	switch by {
+	case "stars":
+	case "topics":
+		if tiers == "" {
+			return nil, fmt.Errorf("--weight-by topics needs --weight-tiers")
+		}
+		for _, pair := range strings.Split(tiers, ",") {
+			topic, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			weight, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if !ok || err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight tier %q", pair)
+			}
+			w.tiers[strings.ToLower(strings.TrimSpace(topic))] = weight
+		}
+	default:
+		return nil, fmt.Errorf("unknown --weight-by %q (expected stars or topics)", by)
+	}
+	return w, nil
+}
+
+func (w *repoWeights) weight(ctx context.Context, client *github.Client, owner, repo string) (float64, error) {
+	meta, err := client.Repo(ctx, owner, repo)
+	if err != nil {
+		return 0, err
+	}
+	if w.by == "stars" {
+		return float64(1 + meta.Stars), nil
+	}
+	best := 0.0
+	for _, t := range meta.Topics {
+		if v, ok := w.tiers[strings.ToLower(t)]; ok && v > best {
+			best = v
+		}
+	}
+	if best == 0 {
+		return 1, nil
+	}
+	return best, nil
+}
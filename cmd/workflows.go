@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"pgithub.com/plasmatrip/pubscan/internal/github"
+	"pgithub.com/plasmatrip/pubscan/internal/toolchain"
+)
+
+// ciVersions reads the GitHub Actions workflows of a repo and returns the
+// Flutter and Dart versions they install. Workflows that do not parse are
+// skipped with a warning; a repo without workflows has no versions.
+func ciVersions(ctx context.Context, client *github.Client, owner, repo, ref string) (toolchain.CIVersions, error) {
+	var out toolchain.CIVersions
+	names, err := client.Dir(ctx, owner, repo, ref, toolchain.WorkflowsDir)
+	if errors.Is(err, github.ErrNotFound) {
+		return out, nil
+	}
+	if err != nil {
+		return out, err
+	}
+	for _, name := range names {
+		if !toolchain.IsWorkflow(name) {
+			continue
+		}
+		file := path.Join(toolchain.WorkflowsDir, name)
+		content, err := client.File(ctx, owner, repo, ref, file)
+		if err != nil {
+			return out, err
+		}
+		v, err := toolchain.ParseWorkflow(content)
+		if err != nil {
+			fmt.Printf("Skipping %s in %s/%s: %v\n", file, owner, repo, err)
+			continue
+		}
+		out.Merge(v)
+	}
+	return out, nil
+}
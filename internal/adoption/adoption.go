@@ -0,0 +1,191 @@
+package adoption
+
+import (
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Package is an internal package whose adoption is tracked. Expected lists
+// the repos that should use it: owner/repo globs such as "acme/*" or
+// CODEOWNERS teams such as "@acme/mobile". Without it every scanned repo is
+// expected to.
+type Package struct {
+	Name     string   `yaml:"name"`
+	Expected []string `yaml:"expected"`
+}
+
+type Config struct {
+	Packages []Package `yaml:"packages"`
+}
+
+type RepoUsage struct {
+	Repo       string `json:"repo"`
+	Constraint string `json:"constraint"`
+}
+
+type TeamAdoption struct {
+	Team    string  `json:"team"`
+	Repos   int     `json:"repos"`
+	Using   int     `json:"using"`
+	Percent float64 `json:"percent"`
+}
+
+type PackageAdoption struct {
+	Name     string         `json:"name"`
+	Using    []RepoUsage    `json:"using"`
+	Versions map[string]int `json:"versions"`
+	Missing  []string       `json:"missing"`
+	Expected int            `json:"expected"`
+	Percent  float64        `json:"percent"`
+	Teams    []TeamAdoption `json:"teams,omitempty"`
+}
+
+type Report struct {
+	Packages []PackageAdoption `json:"packages"`
+}
+
+type tracked struct {
+	Package
+	using    []RepoUsage
+	missing  []string
+	expected int
+	teams    map[string]*TeamAdoption
+}
+
+// Tracker accumulates adoption of internal packages as repo results arrive.
+type Tracker struct {
+	mu       sync.Mutex
+	packages []*tracked
+}
+
+// --- Core logic ---
+
+// Load reads the internal packages file.
+func Load(path string) (*Tracker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	t := &Tracker{}
+	for _, p := range cfg.Packages {
+		t.packages = append(t.packages, &tracked{Package: p, teams: map[string]*TeamAdoption{}})
+	}
+	return t, nil
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range t.packages {
+		c, uses := r.Constraints[p.Name]
+		if !uses {
+			uses = contains(r.Dependencies, p.Name) || contains(r.DevDependencies, p.Name)
+		}
+		if !p.expects(r) {
+			if uses {
+				p.using = append(p.using, RepoUsage{Repo: r.Repo, Constraint: c})
+			}
+			continue
+		}
+
+		p.expected++
+		if uses {
+			p.using = append(p.using, RepoUsage{Repo: r.Repo, Constraint: c})
+		} else {
+			p.missing = append(p.missing, r.Repo)
+		}
+		for _, team := range r.Owners {
+			ta := p.teams[team]
+			if ta == nil {
+				ta = &TeamAdoption{Team: team}
+				p.teams[team] = ta
+			}
+			ta.Repos++
+			if uses {
+				ta.Using++
+			}
+		}
+	}
+}
+
+func (p *tracked) expects(r stats.RepoResult) bool {
+	if len(p.Expected) == 0 {
+		return true
+	}
+	repo := strings.ToLower(r.Repo)
+	for _, e := range p.Expected {
+		if strings.HasPrefix(e, "@") {
+			if contains(r.Owners, e) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(strings.ToLower(e), repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var rep Report
+	for _, p := range t.packages {
+		pa := PackageAdoption{
+			Name:     p.Name,
+			Using:    append([]RepoUsage{}, p.using...),
+			Versions: map[string]int{},
+			Missing:  append([]string{}, p.missing...),
+			Expected: p.expected,
+			Percent:  percent(p.expected-len(p.missing), p.expected),
+		}
+		sort.Slice(pa.Using, func(i, j int) bool { return pa.Using[i].Repo < pa.Using[j].Repo })
+		sort.Strings(pa.Missing)
+		for _, u := range p.using {
+			pa.Versions[u.Constraint]++
+		}
+		for _, ta := range p.teams {
+			ta.Percent = percent(ta.Using, ta.Repos)
+			pa.Teams = append(pa.Teams, *ta)
+		}
+		sort.Slice(pa.Teams, func(i, j int) bool { return pa.Teams[i].Team < pa.Teams[j].Team })
+		rep.Packages = append(rep.Packages, pa)
+	}
+	return rep
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*1000) / 10
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/hosted"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Anonymizer replaces repo and team names with salted hashes. The same salt
+// gives the same names across runs, so anonymized reports stay comparable
+// without revealing the inventory.
+type Anonymizer struct {
+	salt []byte
+}
+
+func New(salt string) *Anonymizer {
+	return &Anonymizer{salt: []byte(salt)}
+}
+
+// --- Core logic ---
+
+func (a *Anonymizer) hash(kind, s string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind + ":" + strings.ToLower(s)))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Repo hashes an owner/repo name.
+func (a *Anonymizer) Repo(name string) string {
+	return "repo-" + a.hash("repo", name)
+}
+
+// GitURL hashes a git dependency URL.
+func (a *Anonymizer) GitURL(url string) string {
+	return "git-" + a.hash("git", url)
+}
+
+// Team hashes a CODEOWNERS team or user. Unowned stays as is.
+func (a *Anonymizer) Team(name string) string {
+	if name == stats.Unowned {
+		return name
+	}
+	return "team-" + a.hash("team", name)
+}
+
+// Error scrubs an error of the repo: errors may quote request URLs, which
+// name the repo and its owner.
+func (a *Anonymizer) Error(e, repo string) string {
+	e = strings.ReplaceAll(e, repo, a.Repo(repo))
+	if owner, _, ok := strings.Cut(repo, "/"); ok && owner != "" {
+		e = strings.ReplaceAll(e, owner, "owner")
+	}
+	return e
+}
+
+// urlRe matches the URLs Text did not know about.
+var urlRe = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s,;()]+`)
+
+// Text scrubs free text about repo, such as the message of a finding: the
+// given git and package server URLs become their GitURL hashes, so they
+// match the hashed report sections, repo becomes its Repo hash, and any
+// other URL is hashed as a whole.
+func (a *Anonymizer) Text(s, repo string, urls []string) string {
+	if s == "" {
+		return s
+	}
+	sorted := append([]string(nil), urls...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	var pairs []string
+	for _, u := range sorted {
+		if u != "" {
+			pairs = append(pairs, u, a.GitURL(u))
+		}
+	}
+	if repo != "" {
+		pairs = append(pairs, repo, a.Repo(repo))
+	}
+	s = urlRe.ReplaceAllStringFunc(s, func(u string) string {
+		for i := 0; i < len(pairs); i += 2 {
+			if strings.Contains(u, pairs[i]) {
+				return u
+			}
+		}
+		return a.GitURL(u)
+	})
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// Constraint drops git URLs and local paths, which name orgs and
+// directory layouts, and keeps hosted version constraints.
+func (a *Anonymizer) Constraint(c string) string {
+	switch {
+	case strings.HasPrefix(c, "git:"):
+		return "git"
+	case strings.HasPrefix(c, "path:"):
+		return "path"
+	}
+	return c
+}
+
+// RepoResult anonymizes one per-repo result. Package names are kept.
+func (a *Anonymizer) RepoResult(r stats.RepoResult) stats.RepoResult {
+	out := r
+	out.Repo = a.Repo(r.Repo)
+	if r.RenamedFrom != "" {
+		out.RenamedFrom = a.Repo(r.RenamedFrom)
+	}
+	if r.SubmoduleOf != "" {
+		out.SubmoduleOf = a.Repo(r.SubmoduleOf)
+	}
+	if r.Error != "" {
+		out.Error = a.Error(r.Error, r.Repo)
+	}
+	out.Owners = nil
+	for _, o := range r.Owners {
+		out.Owners = append(out.Owners, a.Team(o))
+	}
+	if r.Constraints != nil {
+		out.Constraints = make(map[string]string, len(r.Constraints))
+		for name, c := range r.Constraints {
+			out.Constraints[name] = a.Constraint(c)
+		}
+	}
+	if r.Hosted != nil {
+		out.Hosted = make(map[string]pubspec.Hosted, len(r.Hosted))
+		for name, h := range r.Hosted {
+			h.URL = a.GitURL(hosted.Normalize(h.URL))
+			out.Hosted[name] = h
+		}
+	}
+	if r.PublishTo != "" && r.PublishTo != "none" {
+		out.PublishTo = a.GitURL(r.PublishTo)
+	}
+	if r.Metadata != nil {
+		// Links point at the company's repos and issue trackers.
+		m := *r.Metadata
+		m.Homepage, m.Repository, m.IssueTracker, m.Documentation, m.Funding = "", "", "", "", nil
+		out.Metadata = &m
+	}
+	if r.Labels != nil {
+		// Label values often name teams or products.
+		out.Labels = make(map[string]string, len(r.Labels))
+		for k, v := range r.Labels {
+			out.Labels[k] = a.Team(v)
+		}
+	}
+	// Commit SHAs look up the repo on GitHub and file paths name products.
+	out.Provenance = nil
+	return out
+}
+
+// Stats anonymizes the repo and team names of a report and leaves the
+// package statistics intact.
+func (a *Anonymizer) Stats(s stats.Stats) stats.Stats {
+	out := s
+	out.Repos = nil
+	for _, r := range s.Repos {
+		out.Repos = append(out.Repos, a.RepoResult(r))
+	}
+	out.Teams = nil
+	for _, t := range s.Teams {
+		t.Team = a.Team(t.Team)
+		repos := make([]string, len(t.Repos))
+		for i, r := range t.Repos {
+			repos[i] = a.Repo(r)
+		}
+		t.Repos = repos
+		out.Teams = append(out.Teams, t)
+	}
+	out.Conflicts = nil
+	for _, c := range s.Conflicts {
+		if c.Repo != "" {
+			c.Repo = a.Repo(c.Repo)
+		}
+		out.Conflicts = append(out.Conflicts, c)
+	}
+	return out
+}
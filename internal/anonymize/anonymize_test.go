@@ -0,0 +1,137 @@
+package anonymize
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+func TestText(t *testing.T) {
+	a := New("salt")
+	fork := "https://github.com/acme/http"
+	server := "pub.acme.internal"
+	tests := []struct {
+		name string
+		text string
+		repo string
+		urls []string
+		want string
+	}{
+		{"empty", "", "acme/app", nil, ""},
+		{"no URLs", "http is outdated", "acme/app", nil, "http is outdated"},
+		{"repo", "acme/app pins http", "acme/app", nil, a.Repo("acme/app") + " pins http"},
+		{"known URL", "http is used from the fork " + fork + " instead of pub.dev", "acme/app", []string{fork},
+			"http is used from the fork " + a.GitURL(fork) + " instead of pub.dev"},
+		{"longest URL first", "fetched from " + server + "/team", "acme/app", []string{server, server + "/team"},
+			"fetched from " + a.GitURL(server+"/team")},
+		{"unknown URL", "hosted: url https://pub.other.internal, name http", "acme/app", nil,
+			"hosted: url " + a.GitURL("https://pub.other.internal") + ", name http"},
+		{"URL naming the repo", "GET https://api.github.com/repos/acme/app: 404", "acme/app", nil,
+			"GET https://api.github.com/repos/" + a.Repo("acme/app") + ": 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Text(tt.text, tt.repo, tt.urls); got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	a := New("salt")
+	tests := []struct {
+		err  string
+		repo string
+		want string
+	}{
+		{"acme/app: not found", "acme/app", a.Repo("acme/app") + ": not found"},
+		{"token has no access to acme", "acme/app", "token has no access to owner"},
+		{"timeout", "app", "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			if got := a.Error(tt.err, tt.repo); got != tt.want {
+				t.Errorf("Error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashes(t *testing.T) {
+	a, b := New("salt"), New("other")
+	tests := []struct {
+		name string
+		hash func(*Anonymizer, string) string
+		in   string
+	}{
+		{"repo", (*Anonymizer).Repo, "acme/app"},
+		{"git URL", (*Anonymizer).GitURL, "https://github.com/acme/http"},
+		{"team", (*Anonymizer).Team, "@acme/mobile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.hash(a, tt.in)
+			if got != tt.hash(a, strings.ToUpper(tt.in)) {
+				t.Errorf("hash depends on case")
+			}
+			if got == tt.hash(b, tt.in) {
+				t.Errorf("hash does not depend on the salt")
+			}
+			if strings.Contains(got, "acme") {
+				t.Errorf("hash %q names the org", got)
+			}
+		})
+	}
+	if got := a.Team(stats.Unowned); got != stats.Unowned {
+		t.Errorf("Team(Unowned) = %q", got)
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	a := New("salt")
+	tests := []struct {
+		in, want string
+	}{
+		{"^1.2.0", "^1.2.0"},
+		{"any", "any"},
+		{"git:https://github.com/acme/http", "git"},
+		{"path:../packages/core", "path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := a.Constraint(tt.in); got != tt.want {
+				t.Errorf("Constraint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoResult(t *testing.T) {
+	a := New("salt")
+	r := stats.RepoResult{
+		Repo:         "acme/app",
+		Dependencies: []string{"http", "core"},
+		Constraints:  map[string]string{"http": "^1.2.0", "core": "git:https://github.com/acme/core"},
+		Hosted:       map[string]pubspec.Hosted{"core": {URL: "https://pub.acme.internal/"}},
+		Owners:       []string{"@acme/mobile"},
+		Labels:       map[string]string{"product": "payments"},
+		Error:        "acme/app: rate limited",
+	}
+	got := a.RepoResult(r)
+	want := stats.RepoResult{
+		Repo:         a.Repo("acme/app"),
+		Dependencies: []string{"http", "core"},
+		Constraints:  map[string]string{"http": "^1.2.0", "core": "git"},
+		Hosted:       map[string]pubspec.Hosted{"core": {URL: a.GitURL("pub.acme.internal")}},
+		Owners:       []string{a.Team("@acme/mobile")},
+		Labels:       map[string]string{"product": a.Team("payments")},
+		Error:        a.Repo("acme/app") + ": rate limited",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RepoResult = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package backstage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SlugAnnotation is the annotation linking a component to its GitHub repo.
+const SlugAnnotation = "github.com/project-slug"
+
+// --- Structures ---
+
+// Component is a catalog component backed by a GitHub repository.
+type Component struct {
+	Namespace string
+	Name      string
+	Repo      string
+}
+
+type entity struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// Client reads the Backstage software catalog.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	Token   string
+}
+
+// --- Core logic ---
+
+// Components returns the components carrying any of the given tags.
+// Components without a GitHub project slug are skipped and returned by name.
+func (c *Client) Components(ctx context.Context, tags []string) ([]Component, []string, error) {
+	var (
+		out     []Component
+		skipped []string
+		cursor  string
+	)
+	seen := map[string]bool{}
+	for {
+		q := url.Values{"limit": {"500"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		} else {
+			for _, tag := range tags {
+				q.Add("filter", "kind=component,metadata.tags="+strings.TrimSpace(tag))
+			}
+		}
+
+		var page struct {
+			Items    []entity `json:"items"`
+			PageInfo struct {
+				NextCursor string `json:"nextCursor"`
+			} `json:"pageInfo"`
+		}
+		if err := c.get(ctx, "/api/catalog/entities/by-query?"+q.Encode(), &page); err != nil {
+			return nil, nil, err
+		}
+		for _, e := range page.Items {
+			ns := e.Metadata.Namespace
+			if ns == "" {
+				ns = "default"
+			}
+			ref := ns + "/" + e.Metadata.Name
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			slug := e.Metadata.Annotations[SlugAnnotation]
+			if slug == "" {
+				skipped = append(skipped, ref)
+				continue
+			}
+			out = append(out, Component{Namespace: ns, Name: e.Metadata.Name, Repo: slug})
+		}
+
+		cursor = page.PageInfo.NextCursor
+		if cursor == "" || len(page.Items) == 0 {
+			return out, skipped, nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("backstage catalog request failed: %s (%s)", resp.Status, string(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
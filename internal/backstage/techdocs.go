@@ -0,0 +1,53 @@
+package backstage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/l10n"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// DocsPage renders the TechDocs page describing a component's dependencies,
+// in the language of tr.
+func DocsPage(c Component, r stats.RepoResult, scanned time.Time, tr *l10n.Printer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", tr.T("docs.title"))
+	fmt.Fprintf(&b, "%s\n\n", tr.T("docs.generated", r.Repo, r.Branch, scanned.UTC().Format("2006-01-02")))
+
+	section := func(title string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		sorted := append([]string(nil), names...)
+		sort.Strings(sorted)
+		fmt.Fprintf(&b, "## %s\n\n| %s | %s |\n|---------|------------|\n", title, tr.T("docs.package"), tr.T("docs.constraint"))
+		for _, name := range sorted {
+			c := r.Constraints[name]
+			if c == "" {
+				c = "-"
+			}
+			fmt.Fprintf(&b, "| [%s](%s) | `%s` |\n", name, purl.URL(purl.For(r.Ecosystem, name)), strings.ReplaceAll(c, "|", "\\|"))
+		}
+		b.WriteString("\n")
+	}
+	section(tr.T("docs.dependencies"), r.Dependencies)
+	section(tr.T("docs.dev_dependencies"), r.DevDependencies)
+	section(tr.T("docs.dependency_overrides"), r.DependencyOverrides)
+	return b.String()
+}
+
+// WriteDocs writes the page to dir/<namespace>/<name>/dependencies.md, ready
+// to be published with the component's TechDocs.
+func WriteDocs(dir string, c Component, page string) error {
+	path := filepath.Join(dir, c.Namespace, c.Name, "dependencies.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(page), 0644)
+}
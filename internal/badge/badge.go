@@ -0,0 +1,95 @@
+package badge
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+// --- Structures ---
+
+// Badge is a two-part "label | message" badge.
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// Endpoint is the shields.io endpoint schema, see
+// https://shields.io/badges/endpoint-badge.
+type Endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// --- Core logic ---
+
+// Usage is the "used by N repos" badge.
+func Usage(repos int) Badge {
+	color := "blue"
+	if repos == 0 {
+		color = "lightgrey"
+	}
+	unit := "repos"
+	if repos == 1 {
+		unit = "repo"
+	}
+	return Badge{Label: "used by", Message: fmt.Sprintf("%d %s", repos, unit), Color: color}
+}
+
+// Adoption is the badge showing the share of expected repos using a package.
+func Adoption(percent float64) Badge {
+	color := "red"
+	switch {
+	case percent >= 90:
+		color = "brightgreen"
+	case percent >= 60:
+		color = "yellow"
+	case percent >= 30:
+		color = "orange"
+	}
+	return Badge{Label: "adoption", Message: fmt.Sprintf("%g%%", math.Round(percent*10)/10), Color: color}
+}
+
+// Unknown is shown for packages missing from the report.
+func Unknown(label string) Badge {
+	return Badge{Label: label, Message: "unknown", Color: "lightgrey"}
+}
+
+func (b Badge) Endpoint() Endpoint {
+	return Endpoint{SchemaVersion: 1, Label: b.Label, Message: b.Message, Color: b.Color}
+}
+
+var colors = map[string]string{
+	"brightgreen": "#4c1",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"lightgrey":   "#9f9f9f",
+}
+
+// SVG renders the badge in the flat shields.io style. Text widths are
+// estimated, which is close enough for short labels.
+func (b Badge) SVG() string {
+	lw, mw := textWidth(b.Label), textWidth(b.Message)
+	w := lw + mw
+	label, msg := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, w, label, msg)
+	fmt.Fprintf(&sb, `<title>%s: %s</title>`, label, msg)
+	sb.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&sb, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, w)
+	fmt.Fprintf(&sb, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		lw, lw, mw, colors[b.Color], w)
+	sb.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&sb, `<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g></svg>`, lw/2, label, lw+mw/2, msg)
+	return sb.String()
+}
+
+func textWidth(s string) int {
+	return len([]rune(s))*7 + 10
+}
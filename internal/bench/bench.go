@@ -0,0 +1,139 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+// --- Structures ---
+
+// Run is the measurement of one scan of the fleet.
+type Run struct {
+	Wall       time.Duration `json:"wall_ns"`
+	AllocBytes uint64        `json:"alloc_bytes"`
+	Mallocs    uint64        `json:"mallocs"`
+	Requests   int           `json:"requests"`
+}
+
+// Result is a benchmark: the fleet, the scan options, the runs and their
+// medians, and the requests of a run by endpoint. The synthetic APIs answer
+// the same way every time, so the request counts do not vary between runs.
+type Result struct {
+	Config   Config         `json:"config"`
+	Flags    []string       `json:"flags,omitempty"`
+	Runs     []Run          `json:"runs"`
+	Median   Run            `json:"median"`
+	Requests map[string]int `json:"requests"`
+}
+
+// Regression is a metric that got worse than the baseline allows.
+type Regression struct {
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+}
+
+// --- Core logic ---
+
+// Summarize sets the medians of the runs.
+func (r *Result) Summarize() {
+	median := func(value func(Run) float64) float64 {
+		v := make([]float64, len(r.Runs))
+		for i, run := range r.Runs {
+			v[i] = value(run)
+		}
+		sort.Float64s(v)
+		if len(v) == 0 {
+			return 0
+		}
+		return v[len(v)/2]
+	}
+	r.Median = Run{
+		Wall:       time.Duration(median(func(run Run) float64 { return float64(run.Wall) })),
+		AllocBytes: uint64(median(func(run Run) float64 { return float64(run.AllocBytes) })),
+		Mallocs:    uint64(median(func(run Run) float64 { return float64(run.Mallocs) })),
+		Requests:   int(median(func(run Run) float64 { return float64(run.Requests) })),
+	}
+}
+
+// Comparable returns why a result cannot be compared with baseline, or ""
+// when both measured the same fleet with the same scan options.
+func Comparable(baseline, current Result) string {
+	if baseline.Config != current.Config {
+		return fmt.Sprintf("the baseline fleet is %s, not %s", baseline.Config, current.Config)
+	}
+	if !slices.Equal(baseline.Flags, current.Flags) {
+		return fmt.Sprintf("the baseline was scanned with %q, not %q", baseline.Flags, current.Flags)
+	}
+	return ""
+}
+
+// Compare returns the metrics of current that regressed against baseline:
+// median wall time and allocations more than tolerance (0.2 for 20%) above
+// it, and any endpoint requested more often.
+func Compare(baseline, current Result, tolerance float64) []Regression {
+	var out []Regression
+	for _, m := range []struct {
+		metric        string
+		before, after float64
+	}{
+		{"wall time (s)", baseline.Median.Wall.Seconds(), current.Median.Wall.Seconds()},
+		{"allocated bytes", float64(baseline.Median.AllocBytes), float64(current.Median.AllocBytes)},
+		{"allocations", float64(baseline.Median.Mallocs), float64(current.Median.Mallocs)},
+	} {
+		if m.after > m.before*(1+tolerance) {
+			out = append(out, Regression{Metric: m.metric, Baseline: m.before, Current: m.after})
+		}
+	}
+	endpoints := make([]string, 0, len(current.Requests))
+	for e := range current.Requests {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	for _, e := range endpoints {
+		if n := current.Requests[e]; n > baseline.Requests[e] {
+			out = append(out, Regression{Metric: "requests " + e, Baseline: float64(baseline.Requests[e]), Current: float64(n)})
+		}
+	}
+	return out
+}
+
+func (r Regression) String() string {
+	change := "new"
+	if r.Baseline > 0 {
+		change = fmt.Sprintf("%+.1f%%", (r.Current/r.Baseline-1)*100)
+	}
+	return fmt.Sprintf("%s: %.6g → %.6g (%s)", r.Metric, r.Baseline, r.Current, change)
+}
+
+func (c Config) String() string {
+	s := fmt.Sprintf("%d repos, %d dependencies each from %d packages with %d versions", c.Repos, c.Deps, c.Packages, c.Versions)
+	if c.Latency > 0 {
+		s += fmt.Sprintf(", %s latency", c.Latency)
+	}
+	return s
+}
+
+// Load reads a result written by Save.
+func Load(path string) (Result, error) {
+	var r Result
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	return r, err
+}
+
+// Save writes a result as JSON.
+func Save(path string, r Result) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
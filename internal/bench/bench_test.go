@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	r := Result{Runs: []Run{
+		{Wall: 3 * time.Second, AllocBytes: 300, Mallocs: 30, Requests: 10},
+		{Wall: 1 * time.Second, AllocBytes: 100, Mallocs: 20, Requests: 10},
+		{Wall: 2 * time.Second, AllocBytes: 200, Mallocs: 10, Requests: 10},
+	}}
+	r.Summarize()
+	want := Run{Wall: 2 * time.Second, AllocBytes: 200, Mallocs: 20, Requests: 10}
+	if r.Median != want {
+		t.Errorf("median %+v, want %+v", r.Median, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := Result{
+		Median:   Run{Wall: 10 * time.Second, AllocBytes: 1000, Mallocs: 100},
+		Requests: map[string]int{"github/contents": 40, "pub.dev/package": 100},
+	}
+	tests := []struct {
+		name    string
+		median  Run
+		request map[string]int
+		want    []string
+	}{
+		{"same", baseline.Median, baseline.Requests, nil},
+		{"within tolerance", Run{Wall: 11 * time.Second, AllocBytes: 1100, Mallocs: 110}, baseline.Requests, nil},
+		{"faster", Run{Wall: 5 * time.Second, AllocBytes: 500, Mallocs: 50}, map[string]int{"github/contents": 40}, nil},
+		{"slower", Run{Wall: 13 * time.Second, AllocBytes: 1000, Mallocs: 100}, baseline.Requests, []string{"wall time (s)"}},
+		{"more allocations", Run{Wall: 10 * time.Second, AllocBytes: 1300, Mallocs: 130}, baseline.Requests, []string{"allocated bytes", "allocations"}},
+		{"more requests", baseline.Median, map[string]int{"github/contents": 41, "pub.dev/package": 100, "osv/querybatch": 1}, []string{"requests github/contents", "requests osv/querybatch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(baseline, Result{Median: tt.median, Requests: tt.request}, 0.2)
+			if len(got) != len(tt.want) {
+				t.Fatalf("regressions %v, want %v", got, tt.want)
+			}
+			for i, r := range got {
+				if r.Metric != tt.want[i] {
+					t.Errorf("regression %d is %s, want %s", i, r.Metric, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestComparable(t *testing.T) {
+	cfg := Config{Repos: 10, Deps: 5, Packages: 20, Versions: 3}
+	base := Result{Config: cfg, Flags: []string{"--lockfile"}}
+	tests := []struct {
+		name    string
+		current Result
+		want    bool
+	}{
+		{"same", Result{Config: cfg, Flags: []string{"--lockfile"}}, true},
+		{"other fleet", Result{Config: Config{Repos: 20, Deps: 5, Packages: 20, Versions: 3}, Flags: []string{"--lockfile"}}, false},
+		{"other flags", Result{Config: cfg, Flags: []string{"--risk"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if why := Comparable(base, tt.current); (why == "") != tt.want {
+				t.Errorf("Comparable = %q, want comparable %v", why, tt.want)
+			}
+		})
+	}
+}
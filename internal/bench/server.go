@@ -0,0 +1,269 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Owner is the GitHub owner of the synthetic repos.
+const Owner = "bench"
+
+// --- Structures ---
+
+// Config shapes the synthetic fleet a Server serves. Every repo has a
+// pubspec and a lockfile with Deps hosted dependencies, drawn from Packages
+// packages with Versions releases each.
+type Config struct {
+	Repos    int `json:"repos"`
+	Deps     int `json:"deps"`
+	Packages int `json:"packages"`
+	Versions int `json:"versions"`
+
+	// Latency delays every response, to stand in for the round trip to the
+	// real APIs.
+	Latency time.Duration `json:"latency_ns,omitempty"`
+}
+
+// Server answers the GitHub, pub.dev and OSV requests of a scan of the
+// synthetic fleet, the same way on every run, and counts them by endpoint.
+type Server struct {
+	cfg    Config
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func NewServer(cfg Config) *Server {
+	cfg.Deps = min(cfg.Deps, cfg.Packages)
+	return &Server{cfg: cfg, counts: map[string]int{}}
+}
+
+// --- Core logic ---
+
+// Repos returns the owner/repo names of the fleet.
+func (s *Server) Repos() []string {
+	out := make([]string, s.cfg.Repos)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s/app-%04d", Owner, i)
+	}
+	return out
+}
+
+// Requests returns the requests answered since the last call, by endpoint,
+// and starts counting again.
+func (s *Server) Requests() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.counts
+	s.counts = map[string]int{}
+	return out
+}
+
+func (s *Server) count(endpoint string) {
+	s.mu.Lock()
+	s.counts[endpoint]++
+	s.mu.Unlock()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Latency > 0 {
+		time.Sleep(s.cfg.Latency)
+	}
+	p := r.URL.Path
+	switch {
+	case strings.HasPrefix(p, "/repos/"):
+		s.github(w, r, strings.TrimPrefix(p, "/repos/"))
+	case strings.HasPrefix(p, "/api/packages/"):
+		s.pubdev(w, strings.TrimPrefix(p, "/api/packages/"))
+	case p == "/v1/querybatch" && r.Method == "POST":
+		s.count("osv/querybatch")
+		s.osv(w, r)
+	default:
+		s.count("other")
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) github(w http.ResponseWriter, r *http.Request, rest string) {
+	parts := strings.SplitN(rest, "/", 4)
+	if len(parts) < 2 || parts[0] != Owner || s.repoIndex(parts[1]) < 0 {
+		s.count("github/other")
+		http.NotFound(w, r)
+		return
+	}
+	i := s.repoIndex(parts[1])
+	endpoint := ""
+	if len(parts) > 2 {
+		endpoint = parts[2]
+	}
+	s.count("github/" + orDefault(endpoint, "repo"))
+	switch endpoint {
+	case "":
+		writeJSON(w, map[string]interface{}{"full_name": Owner + "/" + parts[1], "stargazers_count": i % 50})
+	case "branches":
+		writeJSON(w, []map[string]interface{}{{"name": "main", "commit": map[string]interface{}{"commit": map[string]interface{}{"author": map[string]interface{}{"date": "2024-01-01T00:00:00Z"}}}}})
+	case "commits":
+		fmt.Fprint(w, commitSHA(i))
+	case "git":
+		if len(parts) < 4 || !strings.HasPrefix(parts[3], "trees/") {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"truncated": false, "tree": []map[string]interface{}{
+			{"path": "pubspec.yaml", "type": "blob"},
+			{"path": "pubspec.lock", "type": "blob"},
+		}})
+	case "contents":
+		var file string
+		if len(parts) > 3 {
+			file = parts[3]
+		}
+		switch file {
+		case "pubspec.yaml":
+			fmt.Fprint(w, s.pubspec(i))
+		case "pubspec.lock":
+			fmt.Fprint(w, s.lockfile(i))
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func (s *Server) repoIndex(name string) int {
+	var i int
+	if _, err := fmt.Sscanf(name, "app-%04d", &i); err != nil || i < 0 || i >= s.cfg.Repos || fmt.Sprintf("app-%04d", i) != name {
+		return -1
+	}
+	return i
+}
+
+// deps returns the packages repo i depends on, each locked at a version.
+func (s *Server) deps(i int) map[string]string {
+	out := map[string]string{}
+	for k := 0; len(out) < s.cfg.Deps; k++ {
+		n := (i*7 + k*13) % s.cfg.Packages
+		out[packageName(n)] = version((i + n) % s.cfg.Versions)
+	}
+	return out
+}
+
+func (s *Server) pubspec(i int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: app_%04d\nenvironment:\n  sdk: \">=3.0.0 <4.0.0\"\ndependencies:\n", i)
+	for _, name := range sortedKeys(s.deps(i)) {
+		fmt.Fprintf(&b, "  %s: ^1.0.0\n", name)
+	}
+	return b.String()
+}
+
+func (s *Server) lockfile(i int) string {
+	var b strings.Builder
+	b.WriteString("packages:\n")
+	deps := s.deps(i)
+	for _, name := range sortedKeys(deps) {
+		v := deps[name]
+		fmt.Fprintf(&b, "  %s:\n    dependency: \"direct main\"\n    description:\n      name: %s\n      sha256: %q\n      url: \"https://pub.dev\"\n    source: hosted\n    version: %q\n", name, name, archiveSHA256(name, v), v)
+	}
+	b.WriteString("sdks:\n  dart: \">=3.0.0 <4.0.0\"\n")
+	return b.String()
+}
+
+func (s *Server) pubdev(w http.ResponseWriter, rest string) {
+	name, sub, _ := strings.Cut(rest, "/")
+	s.count("pub.dev/" + orDefault(sub, "package"))
+	n := -1
+	if _, err := fmt.Sscanf(name, "pkg_%03d", &n); err != nil || n < 0 || n >= s.cfg.Packages || packageName(n) != name {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch sub {
+	case "":
+		versions := make([]map[string]interface{}, s.cfg.Versions)
+		for v := range versions {
+			pubspec := map[string]interface{}{"name": name, "version": version(v)}
+			// Every package but each fifth depends on the next one, for a
+			// dependency graph of some depth.
+			if n%5 != 4 && n+1 < s.cfg.Packages {
+				pubspec["dependencies"] = map[string]interface{}{packageName(n + 1): "^1.0.0"}
+			}
+			versions[v] = map[string]interface{}{
+				"version":        version(v),
+				"pubspec":        pubspec,
+				"archive_sha256": archiveSHA256(name, version(v)),
+				"published":      time.Date(2024, 1, 1+v, 0, 0, 0, 0, time.UTC),
+			}
+		}
+		writeJSON(w, map[string]interface{}{"name": name, "latest": versions[len(versions)-1], "versions": versions})
+	case "publisher":
+		writeJSON(w, map[string]interface{}{"publisherId": "bench.dev"})
+	case "options":
+		writeJSON(w, map[string]interface{}{"isDiscontinued": false, "isUnlisted": false})
+	case "score":
+		writeJSON(w, map[string]interface{}{"grantedPoints": 140, "maxPoints": 160, "likeCount": n, "popularityScore": 0.5, "tags": []string{"license:mit"}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// osv answers every query without advisories.
+func (s *Server) osv(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Queries []json.RawMessage `json:"queries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	results := make([]map[string]interface{}, len(body.Queries))
+	for i := range results {
+		results[i] = map[string]interface{}{}
+	}
+	writeJSON(w, map[string]interface{}{"results": results})
+}
+
+func packageName(n int) string {
+	return fmt.Sprintf("pkg_%03d", n)
+}
+
+func version(v int) string {
+	return fmt.Sprintf("1.%d.0", v)
+}
+
+func commitSHA(i int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("commit %d", i)))
+	return hex.EncodeToString(sum[:20])
+}
+
+func archiveSHA256(name, version string) string {
+	sum := sha256.Sum256([]byte(name + "@" + version))
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
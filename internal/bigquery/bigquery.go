@@ -0,0 +1,284 @@
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the BigQuery REST API.
+const DefaultBaseURL = "https://bigquery.googleapis.com"
+
+// BatchSize is the number of rows per insertAll request, within the limits
+// BigQuery recommends for streaming inserts.
+const BatchSize = 500
+
+// --- Structures ---
+
+// Table identifies a table as project.dataset.table.
+type Table struct {
+	Project string
+	Dataset string
+	Table   string
+}
+
+func (t Table) String() string { return t.Project + "." + t.Dataset + "." + t.Table }
+
+// Field is a column of a table schema.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// Row is a row to stream, with the insert ID BigQuery deduplicates retried
+// inserts by.
+type Row struct {
+	InsertID string      `json:"insertId"`
+	JSON     interface{} `json:"json"`
+}
+
+// ServiceAccount is the part of a service account key file needed to get
+// access tokens.
+type ServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Client streams rows into BigQuery tables. It authenticates with Token,
+// a ready OAuth access token, or with the service account key in Account.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	Token   string
+	Account *ServiceAccount
+
+	mu      sync.Mutex
+	expires time.Time
+}
+
+// --- Core logic ---
+
+// ParseTable reads "project.dataset.table", or "project:dataset.table" in
+// the bq tool's notation.
+func ParseTable(s string) (Table, error) {
+	parts := strings.Split(strings.Replace(s, ":", ".", 1), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Table{}, fmt.Errorf("invalid BigQuery table %q: want project.dataset.table", s)
+	}
+	return Table{Project: parts[0], Dataset: parts[1], Table: parts[2]}, nil
+}
+
+// LoadServiceAccount reads a service account key file, as pointed to by
+// GOOGLE_APPLICATION_CREDENTIALS.
+func LoadServiceAccount(path string) (*ServiceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sa ServiceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("%s is not a service account key", path)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &sa, nil
+}
+
+// EnsureTable creates the table with schema, partitioned by day on the
+// partition column, or adds the columns of schema an existing table lacks.
+// Added columns are nullable, as BigQuery requires.
+func (c *Client) EnsureTable(ctx context.Context, t Table, schema []Field, partition string) error {
+	var existing struct {
+		Schema struct {
+			Fields []Field `json:"fields"`
+		} `json:"schema"`
+	}
+	path := "/bigquery/v2/projects/" + url.PathEscape(t.Project) + "/datasets/" + url.PathEscape(t.Dataset) + "/tables"
+	status, err := c.do(ctx, "GET", path+"/"+url.PathEscape(t.Table), nil, &existing)
+	if status == http.StatusNotFound {
+		body := map[string]interface{}{
+			"tableReference":   map[string]string{"projectId": t.Project, "datasetId": t.Dataset, "tableId": t.Table},
+			"schema":           map[string]interface{}{"fields": schema},
+			"timePartitioning": map[string]string{"type": "DAY", "field": partition},
+		}
+		_, err = c.do(ctx, "POST", path, body, nil)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, f := range existing.Schema.Fields {
+		have[f.Name] = true
+	}
+	fields := existing.Schema.Fields
+	for _, f := range schema {
+		if !have[f.Name] {
+			f.Mode = "NULLABLE"
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == len(existing.Schema.Fields) {
+		return nil
+	}
+	body := map[string]interface{}{"schema": map[string]interface{}{"fields": fields}}
+	_, err = c.do(ctx, "PATCH", path+"/"+url.PathEscape(t.Table), body, nil)
+	return err
+}
+
+// Insert streams rows into t in batches of BatchSize.
+func (c *Client) Insert(ctx context.Context, t Table, rows []Row) error {
+	path := "/bigquery/v2/projects/" + url.PathEscape(t.Project) + "/datasets/" + url.PathEscape(t.Dataset) +
+		"/tables/" + url.PathEscape(t.Table) + "/insertAll"
+	for start := 0; start < len(rows); start += BatchSize {
+		batch := rows[start:min(start+BatchSize, len(rows))]
+		var resp struct {
+			InsertErrors []struct {
+				Index  int `json:"index"`
+				Errors []struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			} `json:"insertErrors"`
+		}
+		body := map[string]interface{}{"kind": "bigquery#tableDataInsertAllRequest", "rows": batch}
+		if _, err := c.do(ctx, "POST", path, body, &resp); err != nil {
+			return err
+		}
+		if len(resp.InsertErrors) > 0 {
+			e := resp.InsertErrors[0]
+			msg := "unknown error"
+			if len(e.Errors) > 0 {
+				msg = e.Errors[0].Reason + ": " + e.Errors[0].Message
+			}
+			return fmt.Errorf("%d rows rejected by %s, first at row %d: %s", len(resp.InsertErrors), t, start+e.Index, msg)
+		}
+	}
+	return nil
+}
+
+// InsertID derives a stable insert ID from the parts that identify a row.
+func InsertID(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(h[:18])
+}
+
+// do sends a JSON request and decodes the response into out. It returns
+// the HTTP status, and an error for non-2xx responses.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var r io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		r = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequestWithContext(ctx, method, c.BaseURL+path, r)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s (%s)", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode, nil
+}
+
+// token returns the access token, exchanging a signed JWT for a new one
+// when the service account's token is about to expire.
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Account == nil || (c.Token != "" && time.Now().Before(c.expires)) {
+		return c.Token, nil
+	}
+	assertion, err := c.Account.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, _ := http.NewRequestWithContext(ctx, "POST", c.Account.TokenURI, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token request for %s failed: %s (%s)", c.Account.ClientEmail, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	c.Token = tok.AccessToken
+	c.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return c.Token, nil
+}
+
+// jwt returns the signed assertion of the OAuth 2.0 JWT bearer flow.
+func (sa *ServiceAccount) jwt(now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("invalid private key of %s", sa.ClientEmail)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key of %s: %v", sa.ClientEmail, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private key of %s is not an RSA key", sa.ClientEmail)
+	}
+	enc := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	unsigned := enc(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + enc(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": "https://www.googleapis.com/auth/bigquery",
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
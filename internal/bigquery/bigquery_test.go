@@ -0,0 +1,241 @@
+package bigquery
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Table
+		wantErr bool
+	}{
+		{"acme.stats.facts", Table{"acme", "stats", "facts"}, false},
+		{"acme:stats.facts", Table{"acme", "stats", "facts"}, false},
+		{"stats.facts", Table{}, true},
+		{"acme..facts", Table{}, true},
+		{"a.b.c.d", Table{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTable(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseTable = %+v, %v, want %+v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInsertID(t *testing.T) {
+	if InsertID("a", "bc") == InsertID("ab", "c") {
+		t.Errorf("parts are not separated")
+	}
+	if got := InsertID("2026-07-01", "acme/app", "http"); got != InsertID("2026-07-01", "acme/app", "http") || len(got) != 24 {
+		t.Errorf("InsertID = %q, want a stable 24 character ID", got)
+	}
+}
+
+func TestLoadServiceAccount(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		content   string
+		wantURI   string
+		wantError string
+	}{
+		{"default token URI", `{"client_email": "pgs@acme.iam", "private_key": "KEY"}`, "https://oauth2.googleapis.com/token", ""},
+		{"own token URI", `{"client_email": "pgs@acme.iam", "private_key": "KEY", "token_uri": "https://token.test"}`, "https://token.test", ""},
+		{"not a key", `{"type": "authorized_user"}`, "", "is not a service account key"},
+		{"not JSON", `KEY`, "", "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			sa, err := LoadServiceAccount(path)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("error %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sa.TokenURI != tt.wantURI {
+				t.Errorf("token URI %q, want %q", sa.TokenURI, tt.wantURI)
+			}
+		})
+	}
+	if _, err := LoadServiceAccount(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("no error for a missing file")
+	}
+}
+
+// fakeAPI serves the BigQuery endpoints the client uses and records the
+// requests it received as "METHOD path".
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []string
+	fields   []Field
+	exists   bool
+	rejected bool
+	tokens   int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/token" {
+		f.tokens++
+		if err := r.ParseForm(); err != nil || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, f.tokens)
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+	switch {
+	case r.Method == "GET" && !f.exists:
+		http.Error(w, "not found", http.StatusNotFound)
+	case r.Method == "GET":
+		json.NewEncoder(w).Encode(map[string]interface{}{"schema": map[string]interface{}{"fields": f.fields}})
+	case strings.HasSuffix(r.URL.Path, "/insertAll") && f.rejected:
+		fmt.Fprint(w, `{"insertErrors": [{"index": 1, "errors": [{"reason": "invalid", "message": "no such field"}]}]}`)
+	default:
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func (f *fakeAPI) methods() []string {
+	var out []string
+	for _, r := range f.requests {
+		method, _, _ := strings.Cut(r, " ")
+		out = append(out, method)
+	}
+	return out
+}
+
+func TestEnsureTable(t *testing.T) {
+	schema := []Field{{Name: "date", Type: "DATE", Mode: "REQUIRED"}, {Name: "package", Type: "STRING", Mode: "REQUIRED"}}
+	tests := []struct {
+		name   string
+		exists bool
+		fields []Field
+		want   string
+	}{
+		{"created", false, nil, "GET POST"},
+		{"column added", true, schema[:1], "GET PATCH"},
+		{"up to date", true, schema, "GET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{exists: tt.exists, fields: tt.fields}
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+			c := &Client{HTTP: srv.Client(), BaseURL: srv.URL, Token: "token"}
+			if err := c.EnsureTable(context.Background(), Table{"acme", "stats", "facts"}, schema, "date"); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(api.methods(), " "); got != tt.want {
+				t.Errorf("requests %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     int
+		rejected bool
+		batches  int
+		wantErr  string
+	}{
+		{"no rows", 0, false, 0, ""},
+		{"one batch", BatchSize, false, 1, ""},
+		{"two batches", BatchSize + 1, false, 2, ""},
+		{"rejected", 3, true, 1, "1 rows rejected by acme.stats.facts, first at row 1: invalid: no such field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{rejected: tt.rejected}
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+			c := &Client{HTTP: srv.Client(), BaseURL: srv.URL, Token: "token"}
+			rows := make([]Row, tt.rows)
+			for i := range rows {
+				rows[i] = Row{InsertID: InsertID(fmt.Sprint(i)), JSON: map[string]int{"n": i}}
+			}
+			err := c.Insert(context.Background(), Table{"acme", "stats", "facts"}, rows)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+			if len(api.requests) != tt.batches {
+				t.Errorf("%d requests, want %d", len(api.requests), tt.batches)
+			}
+		})
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeAPI{exists: true}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		privateKey string
+		wantErr    string
+	}{
+		{"signed", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), ""},
+		{"not PEM", "KEY", "invalid private key of pgs@acme.iam"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api.requests, api.tokens = nil, 0
+			c := &Client{HTTP: srv.Client(), BaseURL: srv.URL,
+				Account: &ServiceAccount{ClientEmail: "pgs@acme.iam", PrivateKey: tt.privateKey, TokenURI: srv.URL + "/token"}}
+			table := Table{"acme", "stats", "facts"}
+			for i := 0; i < 2; i++ {
+				err := c.EnsureTable(context.Background(), table, nil, "date")
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("error %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if api.tokens != 1 || !strings.HasSuffix(api.requests[1], "Bearer token-1") {
+				t.Errorf("%d token requests, requests %q; want one token reused", api.tokens, api.requests)
+			}
+		})
+	}
+}
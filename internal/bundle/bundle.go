@@ -0,0 +1,243 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InputsDir holds the input files of the scan.
+const InputsDir = "inputs"
+
+// Directories of a bundle, holding the captured repo files in the layout
+// of --local-repos and the enrichment cache of --cache-dir.
+const (
+	ReposDir = "repos"
+	CacheDir = "cache"
+)
+
+// ManifestFile describes the bundle; it is the first entry of the archive.
+const ManifestFile = "bundle.json"
+
+// --- Structures ---
+
+// Manifest describes what a bundle holds. Report and Details are file
+// names at the root of the bundle; Inputs maps the scan options naming
+// input files, such as repos or rules, to their copy in the bundle.
+type Manifest struct {
+	Version string            `json:"version"`
+	Created time.Time         `json:"created"`
+	Report  string            `json:"report"`
+	Details string            `json:"details,omitempty"`
+	Inputs  map[string]string `json:"inputs,omitempty"`
+	Repos   bool              `json:"repos"`
+	Cache   bool              `json:"cache"`
+	Files   int               `json:"files"`
+}
+
+// Snapshot is a report taken apart for comparison: its top-level sections
+// and its per-repo results by repo.
+type Snapshot struct {
+	Sections map[string]json.RawMessage
+	Repos    map[string]json.RawMessage
+}
+
+// Source is a file or directory to add to a bundle under Name.
+type Source struct {
+	Name string
+	Path string
+}
+
+// --- Core logic ---
+
+// Write archives sources into w as a tar stream, after the manifest. Files
+// count in m.Files.
+func Write(w io.Writer, m Manifest, sources []Source) error {
+	tw := tar.NewWriter(w)
+	var files []Source
+	for _, src := range sources {
+		err := filepath.WalkDir(src.Path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(src.Path, p)
+			if err != nil {
+				return err
+			}
+			name := path.Join(src.Name, filepath.ToSlash(rel))
+			if rel == "." {
+				name = src.Name
+			}
+			files = append(files, Source{Name: name, Path: p})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	m.Files = len(files)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addData(tw, ManifestFile, data, m.Created); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := addFile(tw, f.Name, f.Path); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func addFile(tw *tar.Writer, name, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func addData(tw *tar.Writer, name string, data []byte, mod time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: mod, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Extract unpacks a bundle written by Write into dir and returns its
+// manifest. Entries other than regular files, and names leaving dir, are
+// rejected.
+func Extract(r io.Reader, dir string) (Manifest, error) {
+	var m Manifest
+	tr := tar.NewReader(r)
+	for first := true; ; first = false {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+		name := path.Clean(hdr.Name)
+		switch {
+		case hdr.Typeflag != tar.TypeReg:
+			return m, fmt.Errorf("unexpected entry %s in bundle", hdr.Name)
+		case path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../"):
+			return m, fmt.Errorf("entry %s leaves the bundle directory", hdr.Name)
+		case first && name != ManifestFile:
+			return m, fmt.Errorf("not a pubscan bundle: %s is missing", ManifestFile)
+		}
+		var body io.Reader = tr
+		if first {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return m, err
+			}
+			if err := json.Unmarshal(data, &m); err != nil {
+				return m, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+			}
+			body = bytes.NewReader(data)
+		}
+		if err := extractFile(filepath.Join(dir, filepath.FromSlash(name)), body); err != nil {
+			return m, err
+		}
+	}
+	if m.Report == "" {
+		return m, fmt.Errorf("not a pubscan bundle: %s is missing", ManifestFile)
+	}
+	return m, nil
+}
+
+func extractFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Diff lists what differs between two snapshots of a report: sections
+// first, then repos, each sorted. Values are compared as decoded JSON, so
+// formatting does not matter.
+func Diff(want, got Snapshot) []string {
+	var out []string
+	for _, name := range keys(want.Sections, got.Sections) {
+		if !equalJSON(want.Sections[name], got.Sections[name]) {
+			out = append(out, "section "+name)
+		}
+	}
+	for _, repo := range keys(want.Repos, got.Repos) {
+		w, inWant := want.Repos[repo]
+		g, inGot := got.Repos[repo]
+		switch {
+		case !inGot:
+			out = append(out, "repo "+repo+": missing from the replay")
+		case !inWant:
+			out = append(out, "repo "+repo+": only in the replay")
+		case !equalJSON(w, g):
+			out = append(out, "repo "+repo)
+		}
+	}
+	return out
+}
+
+func keys(a, b map[string]json.RawMessage) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range []map[string]json.RawMessage{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equalJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
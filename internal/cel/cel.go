@@ -0,0 +1,869 @@
+package cel
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- Structures ---
+
+// Program is a compiled expression.
+type Program struct {
+	src  string
+	root node
+	vars []string
+}
+
+// node evaluates one part of an expression.
+type node func(sc *scope) (interface{}, error)
+
+// scope holds the variables visible to an expression: the activation, and
+// the iteration variables of enclosing macros.
+type scope struct {
+	name   string
+	value  interface{}
+	parent *scope
+	vars   map[string]interface{}
+}
+
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for ; s != nil; s = s.parent {
+		if s.vars != nil {
+			v, ok := s.vars[name]
+			return v, ok
+		}
+		if s.name == name {
+			return s.value, true
+		}
+	}
+	return nil, false
+}
+
+type parser struct {
+	toks []token
+	i    int
+
+	// sel is the last operation member parsed when it was a field
+	// selection, for has().
+	sel *selection
+
+	// bound are the variables of the macros being parsed; free collects
+	// the other names referenced.
+	bound []string
+	free  map[string]bool
+}
+
+type selection struct {
+	recv  node
+	field string
+}
+
+// --- Core logic ---
+
+// Compile parses an expression of the supported CEL subset: literals,
+// lists and maps, field selection and indexing, the usual operators
+// including in and ?:, the macros has, all, exists, exists_one, filter and
+// map, and the functions size, contains, startsWith, endsWith, matches and
+// lowerAscii. Numbers are not split into int and double.
+func Compile(src string) (*Program, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, free: map[string]bool{}}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	vars := make([]string, 0, len(p.free))
+	for name := range p.free {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	return &Program{src: src, root: root, vars: vars}, nil
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string { return p.src }
+
+// Variables returns the variables the expression refers to, sorted, other
+// than the iteration variables of its macros.
+func (p *Program) Variables() []string { return p.vars }
+
+// Eval evaluates the expression with the given variables. Values are those
+// of encoding/json: nil, bool, float64, string, []interface{} and
+// map[string]interface{}.
+func (p *Program) Eval(vars map[string]interface{}) (interface{}, error) {
+	return p.root(&scope{vars: vars})
+}
+
+// EvalBool evaluates an expression that must yield a bool.
+func (p *Program) EvalBool(vars map[string]interface{}) (bool, error) {
+	v, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression yields %s, want bool", typeName(v))
+	}
+	return b, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		if t.kind == tokEOF {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q at %d, found %q", op, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) isBound(name string) bool {
+	for _, b := range p.bound {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expr() (node, error) {
+	cond, err := p.or()
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return func(sc *scope) (interface{}, error) {
+		c, err := evalBool(cond, sc)
+		if err != nil {
+			return nil, err
+		}
+		if c {
+			return then(sc)
+		}
+		return otherwise(sc)
+	}, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right node
+		if right, err = p.and(); err == nil {
+			left = logical(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.relation()
+	for err == nil && p.accept("&&") {
+		var right node
+		if right, err = p.relation(); err == nil {
+			left = logical(left, right, false)
+		}
+	}
+	return left, err
+}
+
+// logical joins two operands with || (or) or &&. As in CEL, an error on
+// one side is absorbed when the other side decides the result.
+func logical(left, right node, or bool) node {
+	return func(sc *scope) (interface{}, error) {
+		l, lerr := evalBool(left, sc)
+		if lerr == nil && l == or {
+			return or, nil
+		}
+		r, rerr := evalBool(right, sc)
+		if rerr == nil && r == or {
+			return or, nil
+		}
+		if lerr != nil {
+			return nil, lerr
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+		return !or, nil
+	}
+}
+
+func (p *parser) relation() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	op := t.text
+	switch {
+	case t.kind == tokOp && (op == "==" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">="):
+	case t.kind == tokIdent && op == "in":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	return func(sc *scope) (interface{}, error) {
+		l, err := left(sc)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(sc)
+		if err != nil {
+			return nil, err
+		}
+		return compare(op, l, r)
+	}, nil
+}
+
+func (p *parser) additive() (node, error) {
+	left, err := p.multiplicative()
+	for err == nil {
+		op := p.peek().text
+		if p.peek().kind != tokOp || op != "+" && op != "-" {
+			break
+		}
+		p.next()
+		var right node
+		if right, err = p.multiplicative(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.unary()
+	for err == nil {
+		op := p.peek().text
+		if p.peek().kind != tokOp || op != "*" && op != "/" && op != "%" {
+			break
+		}
+		p.next()
+		var right node
+		if right, err = p.unary(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	switch {
+	case p.accept("!"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(sc *scope) (interface{}, error) {
+			b, err := evalBool(operand, sc)
+			return !b, err
+		}, nil
+	case p.accept("-"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(sc *scope) (interface{}, error) {
+			v, err := operand(sc)
+			if err != nil {
+				return nil, err
+			}
+			n, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("cannot negate %s", typeName(v))
+			}
+			return -n, nil
+		}, nil
+	}
+	return p.member()
+}
+
+func (p *parser) member() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	p.sel = nil
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected field name at %d", t.pos)
+			}
+			if p.accept("(") {
+				if n, err = p.method(n, t.text); err != nil {
+					return nil, err
+				}
+				p.sel = nil
+				continue
+			}
+			p.sel = &selection{recv: n, field: t.text}
+			n = selectField(n, t.text)
+		case p.accept("["):
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = indexValue(n, index)
+			p.sel = nil
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v := t.num
+		return constant(v), nil
+	case tokString:
+		v := t.text
+		return constant(v), nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		case "null":
+			return constant(nil), nil
+		}
+		if p.accept("(") {
+			return p.function(t.text)
+		}
+		name := t.text
+		if !p.isBound(name) {
+			p.free[name] = true
+		}
+		return func(sc *scope) (interface{}, error) {
+			v, ok := sc.lookup(name)
+			if !ok {
+				return nil, fmt.Errorf("undeclared reference to %q", name)
+			}
+			return v, nil
+		}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.args("]")
+			if err != nil {
+				return nil, err
+			}
+			return func(sc *scope) (interface{}, error) {
+				out := make([]interface{}, len(items))
+				for i, item := range items {
+					v, err := item(sc)
+					if err != nil {
+						return nil, err
+					}
+					out[i] = v
+				}
+				return out, nil
+			}, nil
+		case "{":
+			return p.mapLiteral()
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) mapLiteral() (node, error) {
+	var keys, values []node
+	for !p.accept("}") {
+		if len(keys) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept("}") {
+				break
+			}
+		}
+		k, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		keys, values = append(keys, k), append(values, v)
+	}
+	return func(sc *scope) (interface{}, error) {
+		out := make(map[string]interface{}, len(keys))
+		for i := range keys {
+			k, err := keys[i](sc)
+			if err != nil {
+				return nil, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, not %s", typeName(k))
+			}
+			if out[ks], err = values[i](sc); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}, nil
+}
+
+// args parses expressions separated by commas up to the closing token.
+func (p *parser) args(closing string) ([]node, error) {
+	var out []node
+	for !p.accept(closing) {
+		if len(out) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept(closing) {
+				break
+			}
+		}
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// function parses a global call: size(x) or the has macro.
+func (p *parser) function(name string) (node, error) {
+	if name == "has" {
+		if _, err := p.member(); err != nil {
+			return nil, err
+		}
+		sel := p.sel
+		if sel == nil {
+			return nil, fmt.Errorf("has() needs a field selection such as has(a.b)")
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(sc *scope) (interface{}, error) {
+			v, err := sel.recv(sc)
+			if err != nil {
+				return nil, err
+			}
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("has() on %s", typeName(v))
+			}
+			_, found := m[sel.field]
+			return found, nil
+		}, nil
+	}
+	args, err := p.args(")")
+	if err != nil {
+		return nil, err
+	}
+	if name != "size" || len(args) != 1 {
+		return nil, fmt.Errorf("unknown function %s/%d", name, len(args))
+	}
+	return func(sc *scope) (interface{}, error) {
+		v, err := args[0](sc)
+		if err != nil {
+			return nil, err
+		}
+		return size(v)
+	}, nil
+}
+
+// method parses a receiver call, either a macro or a function.
+func (p *parser) method(recv node, name string) (node, error) {
+	switch name {
+	case "all", "exists", "exists_one", "filter", "map":
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, fmt.Errorf("%s() needs a variable name as first argument", name)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		p.bound = append(p.bound, t.text)
+		body, err := p.expr()
+		p.bound = p.bound[:len(p.bound)-1]
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return macro(name, recv, t.text, body), nil
+	}
+	args, err := p.args(")")
+	if err != nil {
+		return nil, err
+	}
+	want := 1
+	switch name {
+	case "size", "lowerAscii":
+		want = 0
+	case "contains", "startsWith", "endsWith", "matches":
+	default:
+		return nil, fmt.Errorf("unknown method %s", name)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("%s() takes %d arguments", name, want)
+	}
+	return func(sc *scope) (interface{}, error) {
+		v, err := recv(sc)
+		if err != nil {
+			return nil, err
+		}
+		if name == "size" {
+			return size(v)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() on %s", name, typeName(v))
+		}
+		if name == "lowerAscii" {
+			return strings.ToLower(s), nil
+		}
+		a, err := args[0](sc)
+		if err != nil {
+			return nil, err
+		}
+		arg, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() needs a string argument, not %s", name, typeName(a))
+		}
+		switch name {
+		case "contains":
+			return strings.Contains(s, arg), nil
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	}, nil
+}
+
+// macro iterates a list, or the keys of a map, binding each to variable.
+func macro(name string, recv node, variable string, body node) node {
+	return func(sc *scope) (interface{}, error) {
+		v, err := recv(sc)
+		if err != nil {
+			return nil, err
+		}
+		var items []interface{}
+		switch v := v.(type) {
+		case []interface{}:
+			items = v
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				items = append(items, k)
+			}
+		default:
+			return nil, fmt.Errorf("%s() on %s", name, typeName(v))
+		}
+		matches := 0
+		var out []interface{}
+		for _, item := range items {
+			inner := &scope{name: variable, value: item, parent: sc}
+			if name == "map" {
+				r, err := body(inner)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, r)
+				continue
+			}
+			ok, err := evalBool(body, inner)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case name == "all" && !ok:
+				return false, nil
+			case name == "exists" && ok:
+				return true, nil
+			case ok:
+				matches++
+				out = append(out, item)
+			}
+		}
+		switch name {
+		case "all":
+			return true, nil
+		case "exists":
+			return false, nil
+		case "exists_one":
+			return matches == 1, nil
+		}
+		if out == nil {
+			out = []interface{}{}
+		}
+		return out, nil
+	}
+}
+
+func constant(v interface{}) node {
+	return func(*scope) (interface{}, error) { return v, nil }
+}
+
+func selectField(n node, field string) node {
+	return func(sc *scope) (interface{}, error) {
+		v, err := n(sc)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot select %q from %s", field, typeName(v))
+		}
+		f, ok := m[field]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", field)
+		}
+		return f, nil
+	}
+}
+
+func indexValue(n, index node) node {
+	return func(sc *scope) (interface{}, error) {
+		v, err := n(sc)
+		if err != nil {
+			return nil, err
+		}
+		i, err := index(sc)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case []interface{}:
+			f, ok := i.(float64)
+			if !ok || f != float64(int(f)) {
+				return nil, fmt.Errorf("list index must be an integer, not %v", i)
+			}
+			if f < 0 || int(f) >= len(v) {
+				return nil, fmt.Errorf("index %d out of range", int(f))
+			}
+			return v[int(f)], nil
+		case map[string]interface{}:
+			k, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key must be a string, not %s", typeName(i))
+			}
+			f, ok := v[k]
+			if !ok {
+				return nil, fmt.Errorf("no such key: %s", k)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("cannot index %s", typeName(v))
+	}
+}
+
+func arithmetic(op string, left, right node) node {
+	return func(sc *scope) (interface{}, error) {
+		l, err := left(sc)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(sc)
+		if err != nil {
+			return nil, err
+		}
+		switch l := l.(type) {
+		case float64:
+			if r, ok := r.(float64); ok {
+				switch op {
+				case "+":
+					return l + r, nil
+				case "-":
+					return l - r, nil
+				case "*":
+					return l * r, nil
+				}
+				if r == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				if op == "/" {
+					return l / r, nil
+				}
+				return float64(int64(l) % int64(r)), nil
+			}
+		case string:
+			if r, ok := r.(string); ok && op == "+" {
+				return l + r, nil
+			}
+		case []interface{}:
+			if r, ok := r.([]interface{}); ok && op == "+" {
+				return append(append([]interface{}(nil), l...), r...), nil
+			}
+		}
+		return nil, fmt.Errorf("no such overload: %s %s %s", typeName(l), op, typeName(r))
+	}
+}
+
+func compare(op string, l, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(l, r), nil
+	case "!=":
+		return !reflect.DeepEqual(l, r), nil
+	case "in":
+		switch r := r.(type) {
+		case []interface{}:
+			for _, item := range r {
+				if reflect.DeepEqual(l, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			k, ok := l.(string)
+			if !ok {
+				return false, nil
+			}
+			_, found := r[k]
+			return found, nil
+		}
+		return nil, fmt.Errorf("no such overload: %s in %s", typeName(l), typeName(r))
+	}
+	var c int
+	switch l := l.(type) {
+	case float64:
+		rn, ok := r.(float64)
+		if !ok {
+			return nil, fmt.Errorf("no such overload: %s %s %s", typeName(l), op, typeName(r))
+		}
+		switch {
+		case l < rn:
+			c = -1
+		case l > rn:
+			c = 1
+		}
+	case string:
+		rs, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("no such overload: %s %s %s", typeName(l), op, typeName(r))
+		}
+		c = strings.Compare(l, rs)
+	default:
+		return nil, fmt.Errorf("no such overload: %s %s %s", typeName(l), op, typeName(r))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func size(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return float64(len([]rune(v))), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("size() on %s", typeName(v))
+}
+
+func evalBool(n node, sc *scope) (bool, error) {
+	v, err := n(sc)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s used as bool", typeName(v))
+	}
+	return b, nil
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,200 @@
+package cel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testVars = map[string]interface{}{
+	"repo": map[string]interface{}{
+		"name":         "acme/app",
+		"dependencies": []interface{}{"http", "dio", "meta"},
+		"constraints":  map[string]interface{}{"http": "^1.2.0", "dio": "any"},
+		"score":        float64(42),
+	},
+	"tags": []interface{}{},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"1 + 2 * 3", float64(7)},
+		{"(1 + 2) * 3", float64(9)},
+		{"7 % 3 - -1", float64(2)},
+		{"10 / 4", 2.5},
+		{`"a" + 'b'`, "ab"},
+		{`"tab\tnew\nline"`, "tab\tnew\nline"},
+		{"[1] + [2]", []interface{}{float64(1), float64(2)}},
+		{"repo.name", "acme/app"},
+		{`repo["score"] >= 42`, true},
+		{"repo.dependencies[1]", "dio"},
+		{`repo.constraints.dio == "any"`, true},
+		{`"dio" in repo.constraints`, true},
+		{`"path" in repo.dependencies`, false},
+		{`"a" < "b" && 2 > 1`, true},
+		{"1 != 1 || !false", true},
+		{"null == null", true},
+		{`repo.score > 40 ? "high" : "low"`, "high"},
+		{"size(repo.dependencies) + repo.name.size()", float64(11)},
+		{`{"a": 1}.a`, float64(1)},
+		{"has(repo.name)", true},
+		{"has(repo.owner)", false},
+		{`repo.name.startsWith("acme/") && repo.name.endsWith("app")`, true},
+		{`repo.name.contains("me/a")`, true},
+		{`repo.name.matches("^acme/[a-z]+$")`, true},
+		{`"ACME".lowerAscii()`, "acme"},
+		{`repo.dependencies.all(d, size(d) >= 3)`, true},
+		{`repo.dependencies.exists(d, d == "dio")`, true},
+		{`repo.dependencies.exists_one(d, d.startsWith("d"))`, true},
+		{`repo.dependencies.exists_one(d, size(d) > 2)`, false},
+		{`repo.dependencies.filter(d, d != "dio")`, []interface{}{"http", "meta"}},
+		{`repo.dependencies.map(d, d + "!")`, []interface{}{"http!", "dio!", "meta!"}},
+		{`repo.constraints.filter(k, repo.constraints[k] == "any")`, []interface{}{"dio"}},
+		{"tags.filter(t, true)", []interface{}{}},
+		{"tags.all(t, false)", true},
+		{"repo.dependencies.exists(d, repo.dependencies.all(e, e != d || d == e))", true},
+		{"false && repo.missing", false},
+		{"repo.missing || true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Eval(testVars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "unexpected end of expression"},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")" at end of expression`},
+		{"1 2", `unexpected "2" at 2`},
+		{"a.1", "expected field name at 2"},
+		{"a # b", `unexpected '#' at 2`},
+		{`"open`, "unterminated string at 0"},
+		{`"\q"`, `invalid escape \q`},
+		{"has(a)", "has() needs a field selection"},
+		{"len(a)", "unknown function len/1"},
+		{"a.reverse()", "unknown method reverse"},
+		{"a.contains()", "contains() takes 1 arguments"},
+		{"a.all(1, true)", "all() needs a variable name"},
+		{"{1: 2", `expected "," at end of expression`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"missing", `undeclared reference to "missing"`},
+		{"repo.owner", "no such key: owner"},
+		{"repo.name.first", `cannot select "first" from string`},
+		{"repo.dependencies[3]", "index 3 out of range"},
+		{"repo.dependencies[0.5]", "list index must be an integer"},
+		{"1 / 0", "division by zero"},
+		{`1 + "a"`, "no such overload: number + string"},
+		{`1 < "a"`, "no such overload: number < string"},
+		{"-repo.name", "cannot negate string"},
+		{"!1", "number used as bool"},
+		{"repo.score.all(x, true)", "all() on number"},
+		{"size(repo.score)", "size() on number"},
+		{`{1: 2}`, "map keys must be strings, not number"},
+		{`repo.name.matches("(")`, "missing closing )"},
+		{"repo.missing && true", "no such key: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.Eval(testVars); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr string
+	}{
+		{"size(repo.dependencies) > 2", true, ""},
+		{`repo.name == "acme/web"`, false, ""},
+		{"repo.name", false, "expression yields string, want bool"},
+		{"repo.owner == 1", false, "no such key: owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.EvalBool(testVars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"1 + 2", []string{}},
+		{"repo.name == owner", []string{"owner", "repo"}},
+		{"has(repo.name) && repo.name != ''", []string{"repo"}},
+		{"deps.all(d, d.startsWith(prefix))", []string{"deps", "prefix"}},
+		{"deps.exists(d, other.all(o, o != d))", []string{"deps", "other"}},
+		{"deps.map(d, d) + [d]", []string{"d", "deps"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Variables(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
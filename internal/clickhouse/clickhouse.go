@@ -0,0 +1,84 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// BatchSize is the number of rows per INSERT. ClickHouse prefers few large
+// inserts over many small ones.
+const BatchSize = 10000
+
+// --- Structures ---
+
+// Client runs statements over ClickHouse's HTTP interface (port 8123, or
+// 8443 with TLS).
+type Client struct {
+	HTTP     *http.Client
+	BaseURL  string
+	User     string
+	Password string
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// --- Core logic ---
+
+// CheckTable validates a table name, table or database.table, since it is
+// put into statements as is.
+func CheckTable(name string) error {
+	if !tableName.MatchString(name) {
+		return fmt.Errorf("invalid ClickHouse table %q: want table or database.table", name)
+	}
+	return nil
+}
+
+// Exec runs a statement that returns no rows, e.g. CREATE TABLE.
+func (c *Client) Exec(ctx context.Context, query string) error {
+	return c.post(ctx, url.Values{}, strings.NewReader(query))
+}
+
+// Insert writes rows to table in the JSONEachRow format: each row is
+// marshaled to one JSON object whose keys are column names.
+func (c *Client) Insert(ctx context.Context, table string, rows []interface{}) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	q := url.Values{"query": {"INSERT INTO " + table + " FORMAT JSONEachRow"}}
+	return c.post(ctx, q, &body)
+}
+
+func (c *Client) post(ctx context.Context, q url.Values, body io.Reader) error {
+	u := c.BaseURL + "/"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, _ := http.NewRequestWithContext(ctx, "POST", u, body)
+	if c.User != "" {
+		req.Header.Set("X-ClickHouse-User", c.User)
+	}
+	if c.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.Password)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ClickHouse request failed: %s (%s)", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
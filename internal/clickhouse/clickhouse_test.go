@@ -0,0 +1,82 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"pub_facts", false},
+		{"analytics.pub_facts", false},
+		{"_t1", false},
+		{"1facts", true},
+		{"a.b.c", true},
+		{"facts; DROP TABLE x", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckTable(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("CheckTable error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	type row struct {
+		Package string `json:"package"`
+		Count   int    `json:"count"`
+	}
+	tests := []struct {
+		name      string
+		call      func(*Client) error
+		status    int
+		wantQuery string
+		wantBody  string
+		wantErr   string
+	}{
+		{"exec", func(c *Client) error { return c.Exec(context.Background(), "CREATE TABLE t (x String)") },
+			200, "", "CREATE TABLE t (x String)", ""},
+		{"insert", func(c *Client) error {
+			return c.Insert(context.Background(), "db.t", []interface{}{row{"http", 2}, row{"dio", 1}})
+		}, 200, "INSERT INTO db.t FORMAT JSONEachRow", "{\"package\":\"http\",\"count\":2}\n{\"package\":\"dio\",\"count\":1}\n", ""},
+		{"server error", func(c *Client) error { return c.Exec(context.Background(), "SELECT") },
+			500, "", "SELECT", "ClickHouse request failed: 500 Internal Server Error (Code: 62. Syntax error)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if got := r.URL.Query().Get("query"); got != tt.wantQuery {
+					t.Errorf("query %q, want %q", got, tt.wantQuery)
+				}
+				if string(body) != tt.wantBody {
+					t.Errorf("body %q, want %q", body, tt.wantBody)
+				}
+				if r.Header.Get("X-ClickHouse-User") != "pgs" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+					t.Errorf("credentials %q, %q", r.Header.Get("X-ClickHouse-User"), r.Header.Get("X-ClickHouse-Key"))
+				}
+				w.WriteHeader(tt.status)
+				if tt.status != 200 {
+					io.WriteString(w, "Code: 62. Syntax error\n")
+				}
+			}))
+			defer srv.Close()
+
+			c := &Client{HTTP: srv.Client(), BaseURL: srv.URL, User: "pgs", Password: "secret"}
+			err := tt.call(c)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package codeowners
+
+import (
+	"path"
+	"strings"
+)
+
+// Rule maps a CODEOWNERS path pattern to its owners.
+type Rule struct {
+	Pattern string
+	Owners  []string
+}
+
+type Rules []Rule
+
+// Parse reads a CODEOWNERS file. Comments and blank lines are ignored.
+func Parse(content string) Rules {
+	var rules Rules
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, Rule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	return rules
+}
+
+// Owners returns the owners of file, a slash-separated path relative to the
+// repository root. As on GitHub, the last matching rule wins.
+func (r Rules) Owners(file string) []string {
+	for i := len(r) - 1; i >= 0; i-- {
+		if match(r[i].Pattern, file) {
+			return r[i].Owners
+		}
+	}
+	return nil
+}
+
+// match implements the gitignore-style subset used by CODEOWNERS: a leading
+// slash anchors the pattern to the root, a trailing slash matches everything
+// below a directory, and ** spans any number of directories.
+func match(pattern, file string) bool {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	pat := strings.Split(pattern, "/")
+	segs := strings.Split(file, "/")
+	if !anchored {
+		// An unanchored pattern matches at any depth, and a name may also
+		// match a directory containing the file.
+		for i := range segs {
+			if matchSegments(pat, segs[i:]) || matchSegments(append(pat, "**"), segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(pat, segs) || matchSegments(append(pat, "**"), segs)
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if len(pat) == 1 {
+				return len(segs) > 0
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
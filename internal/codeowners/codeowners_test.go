@@ -0,0 +1,50 @@
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse("# owners\n\n*       @acme/all\n/docs/ @acme/docs @alice # inline\n")
+	want := Rules{{Pattern: "*", Owners: []string{"@acme/all"}}, {Pattern: "/docs/", Owners: []string{"@acme/docs", "@alice"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+}
+
+func TestOwners(t *testing.T) {
+	rules := Parse(`
+*                @acme/all
+*.dart           @acme/dart
+/lib/            @acme/lib
+docs             @acme/docs
+apps/**/pubspec.yaml @acme/release
+/packages/core/  @acme/core
+`)
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"README.md", []string{"@acme/all"}},
+		{"bin/main.dart", []string{"@acme/dart"}},
+		{"lib/src/a.dart", []string{"@acme/lib"}},
+		{"tool/lib/a.txt", []string{"@acme/all"}},
+		{"docs/guide.md", []string{"@acme/docs"}},
+		{"packages/docs/index.md", []string{"@acme/docs"}},
+		{"apps/pubspec.yaml", []string{"@acme/release"}},
+		{"apps/mobile/pubspec.yaml", []string{"@acme/release"}},
+		{"packages/core/lib/core.dart", []string{"@acme/core"}},
+		{"packages/core", []string{"@acme/all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := rules.Owners(tt.file); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Owners = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := Parse("/lib/ @acme/lib").Owners("README.md"); got != nil {
+		t.Errorf("unmatched file owned by %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// --- Structures ---
+
+// Formats maps file extensions to the compression they select. gzip is
+// built in; zstd runs the zstd binary.
+var Formats = map[string]string{
+	".gz":  "gzip",
+	".zst": "zstd",
+}
+
+// --- Core logic ---
+
+// ForPath returns the compression selected by the extension of path, and
+// the path without it. The format is "" for uncompressed files.
+func ForPath(path string) (format, base string) {
+	for ext, f := range Formats {
+		if b, ok := strings.CutSuffix(path, ext); ok {
+			return f, b
+		}
+	}
+	return "", path
+}
+
+// Ext returns the extension of format.
+func Ext(format string) string {
+	for ext, f := range Formats {
+		if f == format {
+			return ext
+		}
+	}
+	return ""
+}
+
+// NewWriter returns a writer that compresses into w. The compressed stream
+// is complete once Close returns without error; w is not closed.
+func NewWriter(ctx context.Context, w io.Writer, format string) (io.WriteCloser, error) {
+	switch format {
+	case "":
+		return nopCloser{w}, nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		cmd := exec.CommandContext(ctx, "zstd", "-q", "-c")
+		cmd.Stdout = w
+		return startPipe(cmd)
+	}
+	return nil, fmt.Errorf("unknown compression %q", format)
+}
+
+// NewReader returns a reader that decompresses r.
+func NewReader(ctx context.Context, r io.Reader, format string) (io.ReadCloser, error) {
+	switch format {
+	case "":
+		return io.NopCloser(r), nil
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		cmd := exec.CommandContext(ctx, "zstd", "-q", "-d", "-c")
+		cmd.Stdin = r
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("zstd: %v", err)
+		}
+		return &pipeReader{ReadCloser: out, cmd: cmd, stderr: &stderr}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", format)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// pipeWriter feeds a running command, which writes the compressed stream.
+type pipeWriter struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func startPipe(cmd *exec.Cmd) (io.WriteCloser, error) {
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("zstd: %v", err)
+	}
+	return &pipeWriter{WriteCloser: in, cmd: cmd, stderr: &stderr}, nil
+}
+
+func (p *pipeWriter) Close() error {
+	p.WriteCloser.Close()
+	return wait(p.cmd, p.stderr)
+}
+
+// pipeReader reads the output of a running decompressor.
+type pipeReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func (p *pipeReader) Close() error {
+	p.ReadCloser.Close()
+	return wait(p.cmd, p.stderr)
+}
+
+func wait(cmd *exec.Cmd, stderr *bytes.Buffer) error {
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("zstd: %v: %s", err, msg)
+		}
+		return fmt.Errorf("zstd: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package defectdojo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+)
+
+// --- Structures ---
+
+// Mapping decides where findings land in DefectDojo. Product and
+// Engagement may contain {repo}, {owner} and {name} placeholders, e.g.
+// "{repo}" to create one product per scanned repo.
+type Mapping struct {
+	ProductType string
+	Product     string
+	Engagement  string
+	TestTitle   string
+}
+
+type genericFinding struct {
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	Severity         string `json:"severity"`
+	Active           bool   `json:"active"`
+	Verified         bool   `json:"verified"`
+	ComponentName    string `json:"component_name,omitempty"`
+	ComponentVersion string `json:"component_version,omitempty"`
+	Mitigation       string `json:"mitigation,omitempty"`
+	FilePath         string `json:"file_path"`
+	UniqueID         string `json:"unique_id_from_tool"`
+	VulnID           string `json:"vuln_id_from_tool,omitempty"`
+}
+
+// Client uploads findings through the DefectDojo v2 API.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	Token   string
+}
+
+// --- Core logic ---
+
+// Export groups findings by mapped product and engagement and reimports
+// each group as a Generic Findings Import test. Reimporting into the same
+// test title updates earlier results instead of duplicating them.
+func (c *Client) Export(ctx context.Context, m Mapping, list []findings.Finding) (int, error) {
+	type target struct{ product, engagement string }
+	groups := map[target][]findings.Finding{}
+	for _, f := range list {
+		t := target{expand(m.Product, f.Repo), expand(m.Engagement, f.Repo)}
+		groups[t] = append(groups[t], f)
+	}
+	targets := make([]target, 0, len(groups))
+	for t := range groups {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].product+"\x00"+targets[i].engagement < targets[j].product+"\x00"+targets[j].engagement
+	})
+
+	for _, t := range targets {
+		if err := c.reimport(ctx, m, t.product, t.engagement, groups[t]); err != nil {
+			return 0, fmt.Errorf("product %q: %w", t.product, err)
+		}
+	}
+	return len(targets), nil
+}
+
+func (c *Client) reimport(ctx context.Context, m Mapping, product, engagement string, list []findings.Finding) error {
+	payload := struct {
+		Findings []genericFinding `json:"findings"`
+	}{}
+	for _, f := range list {
+		description := fmt.Sprintf("Repository: %s\nRule: %s\n\n%s", f.Repo, f.Rule, f.Message)
+		if f.Evidence != "" {
+			description += "\n\nEvidence: " + f.Evidence
+		}
+		gf := genericFinding{
+			Title:         fmt.Sprintf("%s: %s", f.Rule, f.Message),
+			Description:   description,
+			Severity:      severity(f.Severity),
+			Active:        true,
+			ComponentName: f.Package,
+			Mitigation:    f.Remediation,
+			FilePath:      f.Repo + "/pubspec.yaml",
+			UniqueID:      f.Fingerprint(),
+		}
+		gf.ComponentVersion = f.Version
+		if len(f.Advisories) > 0 {
+			gf.VulnID = f.Advisories[0]
+		}
+		payload.Findings = append(payload.Findings, gf)
+	}
+	report, _ := json.Marshal(payload)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"scan_type":           "Generic Findings Import",
+		"product_type_name":   m.ProductType,
+		"product_name":        product,
+		"engagement_name":     engagement,
+		"test_title":          m.TestTitle,
+		"auto_create_context": "true",
+		"close_old_findings":  "true",
+		"active":              "true",
+		"verified":            "false",
+	}
+	for k, v := range fields {
+		if v != "" {
+			_ = w.WriteField(k, v)
+		}
+	}
+	fw, _ := w.CreateFormFile("file", "pubscan.json")
+	fw.Write(report)
+	w.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/v2/reimport-scan/", &body)
+	req.Header.Set("Authorization", "Token "+c.Token)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("reimport failed: %s (%s)", resp.Status, string(msg))
+	}
+	return nil
+}
+
+func expand(tmpl, repo string) string {
+	owner, name, _ := strings.Cut(repo, "/")
+	return strings.NewReplacer("{repo}", repo, "{owner}", owner, "{name}", name).Replace(tmpl)
+}
+
+// severity maps finding severities to DefectDojo's capitalized names.
+func severity(s string) string {
+	switch s {
+	case findings.SeverityCritical:
+		return "Critical"
+	case findings.SeverityHigh:
+		return "High"
+	case findings.SeverityMedium:
+		return "Medium"
+	case findings.SeverityLow:
+		return "Low"
+	default:
+		return "Info"
+	}
+}
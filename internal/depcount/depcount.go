@@ -0,0 +1,113 @@
+package depcount
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// bucketEdges are the lower bounds of the histogram buckets.
+var bucketEdges = []int{0, 5, 10, 20, 30, 40, 60, 80, 100}
+
+// --- Structures ---
+
+type Bucket struct {
+	Range string `json:"range"`
+	Count int    `json:"count"`
+}
+
+// Distribution summarizes how many packages repos declare.
+type Distribution struct {
+	Repos   int      `json:"repos"`
+	Min     int      `json:"min"`
+	Max     int      `json:"max"`
+	Mean    float64  `json:"mean"`
+	P50     int      `json:"p50"`
+	P90     int      `json:"p90"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Report holds the distribution of main dependencies and of all distinct
+// packages per repo.
+type Report struct {
+	Dependencies Distribution `json:"dependencies"`
+	All          Distribution `json:"all"`
+}
+
+// Tracker records the dependency counts of successfully scanned repos.
+type Tracker struct {
+	mu   sync.Mutex
+	deps []int
+	all  []int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// --- Core logic ---
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deps = append(t.deps, len(r.Dependencies))
+	t.all = append(t.all, len(stats.PackageNames(r)))
+}
+
+// Report returns the distributions, or nil when no repo was scanned.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.deps) == 0 {
+		return nil
+	}
+	return &Report{Dependencies: distribution(t.deps), All: distribution(t.all)}
+}
+
+func distribution(counts []int) Distribution {
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+	d := Distribution{
+		Repos: len(sorted),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+	}
+	sum := 0
+	for _, c := range sorted {
+		sum += c
+	}
+	d.Mean = math.Round(float64(sum)/float64(len(sorted))*10) / 10
+
+	for i, lo := range bucketEdges {
+		b := Bucket{Range: fmt.Sprintf("%d+", lo)}
+		hi := math.MaxInt
+		if i+1 < len(bucketEdges) {
+			hi = bucketEdges[i+1]
+			b.Range = fmt.Sprintf("%d-%d", lo, hi-1)
+		}
+		for _, c := range sorted {
+			if c >= lo && c < hi {
+				b.Count++
+			}
+		}
+		d.Buckets = append(d.Buckets, b)
+	}
+	return d
+}
+
+// percentile uses the nearest-rank method on sorted counts.
+func percentile(sorted []int, p int) int {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,214 @@
+package depgraph
+
+import (
+	"sort"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Top is the number of repos and packages each list of the report keeps.
+const Top = 20
+
+// --- Structures ---
+
+// Closure is the size of a repo's resolved dependency graph. Depth is the
+// longest chain from the pubspec, 1 when nothing is pulled in transitively;
+// it is only known when the graph was resolved.
+type Closure struct {
+	Repo       string `json:"repo"`
+	Direct     int    `json:"direct"`
+	Transitive int    `json:"transitive"`
+	Depth      int    `json:"depth,omitempty"`
+}
+
+// Package is a package the fleet depends on transitively. Repos counts the
+// repos locking it without declaring it; PulledBy names the direct
+// dependencies that bring it in across the fleet, FanIn counts them.
+type Package struct {
+	Name     string   `json:"name"`
+	Repos    int      `json:"repos"`
+	FanIn    int      `json:"fan_in,omitempty"`
+	PulledBy []string `json:"pulled_by,omitempty"`
+}
+
+// Redundant is a dependency a repo declares although other direct
+// dependencies, named in PulledBy, already pull it in.
+type Redundant struct {
+	Repo     string   `json:"repo"`
+	Package  string   `json:"package"`
+	PulledBy []string `json:"pulled_by"`
+}
+
+// Report holds the transitive dependency metrics of the repos with a
+// lockfile. Common lists the most used transitive packages, FanIn the ones
+// the most distinct direct dependencies pull in: the hidden single points
+// of failure of the fleet.
+type Report struct {
+	Repos          int         `json:"repos"`
+	MeanTransitive float64     `json:"mean_transitive"`
+	Largest        []Closure   `json:"largest"`
+	Common         []Package   `json:"common_transitive"`
+	FanIn          []Package   `json:"fan_in,omitempty"`
+	Redundant      []Redundant `json:"redundant_direct,omitempty"`
+}
+
+// Tracker collects the closures recorded in repo results.
+type Tracker struct {
+	mu         sync.Mutex
+	repos      int
+	transitive int
+	closures   []Closure
+	redundant  []Redundant
+	counts     map[string]int
+	pulledBy   map[string]map[string]bool
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{counts: map[string]int{}, pulledBy: map[string]map[string]bool{}}
+}
+
+// --- Core logic ---
+
+// Analyze walks the graph of the locked packages from the direct
+// dependencies. deps returns the dependencies of a package at its locked
+// version. It returns, for every locked package reachable from a direct
+// dependency other than itself, the direct dependencies reaching it, and
+// the depth of the graph.
+func Analyze(locked map[string]string, direct []string, deps func(name, version string) []string) (map[string][]string, int) {
+	pulledBy := map[string][]string{}
+	depth := 0
+	for _, root := range direct {
+		if _, ok := locked[root]; !ok {
+			continue
+		}
+		if depth == 0 {
+			depth = 1
+		}
+		seen := map[string]bool{root: true}
+		level := []string{root}
+		for d := 1; len(level) > 0; d++ {
+			if d > depth {
+				depth = d
+			}
+			var next []string
+			for _, name := range level {
+				for _, dep := range deps(name, locked[name]) {
+					if _, ok := locked[dep]; !ok || seen[dep] {
+						continue
+					}
+					seen[dep] = true
+					next = append(next, dep)
+					pulledBy[dep] = append(pulledBy[dep], root)
+				}
+			}
+			level = next
+		}
+	}
+	for name := range pulledBy {
+		sort.Strings(pulledBy[name])
+	}
+	return pulledBy, depth
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || r.Locked == nil {
+		return
+	}
+	c := Closure{Repo: r.Repo, Depth: r.LockDepth}
+	var transitive []string
+	for name := range r.Locked {
+		if _, ok := r.Constraints[name]; ok {
+			c.Direct++
+		} else {
+			transitive = append(transitive, name)
+		}
+	}
+	c.Transitive = len(transitive)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos++
+	t.transitive += c.Transitive
+	t.closures = append(t.closures, c)
+	for _, name := range transitive {
+		t.counts[name]++
+	}
+	for name, roots := range r.PulledBy {
+		if _, ok := r.Constraints[name]; ok {
+			t.redundant = append(t.redundant, Redundant{Repo: r.Repo, Package: name, PulledBy: roots})
+			continue
+		}
+		if t.pulledBy[name] == nil {
+			t.pulledBy[name] = map[string]bool{}
+		}
+		for _, root := range roots {
+			t.pulledBy[name][root] = true
+		}
+	}
+}
+
+// Report returns the metrics, or nil when no repo had a lockfile.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.repos == 0 {
+		return nil
+	}
+	rep := &Report{Repos: t.repos, MeanTransitive: float64(t.transitive) / float64(t.repos)}
+
+	rep.Largest = append(rep.Largest, t.closures...)
+	sort.Slice(rep.Largest, func(i, j int) bool {
+		a, b := rep.Largest[i], rep.Largest[j]
+		if a.Transitive != b.Transitive {
+			return a.Transitive > b.Transitive
+		}
+		return a.Repo < b.Repo
+	})
+	rep.Largest = rep.Largest[:min(len(rep.Largest), Top)]
+
+	for name, n := range t.counts {
+		rep.Common = append(rep.Common, t.pkg(name, n))
+	}
+	sort.Slice(rep.Common, func(i, j int) bool {
+		a, b := rep.Common[i], rep.Common[j]
+		if a.Repos != b.Repos {
+			return a.Repos > b.Repos
+		}
+		return a.Name < b.Name
+	})
+	rep.Common = rep.Common[:min(len(rep.Common), Top)]
+
+	for name, roots := range t.pulledBy {
+		if len(roots) > 0 {
+			rep.FanIn = append(rep.FanIn, t.pkg(name, t.counts[name]))
+		}
+	}
+	sort.Slice(rep.FanIn, func(i, j int) bool {
+		a, b := rep.FanIn[i], rep.FanIn[j]
+		if a.FanIn != b.FanIn {
+			return a.FanIn > b.FanIn
+		}
+		return a.Name < b.Name
+	})
+	rep.FanIn = rep.FanIn[:min(len(rep.FanIn), Top)]
+
+	rep.Redundant = append(rep.Redundant, t.redundant...)
+	sort.Slice(rep.Redundant, func(i, j int) bool {
+		a, b := rep.Redundant[i], rep.Redundant[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Package < b.Package
+	})
+	return rep
+}
+
+func (t *Tracker) pkg(name string, repos int) Package {
+	p := Package{Name: name, Repos: repos, FanIn: len(t.pulledBy[name])}
+	for root := range t.pulledBy[name] {
+		p.PulledBy = append(p.PulledBy, root)
+	}
+	sort.Strings(p.PulledBy)
+	return p
+}
@@ -0,0 +1,139 @@
+package depsubmit
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Correlator groups the snapshots of pubscan so a new submission replaces
+// the previous one of the same manifest.
+const Correlator = "pubscan"
+
+// --- Structures ---
+
+// Snapshot is the body of GitHub's dependency submission API.
+type Snapshot struct {
+	Version  int                 `json:"version"`
+	SHA      string              `json:"sha"`
+	Ref      string              `json:"ref"`
+	Job      Job                 `json:"job"`
+	Detector Detector            `json:"detector"`
+	Scanned  string              `json:"scanned"`
+	Manifest map[string]Manifest `json:"manifests"`
+}
+
+type Job struct {
+	Correlator string `json:"correlator"`
+	ID         string `json:"id"`
+}
+
+type Detector struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+type File struct {
+	SourceLocation string `json:"source_location"`
+}
+
+// Manifest is one dependency file of the repo and the packages it resolves
+// to, keyed by name.
+type Manifest struct {
+	Name     string                `json:"name"`
+	File     File                  `json:"file"`
+	Resolved map[string]Dependency `json:"resolved"`
+}
+
+// Dependency is a resolved package. Relationship is direct or indirect,
+// Scope runtime or development.
+type Dependency struct {
+	PackageURL   string   `json:"package_url"`
+	Relationship string   `json:"relationship"`
+	Scope        string   `json:"scope"`
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// --- Core logic ---
+
+// Build describes the dependencies of r at commit sha. file is the path of
+// the manifest in the repo, e.g. packages/app/pubspec.yaml. With a
+// lockfile every locked package is submitted at its locked version;
+// otherwise only the declared dependencies are, at the lowest version
+// their constraint allows. Sdk, git and path dependencies are left out
+// as they are not published packages.
+func Build(r stats.RepoResult, sha, file, jobID, version string, now time.Time) Snapshot {
+	dev := map[string]bool{}
+	for _, name := range r.DevDependencies {
+		dev[name] = true
+	}
+	// pulledBy is inverted so direct dependencies list what they pull in.
+	children := map[string][]string{}
+	for pkg, parents := range r.PulledBy {
+		for _, p := range parents {
+			children[p] = append(children[p], pkg)
+		}
+	}
+
+	resolved := map[string]Dependency{}
+	add := func(name, version string) {
+		_, direct := r.Constraints[name]
+		d := Dependency{PackageURL: purl.For(r.Ecosystem, name), Relationship: "indirect", Scope: "runtime"}
+		if version != "" {
+			d.PackageURL = purl.Versioned(d.PackageURL, version)
+		}
+		if direct {
+			d.Relationship = "direct"
+			if dev[name] {
+				d.Scope = "development"
+			}
+		}
+		resolved[name] = d
+	}
+	if len(r.Locked) > 0 {
+		for name, v := range r.Locked {
+			if c := r.Constraints[name]; strings.HasPrefix(c, "sdk:") || strings.HasPrefix(c, "git:") || strings.HasPrefix(c, "path:") {
+				continue
+			}
+			add(name, v)
+		}
+	} else {
+		for name, c := range r.Constraints {
+			if v, ok := risk.LowerBound(c); ok {
+				add(name, v)
+			} else if !strings.Contains(c, ":") {
+				add(name, "")
+			}
+		}
+	}
+	for name, d := range resolved {
+		for _, child := range children[name] {
+			if c, ok := resolved[child]; ok {
+				d.Dependencies = append(d.Dependencies, c.PackageURL)
+			}
+		}
+		sort.Strings(d.Dependencies)
+		resolved[name] = d
+	}
+
+	branch := r.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	return Snapshot{
+		Version:  0,
+		SHA:      sha,
+		Ref:      "refs/heads/" + branch,
+		Job:      Job{Correlator: Correlator + "/" + file, ID: jobID},
+		Detector: Detector{Name: "pubscan", Version: version, URL: "https://github.com/plasmatrip/pubscan"},
+		Scanned:  now.UTC().Format(time.RFC3339),
+		Manifest: map[string]Manifest{
+			file: {Name: file, File: File{SourceLocation: file}, Resolved: resolved},
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package dtrack
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Client uploads SBOMs to a Dependency-Track server.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	APIKey  string
+}
+
+// UploadBOM submits a CycloneDX document for project/version. The project
+// is created if it does not exist yet.
+func (c *Client) UploadBOM(ctx context.Context, project, version string, bom []byte) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"projectName":    project,
+		"projectVersion": version,
+		"autoCreate":     true,
+		"bom":            base64.StdEncoding.EncodeToString(bom),
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, "PUT", c.BaseURL+"/api/v1/bom", bytes.NewReader(body))
+	req.Header.Set("X-Api-Key", c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("BOM upload for %s failed: %s (%s)", project, resp.Status, string(msg))
+	}
+	return nil
+}
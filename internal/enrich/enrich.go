@@ -0,0 +1,127 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+)
+
+// PackageInfo is what pub.dev knows about a package.
+type PackageInfo struct {
+	Name         string    `json:"name"`
+	Latest       string    `json:"latest,omitempty"`
+	Published    time.Time `json:"published,omitempty"`
+	Discontinued bool      `json:"discontinued,omitempty"`
+	ReplacedBy   string    `json:"replaced_by,omitempty"`
+	Licenses     []string  `json:"licenses,omitempty"`
+
+	// NotFound is set for packages that are not hosted on pub.dev.
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+// Enricher looks packages up on pub.dev, once per package per run.
+type Enricher struct {
+	PubDev *pubdev.Client
+
+	// Licenses makes lookups also fetch the package score for its license.
+	Licenses bool
+
+	mu           sync.Mutex
+	cache        map[string]*PackageInfo
+	hits, misses int
+}
+
+func New(client *pubdev.Client) *Enricher {
+	return &Enricher{PubDev: client, cache: map[string]*PackageInfo{}}
+}
+
+// CacheStats returns how many lookups were answered from the cache and how
+// many went to pub.dev.
+func (e *Enricher) CacheStats() (hits, misses int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.hits, e.misses
+}
+
+// Packages returns info for every name that could be looked up. Lookup
+// failures other than unknown packages are printed and left out.
+func (e *Enricher) Packages(ctx context.Context, names []string, workers int) map[string]*PackageInfo {
+	out := map[string]*PackageInfo{}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+		ch = make(chan string)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range ch {
+				info, err := e.Package(ctx, name)
+				if err != nil {
+					fmt.Printf("Error fetching pub.dev metadata for %s: %v\n", name, err)
+					continue
+				}
+				mu.Lock()
+				out[name] = info
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		ch <- name
+	}
+	close(ch)
+	wg.Wait()
+	return out
+}
+
+// Package returns info for a single package.
+func (e *Enricher) Package(ctx context.Context, name string) (*PackageInfo, error) {
+	e.mu.Lock()
+	if info, ok := e.cache[name]; ok {
+		e.hits++
+		e.mu.Unlock()
+		return info, nil
+	}
+	e.misses++
+	e.mu.Unlock()
+
+	info := &PackageInfo{Name: name}
+	pkg, err := e.PubDev.Package(ctx, name)
+	switch {
+	case errors.Is(err, pubdev.ErrNotFound):
+		info.NotFound = true
+	case err != nil:
+		return nil, err
+	default:
+		info.Latest = pkg.Latest.Version
+		info.Published = pkg.Latest.Published
+		opts, err := e.PubDev.Options(ctx, name)
+		if err != nil && !errors.Is(err, pubdev.ErrNotFound) {
+			return nil, err
+		}
+		if opts != nil {
+			info.Discontinued = opts.IsDiscontinued
+			info.ReplacedBy = opts.ReplacedBy
+		}
+		if e.Licenses {
+			score, err := e.PubDev.Score(ctx, name)
+			if err != nil && !errors.Is(err, pubdev.ErrNotFound) {
+				return nil, err
+			}
+			if score != nil {
+				info.Licenses = score.Licenses()
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.cache[name] = info
+	e.mu.Unlock()
+	return info, nil
+}
@@ -0,0 +1,86 @@
+package facts
+
+import (
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/inventory"
+	"pgithub.com/plasmatrip/pubscan/internal/manifest"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Row is one fact of the per-repo, per-dependency table that warehouse
+// exports load: a dependency a repo declared in a scan.
+type Row struct {
+	ScanDate   Date   `json:"scan_date"`
+	Repo       string `json:"repo"`
+	Branch     string `json:"branch"`
+	Ecosystem  string `json:"ecosystem"`
+	Package    string `json:"package"`
+	PURL       string `json:"purl"`
+	Section    string `json:"section"`
+	Constraint string `json:"constraint"`
+	Source     string `json:"source"`
+	// Version is the lowest version the constraint allows, empty when it
+	// has none (e.g. git and path dependencies).
+	Version string `json:"version,omitempty"`
+}
+
+// Date is a calendar date, written as YYYY-MM-DD in JSON.
+type Date struct{ time.Time }
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.Format("2006-01-02") + `"`), nil
+}
+
+// Fields are the column names of a Row, in order.
+var Fields = []string{"scan_date", "repo", "branch", "ecosystem", "package", "purl", "section", "constraint", "source", "version"}
+
+// --- Core logic ---
+
+// Rows returns the facts of a repo scanned on date. Failed repos have none.
+func Rows(r stats.RepoResult, date time.Time) []Row {
+	if r.Error != "" {
+		return nil
+	}
+	eco := r.Ecosystem
+	if eco == "" {
+		eco = manifest.Pub
+	}
+	var rows []Row
+	add := func(section string, names []string) {
+		for _, name := range names {
+			c := r.Constraints[name]
+			row := Row{
+				ScanDate:   Date{date},
+				Repo:       r.Repo,
+				Branch:     r.Branch,
+				Ecosystem:  eco,
+				Package:    name,
+				PURL:       purl.For(r.Ecosystem, name),
+				Section:    section,
+				Constraint: c,
+				Source:     inventory.Source(c),
+			}
+			row.Version, _ = risk.LowerBound(c)
+			rows = append(rows, row)
+		}
+	}
+	add("dependencies", r.Dependencies)
+	add("dev_dependencies", r.DevDependencies)
+	add("dependency_overrides", r.DependencyOverrides)
+	return rows
+}
+
+// Values returns the row's columns in the order of Fields, with the scan
+// date as a time.Time and a missing version as nil.
+func (r Row) Values() []interface{} {
+	var version interface{}
+	if r.Version != "" {
+		version = r.Version
+	}
+	return []interface{}{r.ScanDate.Time, r.Repo, r.Branch, r.Ecosystem, r.Package, r.PURL, r.Section, r.Constraint, r.Source, version}
+}
@@ -0,0 +1,72 @@
+package findings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Baseline records the findings of one run, so that later runs fail only
+// on findings that are not in it.
+type Baseline struct {
+	CreatedAt string          `json:"created_at"`
+	Findings  []BaselineEntry `json:"findings"`
+}
+
+// BaselineEntry identifies a recorded finding by its fingerprint; the
+// other fields are for readers of the file.
+type BaselineEntry struct {
+	ID      string `json:"id"`
+	Rule    string `json:"rule"`
+	Repo    string `json:"repo"`
+	Package string `json:"package,omitempty"`
+}
+
+// NewBaseline records list.
+func NewBaseline(list []Finding, now time.Time) Baseline {
+	b := Baseline{CreatedAt: now.UTC().Format(time.RFC3339), Findings: []BaselineEntry{}}
+	seen := map[string]bool{}
+	for _, f := range list {
+		id := f.Fingerprint()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		b.Findings = append(b.Findings, BaselineEntry{ID: id, Rule: f.Rule, Repo: f.Repo, Package: f.Package})
+	}
+	return b
+}
+
+// LoadBaseline reads a file written with --write-baseline.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &b, nil
+}
+
+// Apply marks the findings of list that are in the baseline. It returns
+// the number of new findings and of baseline entries no longer found.
+func (b *Baseline) Apply(list []Finding) (fresh, fixed int) {
+	known := make(map[string]bool, len(b.Findings))
+	for _, e := range b.Findings {
+		known[e.ID] = true
+	}
+	found := map[string]bool{}
+	for i := range list {
+		id := list[i].Fingerprint()
+		if known[id] {
+			list[i].Baseline = true
+			found[id] = true
+		} else {
+			fresh++
+		}
+	}
+	return fresh, len(known) - len(found)
+}
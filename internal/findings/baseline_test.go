@@ -0,0 +1,102 @@
+package findings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBaselineApply(t *testing.T) {
+	recorded := []Finding{
+		{Rule: RuleVulnerable, Repo: "acme/app", Package: "http"},
+		{Rule: RuleVulnerable, Repo: "acme/app", Package: "http"},
+		{Rule: RuleMaxOverrides, Repo: "acme/web"},
+	}
+	b := NewBaseline(recorded, time.Date(2026, 7, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600)))
+	if b.CreatedAt != "2026-07-01T10:00:00Z" {
+		t.Errorf("created at %s", b.CreatedAt)
+	}
+	if len(b.Findings) != 2 {
+		t.Fatalf("%d entries, want duplicates recorded once", len(b.Findings))
+	}
+
+	tests := []struct {
+		name      string
+		list      []Finding
+		baseline  []bool
+		wantFresh int
+		wantFixed int
+	}{
+		{"unchanged", []Finding{{Rule: RuleVulnerable, Repo: "acme/app", Package: "http"}, {Rule: RuleMaxOverrides, Repo: "acme/web"}}, []bool{true, true}, 0, 0},
+		{"new finding", []Finding{{Rule: RuleVulnerable, Repo: "acme/app", Package: "http"}, {Rule: RuleMaxOverrides, Repo: "acme/web"}, {Rule: RuleStale, Repo: "acme/app", Package: "dio"}}, []bool{true, true, false}, 1, 0},
+		{"fixed finding", []Finding{{Rule: RuleMaxOverrides, Repo: "ACME/web", Severity: SeverityHigh}}, []bool{true}, 0, 1},
+		{"all fixed", nil, nil, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fresh, fixed := b.Apply(tt.list)
+			if fresh != tt.wantFresh || fixed != tt.wantFixed {
+				t.Errorf("fresh %d, fixed %d, want %d and %d", fresh, fixed, tt.wantFresh, tt.wantFixed)
+			}
+			for i, f := range tt.list {
+				if f.Baseline != tt.baseline[i] {
+					t.Errorf("finding %d baseline %v, want %v", i, f.Baseline, tt.baseline[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(valid, []byte(`{"created_at": "2026-07-01T10:00:00Z", "findings": [{"id": "8b8ef1faf181a53b", "rule": "discontinued-dependency", "repo": "acme/app"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"findings": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBaseline(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []Finding{{Rule: RuleDiscontinued, Repo: "acme/app", Package: "http"}}
+	if fresh, _ := b.Apply(list); fresh != 0 || !list[0].Baseline {
+		t.Errorf("recorded fingerprint not matched")
+	}
+	for _, path := range []string{invalid, filepath.Join(dir, "missing.json")} {
+		if _, err := LoadBaseline(path); err == nil {
+			t.Errorf("LoadBaseline(%s) gave no error", path)
+		}
+	}
+}
+
+func TestFailing(t *testing.T) {
+	list := []Finding{
+		{Rule: RuleMaxDeps, Severity: SeverityHigh},
+		{Rule: RuleMaxOverrides, Severity: SeverityHigh, Baseline: true},
+		{Rule: RuleVulnerable, Severity: SeverityCritical},
+		{Rule: RuleStale, Severity: SeverityLow},
+		{Rule: RuleVulnerable, Severity: SeverityCritical, Baseline: true},
+	}
+	tests := []struct {
+		failOn string
+		want   int
+	}{
+		{"", 1},
+		{SeverityCritical, 2},
+		{SeverityHigh, 2},
+		{SeverityLow, 3},
+		{SeverityInfo, 3},
+	}
+	for _, tt := range tests {
+		t.Run("fail on "+tt.failOn, func(t *testing.T) {
+			if got := len(Failing(list, tt.failOn)); got != tt.want {
+				t.Errorf("%d failing, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/hosted"
+	"pgithub.com/plasmatrip/pubscan/internal/lockfile"
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+	"pgithub.com/plasmatrip/pubscan/internal/typosquat"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+const (
+	RuleVulnerable   = "vulnerable-dependency"
+	RuleDiscontinued = "discontinued-dependency"
+	RuleUnbounded    = "unbounded-constraint"
+	RuleStale        = "stale-dependency"
+	RuleOverrides    = "dependency-overrides"
+	RuleSchema       = "pubspec-schema"
+	RuleForked       = "forked-dependency"
+	RuleUntrusted    = "untrusted-package-server"
+	RuleTyposquat    = "typosquat-suspect"
+	RuleHashMismatch = "archive-hash-mismatch"
+
+	// Quality gate rules are named after their flags. Their findings fail
+	// the run whatever their severity.
+	RuleMaxDeps      = "max-deps-per-repo"
+	RuleMaxOverrides = "max-overrides"
+	RuleMaxUnbounded = "max-unbounded"
+	RuleMaxMutable   = "max-mutable-refs"
+	RuleScanFailed   = gate.ScanFailed
+)
+
+// CustomPrefix starts the rule names of findings of user-defined rules,
+// e.g. "custom:crashlytics-with-analytics".
+const CustomPrefix = "custom:"
+
+// CustomRule names the findings of the user-defined rule id.
+func CustomRule(id string) string { return CustomPrefix + id }
+
+// Rules lists every built-in rule findings are reported under.
+var Rules = []string{RuleVulnerable, RuleDiscontinued, RuleUnbounded, RuleStale, RuleOverrides, RuleSchema, RuleForked, RuleUntrusted, RuleTyposquat, RuleHashMismatch, RuleMaxDeps, RuleMaxOverrides, RuleMaxUnbounded, RuleMaxMutable, RuleScanFailed}
+
+var gateRules = map[string]bool{RuleMaxDeps: true, RuleMaxOverrides: true, RuleMaxUnbounded: true, RuleMaxMutable: true, RuleScanFailed: true}
+
+// Finding is a single issue in a repo, e.g. a vulnerable or discontinued
+// dependency, a schema violation or an exceeded quality gate. ID is the
+// fingerprint, PURL the package URL of the dependency. Evidence is what
+// the rule matched on, Remediation a hint on how to resolve it. Baseline
+// marks findings recorded in the --baseline file.
+type Finding struct {
+	ID          string   `json:"id"`
+	Rule        string   `json:"rule"`
+	Severity    string   `json:"severity"`
+	Repo        string   `json:"repo"`
+	Package     string   `json:"package,omitempty"`
+	PURL        string   `json:"purl,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Message     string   `json:"message"`
+	Evidence    string   `json:"evidence,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+	Advisories  []string `json:"advisories,omitempty"`
+	Baseline    bool     `json:"baseline,omitempty"`
+}
+
+// RepoCheck computes findings from the result of one repo alone. Checks
+// that need no enrichment plug in as a RepoCheck; see RunChecks.
+type RepoCheck func(stats.RepoResult) []Finding
+
+// Fingerprint identifies a finding across runs, so exporters can update
+// existing tickets instead of creating duplicates. Packages of other
+// ecosystems than pub are identified by their package URL, so findings
+// of pub packages keep the fingerprints they had before package URLs.
+// Vulnerabilities also include the resolved version and the advisories,
+// so a new advisory or an upgrade to another affected version is a new
+// finding rather than one hidden by a baseline or a closed ticket.
+func (f Finding) Fingerprint() string {
+	pkg := f.Package
+	if typ, _ := purl.Split(f.PURL); typ != "" && typ != purl.Pub {
+		pkg = f.PURL
+	}
+	parts := []string{f.Rule, strings.ToLower(f.Repo), pkg}
+	if f.Rule == RuleVulnerable {
+		ids := append([]string(nil), f.Advisories...)
+		sort.Strings(ids)
+		parts = append(parts, f.Version, strings.Join(ids, ","))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FromRisk turns the risk signals of every repo into findings.
+func FromRisk(repos []risk.RepoRisk) []Finding {
+	var out []Finding
+	for _, r := range repos {
+		id := func(name string) string { return purl.For(r.Ecosystem, name) }
+		for _, v := range r.Signals.Vulnerable {
+			out = append(out, Finding{
+				Rule:        RuleVulnerable,
+				Severity:    SeverityHigh,
+				Repo:        r.Repo,
+				Package:     v.Package,
+				PURL:        id(v.Package),
+				Version:     v.Version,
+				Message:     fmt.Sprintf("%s %s is affected by %s", v.Package, v.Version, strings.Join(v.Advisories, ", ")),
+				Evidence:    fmt.Sprintf("version %s matches %s", v.Version, strings.Join(v.Advisories, ", ")),
+				Remediation: fmt.Sprintf("Upgrade %s to a release that fixes the advisories", v.Package),
+				Advisories:  v.Advisories,
+			})
+		}
+		for _, p := range r.Signals.Discontinued {
+			out = append(out, Finding{Rule: RuleDiscontinued, Severity: SeverityMedium, Repo: r.Repo, Package: p, PURL: id(p),
+				Message:     fmt.Sprintf("%s is discontinued on pub.dev", p),
+				Evidence:    "marked discontinued by its publisher",
+				Remediation: fmt.Sprintf("Replace %s, e.g. with the package its pub.dev page recommends", p)})
+		}
+		for _, p := range r.Signals.Unbounded {
+			out = append(out, Finding{Rule: RuleUnbounded, Severity: SeverityLow, Repo: r.Repo, Package: p, PURL: id(p),
+				Message:     fmt.Sprintf("constraint on %s has no upper bound", p),
+				Evidence:    "constraint allows every future major version",
+				Remediation: "Use a caret constraint such as ^1.2.0"})
+		}
+		for _, p := range r.Signals.Stale {
+			out = append(out, Finding{Rule: RuleStale, Severity: SeverityInfo, Repo: r.Repo, Package: p, PURL: id(p),
+				Message:     fmt.Sprintf("%s has had no release for a long time", p),
+				Evidence:    "latest release is older than the stale threshold",
+				Remediation: fmt.Sprintf("Check that %s is still maintained, or plan a replacement", p)})
+		}
+		if r.Signals.Overrides > 0 {
+			out = append(out, Finding{Rule: RuleOverrides, Severity: SeverityLow, Repo: r.Repo,
+				Message:     fmt.Sprintf("%d dependency override(s) declared", r.Signals.Overrides),
+				Evidence:    fmt.Sprintf("%d entries under dependency_overrides", r.Signals.Overrides),
+				Remediation: "Remove the overrides once the version conflicts they work around are resolved"})
+		}
+	}
+	identify(out)
+	Sort(out)
+	return out
+}
+
+// schemaSeverity is the severity of each kind of schema violation. Keys that
+// are merely unknown or outdated are harmless; invalid values break pub get
+// or publishing.
+var schemaSeverity = map[string]string{
+	pubspec.ViolationUnknownKey:        SeverityInfo,
+	pubspec.ViolationDeprecatedKey:     SeverityInfo,
+	pubspec.ViolationInvalidName:       SeverityMedium,
+	pubspec.ViolationInvalidVersion:    SeverityMedium,
+	pubspec.ViolationInvalidConstraint: SeverityMedium,
+}
+
+// FromSchema turns the pubspec schema violations of a repo into hygiene
+// findings. The violating field takes the place of the package.
+func FromSchema(repo string, list []pubspec.Violation) []Finding {
+	out := make([]Finding, 0, len(list))
+	for _, v := range list {
+		sev := schemaSeverity[v.Kind]
+		if sev == "" {
+			sev = SeverityLow
+		}
+		msg, evidence := v.Message, "pubspec.yaml"
+		if v.Line > 0 {
+			evidence = fmt.Sprintf("pubspec.yaml:%d", v.Line)
+			msg = evidence + ": " + v.Message
+		}
+		out = append(out, Finding{Rule: RuleSchema, Severity: sev, Repo: repo, Package: v.Field, Message: msg,
+			Evidence: evidence, Remediation: schemaRemediation[v.Kind]})
+	}
+	identify(out)
+	return out
+}
+
+// FromIntegrity turns locked packages whose archive hash pub.dev does not
+// confirm into findings: a different hash means the archive is not the
+// one pub.dev serves, from a tampered cache or mirror or republished
+// content; a version pub.dev does not list cannot be verified at all.
+func FromIntegrity(list []lockfile.HashMismatch) []Finding {
+	out := make([]Finding, 0, len(list))
+	for _, m := range list {
+		f := Finding{Rule: RuleHashMismatch, Severity: SeverityHigh, Repo: m.Repo, Package: m.Package, PURL: purl.For(purl.Pub, m.Package), Version: m.Version,
+			Message:     fmt.Sprintf("%s %s is locked with an archive hash pub.dev does not advertise", m.Package, m.Version),
+			Evidence:    fmt.Sprintf("pubspec.lock sha256 %s, pub.dev sha256 %s", m.Locked, m.Published),
+			Remediation: fmt.Sprintf("Find out where the locked archive of %s came from, then clear the pub cache and run pub get again", m.Package)}
+		if m.Published == "" {
+			f.Severity = SeverityMedium
+			f.Message = fmt.Sprintf("%s %s is locked but pub.dev does not list that version", m.Package, m.Version)
+			f.Evidence = fmt.Sprintf("pubspec.lock sha256 %s, version not on pub.dev", m.Locked)
+			f.Remediation = fmt.Sprintf("Check whether %s %s was removed from pub.dev and upgrade to a listed version", m.Package, m.Version)
+		}
+		out = append(out, f)
+	}
+	identify(out)
+	return out
+}
+
+// SchemaCheck is the RepoCheck of the schema violations found with
+// --validate-pubspec.
+func SchemaCheck(r stats.RepoResult) []Finding {
+	return FromSchema(r.Repo, r.SchemaViolations)
+}
+
+// ForkCheck returns the RepoCheck of dependencies on the given git forks
+// of pub.dev packages.
+func ForkCheck(forks []gitdeps.Fork) RepoCheck {
+	return func(r stats.RepoResult) []Finding {
+		var out []Finding
+		for _, pkg := range sortedKeys(r.Constraints) {
+			f, ok := gitdeps.IsFork(forks, pkg, r.Constraints[pkg])
+			if !ok {
+				continue
+			}
+			out = append(out, Finding{Rule: RuleForked, Severity: SeverityMedium, Repo: r.Repo, Package: pkg, PURL: purl.For(r.Ecosystem, pkg),
+				Message:     fmt.Sprintf("%s is used from the fork %s instead of pub.dev", pkg, f.URL),
+				Evidence:    fmt.Sprintf("git source %s, pub.dev package developed at %s", f.URL, f.Upstream),
+				Remediation: fmt.Sprintf("Upstream the patches of the fork and go back to the pub.dev release of %s, or publish the fork under its own name", pkg)})
+		}
+		identify(out)
+		return out
+	}
+}
+
+// HostedCheck returns the RepoCheck of dependencies fetched from package
+// servers that are neither pub.dev nor on the normalized allowlist.
+func HostedCheck(allowlist []string) RepoCheck {
+	return func(r stats.RepoResult) []Finding {
+		var out []Finding
+		for _, pkg := range sortedKeys(r.Constraints) {
+			h, ok := r.Hosted[pkg]
+			if !ok {
+				continue
+			}
+			url := hosted.Normalize(h.URL)
+			if hosted.Trusted(url, allowlist) {
+				continue
+			}
+			evidence := "hosted: url " + url
+			if h.Name != "" && h.Name != pkg {
+				evidence += ", name " + h.Name
+			}
+			out = append(out, Finding{Rule: RuleUntrusted, Severity: SeverityHigh, Repo: r.Repo, Package: pkg, PURL: purl.For(r.Ecosystem, pkg),
+				Message:     fmt.Sprintf("%s is fetched from %s, which is not an allowed package server", pkg, url),
+				Evidence:    evidence,
+				Remediation: fmt.Sprintf("Fetch %s from pub.dev or an approved server, or add the server to the allowlist after review", pkg)})
+		}
+		identify(out)
+		return out
+	}
+}
+
+// TyposquatCheck returns the RepoCheck of hosted pub dependencies whose
+// names look like popular packages without being one.
+func TyposquatCheck(checker *typosquat.Checker) RepoCheck {
+	return func(r stats.RepoResult) []Finding {
+		if r.Ecosystem != "" && r.Ecosystem != "pub" {
+			return nil
+		}
+		var out []Finding
+		for _, pkg := range sortedKeys(r.Constraints) {
+			if c := r.Constraints[pkg]; strings.HasPrefix(c, "sdk:") || strings.HasPrefix(c, "path:") || strings.HasPrefix(c, "git:") {
+				continue
+			}
+			m, ok := checker.Check(pkg)
+			if !ok {
+				continue
+			}
+			out = append(out, Finding{Rule: RuleTyposquat, Severity: SeverityMedium, Repo: r.Repo, Package: pkg, PURL: purl.For(r.Ecosystem, pkg),
+				Message:     fmt.Sprintf("%s looks like the popular package %s", pkg, m.Popular),
+				Evidence:    "name " + m.Reason,
+				Remediation: fmt.Sprintf("Check that %s is the intended package and not a lookalike of %s; allow it if the near-match is intentional", pkg, m.Popular)})
+		}
+		identify(out)
+		return out
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var gateMessage = map[string]string{
+	RuleMaxDeps:      "declares %d main dependencies, the limit is %d",
+	RuleMaxOverrides: "declares %d dependency overrides, the limit is %d",
+	RuleMaxUnbounded: "has %d constraints without upper bound, the limit is %d",
+	RuleMaxMutable:   "has %d git dependencies on a branch, the limit is %d",
+}
+
+var gateRemediation = map[string]string{
+	RuleMaxDeps:      "Remove unused dependencies or split the package",
+	RuleMaxOverrides: "Remove overrides that are no longer needed",
+	RuleMaxUnbounded: "Add upper bounds, e.g. with caret constraints",
+	RuleMaxMutable:   "Pin git dependencies to a tag or commit SHA with ref:",
+	RuleScanFailed:   "Fix the error, e.g. the token's access or the pubspec.yaml, and scan again",
+}
+
+// FromGate turns quality gate violations into findings. They are high
+// severity and, unlike other findings, always fail the run.
+func FromGate(list []gate.Violation) []Finding {
+	out := make([]Finding, 0, len(list))
+	for _, v := range list {
+		if v.Gate == RuleScanFailed {
+			out = append(out, Finding{Rule: v.Gate, Severity: SeverityHigh, Repo: v.Repo,
+				Message:     "could not be scanned, so the quality gates could not be checked",
+				Evidence:    v.Detail,
+				Remediation: gateRemediation[v.Gate]})
+			continue
+		}
+		out = append(out, Finding{Rule: v.Gate, Severity: SeverityHigh, Repo: v.Repo,
+			Message:     fmt.Sprintf(gateMessage[v.Gate], v.Value, v.Limit),
+			Evidence:    v.Detail,
+			Remediation: gateRemediation[v.Gate]})
+	}
+	identify(out)
+	return out
+}
+
+// RunChecks runs every check on r.
+func RunChecks(r stats.RepoResult, checks []RepoCheck) []Finding {
+	var out []Finding
+	for _, c := range checks {
+		out = append(out, c(r)...)
+	}
+	return out
+}
+
+// identify sets the ID of every finding.
+func identify(list []Finding) {
+	for i := range list {
+		list[i].ID = list[i].Fingerprint()
+	}
+}
+
+// schemaRemediation hints at the fix for each kind of schema violation.
+var schemaRemediation = map[string]string{
+	pubspec.ViolationUnknownKey:        "Remove the key or fix its spelling",
+	pubspec.ViolationDeprecatedKey:     "Remove the key; current pub versions ignore it",
+	pubspec.ViolationInvalidName:       "Use a lowercase name with underscores, as pub requires",
+	pubspec.ViolationInvalidVersion:    "Use a semantic version such as 1.2.0",
+	pubspec.ViolationInvalidConstraint: "Use a valid constraint such as ^1.2.0",
+}
+
+// Sort orders findings by repo, rule and package.
+func Sort(list []Finding) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Package < b.Package
+	})
+}
+
+var severityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SortBySeverity orders findings most severe first, then by rule and
+// package.
+func SortBySeverity(list []Finding) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Package < b.Package
+	})
+}
+
+// Rank orders severities: higher is more severe, unknown names rank as
+// info.
+func Rank(severity string) int { return severityRank[severity] }
+
+// Severities lists the severity names from most to least severe.
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// ValidSeverity reports whether s is a known severity name.
+func ValidSeverity(s string) bool {
+	_, ok := severityRank[s]
+	return ok
+}
+
+// Failing returns the findings that fail a run: quality gate findings, and
+// with failOn set those of that severity or higher. Findings in the
+// baseline never fail.
+func Failing(list []Finding, failOn string) []Finding {
+	var out []Finding
+	for _, f := range list {
+		if f.Baseline {
+			continue
+		}
+		if gateRules[f.Rule] || failOn != "" && severityRank[f.Severity] >= severityRank[failOn] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// AtLeast returns the findings with severity min or higher.
+func AtLeast(list []Finding, min string) []Finding {
+	var out []Finding
+	for _, f := range list {
+		if severityRank[f.Severity] >= severityRank[min] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ByRepo groups findings by repo.
+func ByRepo(list []Finding) map[string][]Finding {
+	out := map[string][]Finding{}
+	for _, f := range list {
+		out[f.Repo] = append(out[f.Repo], f)
+	}
+	return out
+}
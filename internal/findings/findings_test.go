@@ -0,0 +1,106 @@
+package findings
+
+import (
+	"reflect"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/gate"
+)
+
+func TestFingerprint(t *testing.T) {
+	// Fingerprints are stored in tickets and baselines; they must not
+	// change between releases.
+	tests := []struct {
+		name    string
+		finding Finding
+		want    string
+	}{
+		{"dependency", Finding{Rule: RuleDiscontinued, Repo: "acme/app", Package: "http"}, "8b8ef1faf181a53b"},
+		{"repo case ignored", Finding{Rule: RuleDiscontinued, Repo: "Acme/App", Package: "http"}, "8b8ef1faf181a53b"},
+		{"details ignored", Finding{Rule: RuleDiscontinued, Repo: "acme/app", Package: "http", Severity: SeverityCritical, Version: "0.13.0", Message: "changed"}, "8b8ef1faf181a53b"},
+		{"pub package URL ignored", Finding{Rule: RuleDiscontinued, Repo: "acme/app", Package: "http", PURL: "pkg:pub/http"}, "8b8ef1faf181a53b"},
+		{"other ecosystem", Finding{Rule: RuleDiscontinued, Repo: "acme/app", Package: "lodash", PURL: "pkg:npm/lodash"}, "e5b0bc1fda7fc40b"},
+		{"vulnerability", Finding{Rule: RuleVulnerable, Repo: "acme/app", Package: "http", Version: "0.13.0", Advisories: []string{"GHSA-1", "GHSA-2"}}, "e8e22bfb4b10814b"},
+		{"advisory order ignored", Finding{Rule: RuleVulnerable, Repo: "acme/app", Package: "http", Version: "0.13.0", Advisories: []string{"GHSA-2", "GHSA-1"}, Message: "changed"}, "e8e22bfb4b10814b"},
+		{"other version", Finding{Rule: RuleVulnerable, Repo: "acme/app", Package: "http", Version: "0.13.1", Advisories: []string{"GHSA-1", "GHSA-2"}}, "ad99d583c9a19701"},
+		{"other advisories", Finding{Rule: RuleVulnerable, Repo: "acme/app", Package: "http", Version: "0.13.0", Advisories: []string{"GHSA-1"}}, "5fcc61a312851ede"},
+		{"vulnerability in other ecosystem", Finding{Rule: RuleVulnerable, Repo: "acme/app", Package: "lodash", PURL: "pkg:npm/lodash", Version: "4.17.0", Advisories: []string{"GHSA-3"}}, "ef978ba926093912"},
+		{"repo finding", Finding{Rule: RuleMaxOverrides, Repo: "acme/app"}, "5ef73f1c233d5dc3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.finding.Fingerprint(); got != tt.want {
+				t.Errorf("Fingerprint() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortBySeverity(t *testing.T) {
+	list := []Finding{
+		{Rule: RuleStale, Severity: SeverityLow, Package: "b"},
+		{Rule: RuleVulnerable, Severity: SeverityHigh, Package: "b"},
+		{Rule: RuleStale, Severity: SeverityLow, Package: "a"},
+		{Rule: RuleDiscontinued, Severity: SeverityHigh, Package: "c"},
+		{Rule: RuleSchema, Severity: "unknown"},
+	}
+	SortBySeverity(list)
+	var got []string
+	for _, f := range list {
+		got = append(got, f.Rule+"/"+f.Package)
+	}
+	want := []string{RuleDiscontinued + "/c", RuleVulnerable + "/b", RuleStale + "/a", RuleStale + "/b", RuleSchema + "/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order %v, want %v", got, want)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	list := []Finding{
+		{Rule: "a", Severity: SeverityInfo},
+		{Rule: "b", Severity: SeverityMedium},
+		{Rule: "c", Severity: SeverityCritical},
+	}
+	tests := []struct {
+		min  string
+		want int
+	}{
+		{SeverityInfo, 3},
+		{SeverityLow, 2},
+		{SeverityMedium, 2},
+		{SeverityHigh, 1},
+		{SeverityCritical, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.min, func(t *testing.T) {
+			if got := len(AtLeast(list, tt.min)); got != tt.want {
+				t.Errorf("AtLeast(%s) has %d findings, want %d", tt.min, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromGate(t *testing.T) {
+	tests := []struct {
+		name         string
+		v            gate.Violation
+		wantMessage  string
+		wantEvidence string
+	}{
+		{"limit", gate.Violation{Repo: "acme/app", Gate: RuleMaxOverrides, Value: 2, Limit: 0, Detail: "meta, async"},
+			"declares 2 dependency overrides, the limit is 0", "meta, async"},
+		{"scan failed", gate.Violation{Repo: "acme/app", Gate: RuleScanFailed, Detail: "invalid pubspec.yaml"},
+			"could not be scanned, so the quality gates could not be checked", "invalid pubspec.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromGate([]gate.Violation{tt.v})
+			if len(got) != 1 || got[0].Message != tt.wantMessage || got[0].Evidence != tt.wantEvidence {
+				t.Fatalf("got %+v", got)
+			}
+			if len(Failing(got, "")) != 1 {
+				t.Errorf("gate finding does not fail the run")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package findings
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suppression waives matching findings until it expires. Repo may be an
+// owner/repo glob such as "acme/*"; Package is a package name, matching it
+// in any ecosystem, or a package URL; empty Package or Rule match any.
+type Suppression struct {
+	Repo    string `yaml:"repo" json:"repo"`
+	Package string `yaml:"package,omitempty" json:"package,omitempty"`
+	Rule    string `yaml:"rule,omitempty" json:"rule,omitempty"`
+	Reason  string `yaml:"reason" json:"reason"`
+	Expires string `yaml:"expires,omitempty" json:"expires,omitempty"`
+
+	expires time.Time
+}
+
+type suppressionsFile struct {
+	Suppressions []Suppression `yaml:"suppressions"`
+}
+
+// Suppressed is a finding waived by a suppression.
+type Suppressed struct {
+	Finding
+	Reason  string `json:"reason"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// LoadSuppressions reads a suppressions file. Every entry needs a repo and a
+// reason; expires is a YYYY-MM-DD date after which the waiver lapses.
+func LoadSuppressions(file string) ([]Suppression, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sf suppressionsFile
+	if err := yaml.Unmarshal(data, &sf); err != nil {
+		return nil, err
+	}
+	for i := range sf.Suppressions {
+		s := &sf.Suppressions[i]
+		if s.Repo == "" || strings.TrimSpace(s.Reason) == "" {
+			return nil, fmt.Errorf("suppression %d: repo and reason are required", i+1)
+		}
+		if s.Rule != "" && !knownRule(s.Rule) {
+			return nil, fmt.Errorf("suppression %d: unknown rule %q", i+1, s.Rule)
+		}
+		if s.Expires != "" {
+			if s.expires, err = time.Parse("2006-01-02", s.Expires); err != nil {
+				return nil, fmt.Errorf("suppression %d: invalid expires date %q", i+1, s.Expires)
+			}
+		}
+	}
+	return sf.Suppressions, nil
+}
+
+func knownRule(r string) bool {
+	if strings.HasPrefix(r, CustomPrefix) && len(r) > len(CustomPrefix) {
+		return true
+	}
+	for _, known := range Rules {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the suppression lapsed before now. A suppression
+// is valid through the whole of its expiry day.
+func (s Suppression) Expired(now time.Time) bool {
+	return !s.expires.IsZero() && !now.Before(s.expires.AddDate(0, 0, 1))
+}
+
+func (s Suppression) matches(f Finding) bool {
+	if ok, _ := path.Match(strings.ToLower(s.Repo), strings.ToLower(f.Repo)); !ok {
+		return false
+	}
+	pkg := s.Package == "" || s.Package == f.Package || f.PURL != "" && s.Package == f.PURL
+	return pkg && (s.Rule == "" || s.Rule == f.Rule)
+}
+
+// Suppress splits findings into the ones still reported and the ones waived
+// by an active suppression. Expired suppressions are returned separately;
+// the findings they covered are reported again.
+func Suppress(list []Finding, rules []Suppression, now time.Time) (kept []Finding, suppressed []Suppressed, expired []Suppression) {
+	expiredSeen := map[int]bool{}
+	for _, f := range list {
+		waived := false
+		for i, s := range rules {
+			if !s.matches(f) {
+				continue
+			}
+			if s.Expired(now) {
+				if !expiredSeen[i] {
+					expiredSeen[i] = true
+					expired = append(expired, s)
+				}
+				continue
+			}
+			suppressed = append(suppressed, Suppressed{Finding: f, Reason: s.Reason, Expires: s.Expires})
+			waived = true
+			break
+		}
+		if !waived {
+			kept = append(kept, f)
+		}
+	}
+	return kept, suppressed, expired
+}
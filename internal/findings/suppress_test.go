@@ -0,0 +1,112 @@
+package findings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSuppressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr string
+	}{
+		{"valid", "suppressions:\n  - repo: acme/*\n    rule: stale-dependency\n    reason: tracked in JIRA-1\n    expires: 2026-12-31\n  - repo: acme/app\n    rule: custom:no-analytics\n    reason: accepted\n", 2, ""},
+		{"empty", "suppressions: []\n", 0, ""},
+		{"no repo", "suppressions:\n  - reason: why\n", 0, "repo and reason are required"},
+		{"blank reason", "suppressions:\n  - repo: acme/app\n    reason: \"  \"\n", 0, "repo and reason are required"},
+		{"unknown rule", "suppressions:\n  - repo: acme/app\n    rule: no-such-rule\n    reason: why\n", 0, "unknown rule"},
+		{"bare custom prefix", "suppressions:\n  - repo: acme/app\n    rule: \"custom:\"\n    reason: why\n", 0, "unknown rule"},
+		{"bad date", "suppressions:\n  - repo: acme/app\n    reason: why\n    expires: 31.12.2026\n", 0, "invalid expires date"},
+		{"not yaml", "suppressions: [\n", 0, "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "suppressions.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadSuppressions(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("%d suppressions, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppressionExpired(t *testing.T) {
+	s := Suppression{Expires: "2026-06-30", expires: time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name string
+		s    Suppression
+		now  time.Time
+		want bool
+	}{
+		{"before", s, time.Date(2026, 6, 29, 12, 0, 0, 0, time.UTC), false},
+		{"on the day", s, time.Date(2026, 6, 30, 23, 59, 0, 0, time.UTC), false},
+		{"after", s, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), true},
+		{"no expiry", Suppression{}, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Expired(tt.now); got != tt.want {
+				t.Errorf("Expired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	now := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	finding := Finding{Rule: RuleStale, Repo: "Acme/App", Package: "http", PURL: "pkg:pub/http"}
+	tests := []struct {
+		name        string
+		rule        Suppression
+		suppressed  bool
+		wantExpired bool
+	}{
+		{"exact", Suppression{Repo: "acme/app", Package: "http", Rule: RuleStale}, true, false},
+		{"repo glob", Suppression{Repo: "acme/*"}, true, false},
+		{"package URL", Suppression{Repo: "acme/app", Package: "pkg:pub/http"}, true, false},
+		{"other repo", Suppression{Repo: "acme/web"}, false, false},
+		{"other package", Suppression{Repo: "acme/app", Package: "dio"}, false, false},
+		{"other rule", Suppression{Repo: "acme/app", Rule: RuleVulnerable}, false, false},
+		{"expired", Suppression{Repo: "acme/app", Expires: "2026-06-30", expires: time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, suppressed, expired := Suppress([]Finding{finding}, []Suppression{tt.rule}, now)
+			if got := len(suppressed) == 1; got != tt.suppressed {
+				t.Errorf("suppressed %v, want %v", got, tt.suppressed)
+			}
+			if len(kept)+len(suppressed) != 1 {
+				t.Errorf("%d kept and %d suppressed, want 1 in all", len(kept), len(suppressed))
+			}
+			if got := len(expired) == 1; got != tt.wantExpired {
+				t.Errorf("expired %v, want %v", got, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestSuppressReportsExpiredOnce(t *testing.T) {
+	expired := Suppression{Repo: "acme/*", Expires: "2026-01-01", expires: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	list := []Finding{{Rule: RuleStale, Repo: "acme/app"}, {Rule: RuleStale, Repo: "acme/web"}}
+	kept, _, lapsed := Suppress(list, []Suppression{expired}, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	if len(kept) != 2 || len(lapsed) != 1 {
+		t.Errorf("%d kept and %d expired, want 2 and 1", len(kept), len(lapsed))
+	}
+}
@@ -0,0 +1,120 @@
+package flavors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// ConfigFile is the standalone flutter_flavorizr config, next to the
+// pubspec.
+const ConfigFile = "flavorizr.yaml"
+
+// Sources a flavor setup is detected from.
+const (
+	SourceFlavorizr   = "flutter_flavorizr"
+	SourceEntrypoints = "entrypoints"
+)
+
+// --- Structures ---
+
+type flavorizrConfig struct {
+	Flavorizr struct {
+		Flavors map[string]interface{} `yaml:"flavors"`
+	} `yaml:"flavorizr"`
+}
+
+// Repo is a repo with more than one flavor, and the sources they were found
+// in.
+type Repo struct {
+	Repo    string   `json:"repo"`
+	Flavors []string `json:"flavors"`
+	Sources []string `json:"sources"`
+}
+
+// Report counts the flavored repos, the repos per number of flavors and
+// per flavor name.
+type Report struct {
+	Flavored int            `json:"flavored"`
+	PerRepo  map[string]int `json:"flavors_per_repo"`
+	Names    map[string]int `json:"names"`
+	Repos    []Repo         `json:"repos,omitempty"`
+}
+
+// Tracker collects the flavors recorded in repo results.
+type Tracker struct {
+	mu    sync.Mutex
+	repos []Repo
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// --- Core logic ---
+
+// Flavorizr returns the flavors a flutter_flavorizr config declares, from
+// the flavorizr section of a pubspec or a flavorizr.yaml, whose top level
+// is the section itself.
+func Flavorizr(content string, standalone bool) []string {
+	var cfg flavorizrConfig
+	if standalone {
+		if yaml.Unmarshal([]byte(content), &cfg.Flavorizr) != nil {
+			return nil
+		}
+	} else if yaml.Unmarshal([]byte(content), &cfg) != nil {
+		return nil
+	}
+	var out []string
+	for name := range cfg.Flavorizr.Flavors {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Entrypoints returns the flavors named by main_<flavor>.dart files in a
+// listing of lib/.
+func Entrypoints(names []string) []string {
+	var out []string
+	for _, name := range names {
+		if flavor, ok := strings.CutPrefix(name, "main_"); ok && strings.HasSuffix(flavor, ".dart") {
+			out = append(out, strings.TrimSuffix(flavor, ".dart"))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || len(r.Flavors) < 2 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos = append(t.repos, Repo{Repo: r.Repo, Flavors: r.Flavors, Sources: r.FlavorSources})
+}
+
+// Report returns the flavored repos, or nil when there are none.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.repos) == 0 {
+		return nil
+	}
+	rep := &Report{Flavored: len(t.repos), PerRepo: map[string]int{}, Names: map[string]int{}}
+	for _, r := range t.repos {
+		rep.PerRepo[strconv.Itoa(len(r.Flavors))]++
+		for _, f := range r.Flavors {
+			rep.Names[f]++
+		}
+	}
+	rep.Repos = append(rep.Repos, t.repos...)
+	sort.Slice(rep.Repos, func(i, j int) bool { return rep.Repos[i].Repo < rep.Repos[j].Repo })
+	return rep
+}
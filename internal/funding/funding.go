@@ -0,0 +1,209 @@
+package funding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubdev"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// FirstPartyPublishers are the publishers of Dart and Flutter team packages,
+// which are not targets of the sponsorship program.
+var FirstPartyPublishers = []string{
+	"dart.dev", "flutter.dev", "google.dev", "tools.dart.dev", "labs.dart.dev",
+	"firebase.google.com", "fluttercommunity.dev",
+}
+
+// --- Structures ---
+
+// Entry is a community package the fleet relies on. Weight is its weighted
+// usage when repos carry weights.
+type Entry struct {
+	Name      string   `json:"name"`
+	Count     int      `json:"count"`
+	Weight    float64  `json:"weight,omitempty"`
+	URL       string   `json:"url"`
+	Publisher string   `json:"publisher,omitempty"`
+	Funding   []string `json:"funding"`
+	Contact   Contact  `json:"contact"`
+}
+
+// Contact is how to reach the maintainers of a package, from pub.dev and
+// its latest pubspec. Authors are the legacy pubspec authors, often with
+// an email address.
+type Contact struct {
+	PublisherURL string   `json:"publisher_url,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	IssueTracker string   `json:"issue_tracker,omitempty"`
+	Homepage     string   `json:"homepage,omitempty"`
+	Authors      []string `json:"authors,omitempty"`
+}
+
+type Report struct {
+	Packages []Entry  `json:"packages"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// --- Core logic ---
+
+// Build looks up the top most-used packages on pub.dev and collects their
+// funding links and maintainer contacts. Packages not hosted on pub.dev and
+// first-party packages are skipped. Usage counts dependencies and dev
+// dependencies; when repos carry weights, packages are ranked by weighted
+// usage instead.
+func Build(ctx context.Context, client *pubdev.Client, s stats.Stats, top, workers int) Report {
+	usage := map[string]int{}
+	for _, list := range [][]stats.PackageStat{s.Dependencies, s.DevDependencies} {
+		for _, p := range list {
+			if typ, _ := purl.Split(p.Key()); typ == purl.Pub {
+				usage[p.Name] += p.Count
+			}
+		}
+	}
+	weights := map[string]float64{}
+	for _, w := range s.Weighted {
+		if typ, _ := purl.Split(w.Key()); typ == purl.Pub {
+			weights[w.Name] = w.Weight
+		}
+	}
+	type candidate struct {
+		stats.PackageStat
+		weight float64
+	}
+	candidates := make([]candidate, 0, len(usage))
+	for name, n := range usage {
+		candidates = append(candidates, candidate{stats.PackageStat{Name: name, Count: n}, weights[name]})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].weight != candidates[j].weight {
+			return candidates[i].weight > candidates[j].weight
+		}
+		if candidates[i].Count != candidates[j].Count {
+			return candidates[i].Count > candidates[j].Count
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	// Look packages up a batch at a time until enough community packages
+	// were found; skipped ones make room for the next most-used.
+	var rep Report
+	for start := 0; start < len(candidates) && len(rep.Packages) < top; start += workers {
+		batch := candidates[start:min(start+workers, len(candidates))]
+		entries := make([]*Entry, len(batch))
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, c := range batch {
+			wg.Add(1)
+			go func(i int, c candidate) {
+				defer wg.Done()
+				entries[i], errs[i] = lookup(ctx, client, c.PackageStat)
+				if entries[i] != nil {
+					entries[i].Weight = c.weight
+				}
+			}(i, c)
+		}
+		wg.Wait()
+
+		for i, e := range entries {
+			if errs[i] != nil {
+				if !errors.Is(errs[i], pubdev.ErrNotFound) {
+					fmt.Printf("Error fetching pub.dev metadata for %s: %v\n", batch[i].Name, errs[i])
+				}
+				rep.Skipped = append(rep.Skipped, batch[i].Name)
+				continue
+			}
+			if len(rep.Packages) < top {
+				rep.Packages = append(rep.Packages, *e)
+			}
+		}
+	}
+	sort.Strings(rep.Skipped)
+	return rep
+}
+
+func lookup(ctx context.Context, client *pubdev.Client, c stats.PackageStat) (*Entry, error) {
+	publisher, err := client.Publisher(ctx, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, fp := range FirstPartyPublishers {
+		if strings.EqualFold(publisher, fp) {
+			return nil, pubdev.ErrNotFound
+		}
+	}
+	pkg, err := client.Package(ctx, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{
+		Name:      c.Name,
+		Count:     c.Count,
+		URL:       purl.URL(purl.For("", c.Name)),
+		Publisher: publisher,
+		Funding:   append([]string{}, pkg.Latest.Funding()...),
+		Contact: Contact{
+			Repository:   field(pkg.Latest.Pubspec, "repository"),
+			IssueTracker: field(pkg.Latest.Pubspec, "issue_tracker"),
+			Homepage:     field(pkg.Latest.Pubspec, "homepage"),
+		},
+	}
+	if publisher != "" {
+		e.Contact.PublisherURL = client.BaseURL + "/publishers/" + publisher
+	}
+	if a := field(pkg.Latest.Pubspec, "author"); a != "" {
+		e.Contact.Authors = []string{a}
+	}
+	authors, _ := pkg.Latest.Pubspec["authors"].([]interface{})
+	for _, a := range authors {
+		if s, ok := a.(string); ok {
+			e.Contact.Authors = append(e.Contact.Authors, s)
+		}
+	}
+	return e, nil
+}
+
+func field(pubspec map[string]interface{}, key string) string {
+	s, _ := pubspec[key].(string)
+	return s
+}
+
+// WriteNotes writes outreach notes in Markdown: for every package the way
+// to support it, sponsoring where it asks for funding and contributing
+// upstream otherwise, with its maintainer contacts.
+func WriteNotes(w io.Writer, rep Report) error {
+	var b strings.Builder
+	b.WriteString("# Community Packages\n\n")
+	b.WriteString("| Package | Repos | Publisher | Support | Contact |\n")
+	b.WriteString("|---|---:|---|---|---|\n")
+	for _, e := range rep.Packages {
+		support := "contribute"
+		if len(e.Funding) > 0 {
+			support = "sponsor: " + strings.Join(e.Funding, ", ")
+		}
+		var contact []string
+		for _, c := range []string{e.Contact.PublisherURL, e.Contact.Repository, e.Contact.IssueTracker} {
+			if c != "" {
+				contact = append(contact, c)
+			}
+		}
+		contact = append(contact, e.Contact.Authors...)
+		if len(contact) == 0 {
+			contact = []string{e.URL}
+		}
+		publisher := e.Publisher
+		if publisher == "" {
+			publisher = "-"
+		}
+		fmt.Fprintf(&b, "| [%s](%s) | %d | %s | %s | %s |\n", e.Name, e.URL, e.Count, publisher,
+			strings.ReplaceAll(support, "|", "\\|"), strings.ReplaceAll(strings.Join(contact, ", "), "|", "\\|"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,103 @@
+package gate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/gitdeps"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Disabled turns a limit off.
+const Disabled = -1
+
+// ScanFailed is the gate of repos that failed to scan while gates are
+// enabled: they cannot be checked, so they do not pass.
+const ScanFailed = "scan-failed"
+
+// --- Structures ---
+
+// Limits are the CI quality gates. A negative value disables a gate.
+type Limits struct {
+	MaxDeps      int
+	MaxOverrides int
+	MaxUnbounded int
+
+	// MaxMutableRefs limits git dependencies on a branch or the default
+	// branch rather than a tag or commit; 0 requires immutable refs.
+	MaxMutableRefs int
+}
+
+// Violation is a repo exceeding one limit.
+type Violation struct {
+	Repo   string `json:"repo"`
+	Gate   string `json:"gate"`
+	Value  int    `json:"value"`
+	Limit  int    `json:"limit"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// --- Core logic ---
+
+// Enabled reports whether any gate is set.
+func (l Limits) Enabled() bool {
+	return l.MaxDeps >= 0 || l.MaxOverrides >= 0 || l.MaxUnbounded >= 0 || l.MaxMutableRefs >= 0
+}
+
+// Check returns the limits r exceeds. A repo that failed to scan is one
+// ScanFailed violation with the error as detail, unless no gate is set or
+// it is not a Dart project.
+func (l Limits) Check(r stats.RepoResult) []Violation {
+	if r.Error != "" {
+		if !l.Enabled() || r.NotDart {
+			return nil
+		}
+		return []Violation{{Repo: r.Repo, Gate: ScanFailed, Detail: r.Error}}
+	}
+	var out []Violation
+	check := func(gate string, limit int, names []string) {
+		if limit >= 0 && len(names) > limit {
+			out = append(out, Violation{Repo: r.Repo, Gate: gate, Value: len(names), Limit: limit, Detail: strings.Join(names, ", ")})
+		}
+	}
+	if l.MaxDeps >= 0 && len(r.Dependencies) > l.MaxDeps {
+		out = append(out, Violation{Repo: r.Repo, Gate: "max-deps-per-repo", Value: len(r.Dependencies), Limit: l.MaxDeps})
+	}
+	check("max-overrides", l.MaxOverrides, r.DependencyOverrides)
+	check("max-unbounded", l.MaxUnbounded, risk.Unbounded(r))
+	check("max-mutable-refs", l.MaxMutableRefs, MutableRefs(r))
+	return out
+}
+
+// MutableRefs returns the git dependencies of r that follow a branch, as
+// package@ref, or the bare package name for the default branch.
+func MutableRefs(r stats.RepoResult) []string {
+	var out []string
+	for name, c := range r.Constraints {
+		dep, ok := gitdeps.Parse(c)
+		if !ok {
+			continue
+		}
+		switch gitdeps.RefKind(dep.Ref) {
+		case gitdeps.RefBranch:
+			out = append(out, name+"@"+dep.Ref)
+		case gitdeps.RefDefault:
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (v Violation) String() string {
+	if v.Gate == ScanFailed {
+		return fmt.Sprintf("%s: %s (%s)", v.Repo, v.Gate, v.Detail)
+	}
+	s := fmt.Sprintf("%s: %s %d > %d", v.Repo, v.Gate, v.Value, v.Limit)
+	if v.Detail != "" {
+		s += " (" + v.Detail + ")"
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package gate
+
+import (
+	"reflect"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+func TestCheck(t *testing.T) {
+	off := Limits{MaxDeps: Disabled, MaxOverrides: Disabled, MaxUnbounded: Disabled, MaxMutableRefs: Disabled}
+	with := func(set func(*Limits)) Limits {
+		l := off
+		set(&l)
+		return l
+	}
+	repo := stats.RepoResult{
+		Repo:                "acme/app",
+		Dependencies:        []string{"http", "dio", "meta"},
+		DependencyOverrides: []string{"meta", "async"},
+		Constraints: map[string]string{
+			"http":    "^1.2.0",
+			"dio":     "any",
+			"meta":    ">=1.9.0",
+			"widgets": "git:https://github.com/acme/widgets.git@main",
+		},
+	}
+	tests := []struct {
+		name   string
+		limits Limits
+		repo   stats.RepoResult
+		want   []Violation
+	}{
+		{"all disabled", off, repo, nil},
+		{"deps over", with(func(l *Limits) { l.MaxDeps = 2 }), repo,
+			[]Violation{{Repo: "acme/app", Gate: "max-deps-per-repo", Value: 3, Limit: 2}}},
+		{"deps at limit", with(func(l *Limits) { l.MaxDeps = 3 }), repo, nil},
+		{"overrides over zero", with(func(l *Limits) { l.MaxOverrides = 0 }), repo,
+			[]Violation{{Repo: "acme/app", Gate: "max-overrides", Value: 2, Limit: 0, Detail: "meta, async"}}},
+		{"overrides at limit", with(func(l *Limits) { l.MaxOverrides = 2 }), repo, nil},
+		{"unbounded over", with(func(l *Limits) { l.MaxUnbounded = 1 }), repo,
+			[]Violation{{Repo: "acme/app", Gate: "max-unbounded", Value: 2, Limit: 1, Detail: "dio, meta"}}},
+		{"zero limit with none", with(func(l *Limits) { l.MaxOverrides = 0 }), stats.RepoResult{Repo: "acme/web"}, nil},
+		{"mutable refs over zero", with(func(l *Limits) { l.MaxMutableRefs = 0 }), repo,
+			[]Violation{{Repo: "acme/app", Gate: "max-mutable-refs", Value: 1, Limit: 0, Detail: "widgets@main"}}},
+		{"failed scan fails the gates", Limits{}, stats.RepoResult{Repo: "acme/app", Error: "invalid pubspec.yaml"},
+			[]Violation{{Repo: "acme/app", Gate: ScanFailed, Detail: "invalid pubspec.yaml"}}},
+		{"failed scan without gates", off, stats.RepoResult{Repo: "acme/app", Error: "invalid pubspec.yaml"}, nil},
+		{"not a Dart project", Limits{}, stats.RepoResult{Repo: "acme/docs", Error: "pubspec.yaml not found", NotDart: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.Check(tt.repo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		want   bool
+	}{
+		{"all disabled", Limits{MaxDeps: Disabled, MaxOverrides: Disabled, MaxUnbounded: Disabled, MaxMutableRefs: Disabled}, false},
+		{"one zero limit", Limits{MaxDeps: Disabled, MaxOverrides: 0, MaxUnbounded: Disabled, MaxMutableRefs: Disabled}, true},
+		{"all set", Limits{MaxDeps: 50, MaxOverrides: 1, MaxUnbounded: 2, MaxMutableRefs: 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViolationString(t *testing.T) {
+	tests := []struct {
+		v    Violation
+		want string
+	}{
+		{Violation{Repo: "acme/app", Gate: "max-deps-per-repo", Value: 3, Limit: 2}, "acme/app: max-deps-per-repo 3 > 2"},
+		{Violation{Repo: "acme/app", Gate: "max-overrides", Value: 2, Limit: 0, Detail: "meta, async"}, "acme/app: max-overrides 2 > 0 (meta, async)"},
+		{Violation{Repo: "acme/app", Gate: ScanFailed, Detail: "invalid pubspec.yaml"}, "acme/app: scan-failed (invalid pubspec.yaml)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v.Gate, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutableRefs(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints map[string]string
+		want        []string
+	}{
+		{"branch", map[string]string{"widgets": "git:https://github.com/acme/widgets.git@main"}, []string{"widgets@main"}},
+		{"default branch", map[string]string{"widgets": "git:https://github.com/acme/widgets.git"}, []string{"widgets"}},
+		{"commit", map[string]string{"widgets": "git:https://github.com/acme/widgets.git@0123456789abcdef"}, nil},
+		{"tag", map[string]string{"widgets": "git:https://github.com/acme/widgets.git@v1.2.0"}, nil},
+		{"ssh user is not a ref", map[string]string{"widgets": "git:ssh://git@github.com/acme/widgets.git"}, []string{"widgets"}},
+		{"hosted", map[string]string{"http": "^1.2.0"}, nil},
+		{"sorted", map[string]string{
+			"b": "git:https://github.com/acme/b.git@develop",
+			"a": "git:https://github.com/acme/a.git",
+		}, []string{"a", "b@develop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MutableRefs(stats.RepoResult{Constraints: tt.constraints})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
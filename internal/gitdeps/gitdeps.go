@@ -0,0 +1,300 @@
+package gitdeps
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+const (
+	RefSHA     = "sha"
+	RefTag     = "tag"
+	RefBranch  = "branch"
+	RefDefault = "default"
+)
+
+var (
+	shaRe = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	tagRe = regexp.MustCompile(`^v?\d+(\.\d+)*([-+.][0-9A-Za-z.-]+)?$`)
+)
+
+// --- Structures ---
+
+// Dep is a git dependency as declared in a pubspec.
+type Dep struct {
+	URL string
+	Ref string
+}
+
+type RefUsage struct {
+	Ref   string   `json:"ref"`
+	Kind  string   `json:"kind"`
+	Count int      `json:"count"`
+	Repos []string `json:"repos,omitempty"`
+}
+
+// Source is one git repository used as a dependency across the fleet.
+type Source struct {
+	URL      string     `json:"url"`
+	Packages []string   `json:"packages"`
+	Count    int        `json:"count"`
+	Refs     []RefUsage `json:"refs"`
+}
+
+// BranchPin is a git dependency that follows a branch instead of a tag or
+// commit, so builds are not reproducible. Kind is RefBranch or RefDefault.
+type BranchPin struct {
+	Repo    string `json:"repo"`
+	Package string `json:"package"`
+	URL     string `json:"url"`
+	Ref     string `json:"ref,omitempty"`
+	Kind    string `json:"kind"`
+}
+
+// Fork is a git source a package is used from although a pub.dev package
+// of the same name is developed in another org's repository, Upstream.
+// Forks often carry unreviewed patches and hold back upgrades.
+type Fork struct {
+	Package  string   `json:"package"`
+	URL      string   `json:"url"`
+	Upstream string   `json:"upstream"`
+	Count    int      `json:"count"`
+	Repos    []string `json:"repos,omitempty"`
+}
+
+type Report struct {
+	Sources     []Source    `json:"sources"`
+	BranchPins  []BranchPin `json:"branch_pins,omitempty"`
+	BranchCount int         `json:"branch_pinned"`
+	PinnedCount int         `json:"pinned"`
+	Forks       []Fork      `json:"forks,omitempty"`
+}
+
+type source struct {
+	packages map[string]bool
+	count    int
+	refs     map[string]*RefUsage
+}
+
+// Tracker aggregates the git dependencies of repo results by normalized
+// URL. With CountOnly set it keeps counts but no repo names.
+type Tracker struct {
+	mu          sync.Mutex
+	CountOnly   bool
+	sources     map[string]*source
+	pins        []BranchPin
+	branchCount int
+	pinnedCount int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{sources: map[string]*source{}}
+}
+
+// --- Core logic ---
+
+// Parse splits a "git:<url>[@<ref>]" constraint as recorded by the scan.
+// Git refs cannot contain ':', so the ref separator is the last '@' after
+// the last ':'; the user part of ssh://git@host URLs is not mistaken for it.
+func Parse(constraint string) (Dep, bool) {
+	rest, ok := strings.CutPrefix(constraint, "git:")
+	if !ok {
+		return Dep{}, false
+	}
+	at := strings.LastIndex(rest, "@")
+	if at < 0 || at < strings.LastIndex(rest, ":") {
+		return Dep{URL: rest}, true
+	}
+	if _, afterScheme, ok := strings.Cut(rest[:at], "://"); ok && !strings.Contains(afterScheme, "/") {
+		return Dep{URL: rest}, true
+	}
+	return Dep{URL: rest[:at], Ref: rest[at+1:]}, true
+}
+
+// Normalize reduces the https, ssh, git and scp-like forms of a git URL to
+// lowercased host/path, e.g. github.com/acme/widgets; the common hosts
+// treat repo paths case-insensitively.
+func Normalize(url string) string {
+	u := strings.TrimSpace(url)
+	if scheme, rest, ok := strings.Cut(u, "://"); ok && !strings.Contains(scheme, "/") {
+		u = rest
+		if at := strings.Index(u, "@"); at >= 0 && at < strings.Index(u+"/", "/") {
+			u = u[at+1:]
+		}
+	} else if at := strings.Index(u, "@"); at >= 0 {
+		// scp-like: git@github.com:acme/widgets.git
+		u = strings.Replace(u[at+1:], ":", "/", 1)
+	}
+	host, path, _ := strings.Cut(u, "/")
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h // drop ports
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	if path == "" {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(host + "/" + path)
+}
+
+// Org returns the host and owner of a normalized URL, e.g. github.com/acme,
+// or "" when the URL names no owner.
+func Org(normalized string) string {
+	parts := strings.SplitN(normalized, "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// RefKind guesses what a ref points at from its shape: commit SHAs are hex,
+// tags look like versions, and everything else is taken to be a branch.
+// An empty ref follows the default branch.
+func RefKind(ref string) string {
+	switch {
+	case ref == "":
+		return RefDefault
+	case shaRe.MatchString(ref):
+		return RefSHA
+	case tagRe.MatchString(ref), strings.HasPrefix(ref, "refs/tags/"):
+		return RefTag
+	default:
+		return RefBranch
+	}
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	names := make([]string, 0, len(r.Constraints))
+	for name := range r.Constraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range names {
+		dep, ok := Parse(r.Constraints[name])
+		if !ok {
+			continue
+		}
+		url := Normalize(dep.URL)
+		src := t.sources[url]
+		if src == nil {
+			src = &source{packages: map[string]bool{}, refs: map[string]*RefUsage{}}
+			t.sources[url] = src
+		}
+		src.packages[name] = true
+		src.count++
+		kind := RefKind(dep.Ref)
+		ru := src.refs[dep.Ref]
+		if ru == nil {
+			ru = &RefUsage{Ref: dep.Ref, Kind: kind}
+			src.refs[dep.Ref] = ru
+		}
+		ru.Count++
+		if !t.CountOnly {
+			ru.Repos = append(ru.Repos, r.Repo)
+		}
+		if kind == RefBranch || kind == RefDefault {
+			t.branchCount++
+			if !t.CountOnly {
+				t.pins = append(t.pins, BranchPin{Repo: r.Repo, Package: name, URL: url, Ref: dep.Ref, Kind: kind})
+			}
+		} else {
+			t.pinnedCount++
+		}
+	}
+}
+
+// Report returns the git sources by number of dependents, or nil when no
+// repo declares a git dependency.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.sources) == 0 {
+		return nil
+	}
+	rep := &Report{BranchCount: t.branchCount, PinnedCount: t.pinnedCount, BranchPins: append([]BranchPin(nil), t.pins...)}
+	for url, src := range t.sources {
+		s := Source{URL: url, Count: src.count}
+		for p := range src.packages {
+			s.Packages = append(s.Packages, p)
+		}
+		sort.Strings(s.Packages)
+		for _, ru := range src.refs {
+			ref := *ru
+			ref.Repos = append([]string(nil), ru.Repos...)
+			sort.Strings(ref.Repos)
+			s.Refs = append(s.Refs, ref)
+		}
+		sort.Slice(s.Refs, func(i, j int) bool {
+			if s.Refs[i].Count != s.Refs[j].Count {
+				return s.Refs[i].Count > s.Refs[j].Count
+			}
+			return s.Refs[i].Ref < s.Refs[j].Ref
+		})
+		rep.Sources = append(rep.Sources, s)
+	}
+	sort.Slice(rep.Sources, func(i, j int) bool {
+		if rep.Sources[i].Count != rep.Sources[j].Count {
+			return rep.Sources[i].Count > rep.Sources[j].Count
+		}
+		return rep.Sources[i].URL < rep.Sources[j].URL
+	})
+	sort.Slice(rep.BranchPins, func(i, j int) bool {
+		a, b := rep.BranchPins[i], rep.BranchPins[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Package < b.Package
+	})
+	return rep
+}
+
+// FindForks records the sources of packages whose pub.dev namesake is
+// developed under another org. upstream returns the normalized repository
+// URL of a pub.dev package, or "" for packages that are not published or
+// name no repository.
+func (r *Report) FindForks(upstream func(pkg string) string) {
+	r.Forks = nil
+	for _, src := range r.Sources {
+		for _, pkg := range src.Packages {
+			up := upstream(pkg)
+			if Org(up) == "" || Org(up) == Org(src.URL) {
+				continue
+			}
+			f := Fork{Package: pkg, URL: src.URL, Upstream: up, Count: src.Count}
+			for _, ru := range src.Refs {
+				f.Repos = append(f.Repos, ru.Repos...)
+			}
+			sort.Strings(f.Repos)
+			r.Forks = append(r.Forks, f)
+		}
+	}
+	sort.Slice(r.Forks, func(i, j int) bool {
+		if r.Forks[i].Count != r.Forks[j].Count {
+			return r.Forks[i].Count > r.Forks[j].Count
+		}
+		return r.Forks[i].Package < r.Forks[j].Package
+	})
+}
+
+// IsFork reports whether the constraint of pkg uses one of the forks.
+func IsFork(forks []Fork, pkg, constraint string) (Fork, bool) {
+	dep, ok := Parse(constraint)
+	if !ok {
+		return Fork{}, false
+	}
+	url := Normalize(dep.URL)
+	for _, f := range forks {
+		if f.Package == pkg && f.URL == url {
+			return f, true
+		}
+	}
+	return Fork{}, false
+}
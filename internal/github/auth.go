@@ -0,0 +1,85 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Kinds of AuthError.
+const (
+	AuthBadCredentials = "bad_credentials"
+	AuthSAML           = "saml_sso"
+	AuthPermissions    = "missing_permissions"
+)
+
+// AuthError is a 401 or 403 response caused by the token rather than the
+// repo, with guidance on how to fix it.
+type AuthError struct {
+	Kind   string
+	Status string
+	// URL is where SAML SSO is authorized for the token, when GitHub
+	// gives one.
+	URL string
+	// Permissions are the fine-grained permissions GitHub asked for.
+	Permissions string
+}
+
+func (e *AuthError) Error() string {
+	switch e.Kind {
+	case AuthSAML:
+		msg := "the organization enforces SAML SSO and the token is not authorized for it"
+		if e.URL != "" {
+			msg += "; authorize it at " + e.URL
+		} else {
+			msg += "; authorize it under Settings → Developer settings → Tokens → Configure SSO"
+		}
+		return fmt.Sprintf("%s (%s)", msg, e.Status)
+	case AuthPermissions:
+		msg := "the token lacks permissions for this repository"
+		if e.Permissions != "" {
+			msg += "; grant " + e.Permissions
+		} else {
+			msg += "; a fine-grained token needs read access to Contents and Metadata and must include the repository"
+		}
+		return fmt.Sprintf("%s (%s)", msg, e.Status)
+	default:
+		return fmt.Sprintf("the token was rejected; check that GITHUB_TOKEN is valid and not expired (%s)", e.Status)
+	}
+}
+
+// authError classifies a 401 or 403 response, or returns nil when it is
+// about something else, e.g. rate limiting. The body is consumed.
+func authError(resp *http.Response) *AuthError {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return &AuthError{Kind: AuthBadCredentials, Status: resp.Status}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return nil
+	}
+	if sso := resp.Header.Get("X-GitHub-SSO"); strings.HasPrefix(sso, "required") {
+		e := &AuthError{Kind: AuthSAML, Status: resp.Status}
+		if _, url, ok := strings.Cut(sso, "url="); ok {
+			e.URL = strings.TrimSpace(url)
+		}
+		return e
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	msg := strings.ToLower(body.Message)
+	switch {
+	case strings.Contains(msg, "saml"):
+		return &AuthError{Kind: AuthSAML, Status: resp.Status}
+	case strings.Contains(msg, "not accessible by"), resp.Header.Get("X-Accepted-GitHub-Permissions") != "":
+		return &AuthError{Kind: AuthPermissions, Status: resp.Status, Permissions: resp.Header.Get("X-Accepted-GitHub-Permissions")}
+	}
+	return nil
+}
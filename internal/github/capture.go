@@ -0,0 +1,223 @@
+package github
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Structures ---
+
+// CaptureTransport sends requests through Base and writes the repo files
+// it receives to Dir in the layout LocalTransport reads, so a scan can be
+// repeated offline from what it fetched. Entries of fetched directory
+// listings that were not fetched themselves are written empty; the
+// scanned branch and commit go to a minimal .git directory. Failures to
+// write are returned as request errors.
+type CaptureTransport struct {
+	Base http.RoundTripper
+	Dir  string
+
+	mu    sync.Mutex
+	blobs map[string]string // repo@SHA -> path, from trees and large files
+}
+
+// --- Core logic ---
+
+func (t *CaptureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || req.Method != "GET" {
+		return resp, err
+	}
+	_, rest, ok := strings.Cut(req.URL.Path, "/repos/")
+	parts := strings.SplitN(rest, "/", 3)
+	if !ok || len(parts) < 3 {
+		return resp, nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	root := filepath.Join(t.Dir, parts[0], parts[1])
+	endpoint := parts[2]
+	raw := !isJSON(resp.Header.Get("Content-Type"))
+	switch {
+	case endpoint == "branches":
+		err = t.branches(root, data)
+	case strings.HasPrefix(endpoint, "commits/"):
+		err = t.commit(root, strings.TrimPrefix(endpoint, "commits/"), raw, data)
+	case endpoint == "contents" || strings.HasPrefix(endpoint, "contents/"):
+		err = t.contents(parts[0]+"/"+parts[1], root, strings.TrimPrefix(strings.TrimPrefix(endpoint, "contents"), "/"), raw, data)
+	case strings.HasPrefix(endpoint, "git/trees/"):
+		err = t.tree(parts[0]+"/"+parts[1], data)
+	case strings.HasPrefix(endpoint, "git/blobs/"):
+		err = t.blob(parts[0]+"/"+parts[1], strings.TrimPrefix(endpoint, "git/blobs/"), raw, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// branches records the branch LatestBranch picks as the checked-out one.
+func (t *CaptureTransport) branches(root string, data []byte) error {
+	var list []Branch
+	if json.Unmarshal(data, &list) != nil || len(list) == 0 {
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Commit.Commit.Author.Date.After(list[j].Commit.Commit.Author.Date)
+	})
+	return writeFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/"+list[0].Name+"\n"))
+}
+
+func (t *CaptureTransport) commit(root, ref string, raw bool, data []byte) error {
+	if !cleanRef(ref) {
+		return nil
+	}
+	sha := strings.TrimSpace(string(data))
+	if !raw {
+		var c struct {
+			SHA string `json:"sha"`
+		}
+		if json.Unmarshal(data, &c) != nil {
+			return nil
+		}
+		sha = c.SHA
+	}
+	head := filepath.Join(root, ".git", "HEAD")
+	if _, err := os.Stat(head); os.IsNotExist(err) {
+		if err := writeFile(head, []byte("ref: refs/heads/"+ref+"\n")); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(root, ".git", "refs", "heads", filepath.FromSlash(ref)), []byte(sha+"\n"))
+}
+
+func (t *CaptureTransport) contents(repo, root, p string, raw bool, data []byte) error {
+	full, ok := inside(root, p)
+	if !ok {
+		return nil
+	}
+	if raw {
+		return writeFile(full, data)
+	}
+	var entries []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &entries) == nil {
+		if err := os.MkdirAll(full, 0755); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Name == "" || strings.ContainsAny(e.Name, `/\`) || e.Name == ".." || e.Name == ".git" {
+				continue
+			}
+			child := filepath.Join(full, e.Name)
+			if _, err := os.Stat(child); err == nil {
+				continue
+			}
+			var err error
+			if e.Type == "dir" {
+				err = os.MkdirAll(child, 0755)
+			} else {
+				err = writeFile(child, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Servers ignoring the raw media type send the file as base64 JSON;
+	// files too large to inline are fetched as blobs next.
+	var file FileContent
+	if json.Unmarshal(data, &file) != nil {
+		return nil
+	}
+	if file.Encoding != "base64" {
+		if file.SHA != "" {
+			t.remember(repo, file.SHA, p)
+		}
+		return nil
+	}
+	content, err := base64.StdEncoding.DecodeString(file.Content)
+	if err != nil {
+		return nil
+	}
+	return writeFile(full, content)
+}
+
+func (t *CaptureTransport) tree(repo string, data []byte) error {
+	var tree struct {
+		Tree []TreeEntry `json:"tree"`
+	}
+	if json.Unmarshal(data, &tree) != nil {
+		return nil
+	}
+	for _, e := range tree.Tree {
+		if e.Type == "blob" {
+			t.remember(repo, e.SHA, e.Path)
+		}
+	}
+	return nil
+}
+
+// remember maps a blob to its path, so the blob is saved when fetched.
+func (t *CaptureTransport) remember(repo, sha, p string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.blobs == nil {
+		t.blobs = map[string]string{}
+	}
+	t.blobs[repo+"@"+sha] = p
+}
+
+func (t *CaptureTransport) blob(repo, sha string, raw bool, data []byte) error {
+	t.mu.Lock()
+	p, ok := t.blobs[repo+"@"+sha]
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if !raw {
+		var b FileContent
+		if json.Unmarshal(data, &b) != nil || b.Encoding != "base64" {
+			return nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(b.Content)
+		if err != nil {
+			return nil
+		}
+		data = decoded
+	}
+	full, ok := inside(filepath.Join(t.Dir, filepath.FromSlash(repo)), p)
+	if !ok {
+		return nil
+	}
+	return writeFile(full, data)
+}
+
+func writeFile(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// cleanRef keeps refs that are safe as file names below refs/heads.
+func cleanRef(ref string) bool {
+	return ref != "" && path.Clean(ref) == ref && !strings.HasPrefix(ref, "../") && ref != ".."
+}
@@ -0,0 +1,550 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/redact"
+)
+
+// --- Structures ---
+
+type Branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		Commit struct {
+			Author struct {
+				Date time.Time `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+	} `json:"commit"`
+}
+
+// Repository is the repo metadata used for weighting.
+type Repository struct {
+	FullName string   `json:"full_name"`
+	Stars    int      `json:"stargazers_count"`
+	Topics   []string `json:"topics"`
+}
+
+// TreeEntry is a file or directory of a git tree. Type is "blob" for files
+// and "tree" for directories.
+type TreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+	Size int64  `json:"size"`
+}
+
+// FileContent is the JSON shape of the contents API. Content is empty and
+// Encoding "none" for files over 1 MB.
+type FileContent struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+	Size     int64  `json:"size"`
+	SHA      string `json:"sha"`
+}
+
+// rawMediaType asks the contents and blobs APIs for the file itself instead
+// of base64 in JSON.
+const rawMediaType = "application/vnd.github.raw"
+
+const DefaultBaseURL = "https://api.github.com"
+
+// DefaultAPIVersion is the REST API version requested with
+// X-GitHub-Api-Version.
+const DefaultAPIVersion = "2022-11-28"
+
+// Client is a minimal GitHub REST API client authenticated with a single token.
+// APIVersion is sent as X-GitHub-Api-Version when set. OnFetch, when set,
+// is called with the path of every file File returns, and OnRedirect with
+// the URL of every request that was redirected, as for renamed repos.
+type Client struct {
+	HTTP       *http.Client
+	Token      string
+	BaseURL    string
+	APIVersion string
+	OnFetch    func(path string)
+	OnRedirect func(url string)
+}
+
+func NewClient(httpClient *http.Client, token string) *Client {
+	return &Client{HTTP: httpClient, Token: token, BaseURL: DefaultBaseURL, APIVersion: DefaultAPIVersion}
+}
+
+// --- Core logic ---
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.getAs(ctx, url, "")
+}
+
+// getAs is get with an Accept header.
+func (c *Client) getAs(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req.Header.Set("Authorization", "token "+c.Token)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.APIVersion != "" {
+		req.Header.Set("X-GitHub-Api-Version", c.APIVersion)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err == nil && c.OnRedirect != nil && resp.Request != nil && resp.Request.URL.Path != req.URL.Path {
+		c.OnRedirect(url)
+	}
+	return resp, err
+}
+
+// LatestBranch returns the name of the branch with the most recent commit.
+func (c *Client) LatestBranch(ctx context.Context, owner, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/branches", c.BaseURL, owner, repo)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if e := authError(resp); e != nil {
+		return "", e
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to get branches: %s (%s)", resp.Status, redact.Snippet(resp.Body))
+	}
+
+	var branches []Branch
+	if err := json.NewDecoder(resp.Body).Decode(&branches); err != nil {
+		return "", err
+	}
+	if len(branches) == 0 {
+		return "", fmt.Errorf("no branches found")
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Commit.Commit.Author.Date.After(branches[j].Commit.Commit.Author.Date)
+	})
+
+	return branches[0].Name, nil
+}
+
+// Repo returns the metadata of a repository.
+func (c *Client) Repo(ctx context.Context, owner, repo string) (Repository, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", c.BaseURL, owner, repo)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return Repository{}, err
+	}
+	defer resp.Body.Close()
+
+	if e := authError(resp); e != nil {
+		return Repository{}, e
+	}
+	if resp.StatusCode != 200 {
+		return Repository{}, fmt.Errorf("failed to get repository %s/%s (%s)", owner, repo, resp.Status)
+	}
+	var r Repository
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	return r, err
+}
+
+// CommitSHA returns the SHA of the commit ref points at.
+func (c *Client) CommitSHA(ctx context.Context, owner, repo, ref string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.BaseURL, owner, repo, ref)
+	resp, err := c.getAs(ctx, url, "application/vnd.github.sha")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if e := authError(resp); e != nil {
+		return "", e
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to get commit %s of %s/%s (%s)", ref, owner, repo, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	sha := strings.TrimSpace(string(body))
+	if strings.HasPrefix(sha, "{") {
+		// Servers that ignore the media type answer with the commit.
+		var commit struct {
+			SHA string `json:"sha"`
+		}
+		if err := json.Unmarshal(body, &commit); err != nil {
+			return "", err
+		}
+		sha = commit.SHA
+	}
+	return sha, nil
+}
+
+// SubmitDependencies posts a dependency graph snapshot, the JSON body of
+// the dependency submission API. The token needs write access to contents.
+func (c *Client) SubmitDependencies(ctx context.Context, owner, repo string, snapshot []byte) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/dependency-graph/snapshots", c.BaseURL, owner, repo)
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(snapshot))
+	req.Header.Set("Authorization", "token "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIVersion != "" {
+		req.Header.Set("X-GitHub-Api-Version", c.APIVersion)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if e := authError(resp); e != nil {
+		return e
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to submit dependencies of %s/%s: %s (%s)", owner, repo, resp.Status, redact.Snippet(resp.Body))
+	}
+	return nil
+}
+
+// ErrNotFound is returned when a requested file does not exist at the ref.
+var ErrNotFound = errors.New("not found")
+
+// ErrTooLarge is returned by OpenFile for files over its size limit.
+var ErrTooLarge = errors.New("file too large")
+
+// File returns the contents of path at the given ref, or on the default
+// branch when ref is empty. It asks for the raw media type, and falls back to
+// the JSON shape for servers that ignore it, fetching the blob when the file
+// is too large to be inlined.
+func (c *Client) File(ctx context.Context, owner, repo, ref, path string) (string, error) {
+	body, err := c.OpenFile(ctx, owner, repo, ref, path, 0)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+// OpenFile is File for reading the file as it arrives, so only what the
+// caller keeps of it is held in memory. When max is set, larger files fail
+// with ErrTooLarge, without being read past the limit. A file the server
+// only returns in the JSON shape is read whole before OpenFile returns.
+func (c *Client) OpenFile(ctx context.Context, owner, repo, ref, path string, max int64) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
+	if ref != "" {
+		url += "?ref=" + ref
+	}
+	resp, err := c.getAs(ctx, url, rawMediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	tooLarge := func(size string) error {
+		return fmt.Errorf("failed to fetch %s from %s/%s: %s: %w", path, owner, repo, size, ErrTooLarge)
+	}
+	switch auth := authError(resp); {
+	case resp.StatusCode == http.StatusNotFound:
+		err = fmt.Errorf("failed to fetch %s from %s/%s: %w", path, owner, repo, ErrNotFound)
+	case auth != nil:
+		err = auth
+	case resp.StatusCode != 200:
+		err = fmt.Errorf("failed to fetch %s from %s/%s (%s)", path, owner, repo, resp.Status)
+	case max > 0 && resp.ContentLength > max && !isJSON(resp.Header.Get("Content-Type")):
+		// The JSON shape is larger than the file; its size is checked
+		// once decoded.
+		err = tooLarge(fmt.Sprintf("%d bytes", resp.ContentLength))
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	var body io.ReadCloser
+	if !isJSON(resp.Header.Get("Content-Type")) {
+		body = resp.Body
+		if max > 0 {
+			body = &limitedBody{ReadCloser: resp.Body, max: max, err: tooLarge(fmt.Sprintf("over %d bytes", max))}
+		}
+	} else {
+		content, err := c.jsonContent(ctx, owner, repo, path, resp, max)
+		resp.Body.Close()
+		if errors.Is(err, ErrTooLarge) {
+			return nil, tooLarge(fmt.Sprintf("over %d bytes", max))
+		} else if err != nil {
+			return nil, err
+		}
+		body = io.NopCloser(strings.NewReader(content))
+	}
+	if c.OnFetch != nil {
+		c.OnFetch(path)
+	}
+	return body, nil
+}
+
+// limitedBody fails with err once more than max bytes were read.
+type limitedBody struct {
+	io.ReadCloser
+	max, read int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.max {
+		return 0, b.err
+	}
+	if rest := b.max + 1 - b.read; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.read += int64(n); b.read > b.max {
+		return n, b.err
+	}
+	return n, err
+}
+
+// jsonContent decodes a contents response in the JSON shape, of at most
+// max bytes when max is set.
+func (c *Client) jsonContent(ctx context.Context, owner, repo, path string, resp *http.Response, max int64) (string, error) {
+	var file FileContent
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return "", err
+	}
+	if max > 0 && file.Size > max {
+		return "", ErrTooLarge
+	}
+	switch {
+	case file.Encoding == "base64":
+		data, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case file.Content == "" && file.SHA != "" && file.Size > 0:
+		return c.blob(ctx, owner, repo, file.SHA, max)
+	case file.Content != "" || file.Size > 0:
+		return "", fmt.Errorf("failed to fetch %s from %s/%s: unsupported encoding %q", path, owner, repo, file.Encoding)
+	}
+	return "", nil
+}
+
+// Blob returns a file by its blob SHA, for files over the contents API limit
+// or listed by Tree.
+func (c *Client) Blob(ctx context.Context, owner, repo, sha string) (string, error) {
+	return c.blob(ctx, owner, repo, sha, 0)
+}
+
+// blob is Blob for blobs of at most max bytes when max is set.
+func (c *Client) blob(ctx context.Context, owner, repo, sha string, max int64) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/blobs/%s", c.BaseURL, owner, repo, sha)
+	resp, err := c.getAs(ctx, url, rawMediaType)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to fetch blob %s from %s/%s (%s)", sha, owner, repo, resp.Status)
+	}
+	if !isJSON(resp.Header.Get("Content-Type")) {
+		data, err := readUpTo(resp.Body, max)
+		return string(data), err
+	}
+	var b FileContent
+	if err := json.NewDecoder(resp.Body).Decode(&b); err != nil {
+		return "", err
+	}
+	if max > 0 && b.Size > max {
+		return "", ErrTooLarge
+	}
+	if b.Encoding != "base64" {
+		return "", fmt.Errorf("failed to fetch blob %s from %s/%s: unsupported encoding %q", sha, owner, repo, b.Encoding)
+	}
+	data, err := base64.StdEncoding.DecodeString(b.Content)
+	return string(data), err
+}
+
+// readUpTo reads r to the end, failing with ErrTooLarge past max bytes
+// when max is set.
+func readUpTo(r io.Reader, max int64) ([]byte, error) {
+	if max <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err == nil && int64(len(data)) > max {
+		return nil, ErrTooLarge
+	}
+	return data, err
+}
+
+// isJSON reports whether a response is the JSON shape rather than the raw
+// file. Raw responses are application/vnd.github.raw or text/plain.
+func isJSON(contentType string) bool {
+	return strings.Contains(contentType, "json") && !strings.Contains(contentType, rawMediaType)
+}
+
+// Dir returns the names of the entries of the directory at path, or of
+// the repo root when path is empty.
+func (c *Client) Dir(ctx context.Context, owner, repo, ref, path string) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents", c.BaseURL, owner, repo)
+	if path != "" {
+		url += "/" + path
+	}
+	if ref != "" {
+		url += "?ref=" + ref
+	}
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to list %s in %s/%s: %w", path, owner, repo, ErrNotFound)
+	}
+	if e := authError(resp); e != nil {
+		return nil, e
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to list %s in %s/%s (%s)", path, owner, repo, resp.Status)
+	}
+	var entries []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to list %s in %s/%s: %w", path, owner, repo, err)
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name
+	}
+	return names, nil
+}
+
+// Tree returns every entry of the repo at ref, recursively. truncated is set
+// when the tree is too large for one response and entries are missing.
+func (c *Client) Tree(ctx context.Context, owner, repo, ref string) (entries []TreeEntry, truncated bool, err error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1", c.BaseURL, owner, repo, ref)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, fmt.Errorf("failed to list the tree of %s/%s: %w", owner, repo, ErrNotFound)
+	}
+	if e := authError(resp); e != nil {
+		return nil, false, e
+	}
+	if resp.StatusCode != 200 {
+		return nil, false, fmt.Errorf("failed to list the tree of %s/%s (%s)", owner, repo, resp.Status)
+	}
+	var tree struct {
+		Tree      []TreeEntry `json:"tree"`
+		Truncated bool        `json:"truncated"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tree); err != nil {
+		return nil, false, fmt.Errorf("failed to list the tree of %s/%s: %w", owner, repo, err)
+	}
+	return tree.Tree, tree.Truncated, nil
+}
+
+// Exists reports whether a file or directory exists at path on the given
+// ref.
+func (c *Client) Exists(ctx context.Context, owner, repo, ref, path string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
+	if ref != "" {
+		url += "?ref=" + ref
+	}
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if e := authError(resp); e != nil {
+		return false, e
+	}
+	if resp.StatusCode != 200 {
+		return false, fmt.Errorf("failed to look up %s in %s/%s (%s)", path, owner, repo, resp.Status)
+	}
+	return true, nil
+}
+
+// Submodule returns the commit the submodule at path is pinned to at ref.
+func (c *Client) Submodule(ctx context.Context, owner, repo, ref, path string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.BaseURL, owner, repo, path)
+	if ref != "" {
+		url += "?ref=" + ref
+	}
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("failed to look up submodule %s in %s/%s: %w", path, owner, repo, ErrNotFound)
+	}
+	if e := authError(resp); e != nil {
+		return "", e
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to look up submodule %s in %s/%s (%s)", path, owner, repo, resp.Status)
+	}
+	var entry struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil || entry.Type != "submodule" {
+		return "", fmt.Errorf("%s in %s/%s is not a submodule", path, owner, repo)
+	}
+	return entry.SHA, nil
+}
+
+// Pubspec returns the decoded contents of pubspec.yaml at the given branch.
+func (c *Client) Pubspec(ctx context.Context, owner, repo, branch string) (string, error) {
+	return c.File(ctx, owner, repo, branch, "pubspec.yaml")
+}
+
+// AnalysisOptions returns the analyzer configuration at the repo root.
+func (c *Client) AnalysisOptions(ctx context.Context, owner, repo, branch string) (string, error) {
+	return c.File(ctx, owner, repo, branch, "analysis_options.yaml")
+}
+
+// FlutterPin returns the first of the given pin files present in the repo
+// and its content.
+func (c *Client) FlutterPin(ctx context.Context, owner, repo, branch string, files []string) (string, string, error) {
+	for _, path := range files {
+		content, err := c.File(ctx, owner, repo, branch, path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return path, content, err
+	}
+	return "", "", ErrNotFound
+}
+
+// CodeOwners returns the first CODEOWNERS file found in the locations GitHub
+// supports, or ErrNotFound.
+func (c *Client) CodeOwners(ctx context.Context, owner, repo, branch string) (string, error) {
+	for _, path := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		content, err := c.File(ctx, owner, repo, branch, path)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return content, err
+	}
+	return "", ErrNotFound
+}
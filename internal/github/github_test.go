@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLatestBranchErrorOmitsBody(t *testing.T) {
+	const token = "ghp_abcdefghijklmnopqrstuvwx"
+	tests := []struct {
+		name   string
+		body   string
+		want   string
+		absent string
+	}{
+		{"message", `{"message": "Server Error"}`, `500 Internal Server Error ({"message": "Server Error"})`, ""},
+		{"credential", `{"message": "echo", "token": "` + token + `"}`, "[REDACTED]", token},
+		{"long body", strings.Repeat("<html>", 1000), "…", strings.Repeat("<html>", 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			c := NewClient(srv.Client(), "")
+			c.BaseURL = srv.URL
+
+			_, err := c.LatestBranch(context.Background(), "acme", "app")
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+			if tt.absent != "" && strings.Contains(err.Error(), tt.absent) {
+				t.Errorf("error %q contains %q", err, tt.absent)
+			}
+		})
+	}
+}
+
+func TestOpenFileLimit(t *testing.T) {
+	content := strings.Repeat("a", 100)
+	raw := func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first sends the file without a Content-Length.
+		w.Write([]byte(content[:10]))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(content[10:]))
+	}
+	sized := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}
+	inlined := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FileContent{Content: base64.StdEncoding.EncodeToString([]byte(content)), Encoding: "base64", Size: int64(len(content))})
+	}
+	missing := func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		max     int64
+		wantErr error
+	}{
+		{"raw unlimited", raw, 0, nil},
+		{"raw at limit", raw, 100, nil},
+		{"raw over limit", raw, 99, ErrTooLarge},
+		{"content length over limit", sized, 50, ErrTooLarge},
+		{"inlined at limit", inlined, 100, nil},
+		{"inlined over limit", inlined, 99, ErrTooLarge},
+		{"missing", missing, 100, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			c := NewClient(srv.Client(), "")
+			c.BaseURL = srv.URL
+
+			var got []byte
+			body, err := c.OpenFile(context.Background(), "acme", "app", "main", "pubspec.lock", tt.max)
+			if err == nil {
+				got, err = io.ReadAll(body)
+				body.Close()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != content {
+				t.Errorf("read %d bytes, want %d", len(got), len(content))
+			}
+		})
+	}
+}
+
+func TestLocalCommitSHA(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"not a git repo", nil, ""},
+		{"detached", map[string]string{"HEAD": sha + "\n"}, sha},
+		{"loose ref", map[string]string{"HEAD": "ref: refs/heads/main\n", "refs/heads/main": sha + "\n"}, sha},
+		{"packed ref", map[string]string{"HEAD": "ref: refs/heads/main\n", "packed-refs": "# pack-refs\n" + sha + " refs/heads/main\n"}, sha},
+		{"unborn branch", map[string]string{"HEAD": "ref: refs/heads/main\n"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "acme", "app")
+			if err := os.MkdirAll(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.files {
+				path := filepath.Join(root, ".git", filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := NewClient(&http.Client{Transport: LocalTransport{Dir: dir}}, "")
+			got, err := c.CommitSHA(context.Background(), "acme", "app", "main")
+			if err != nil || got != tt.want {
+				t.Errorf("CommitSHA = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package github
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Structures ---
+
+// LocalTransport answers the repository requests of Client from checkouts
+// under Dir, one per repo at Dir/owner/repo, so repos can be scanned
+// without the API. The working tree is read as it is: every ref names the
+// checked-out commit. Repos without a checkout are not found.
+type LocalTransport struct {
+	Dir string
+}
+
+// --- Core logic ---
+
+func (t LocalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	_, rest, ok := strings.Cut(req.URL.Path, "/repos/")
+	parts := strings.SplitN(rest, "/", 3)
+	if !ok || len(parts) < 2 || req.Method != "GET" {
+		return respond(req, http.StatusNotImplemented, "text/plain", []byte("not available from local checkouts"))
+	}
+	root := filepath.Join(t.Dir, parts[0], parts[1])
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return respond(req, http.StatusNotFound, "text/plain", nil)
+	}
+	var endpoint string
+	if len(parts) == 3 {
+		endpoint = parts[2]
+	}
+
+	switch {
+	case endpoint == "":
+		return respondJSON(req, Repository{FullName: parts[0] + "/" + parts[1]})
+	case endpoint == "branches":
+		var b Branch
+		b.Name = headBranch(root)
+		if info, err := os.Stat(root); err == nil {
+			b.Commit.Commit.Author.Date = info.ModTime()
+		}
+		return respondJSON(req, []Branch{b})
+	case strings.HasPrefix(endpoint, "commits/"):
+		// A checkout without a HEAD has no commit; an empty SHA lets the
+		// scan read its files at the branch without a warning.
+		sha := headSHA(root)
+		if strings.Contains(req.Header.Get("Accept"), "sha") {
+			return respond(req, http.StatusOK, "text/plain", []byte(sha))
+		}
+		return respondJSON(req, map[string]string{"sha": sha})
+	case endpoint == "contents" || strings.HasPrefix(endpoint, "contents/"):
+		return t.contents(req, root, strings.TrimPrefix(strings.TrimPrefix(endpoint, "contents"), "/"))
+	case strings.HasPrefix(endpoint, "git/trees/"):
+		return tree(req, root)
+	case strings.HasPrefix(endpoint, "git/blobs/"):
+		// Tree hands out the hex-encoded path as the blob SHA.
+		p, err := hex.DecodeString(strings.TrimPrefix(endpoint, "git/blobs/"))
+		if err != nil {
+			return respond(req, http.StatusNotFound, "text/plain", nil)
+		}
+		return t.contents(req, root, string(p))
+	}
+	return respond(req, http.StatusNotImplemented, "text/plain", []byte("not available from local checkouts"))
+}
+
+// contents serves a file raw or a directory as its entry list.
+func (t LocalTransport) contents(req *http.Request, root, p string) (*http.Response, error) {
+	full, ok := inside(root, p)
+	if !ok {
+		return respond(req, http.StatusNotFound, "text/plain", nil)
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return respond(req, http.StatusNotFound, "text/plain", nil)
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, err
+		}
+		return respond(req, http.StatusOK, rawMediaType, data)
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	list := []entry{}
+	for _, e := range entries {
+		if e.Name() == ".git" {
+			continue
+		}
+		typ := "file"
+		if e.IsDir() {
+			typ = "dir"
+		}
+		list = append(list, entry{Name: e.Name(), Type: typ})
+	}
+	return respondJSON(req, list)
+}
+
+func tree(req *http.Request, root string) (*http.Response, error) {
+	entries := []TreeEntry{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case rel == ".":
+			return nil
+		case d.IsDir() && d.Name() == ".git":
+			return filepath.SkipDir
+		case d.IsDir():
+			entries = append(entries, TreeEntry{Path: rel, Type: "tree"})
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			entries = append(entries, TreeEntry{Path: rel, Type: "blob", SHA: hex.EncodeToString([]byte(rel)), Size: info.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return respondJSON(req, map[string]interface{}{"tree": entries, "truncated": false})
+}
+
+// inside resolves p within root, refusing paths that leave it.
+func inside(root, p string) (string, bool) {
+	clean := path.Clean("/" + p)
+	if clean == "/.git" || strings.HasPrefix(clean, "/.git/") {
+		return "", false
+	}
+	return filepath.Join(root, filepath.FromSlash(clean)), true
+}
+
+// headBranch returns the checked-out branch, or HEAD when it is detached
+// or the checkout is not a git repo.
+func headBranch(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, ".git", "HEAD"))
+	if ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/"); err == nil && ok {
+		return ref
+	}
+	return "HEAD"
+}
+
+// headSHA resolves HEAD through loose and packed refs, or returns "".
+func headSHA(root string) string {
+	gitDir := filepath.Join(root, ".git")
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		return head
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	packed, err := os.ReadFile(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(packed), "\n") {
+		if sha, name, ok := strings.Cut(strings.TrimSpace(line), " "); ok && name == ref {
+			return sha
+		}
+	}
+	return ""
+}
+
+func respondJSON(req *http.Request, v interface{}) (*http.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return respond(req, http.StatusOK, "application/json", data)
+}
+
+func respond(req *http.Request, status int, contentType string, body []byte) (*http.Response, error) {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package gitmodules
+
+import (
+	"path"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/repolist"
+)
+
+// File is where git records the submodules of a repo.
+const File = ".gitmodules"
+
+// Submodule is one [submodule "name"] section of a .gitmodules file.
+type Submodule struct {
+	Name string
+	Path string
+	URL  string
+}
+
+// Parse reads the submodules of a .gitmodules file, in file order.
+// Sections without a path or url are left out.
+func Parse(content string) []Submodule {
+	var out []Submodule
+	var cur *Submodule
+	flush := func() {
+		if cur != nil && cur.Path != "" && cur.URL != "" {
+			out = append(out, *cur)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			flush()
+			if name, ok := strings.CutPrefix(strings.TrimSuffix(line, "]"), "[submodule"); ok {
+				cur = &Submodule{Name: strings.Trim(strings.TrimSpace(name), `"`)}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || cur == nil {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "path":
+			cur.Path = strings.Trim(path.Clean(value), "/")
+		case "url":
+			cur.URL = value
+		}
+	}
+	flush()
+	return out
+}
+
+// Repo returns the owner/repo of a submodule URL of the GitHub repo parent.
+// Relative URLs such as ../other.git resolve against the parent. It
+// returns false for repos hosted elsewhere than github.com.
+func Repo(parent, url string) (string, bool) {
+	var name string
+	switch {
+	case strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../"):
+		name = repolist.Normalize(path.Join(parent, url))
+	case strings.Contains(strings.ToLower(url), "github.com"):
+		name = repolist.Normalize(url)
+	default:
+		return "", false
+	}
+	if strings.Count(name, "/") != 1 || strings.ContainsAny(name, ":@") || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,183 @@
+package hosted
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// DefaultServers are pub.dev under its current and former name. They are
+// trusted whatever the allowlist says.
+var DefaultServers = []string{"pub.dev", "pub.dartlang.org"}
+
+// --- Structures ---
+
+// Server is a package server dependencies are fetched from with a hosted:
+// block, and the repos doing so.
+type Server struct {
+	URL      string   `json:"url"`
+	Packages []string `json:"packages"`
+	Count    int      `json:"count"`
+	Repos    []string `json:"repos,omitempty"`
+}
+
+// Use is a dependency fetched from a server that is not on the allowlist.
+// Name is the package's name on the server when it differs.
+type Use struct {
+	Repo    string `json:"repo"`
+	Package string `json:"package"`
+	Name    string `json:"name,omitempty"`
+	URL     string `json:"url"`
+}
+
+// Report lists the package servers by number of dependents. With an
+// allowlist, the dependencies on other servers are Untrusted.
+type Report struct {
+	Servers        []Server `json:"servers"`
+	Allowlist      []string `json:"allowlist,omitempty"`
+	Untrusted      []Use    `json:"untrusted,omitempty"`
+	UntrustedCount int      `json:"untrusted_count,omitempty"`
+}
+
+type server struct {
+	packages map[string]bool
+	count    int
+	repos    map[string]bool
+}
+
+// Tracker aggregates the hosted: blocks of repo results by server. With
+// CountOnly set it keeps counts but no repo names.
+type Tracker struct {
+	mu             sync.Mutex
+	CountOnly      bool
+	allowlist      []string
+	servers        map[string]*server
+	untrusted      []Use
+	untrustedCount int
+}
+
+// NewTracker returns a tracker checking servers against allowlist, a list
+// of hosts or URLs; with none given nothing is flagged.
+func NewTracker(allowlist []string) *Tracker {
+	t := &Tracker{servers: map[string]*server{}}
+	for _, a := range allowlist {
+		if a = Normalize(a); a != "" {
+			t.allowlist = append(t.allowlist, a)
+		}
+	}
+	sort.Strings(t.allowlist)
+	return t
+}
+
+// --- Core logic ---
+
+// Normalize reduces a server URL to its lowercased host and path, e.g.
+// pub.acme.dev/api, so http and https forms and trailing slashes match.
+func Normalize(url string) string {
+	u := strings.TrimSpace(url)
+	if _, rest, ok := strings.Cut(u, "://"); ok {
+		u = rest
+	}
+	host, path, _ := strings.Cut(u, "/")
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		// Credentials in the URL are not part of the server.
+		host = host[at+1:]
+	}
+	host = strings.ToLower(host)
+	if path = strings.Trim(path, "/"); path == "" {
+		return host
+	}
+	return host + "/" + path
+}
+
+// Trusted reports whether a normalized server URL is pub.dev or on the
+// normalized allowlist. An allowlisted host trusts every path on it, an
+// allowlisted URL the paths below it.
+func Trusted(url string, allowlist []string) bool {
+	for _, list := range [][]string{DefaultServers, allowlist} {
+		for _, a := range list {
+			if url == a || strings.HasPrefix(url, a+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || len(r.Hosted) == 0 {
+		return
+	}
+	names := make([]string, 0, len(r.Hosted))
+	for name := range r.Hosted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range names {
+		h := r.Hosted[name]
+		url := Normalize(h.URL)
+		s := t.servers[url]
+		if s == nil {
+			s = &server{packages: map[string]bool{}, repos: map[string]bool{}}
+			t.servers[url] = s
+		}
+		s.packages[name] = true
+		s.count++
+		if !t.CountOnly {
+			s.repos[r.Repo] = true
+		}
+		if len(t.allowlist) == 0 || Trusted(url, t.allowlist) {
+			continue
+		}
+		t.untrustedCount++
+		if !t.CountOnly {
+			u := Use{Repo: r.Repo, Package: name, URL: url}
+			if h.Name != name {
+				u.Name = h.Name
+			}
+			t.untrusted = append(t.untrusted, u)
+		}
+	}
+}
+
+// Report returns the servers, or nil when no repo declares a hosted:
+// block.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.servers) == 0 {
+		return nil
+	}
+	rep := &Report{Allowlist: t.allowlist, Untrusted: append([]Use(nil), t.untrusted...), UntrustedCount: t.untrustedCount}
+	for url, s := range t.servers {
+		srv := Server{URL: url, Count: s.count}
+		for p := range s.packages {
+			srv.Packages = append(srv.Packages, p)
+		}
+		sort.Strings(srv.Packages)
+		for r := range s.repos {
+			srv.Repos = append(srv.Repos, r)
+		}
+		sort.Strings(srv.Repos)
+		rep.Servers = append(rep.Servers, srv)
+	}
+	sort.Slice(rep.Servers, func(i, j int) bool {
+		if rep.Servers[i].Count != rep.Servers[j].Count {
+			return rep.Servers[i].Count > rep.Servers[j].Count
+		}
+		return rep.Servers[i].URL < rep.Servers[j].URL
+	})
+	sort.Slice(rep.Untrusted, func(i, j int) bool {
+		a, b := rep.Untrusted[i], rep.Untrusted[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Package < b.Package
+	})
+	return rep
+}
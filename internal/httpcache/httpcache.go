@@ -0,0 +1,210 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --- Structures ---
+
+// Cache stores API responses on disk, one file per request, so repeated
+// runs within a TTL reuse them instead of asking the API again. Only
+// successful and not-found responses are stored; requests with
+// credentials are never cached.
+type Cache struct {
+	Dir string
+
+	// Refresh ignores stored responses; fresh ones are still stored.
+	Refresh bool
+
+	// Offline answers only from the cache, whatever the age of the
+	// responses, and fails requests it has no response for.
+	Offline bool
+
+	mu           sync.Mutex
+	hits, misses int
+}
+
+type entry struct {
+	URL      string      `json:"url"`
+	StoredAt time.Time   `json:"stored_at"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+type transport struct {
+	c    *Cache
+	base http.RoundTripper
+	ttl  time.Duration
+}
+
+// ErrMiss is returned in offline mode for requests without a cached
+// response.
+var ErrMiss = errors.New("no cached response")
+
+// --- Core logic ---
+
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// Wrap returns a transport that answers from the cache with responses
+// younger than ttl and sends everything else through base.
+func (c *Cache) Wrap(base http.RoundTripper, ttl time.Duration) http.RoundTripper {
+	return &transport{c: c, base: base, ttl: ttl}
+}
+
+// Stats returns how many requests were answered from the cache and how
+// many were sent.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *Cache) count(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// key identifies a request by method, URL, Accept header and body, so the
+// POST queries of OSV are cached per query.
+func key(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n"+req.Header.Get("Accept")+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(k string) string {
+	return filepath.Join(c.Dir, k[:2], k+".json")
+}
+
+func (c *Cache) load(k string, ttl time.Duration, now time.Time) (*entry, bool) {
+	data, err := os.ReadFile(c.path(k))
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false
+	}
+	if now.Sub(e.StoredAt) > ttl {
+		return nil, false
+	}
+	return &e, true
+}
+
+// store writes e through a temporary file, so concurrent runs never read
+// a partial entry. Failures only cost a cache miss next time.
+func (c *Cache) store(k string, e entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	p := c.path(k)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (e *entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || (req.Method != "GET" && req.Method != "POST") {
+		if t.c.Offline {
+			return nil, fmt.Errorf("offline: %s %s cannot be cached", req.Method, req.URL.Redacted())
+		}
+		return t.base.RoundTrip(req)
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	k := key(req, body)
+	ttl := t.ttl
+	if t.c.Offline {
+		ttl = time.Duration(math.MaxInt64)
+	}
+	if !t.c.Refresh {
+		if e, ok := t.c.load(k, ttl, time.Now()); ok {
+			t.c.count(true)
+			return e.response(req), nil
+		}
+	}
+	t.c.count(false)
+	if t.c.Offline {
+		return nil, fmt.Errorf("offline: %s %s: %w", req.Method, req.URL.Redacted(), ErrMiss)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound) {
+		return resp, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		header.Set("Content-Type", ct)
+	}
+	t.c.store(k, entry{URL: req.URL.String(), StoredAt: time.Now().UTC(), Status: resp.StatusCode, Header: header, Body: data})
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
@@ -0,0 +1,141 @@
+package httpcache
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTTL(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		age  time.Duration
+		ttl  time.Duration
+		want bool
+	}{
+		{"fresh", time.Minute, time.Hour, true},
+		{"at the TTL", time.Hour, time.Hour, true},
+		{"expired", time.Hour + time.Second, time.Hour, false},
+		{"zero TTL", time.Second, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			k := strings.Repeat("ab", 32)
+			c.store(k, entry{StoredAt: now.Add(-tt.age), Status: 200, Body: []byte("ok")})
+			if _, ok := c.load(k, tt.ttl, now); ok != tt.want {
+				t.Errorf("load = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		refresh   bool
+		offline   bool
+		age       time.Duration
+		method    string
+		auth      bool
+		status    int
+		wantCalls int
+		wantHits  int
+		wantErr   error
+	}{
+		{name: "fresh hit", age: time.Minute, method: "GET", status: 200, wantCalls: 1, wantHits: 1},
+		{name: "expired", age: 2 * time.Hour, method: "GET", status: 200, wantCalls: 2},
+		{name: "post cached per body", age: time.Minute, method: "POST", status: 200, wantCalls: 1, wantHits: 1},
+		{name: "not found cached", age: time.Minute, method: "GET", status: 404, wantCalls: 1, wantHits: 1},
+		{name: "server error not cached", age: time.Minute, method: "GET", status: 500, wantCalls: 2},
+		{name: "credentials not cached", age: time.Minute, method: "GET", auth: true, status: 200, wantCalls: 2},
+		{name: "refresh", refresh: true, age: time.Minute, method: "GET", status: 200, wantCalls: 2},
+		{name: "offline ignores TTL", offline: true, age: 24 * time.Hour, method: "GET", status: 200, wantCalls: 1, wantHits: 1},
+		{name: "offline miss", offline: true, age: time.Minute, method: "GET", status: 500, wantErr: ErrMiss},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				io.Copy(w, r.Body)
+			}))
+			defer srv.Close()
+
+			dir := t.TempDir()
+			send := func(c *Cache) (*http.Response, error) {
+				req, _ := http.NewRequest(tt.method, srv.URL+"/pkg", nil)
+				if tt.method == "POST" {
+					req, _ = http.NewRequest(tt.method, srv.URL+"/query", strings.NewReader(`{"package": "http"}`))
+				}
+				if tt.auth {
+					req.Header.Set("Authorization", "Bearer token")
+				}
+				return c.Wrap(http.DefaultTransport, time.Hour).RoundTrip(req)
+			}
+
+			// A first run fills the cache, then its entries are aged.
+			if tt.wantErr == nil {
+				warm := &Cache{Dir: dir}
+				resp, err := send(warm)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				age(t, warm, tt.age)
+			}
+
+			c := &Cache{Dir: dir, Refresh: tt.refresh, Offline: tt.offline}
+			resp, err := send(c)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.method == "POST" && string(body) != `{"package": "http"}` {
+				t.Errorf("body %q", body)
+			}
+			if hits, _ := c.Stats(); calls != tt.wantCalls || hits != tt.wantHits {
+				t.Errorf("%d calls and %d hits, want %d and %d", calls, hits, tt.wantCalls, tt.wantHits)
+			}
+		})
+	}
+}
+
+// age moves the stored time of every entry in c back by d.
+func age(t *testing.T, c *Cache, d time.Duration) {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		k := strings.TrimSuffix(filepath.Base(p), ".json")
+		e, ok := c.load(k, time.Hour, time.Now())
+		if !ok {
+			t.Fatalf("entry %s not readable", k)
+		}
+		e.StoredAt = e.StoredAt.Add(-d)
+		c.store(k, *e)
+	}
+}
@@ -0,0 +1,259 @@
+package imports
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// SourceDirs are the directories, relative to the pubspec, whose Dart files
+// ship with the package and so must only import its dependencies.
+var SourceDirs = []string{"lib", "bin"}
+
+// MaxFiles bounds the Dart files read per repo.
+const MaxFiles = 2000
+
+// NotImported are packages that are used without being imported, e.g.
+// for the assets they bundle, and are never reported as unused. SDK
+// dependencies are not reported either.
+var NotImported = map[string]bool{
+	"cupertino_icons": true,
+}
+
+// Usage levels of a declared dependency in the sources of a repo.
+const (
+	LevelUnused = "unused"
+	LevelBarely = "barely_used"
+	LevelUsed   = "used"
+	LevelCore   = "core"
+)
+
+// A dependency imported at most BarelyImports times is barely used; one
+// imported at least CoreShare times per scanned file is a core dependency.
+const (
+	BarelyImports = 2
+	CoreShare     = 0.2
+)
+
+// --- Structures ---
+
+// Repo is a repo whose imports do not match its dependencies:
+//   - Unused: dependencies no source file imports
+//   - Undeclared: imported packages the pubspec does not declare
+//   - TransitiveOnly: the undeclared ones that only resolve because the
+//     lockfile pulls them in through other dependencies
+//   - DevOnly: imported packages declared only as dev dependencies
+type Repo struct {
+	Repo           string   `json:"repo"`
+	Files          int      `json:"files"`
+	Unused         []string `json:"unused,omitempty"`
+	Undeclared     []string `json:"undeclared,omitempty"`
+	TransitiveOnly []string `json:"transitive_only,omitempty"`
+	DevOnly        []string `json:"dev_only,omitempty"`
+}
+
+// Heat is how intensely the fleet uses a dependency: the repos declaring
+// it, the imports across them and the repos per usage level.
+type Heat struct {
+	Package string         `json:"package"`
+	Repos   int            `json:"repos"`
+	Imports int            `json:"imports"`
+	Levels  map[string]int `json:"levels"`
+}
+
+// RepoUsage is the use of one dependency in one repo.
+type RepoUsage struct {
+	Repo    string  `json:"repo"`
+	Package string  `json:"package"`
+	Imports int     `json:"imports"`
+	Share   float64 `json:"share"`
+}
+
+// Report is the import hygiene of the repos whose sources were scanned.
+// Unused counts, per package, the repos declaring it without importing it.
+// Heatmap rates every declared dependency from unused to core; BarelyUsed
+// lists the repos that could drop a dependency with little effort.
+type Report struct {
+	Scanned    int                 `json:"scanned"`
+	Repos      []Repo              `json:"repos,omitempty"`
+	Unused     []stats.PackageStat `json:"unused,omitempty"`
+	Heatmap    []Heat              `json:"heatmap,omitempty"`
+	BarelyUsed []RepoUsage         `json:"barely_used,omitempty"`
+}
+
+// Tracker collects the imports recorded in repo results.
+type Tracker struct {
+	mu      sync.Mutex
+	scanned int
+	repos   []Repo
+	unused  map[string]int
+	heat    map[string]*Heat
+	barely  []RepoUsage
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{unused: map[string]int{}, heat: map[string]*Heat{}}
+}
+
+var (
+	directive  = regexp.MustCompile(`(?m)^\s*(?:import|export)\s[^;]*;`)
+	packageURI = regexp.MustCompile(`['"]package:([a-zA-Z_][a-zA-Z0-9_]*)/`)
+)
+
+// --- Core logic ---
+
+// IsSource reports whether file, a path relative to the repo root, is a Dart
+// file in one of the SourceDirs of the package in dir.
+func IsSource(dir, file string) bool {
+	if !strings.HasSuffix(file, ".dart") {
+		return false
+	}
+	for _, d := range SourceDirs {
+		if strings.HasPrefix(file, path.Join(dir, d)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse counts the package imports and exports of a Dart file by package.
+// Configurable imports count every package they may load.
+func Parse(content string) map[string]int {
+	out := map[string]int{}
+	for _, stmt := range directive.FindAllString(content, -1) {
+		for _, m := range packageURI.FindAllStringSubmatch(stmt, -1) {
+			out[m[1]]++
+		}
+	}
+	return out
+}
+
+// Level rates a dependency imported n times in a repo with files scanned
+// Dart files, and returns the imports per file.
+func Level(n, files int) (string, float64) {
+	if n == 0 {
+		return LevelUnused, 0
+	}
+	share := 0.0
+	if files > 0 {
+		share = float64(n) / float64(files)
+	}
+	switch {
+	case n <= BarelyImports:
+		return LevelBarely, share
+	case share >= CoreShare:
+		return LevelCore, share
+	}
+	return LevelUsed, share
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || r.Imports == nil {
+		return
+	}
+	rep := Repo{Repo: r.Repo, Files: r.ImportFiles}
+	main := map[string]bool{}
+	for _, name := range r.Dependencies {
+		main[name] = true
+		if r.Imports[name] == 0 && !NotImported[name] && !strings.HasPrefix(r.Constraints[name], "sdk:") {
+			rep.Unused = append(rep.Unused, name)
+		}
+	}
+	dev := map[string]bool{}
+	for _, name := range r.DevDependencies {
+		dev[name] = true
+	}
+	for name := range r.Imports {
+		switch {
+		case name == r.Package || main[name]:
+		case dev[name]:
+			rep.DevOnly = append(rep.DevOnly, name)
+		default:
+			rep.Undeclared = append(rep.Undeclared, name)
+			if _, ok := r.Locked[name]; ok {
+				rep.TransitiveOnly = append(rep.TransitiveOnly, name)
+			}
+		}
+	}
+	for _, list := range [][]string{rep.Unused, rep.Undeclared, rep.TransitiveOnly, rep.DevOnly} {
+		sort.Strings(list)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scanned++
+	for _, name := range rep.Unused {
+		t.unused[name]++
+	}
+	for _, name := range r.Dependencies {
+		if NotImported[name] || strings.HasPrefix(r.Constraints[name], "sdk:") {
+			continue
+		}
+		h := t.heat[name]
+		if h == nil {
+			h = &Heat{Package: name, Levels: map[string]int{}}
+			t.heat[name] = h
+		}
+		n := r.Imports[name]
+		level, share := Level(n, r.ImportFiles)
+		h.Repos++
+		h.Imports += n
+		h.Levels[level]++
+		if level == LevelBarely {
+			t.barely = append(t.barely, RepoUsage{Repo: r.Repo, Package: name, Imports: n, Share: share})
+		}
+	}
+	if len(rep.Unused)+len(rep.Undeclared)+len(rep.DevOnly) > 0 {
+		t.repos = append(t.repos, rep)
+	}
+}
+
+// Report returns the mismatches, or nil when no sources were scanned.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.scanned == 0 {
+		return nil
+	}
+	rep := &Report{Scanned: t.scanned, Repos: append([]Repo(nil), t.repos...)}
+	sort.Slice(rep.Repos, func(i, j int) bool { return rep.Repos[i].Repo < rep.Repos[j].Repo })
+	for name, n := range t.unused {
+		p := purl.For("", name)
+		rep.Unused = append(rep.Unused, stats.PackageStat{Name: name, PURL: p, Count: n, URL: purl.URL(p)})
+	}
+	sort.Slice(rep.Unused, func(i, j int) bool {
+		if rep.Unused[i].Count != rep.Unused[j].Count {
+			return rep.Unused[i].Count > rep.Unused[j].Count
+		}
+		return rep.Unused[i].Name < rep.Unused[j].Name
+	})
+
+	for _, h := range t.heat {
+		c := *h
+		c.Levels = map[string]int{}
+		for k, v := range h.Levels {
+			c.Levels[k] = v
+		}
+		rep.Heatmap = append(rep.Heatmap, c)
+	}
+	sort.Slice(rep.Heatmap, func(i, j int) bool {
+		if rep.Heatmap[i].Repos != rep.Heatmap[j].Repos {
+			return rep.Heatmap[i].Repos > rep.Heatmap[j].Repos
+		}
+		return rep.Heatmap[i].Package < rep.Heatmap[j].Package
+	})
+	rep.BarelyUsed = append(rep.BarelyUsed, t.barely...)
+	sort.Slice(rep.BarelyUsed, func(i, j int) bool {
+		a, b := rep.BarelyUsed[i], rep.BarelyUsed[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Repo < b.Repo
+	})
+	return rep
+}
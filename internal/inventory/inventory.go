@@ -0,0 +1,186 @@
+package inventory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatTFVars = "tfvars"
+)
+
+// Fields are the inventory columns, in output order.
+var Fields = []string{"repo", "package", "purl", "version", "constraint", "source", "section", "category", "license", "owner"}
+
+// --- Structures ---
+
+// Mapping renames inventory fields to the names the asset system expects.
+// Fields missing from a non-empty mapping are left out.
+type Mapping map[string]string
+
+// Writer streams inventory rows as a JSON array, or as the "inventory"
+// variable of a Terraform .tfvars.json file.
+type Writer struct {
+	// CategoryOf fills the category field, e.g. from the stack taxonomy.
+	CategoryOf func(pkg string) string
+
+	w       *bufio.Writer
+	mapping Mapping
+	format  string
+	rows    int
+}
+
+// --- Core logic ---
+
+// ParseMapping reads "field=name" pairs separated by commas, e.g.
+// "repo=ci_name,package=component".
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+	known := map[string]bool{}
+	for _, f := range Fields {
+		known[f] = true
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || v == "" {
+			return nil, fmt.Errorf("invalid field mapping %q", pair)
+		}
+		if !known[k] {
+			return nil, fmt.Errorf("unknown inventory field %q (known: %s)", k, strings.Join(Fields, ", "))
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func NewWriter(w io.Writer, mapping Mapping, format string) (*Writer, error) {
+	iw := &Writer{w: bufio.NewWriter(w), mapping: mapping, format: format}
+	switch format {
+	case FormatJSON:
+		iw.w.WriteString("[")
+	case FormatTFVars:
+		iw.w.WriteString(`{"inventory": [`)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q", format)
+	}
+	return iw, nil
+}
+
+// WriteRepo writes one row per declared dependency of r. licenses maps
+// package URLs to their licenses.
+func (iw *Writer) WriteRepo(r stats.RepoResult, licenses map[string][]string) error {
+	if r.Error != "" {
+		return nil
+	}
+	add := func(section string, names []string) error {
+		for _, name := range names {
+			c := r.Constraints[name]
+			row := map[string]string{
+				"repo":       r.Repo,
+				"package":    name,
+				"purl":       purl.For(r.Ecosystem, name),
+				"constraint": c,
+				"source":     Source(c),
+				"section":    section,
+				"category":   iw.category(name),
+				"license":    strings.Join(licenses[purl.For(r.Ecosystem, name)], " OR "),
+				"owner":      strings.Join(r.Owners, " "),
+			}
+			if v, ok := risk.LowerBound(c); ok {
+				row["version"] = v
+			}
+			if err := iw.write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add("dependencies", r.Dependencies); err != nil {
+		return err
+	}
+	if err := add("dev_dependencies", r.DevDependencies); err != nil {
+		return err
+	}
+	return add("dependency_overrides", r.DependencyOverrides)
+}
+
+func (iw *Writer) category(pkg string) string {
+	if iw.CategoryOf == nil {
+		return ""
+	}
+	return iw.CategoryOf(pkg)
+}
+
+func (iw *Writer) write(row map[string]string) error {
+	out := map[string]string{}
+	for _, f := range Fields {
+		name := f
+		if len(iw.mapping) > 0 {
+			var ok bool
+			if name, ok = iw.mapping[f]; !ok {
+				continue
+			}
+		}
+		out[name] = row[f]
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	if iw.rows > 0 {
+		iw.w.WriteString(",")
+	}
+	iw.w.WriteString("\n  ")
+	iw.w.Write(data)
+	iw.rows++
+	return nil
+}
+
+// Close finishes the document and flushes it. It returns the row count.
+func (iw *Writer) Close() (int, error) {
+	iw.w.WriteString("\n]")
+	if iw.format == FormatTFVars {
+		iw.w.WriteString("}")
+	}
+	iw.w.WriteString("\n")
+	return iw.rows, iw.w.Flush()
+}
+
+// Source classifies a declared constraint: sdk, path, git or hosted.
+func Source(c string) string {
+	switch {
+	case strings.HasPrefix(c, "sdk:"):
+		return "sdk"
+	case strings.HasPrefix(c, "path:"):
+		return "path"
+	case strings.HasPrefix(c, "git:"):
+		return "git"
+	default:
+		return "hosted"
+	}
+}
+
+// Packages returns the hosted package names of r, for license lookups.
+func Packages(r stats.RepoResult) []string {
+	var out []string
+	for name, c := range r.Constraints {
+		if Source(c) == "hosted" {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,190 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+)
+
+// Label is attached to every ticket pubscan manages.
+const Label = "pubscan"
+
+// --- Structures ---
+
+// Client manages one ticket per repo in a Jira project through the v2 REST
+// API. With User set it authenticates with basic auth (Jira Cloud: email and
+// API token), otherwise with the token as a bearer PAT (Server/Data Center).
+type Client struct {
+	HTTP      *http.Client
+	BaseURL   string
+	User      string
+	Token     string
+	Project   string
+	IssueType string
+}
+
+type issue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Labels      []string `json:"labels"`
+		Description string   `json:"description"`
+	} `json:"fields"`
+}
+
+// Result counts what a sync did.
+type Result struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Resolved  int
+}
+
+// --- Core logic ---
+
+// RepoLabel is the stable label identifying a repo's ticket across runs.
+func RepoLabel(repo string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(repo)))
+	return Label + "-" + hex.EncodeToString(sum[:6])
+}
+
+// Sync opens a ticket for every repo in byRepo, updates tickets whose
+// findings changed, and comments on open tickets of clean repos.
+func (c *Client) Sync(ctx context.Context, byRepo map[string][]findings.Finding, clean []string) (Result, error) {
+	var res Result
+	open, err := c.openIssues(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	for repo, list := range byRepo {
+		summary := fmt.Sprintf("[pubscan] %d dependency policy violation(s) in %s", len(list), repo)
+		desc := describe(repo, list)
+		label := RepoLabel(repo)
+
+		existing, ok := open[label]
+		switch {
+		case !ok:
+			fields := map[string]interface{}{
+				"project":     map[string]string{"key": c.Project},
+				"issuetype":   map[string]string{"name": c.IssueType},
+				"summary":     summary,
+				"description": desc,
+				"labels":      []string{Label, label},
+			}
+			if err := c.do(ctx, "POST", "/rest/api/2/issue", map[string]interface{}{"fields": fields}, nil); err != nil {
+				return res, fmt.Errorf("create ticket for %s: %w", repo, err)
+			}
+			res.Created++
+		case existing.Fields.Description == desc:
+			res.Unchanged++
+		default:
+			fields := map[string]interface{}{"summary": summary, "description": desc}
+			if err := c.do(ctx, "PUT", "/rest/api/2/issue/"+existing.Key, map[string]interface{}{"fields": fields}, nil); err != nil {
+				return res, fmt.Errorf("update %s: %w", existing.Key, err)
+			}
+			res.Updated++
+		}
+	}
+
+	for _, repo := range clean {
+		existing, ok := open[RepoLabel(repo)]
+		if !ok {
+			continue
+		}
+		body := map[string]string{"body": "pubscan found no outstanding policy violations in " + repo + " anymore."}
+		if err := c.do(ctx, "POST", "/rest/api/2/issue/"+existing.Key+"/comment", body, nil); err != nil {
+			return res, fmt.Errorf("comment on %s: %w", existing.Key, err)
+		}
+		res.Resolved++
+	}
+	return res, nil
+}
+
+// openIssues returns the unresolved pubscan tickets keyed by repo label.
+func (c *Client) openIssues(ctx context.Context) (map[string]issue, error) {
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" AND statusCategory != Done`, c.Project, Label)
+	out := map[string]issue{}
+	for start := 0; ; {
+		var page struct {
+			Issues []issue `json:"issues"`
+			Total  int     `json:"total"`
+		}
+		q := url.Values{"jql": {jql}, "fields": {"labels,description"}, "startAt": {fmt.Sprint(start)}, "maxResults": {"100"}}
+		if err := c.do(ctx, "GET", "/rest/api/2/search?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("search tickets: %w", err)
+		}
+		for _, is := range page.Issues {
+			for _, l := range is.Fields.Labels {
+				if strings.HasPrefix(l, Label+"-") {
+					out[l] = is
+				}
+			}
+		}
+		start += len(page.Issues)
+		if len(page.Issues) == 0 || start >= page.Total {
+			return out, nil
+		}
+	}
+}
+
+// describe renders the findings as a Jira wiki markup table.
+func describe(repo string, list []findings.Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pubscan found the following dependency policy violations in *%s*.\n\n", repo)
+	b.WriteString("||Rule||Severity||Package||Details||Remediation||\n")
+	for _, f := range list {
+		fmt.Fprintf(&b, "|%s|%s|%s|%s|%s|\n", f.Rule, f.Severity, orDash(f.Package), escapeCell(f.Message), orDash(escapeCell(f.Remediation)))
+	}
+	b.WriteString("\nThis ticket is kept up to date by pubscan; do not remove its labels.")
+	return b.String()
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, _ := json.Marshal(in)
+		body = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s (%s)", method, path, resp.Status, string(msg))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
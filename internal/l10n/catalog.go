@@ -0,0 +1,95 @@
+package l10n
+
+// catalogs holds the user-facing strings of the terminal summary and the
+// generated reports, by language. Plural messages have one key per form
+// (one and other; one, few and many for Russian).
+var catalogs = map[string]map[string]string{
+	"en": {
+		"summary.title":             "Summary",
+		"summary.repos":             "Repositories: %s",
+		"summary.scanned":           "%d scanned",
+		"summary.failed":            "%d failed",
+		"summary.not_dart":          "%d not Dart",
+		"summary.more":              "... and %d more",
+		"summary.top":               "Top packages:",
+		"summary.technologies":      "Technologies (repos): %s",
+		"summary.repo_count.one":    "%d repo",
+		"summary.repo_count.other":  "%d repos",
+		"summary.findings":          "Findings: %s",
+		"summary.gates":             "Quality gates: %s",
+		"summary.violations.one":    "%d violation",
+		"summary.violations.other":  "%d violations",
+		"summary.severity_count":    "%d %s",
+		"severity.critical":         "critical",
+		"severity.high":             "high",
+		"severity.medium":           "medium",
+		"severity.low":              "low",
+		"severity.info":             "info",
+		"docs.title":                "Dependencies",
+		"docs.generated":            "Generated by pubscan from `%s` (branch `%s`) on %s.",
+		"docs.package":              "Package",
+		"docs.constraint":           "Constraint",
+		"docs.dependencies":         "Dependencies",
+		"docs.dev_dependencies":     "Dev dependencies",
+		"docs.dependency_overrides": "Dependency overrides",
+	},
+	"ru": {
+		"summary.title":             "Итоги",
+		"summary.repos":             "Репозитории: %s",
+		"summary.scanned":           "просканировано: %d",
+		"summary.failed":            "с ошибками: %d",
+		"summary.not_dart":          "не Dart: %d",
+		"summary.more":              "... и ещё %d",
+		"summary.top":               "Самые используемые пакеты:",
+		"summary.technologies":      "Технологии (репозитории): %s",
+		"summary.repo_count.one":    "%d репозиторий",
+		"summary.repo_count.few":    "%d репозитория",
+		"summary.repo_count.many":   "%d репозиториев",
+		"summary.findings":          "Замечания: %s",
+		"summary.gates":             "Пороговые проверки: %s",
+		"summary.violations.one":    "%d нарушение",
+		"summary.violations.few":    "%d нарушения",
+		"summary.violations.many":   "%d нарушений",
+		"summary.severity_count":    "%[2]s: %[1]d",
+		"severity.critical":         "критичные",
+		"severity.high":             "высокие",
+		"severity.medium":           "средние",
+		"severity.low":              "низкие",
+		"severity.info":             "информационные",
+		"docs.title":                "Зависимости",
+		"docs.generated":            "Сформировано pubscan по `%s` (ветка `%s`) %s.",
+		"docs.package":              "Пакет",
+		"docs.constraint":           "Ограничение версии",
+		"docs.dependencies":         "Зависимости",
+		"docs.dev_dependencies":     "Зависимости для разработки",
+		"docs.dependency_overrides": "Переопределения зависимостей",
+	},
+	"de": {
+		"summary.title":             "Zusammenfassung",
+		"summary.repos":             "Repositories: %s",
+		"summary.scanned":           "%d gescannt",
+		"summary.failed":            "%d fehlgeschlagen",
+		"summary.not_dart":          "%d kein Dart",
+		"summary.more":              "... und %d weitere",
+		"summary.top":               "Meistgenutzte Pakete:",
+		"summary.technologies":      "Technologien (Repositories): %s",
+		"summary.repo_count.one":    "%d Repository",
+		"summary.repo_count.other":  "%d Repositories",
+		"summary.findings":          "Befunde: %s",
+		"summary.gates":             "Qualitätsschwellen: %s",
+		"summary.violations.one":    "%d Verstoß",
+		"summary.violations.other":  "%d Verstöße",
+		"severity.critical":         "kritisch",
+		"severity.high":             "hoch",
+		"severity.medium":           "mittel",
+		"severity.low":              "niedrig",
+		"severity.info":             "info",
+		"docs.title":                "Abhängigkeiten",
+		"docs.generated":            "Erstellt von pubscan aus `%s` (Branch `%s`) am %s.",
+		"docs.package":              "Paket",
+		"docs.constraint":           "Versionsbedingung",
+		"docs.dependencies":         "Abhängigkeiten",
+		"docs.dev_dependencies":     "Entwicklungsabhängigkeiten",
+		"docs.dependency_overrides": "Überschriebene Abhängigkeiten",
+	},
+}
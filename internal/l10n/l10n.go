@@ -0,0 +1,98 @@
+package l10n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default is the language used when none is given, and the fallback for
+// messages a catalog lacks.
+const Default = "en"
+
+// --- Structures ---
+
+// Printer formats messages in one language.
+type Printer struct {
+	lang string
+	msgs map[string]string
+}
+
+// --- Core logic ---
+
+// Languages returns the supported language codes.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// New returns the printer for lang, e.g. "ru" or "de-AT"; regions fall back
+// to the language.
+func New(lang string) (*Printer, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		lang = Default
+	}
+	if base, _, ok := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-"); ok {
+		lang = base
+	}
+	msgs, ok := catalogs[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return &Printer{lang: lang, msgs: msgs}, nil
+}
+
+// Lang returns the language code of p.
+func (p *Printer) Lang() string {
+	if p == nil {
+		return Default
+	}
+	return p.lang
+}
+
+// T formats the message key with args. A nil printer prints English.
+func (p *Printer) T(key string, args ...interface{}) string {
+	format, ok := "", false
+	if p != nil {
+		format, ok = p.msgs[key]
+	}
+	if !ok {
+		if format, ok = catalogs[Default][key]; !ok {
+			format = key
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// N formats the plural form of key for count n, e.g. "%d repos". The count
+// is the first argument of the message.
+func (p *Printer) N(key string, n int) string {
+	return p.T(key+"."+pluralForm(p.Lang(), n), n)
+}
+
+// pluralForm returns the CLDR plural category of n: one or other, and few
+// or many for Russian.
+func pluralForm(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	if lang == "ru" {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	}
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
@@ -0,0 +1,126 @@
+package lints
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+const (
+	// None marks repos without an analysis_options.yaml.
+	None = "(none)"
+	// Custom marks rules defined in the repo itself, either inline or in a
+	// local included file.
+	Custom = "(custom)"
+)
+
+// --- Structures ---
+
+type analysisOptions struct {
+	Include interface{} `yaml:"include"`
+}
+
+// Set is a lint rule set and the repos that use it.
+type Set struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Repos []string `json:"repos,omitempty"`
+}
+
+// Tracker counts the lint sets recorded in repo results. With CountOnly set
+// it keeps counts but no repo names.
+type Tracker struct {
+	mu        sync.Mutex
+	CountOnly bool
+	sets      map[string]*Set
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{sets: map[string]*Set{}}
+}
+
+// --- Core logic ---
+
+// Parse returns the lint sets an analysis_options.yaml builds on: the
+// package of each package: include, e.g. flutter_lints or
+// very_good_analysis, and Custom for local includes or inline-only rules.
+func Parse(content string) ([]string, error) {
+	var ao analysisOptions
+	if err := yaml.Unmarshal([]byte(content), &ao); err != nil {
+		return nil, err
+	}
+	var includes []string
+	switch inc := ao.Include.(type) {
+	case nil:
+	case string:
+		includes = []string{inc}
+	case []interface{}:
+		for _, v := range inc {
+			includes = append(includes, fmt.Sprint(v))
+		}
+	default:
+		return nil, fmt.Errorf("unexpected include %v", inc)
+	}
+
+	seen := map[string]bool{}
+	var sets []string
+	for _, inc := range includes {
+		name := Custom
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(inc), "package:"); ok {
+			name, _, _ = strings.Cut(rest, "/")
+		}
+		if !seen[name] {
+			seen[name] = true
+			sets = append(sets, name)
+		}
+	}
+	if len(sets) == 0 {
+		sets = []string{Custom}
+	}
+	sort.Strings(sets)
+	return sets, nil
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, name := range r.LintSets {
+		s := t.sets[name]
+		if s == nil {
+			s = &Set{Name: name}
+			t.sets[name] = s
+		}
+		s.Count++
+		if !t.CountOnly {
+			s.Repos = append(s.Repos, r.Repo)
+		}
+	}
+}
+
+// Sets returns the lint sets, most used first.
+func (t *Tracker) Sets() []Set {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []Set
+	for _, s := range t.sets {
+		set := *s
+		set.Repos = append([]string(nil), s.Repos...)
+		sort.Strings(set.Repos)
+		out = append(out, set)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
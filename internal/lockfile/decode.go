@@ -0,0 +1,190 @@
+package lockfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default limits of a scan; big monorepos lock a few thousand packages in a
+// few MB.
+const (
+	DefaultMaxBytes    = 32 << 20
+	DefaultMaxPackages = 20000
+)
+
+// ErrTooLarge is returned for lockfiles over a limit.
+var ErrTooLarge = errors.New("lockfile too large")
+
+// --- Structures ---
+
+// Limits bound what decoding a lockfile holds in memory. Zero fields are
+// unlimited.
+type Limits struct {
+	MaxBytes    int64
+	MaxPackages int
+}
+
+// limitedReader fails with ErrTooLarge once more than max bytes were read.
+type limitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+// packageDocs converts the packages: section of a lockfile being read.
+type packageDocs struct {
+	r      *bufio.Reader
+	buf    []byte
+	indent int
+	done   bool
+	err    error
+}
+
+// --- Core logic ---
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, fmt.Errorf("%w: over %d bytes", ErrTooLarge, l.max)
+	}
+	return n, err
+}
+
+// Parse returns the packages of a pubspec.lock by name.
+func Parse(content string) (map[string]Package, error) {
+	return Decode(strings.NewReader(content), Limits{})
+}
+
+// Decode reads the packages of a pubspec.lock from r within limits. Rather
+// than building the YAML tree of the whole file, it streams the packages:
+// section through one decoder as a document per package, so memory holds
+// the packages decoded so far and the tree of one of them. Sections after
+// packages: are not read. A packages: section not in the block layout pub
+// writes is decoded whole.
+func Decode(r io.Reader, limits Limits) (map[string]Package, error) {
+	var lr *limitedReader
+	if limits.MaxBytes > 0 {
+		lr = &limitedReader{r: r, max: limits.MaxBytes}
+		r = lr
+	}
+	// The decoder reports read errors as text, and reads ahead of what it
+	// decoded; the limit is checked here.
+	tooLarge := func(err error) error {
+		if lr != nil && lr.read > lr.max {
+			return fmt.Errorf("%w: over %d bytes", ErrTooLarge, lr.max)
+		}
+		return err
+	}
+	br := bufio.NewReader(r)
+	var head strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		head.WriteString(line)
+		key, value, _ := strings.Cut(strings.TrimRight(line, " \r\n"), ":")
+		if key == "packages" {
+			if value = strings.TrimSpace(value); value != "" && !strings.HasPrefix(value, "#") {
+				// Flow style or an anchor: not pub's layout.
+				pkgs, err := decodeWhole(io.MultiReader(strings.NewReader(head.String()), br), limits)
+				if err = tooLarge(err); err != nil {
+					return nil, err
+				}
+				return pkgs, nil
+			}
+			break
+		}
+		if err == io.EOF {
+			return map[string]Package{}, tooLarge(nil)
+		}
+	}
+
+	dec := yaml.NewDecoder(&packageDocs{r: br, indent: -1})
+	pkgs := map[string]Package{}
+	for {
+		var entry map[string]Package
+		if err := dec.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, tooLarge(err)
+		}
+		for name, p := range entry {
+			if _, dup := pkgs[name]; dup {
+				return nil, fmt.Errorf("package %q is locked twice", name)
+			}
+			if limits.MaxPackages > 0 && len(pkgs) >= limits.MaxPackages {
+				return nil, fmt.Errorf("%w: over %d packages", ErrTooLarge, limits.MaxPackages)
+			}
+			pkgs[name] = p
+		}
+	}
+	if err := tooLarge(nil); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// Read returns the packages: section as YAML documents, one per package and
+// dedented to the top level. It ends at the next top-level key.
+func (p *packageDocs) Read(b []byte) (int, error) {
+	for len(p.buf) == 0 {
+		if p.done {
+			if p.err != nil {
+				return 0, p.err
+			}
+			return 0, io.EOF
+		}
+		p.next()
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// next converts the next line. Blank lines and comments are dropped.
+func (p *packageDocs) next() {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		if err != io.EOF {
+			p.err = err
+		}
+		p.done = true
+	}
+	trimmed := strings.TrimSpace(line)
+	depth := len(line) - len(strings.TrimLeft(line, " "))
+	switch {
+	case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		return
+	case depth == 0:
+		p.done = true
+		return
+	case p.indent < 0 || depth == p.indent:
+		p.indent = depth
+		p.buf = append(p.buf, "---\n"...)
+	case depth < p.indent:
+		p.err = fmt.Errorf("unexpected indentation in packages: %q", trimmed)
+		p.done = true
+		return
+	}
+	p.buf = append(p.buf, strings.TrimRight(line[p.indent:], "\r\n")...)
+	p.buf = append(p.buf, '\n')
+}
+
+// decodeWhole decodes a lockfile as one YAML document.
+func decodeWhole(r io.Reader, limits Limits) (map[string]Package, error) {
+	var lf lockfile
+	if err := yaml.NewDecoder(r).Decode(&lf); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if limits.MaxPackages > 0 && len(lf.Packages) > limits.MaxPackages {
+		return nil, fmt.Errorf("%w: over %d packages", ErrTooLarge, limits.MaxPackages)
+	}
+	return lf.Packages, nil
+}
@@ -0,0 +1,149 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pubLock = `# Generated by pub
+# See https://dart.dev/tools/pub/glossary#lockfile
+packages:
+  async:
+    dependency: transitive
+    description:
+      name: async
+      sha256: "947bfcf187f74dbc5e146c9eb9c0f10c9f8b30743e341481c1e2ed3ecc18c20c"
+      url: "https://pub.dev"
+    source: hosted
+    version: "2.11.0"
+
+  flutter:
+    dependency: "direct main"
+    description: flutter
+    source: sdk
+    version: "0.0.0"
+  widgets:
+    dependency: "direct main"
+    description:
+      path: "ref: main"
+      ref: main
+      resolved-ref: "0123456789abcdef0123456789abcdef01234567"
+      url: "https://github.com/acme/widgets.git"
+    source: git
+    version: "1.2.0"
+  local_pkg:
+    dependency: "direct overridden"
+    description:
+      path: "../local_pkg"
+      relative: true
+    source: path
+    version: "0.1.0"
+sdks:
+  dart: ">=3.0.0 <4.0.0"
+  flutter: ">=3.10.0"
+`
+
+var pubLockPackages = map[string]Package{
+	"async": {Version: "2.11.0", Source: "hosted", Dependency: "transitive", Description: Description{
+		Name: "async", SHA256: "947bfcf187f74dbc5e146c9eb9c0f10c9f8b30743e341481c1e2ed3ecc18c20c", URL: "https://pub.dev"}},
+	"flutter":   {Version: "0.0.0", Source: "sdk", Dependency: "direct main"},
+	"widgets":   {Version: "1.2.0", Source: "git", Dependency: "direct main", Description: Description{URL: "https://github.com/acme/widgets.git"}},
+	"local_pkg": {Version: "0.1.0", Source: "path", Dependency: "direct overridden"},
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]Package
+	}{
+		{"pub layout", pubLock, pubLockPackages},
+		{"crlf", strings.ReplaceAll(pubLock, "\n", "\r\n"), pubLockPackages},
+		{"no trailing newline", strings.TrimSuffix(pubLock, "\n"), pubLockPackages},
+		{"packages last", "sdks:\n  dart: \">=3.0.0\"\npackages:\n  meta:\n    source: hosted\n    version: \"1.9.1\"\n",
+			map[string]Package{"meta": {Version: "1.9.1", Source: "hosted"}}},
+		{"four space indent", "packages:\n    meta:\n        source: hosted\n        version: \"1.9.1\"\n",
+			map[string]Package{"meta": {Version: "1.9.1", Source: "hosted"}}},
+		{"flow style", "packages: {meta: {source: hosted, version: \"1.9.1\"}}\n",
+			map[string]Package{"meta": {Version: "1.9.1", Source: "hosted"}}},
+		{"empty packages", "packages: {}\nsdks:\n  dart: \">=3.0.0\"\n", map[string]Package{}},
+		{"no packages", "sdks:\n  dart: \">=3.0.0\"\n", map[string]Package{}},
+		{"empty file", "", map[string]Package{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.content), Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMatchesWhole(t *testing.T) {
+	whole, err := decodeWhole(strings.NewReader(pubLock), Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := Parse(pubLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(streamed, whole) {
+		t.Errorf("streamed %+v\nwhole %+v", streamed, whole)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	many := func(n int) string {
+		var b strings.Builder
+		b.WriteString("packages:\n")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "  pkg_%d:\n    source: hosted\n    version: \"1.0.%d\"\n", i, i)
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name     string
+		content  string
+		limits   Limits
+		tooLarge bool
+		wantErr  string
+	}{
+		{name: "within limits", content: many(10), limits: Limits{MaxBytes: int64(len(many(10))), MaxPackages: 10}},
+		{name: "over bytes", content: many(100), limits: Limits{MaxBytes: 1024}, tooLarge: true},
+		{name: "over bytes after packages", content: many(1) + "sdks:\n  dart: \">=3.0.0 <4.0.0\"\n", limits: Limits{MaxBytes: int64(len(many(1)))}, tooLarge: true},
+		{name: "over bytes in header", content: strings.Repeat("# comment\n", 200) + many(1), limits: Limits{MaxBytes: 1024}, tooLarge: true},
+		{name: "over packages", content: many(11), limits: Limits{MaxPackages: 10}, tooLarge: true},
+		{name: "flow style over packages", content: "packages: {a: {version: \"1\"}, b: {version: \"2\"}}\n", limits: Limits{MaxPackages: 1}, tooLarge: true},
+		{name: "locked twice", content: "packages:\n  meta:\n    version: \"1\"\n  meta:\n    version: \"2\"\n", wantErr: "locked twice"},
+		{name: "bad indentation", content: "packages:\n    meta:\n      version: \"1\"\n  other:\n    version: \"2\"\n", wantErr: "unexpected indentation"},
+		{name: "not yaml", content: "packages:\n  meta: [\n", wantErr: "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.content), tt.limits)
+			switch {
+			case tt.tooLarge:
+				if !errors.Is(err, ErrTooLarge) {
+					t.Errorf("error %v, want ErrTooLarge", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package lockfile
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PubDevURLs are the URLs lockfiles record for packages hosted on pub.dev.
+var PubDevURLs = []string{"https://pub.dev", "https://pub.dartlang.org"}
+
+// --- Structures ---
+
+// HashMismatch is a locked pub.dev package whose recorded archive hash is
+// not the one pub.dev advertises for its version. Published is empty when
+// pub.dev does not list the version at all.
+type HashMismatch struct {
+	Repo      string `json:"repo"`
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	Locked    string `json:"locked_sha256"`
+	Published string `json:"published_sha256,omitempty"`
+}
+
+// Integrity is the result of checking the locked archive hashes of the
+// fleet against pub.dev. Checked counts the locked packages compared.
+type Integrity struct {
+	Checked    int            `json:"checked"`
+	Mismatches []HashMismatch `json:"mismatches,omitempty"`
+}
+
+// --- Core logic ---
+
+func (d *Description) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	type plain Description
+	return n.Decode((*plain)(d))
+}
+
+// PubDevHashes returns the archive hashes a lockfile records for packages
+// hosted on pub.dev, keyed by name. Lockfiles written before Dart 3 record
+// none.
+func PubDevHashes(pkgs map[string]Package) map[string]string {
+	var out map[string]string
+	for name, p := range pkgs {
+		if p.Source != "hosted" || p.Description.SHA256 == "" || !isPubDev(p.Description.URL) {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[name] = strings.ToLower(p.Description.SHA256)
+	}
+	return out
+}
+
+func isPubDev(url string) bool {
+	url = strings.TrimSuffix(url, "/")
+	for _, u := range PubDevURLs {
+		if url == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders the mismatches by repo and package.
+func (in *Integrity) Sort() {
+	sort.Slice(in.Mismatches, func(i, j int) bool {
+		a, b := in.Mismatches[i], in.Mismatches[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		return a.Package < b.Package
+	})
+}
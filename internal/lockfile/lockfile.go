@@ -0,0 +1,208 @@
+package lockfile
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// File is the name of the lockfile pub writes next to the pubspec.
+const File = "pubspec.lock"
+
+// StaleShare is the share of a repo's locked hosted direct dependencies that
+// must be behind for the lockfile to be reported as stale.
+const StaleShare = 0.5
+
+// --- Structures ---
+
+// Package is one resolved package of a lockfile. Dependency is how the
+// pubspec reaches it: "direct main", "direct dev", "direct overridden" or
+// "transitive".
+type Package struct {
+	Version     string      `yaml:"version"`
+	Source      string      `yaml:"source"`
+	Dependency  string      `yaml:"dependency"`
+	Description Description `yaml:"description"`
+}
+
+// Description is where a locked package comes from. Hosted packages name
+// their server and, since Dart 3, the sha256 of their archive. The plain
+// string description of sdk packages is left empty.
+type Description struct {
+	Name   string `yaml:"name"`
+	SHA256 string `yaml:"sha256"`
+	URL    string `yaml:"url"`
+}
+
+type lockfile struct {
+	Packages map[string]Package `yaml:"packages"`
+}
+
+// Violation is a locked version the pubspec constraint no longer allows.
+type Violation struct {
+	Package    string `json:"package"`
+	Constraint string `json:"constraint"`
+	Locked     string `json:"locked"`
+}
+
+// Behind is a locked dependency whose constraint allows a newer minor or
+// major release than the one locked.
+type Behind struct {
+	Package string `json:"package"`
+	Locked  string `json:"locked"`
+	Latest  string `json:"latest"`
+}
+
+// Repo is a repo whose lockfile drifted from its pubspec. Stale is set when
+// at least StaleShare of its hosted direct dependencies are behind.
+type Repo struct {
+	Repo       string      `json:"repo"`
+	Violations []Violation `json:"violations,omitempty"`
+	Behind     []Behind    `json:"behind,omitempty"`
+	Stale      bool        `json:"stale,omitempty"`
+}
+
+// Report is the lockfile drift of the fleet. Locked counts the repos with a
+// lockfile, Missing those without one.
+type Report struct {
+	Locked  int    `json:"locked"`
+	Missing int    `json:"missing"`
+	Stale   int    `json:"stale"`
+	Repos   []Repo `json:"repos,omitempty"`
+}
+
+// Tracker collects the violations of repo results with a lockfile.
+type Tracker struct {
+	mu      sync.Mutex
+	locked  int
+	missing int
+	repos   map[string]*Repo
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{repos: map[string]*Repo{}}
+}
+
+// --- Core logic ---
+
+// Versions returns the versions of the packages, keyed by name.
+func Versions(pkgs map[string]Package) map[string]string {
+	out := make(map[string]string, len(pkgs))
+	for name, p := range pkgs {
+		out[name] = p.Version
+	}
+	return out
+}
+
+// Hosted returns the constraints of the hosted direct dependencies r locks.
+// Overridden dependencies are left out, as the override, not the
+// constraint, decides their version.
+func Hosted(r stats.RepoResult) map[string]string {
+	overridden := map[string]bool{}
+	for _, name := range r.DependencyOverrides {
+		overridden[name] = true
+	}
+	out := map[string]string{}
+	for name, c := range r.Constraints {
+		if _, ok := r.Locked[name]; ok && !overridden[name] && !strings.Contains(c, ":") {
+			out[name] = c
+		}
+	}
+	return out
+}
+
+// Violations returns the hosted dependencies whose locked version is
+// outside their constraint, sorted by package.
+func Violations(constraints, locked map[string]string) []Violation {
+	var out []Violation
+	for name, c := range constraints {
+		v := locked[name]
+		rng, err1 := semver.ParseConstraint(c)
+		ver, err2 := semver.ParseVersion(v)
+		if err1 == nil && err2 == nil && !rng.Allows(ver) {
+			out = append(out, Violation{Package: name, Constraint: c, Locked: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Package < out[j].Package })
+	return out
+}
+
+// IsBehind reports whether the constraint allows both locked and latest and
+// latest is a newer minor or major release than locked.
+func IsBehind(constraint, locked, latest string) bool {
+	rng, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	l, err1 := semver.ParseVersion(locked)
+	v, err2 := semver.ParseVersion(latest)
+	if err1 != nil || err2 != nil || !rng.Allows(v) || !rng.Allows(l) {
+		return false
+	}
+	return v.Major > l.Major || (v.Major == l.Major && v.Minor > l.Minor)
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || (r.Locked == nil && !r.LockMissing) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if r.LockMissing {
+		t.missing++
+		return
+	}
+	t.locked++
+	if v := Violations(Hosted(r), r.Locked); len(v) > 0 {
+		t.repos[r.Repo] = &Repo{Repo: r.Repo, Violations: v}
+	}
+}
+
+// Report returns the drift, or nil when no lockfile was looked for.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.locked == 0 && t.missing == 0 {
+		return nil
+	}
+	rep := &Report{Locked: t.locked, Missing: t.missing}
+	for _, r := range t.repos {
+		rep.Repos = append(rep.Repos, *r)
+	}
+	rep.sort()
+	return rep
+}
+
+// SetBehind records the dependencies each repo locks behind, given the
+// number of hosted direct dependencies it locks, and marks repos stale.
+func (rep *Report) SetBehind(behind map[string][]Behind, direct map[string]int) {
+	byRepo := map[string]int{}
+	for i, r := range rep.Repos {
+		byRepo[r.Repo] = i
+	}
+	for repo, list := range behind {
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Package < list[j].Package })
+		i, ok := byRepo[repo]
+		if !ok {
+			i = len(rep.Repos)
+			byRepo[repo] = i
+			rep.Repos = append(rep.Repos, Repo{Repo: repo})
+		}
+		rep.Repos[i].Behind = list
+		if n := direct[repo]; n > 0 && float64(len(list)) >= StaleShare*float64(n) {
+			rep.Repos[i].Stale = true
+			rep.Stale++
+		}
+	}
+	rep.sort()
+}
+
+func (rep *Report) sort() {
+	sort.Slice(rep.Repos, func(i, j int) bool { return rep.Repos[i].Repo < rep.Repos[j].Repo })
+}
@@ -0,0 +1,119 @@
+package lockfile
+
+import (
+	"reflect"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+func TestViolations(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints map[string]string
+		locked      map[string]string
+		want        []Violation
+	}{
+		{"allowed", map[string]string{"http": "^1.2.0"}, map[string]string{"http": "1.4.1"}, nil},
+		{"above caret", map[string]string{"http": "^1.2.0"}, map[string]string{"http": "2.0.0"},
+			[]Violation{{Package: "http", Constraint: "^1.2.0", Locked: "2.0.0"}}},
+		{"below range", map[string]string{"meta": ">=1.9.0 <2.0.0"}, map[string]string{"meta": "1.8.0"},
+			[]Violation{{Package: "meta", Constraint: ">=1.9.0 <2.0.0", Locked: "1.8.0"}}},
+		{"pre-1.0 caret", map[string]string{"dio": "^0.13.0"}, map[string]string{"dio": "0.14.0"},
+			[]Violation{{Package: "dio", Constraint: "^0.13.0", Locked: "0.14.0"}}},
+		{"any", map[string]string{"dio": "any"}, map[string]string{"dio": "5.0.0"}, nil},
+		{"unparsable skipped", map[string]string{"http": "^x", "dio": "^1.0.0"}, map[string]string{"http": "1.0.0", "dio": "1.0"}, nil},
+		{"sorted", map[string]string{"b": "1.0.0", "a": "1.0.0"}, map[string]string{"a": "1.0.1", "b": "1.0.1"},
+			[]Violation{{Package: "a", Constraint: "1.0.0", Locked: "1.0.1"}, {Package: "b", Constraint: "1.0.0", Locked: "1.0.1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Violations(tt.constraints, tt.locked); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBehind(t *testing.T) {
+	tests := []struct {
+		name                       string
+		constraint, locked, latest string
+		want                       bool
+	}{
+		{"newer minor", "^1.2.0", "1.2.3", "1.4.0", true},
+		{"newer patch only", "^1.2.0", "1.2.3", "1.2.9", false},
+		{"newer major allowed", ">=1.0.0", "1.2.0", "2.0.0", true},
+		{"newer major not allowed", "^1.2.0", "1.2.0", "2.0.0", false},
+		{"locked outside", "^1.2.0", "1.1.0", "1.4.0", false},
+		{"up to date", "^1.2.0", "1.4.0", "1.4.0", false},
+		{"unparsable", "^1.2.0", "main", "1.4.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBehind(tt.constraint, tt.locked, tt.latest); got != tt.want {
+				t.Errorf("IsBehind(%q, %q, %q) = %v, want %v", tt.constraint, tt.locked, tt.latest, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHosted(t *testing.T) {
+	r := stats.RepoResult{
+		Constraints: map[string]string{
+			"http":    "^1.2.0",
+			"meta":    "^1.9.0",
+			"widgets": "git:https://github.com/acme/widgets.git",
+			"dio":     "^5.0.0",
+		},
+		DependencyOverrides: []string{"meta"},
+		Locked:              map[string]string{"http": "1.2.0", "meta": "1.8.0", "widgets": "1.0.0"},
+	}
+	want := map[string]string{"http": "^1.2.0"}
+	if got := Hosted(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	if tr.Report() != nil {
+		t.Fatal("report without lockfiles")
+	}
+	for _, r := range []stats.RepoResult{
+		{Repo: "acme/web", Constraints: map[string]string{"http": "^1.2.0"}, Locked: map[string]string{"http": "2.0.0"}},
+		{Repo: "acme/app", Constraints: map[string]string{"http": "^1.2.0"}, Locked: map[string]string{"http": "1.2.0"}},
+		{Repo: "acme/cli", LockMissing: true},
+		{Repo: "acme/old", Error: "not found", LockMissing: true},
+		{Repo: "acme/unlocked"},
+	} {
+		tr.Add(r)
+	}
+	rep := tr.Report()
+	if rep.Locked != 2 || rep.Missing != 1 || len(rep.Repos) != 1 || rep.Repos[0].Repo != "acme/web" {
+		t.Fatalf("report %+v", rep)
+	}
+
+	tests := []struct {
+		name      string
+		behind    []Behind
+		direct    int
+		wantStale bool
+	}{
+		{"half behind", []Behind{{Package: "http"}}, 2, true},
+		{"under the share", []Behind{{Package: "http"}}, 3, false},
+		{"direct unknown", []Behind{{Package: "http"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := tr.Report()
+			rep.SetBehind(map[string][]Behind{"acme/app": tt.behind, "acme/cli": nil}, map[string]int{"acme/app": tt.direct})
+			if len(rep.Repos) != 2 || rep.Repos[0].Repo != "acme/app" || len(rep.Repos[0].Behind) != 1 {
+				t.Fatalf("repos %+v", rep.Repos)
+			}
+			if rep.Repos[0].Stale != tt.wantStale || (rep.Stale == 1) != tt.wantStale {
+				t.Errorf("stale %v, %d stale in all, want %v", rep.Repos[0].Stale, rep.Stale, tt.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package majors
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+type Line struct {
+	Major string   `json:"major"`
+	Count int      `json:"count"`
+	Repos []string `json:"repos,omitempty"`
+}
+
+// Split is a package whose declared constraints put the fleet on more than
+// one major version.
+type Split struct {
+	Name  string `json:"name"`
+	Lines []Line `json:"majors"`
+}
+
+// Tracker groups each package's declared constraints by the major version of
+// their lower bound. With CountOnly set it keeps counts but no repo names.
+type Tracker struct {
+	mu        sync.Mutex
+	CountOnly bool
+	lines     map[string]map[string]*Line
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{lines: map[string]map[string]*Line{}}
+}
+
+// --- Core logic ---
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, c := range r.Constraints {
+		if strings.Contains(c, ":") {
+			// sdk, path and git sources carry no version.
+			continue
+		}
+		rng, err := semver.ParseConstraint(c)
+		if err != nil {
+			continue
+		}
+		major := rng.MajorKey()
+		if major == "" {
+			continue
+		}
+
+		byMajor := t.lines[name]
+		if byMajor == nil {
+			byMajor = map[string]*Line{}
+			t.lines[name] = byMajor
+		}
+		l := byMajor[major]
+		if l == nil {
+			l = &Line{Major: major}
+			byMajor[major] = l
+		}
+		l.Count++
+		if !t.CountOnly {
+			l.Repos = append(l.Repos, r.Repo)
+		}
+	}
+}
+
+// Splits returns the packages used on more than one major version, most
+// widely used first.
+func (t *Tracker) Splits() []Split {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var splits []Split
+	for name, byMajor := range t.lines {
+		if len(byMajor) < 2 {
+			continue
+		}
+		s := Split{Name: name}
+		for _, l := range byMajor {
+			line := *l
+			line.Repos = append([]string(nil), l.Repos...)
+			sort.Strings(line.Repos)
+			s.Lines = append(s.Lines, line)
+		}
+		sort.Slice(s.Lines, func(i, j int) bool {
+			if s.Lines[i].Count != s.Lines[j].Count {
+				return s.Lines[i].Count > s.Lines[j].Count
+			}
+			return s.Lines[i].Major < s.Lines[j].Major
+		})
+		splits = append(splits, s)
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		if ri, rj := total(splits[i]), total(splits[j]); ri != rj {
+			return ri > rj
+		}
+		return splits[i].Name < splits[j].Name
+	})
+	return splits
+}
+
+func total(s Split) int {
+	n := 0
+	for _, l := range s.Lines {
+		n += l.Count
+	}
+	return n
+}
@@ -0,0 +1,124 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CargoParser reads Cargo.toml. Version requirements are converted to
+// pub's syntax, a bare "1.2" being a caret requirement in Cargo. Build
+// dependencies count as dev dependencies and target-specific ones with
+// their section; patched and replaced crates count as overrides, edition
+// and rust-version as the environment. publish = false marks the crate as
+// unpublished.
+type CargoParser struct{}
+
+func init() { Register(CargoParser{}) }
+
+func (CargoParser) Ecosystem() string { return "cargo" }
+func (CargoParser) Files() []string   { return []string{"Cargo.toml"} }
+func (CargoParser) PURLType() string  { return "cargo" }
+
+func (CargoParser) URL(name string) string {
+	return "https://crates.io/crates/" + name
+}
+
+func (CargoParser) Parse(_, content string) (Manifest, error) {
+	doc, err := parseTOML(content)
+	if err != nil {
+		return Manifest{}, err
+	}
+	m := Manifest{Dependencies: map[string]string{}, DevDependencies: map[string]string{}, Environment: map[string]string{}}
+	pkg := table(doc, "package")
+	m.Name, _ = pkg["name"].(string)
+	m.Version, _ = pkg["version"].(string)
+	switch p := pkg["publish"].(type) {
+	case bool:
+		if !p {
+			m.PublishTo = "none"
+		}
+	case []interface{}:
+		if len(p) == 0 {
+			m.PublishTo = "none"
+		} else {
+			m.PublishTo = fmt.Sprint(p[0])
+		}
+	}
+	for _, key := range []string{"edition", "rust-version"} {
+		if v, ok := pkg[key].(string); ok {
+			m.Environment[strings.TrimSuffix(key, "-version")] = v
+		}
+	}
+
+	sections := []map[string]interface{}{doc}
+	for _, target := range table(doc, "target") {
+		if t, ok := target.(map[string]interface{}); ok {
+			sections = append(sections, t)
+		}
+	}
+	for _, s := range sections {
+		cargoDeps(m.Dependencies, table(s, "dependencies"))
+		cargoDeps(m.DevDependencies, table(s, "dev-dependencies"))
+		cargoDeps(m.DevDependencies, table(s, "build-dependencies"))
+	}
+	for _, registry := range table(doc, "patch") {
+		if r, ok := registry.(map[string]interface{}); ok {
+			for name := range r {
+				m.Overrides = append(m.Overrides, name)
+			}
+		}
+	}
+	for spec := range table(doc, "replace") {
+		name, _, _ := strings.Cut(spec, ":")
+		m.Overrides = append(m.Overrides, name)
+	}
+	return m, nil
+}
+
+// cargoDeps adds the dependencies of a Cargo section to out, under the
+// crate names of renamed ones.
+func cargoDeps(out map[string]string, section map[string]interface{}) {
+	for name, v := range section {
+		switch d := v.(type) {
+		case string:
+			out[name] = requirement(d, "^")
+		case map[string]interface{}:
+			if pkg, ok := d["package"].(string); ok {
+				name = pkg
+			}
+			out[name] = cargoSource(d)
+		}
+	}
+}
+
+// cargoSource describes a detailed dependency the way pubspec constraints
+// are: the source for path, git and workspace dependencies, the version
+// requirement otherwise.
+func cargoSource(d map[string]interface{}) string {
+	if d["workspace"] == true {
+		return "workspace"
+	}
+	if p, ok := d["path"]; ok {
+		return fmt.Sprintf("path:%v", p)
+	}
+	if g, ok := d["git"]; ok {
+		s := fmt.Sprintf("git:%v", g)
+		for _, ref := range []string{"rev", "tag", "branch"} {
+			if r, ok := d[ref]; ok {
+				s += fmt.Sprintf("@%v", r)
+				break
+			}
+		}
+		return s
+	}
+	if v, ok := d["version"].(string); ok {
+		return requirement(v, "^")
+	}
+	return "any"
+}
+
+// table returns the table at key of t, or nil.
+func table(t map[string]interface{}, key string) map[string]interface{} {
+	m, _ := t[key].(map[string]interface{})
+	return m
+}
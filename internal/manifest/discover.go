@@ -0,0 +1,211 @@
+package manifest
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// All scans every manifest found in the tree of each repo.
+const All = "all"
+
+// SkippedDirs hold vendored, installed or generated code whose manifests
+// are not the repo's own. Hidden directories are skipped as well.
+var SkippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"build":        true,
+	"target":       true,
+	"venv":         true,
+	"__pycache__":  true,
+	"Pods":         true,
+}
+
+// ExampleDirs usually hold example apps and test fixtures, whose manifests
+// would count as users of the packages they demonstrate or test. Entries
+// with a slash match consecutive directories.
+var ExampleDirs = []string{
+	"example",
+	"examples",
+	"samples",
+	"test/fixtures",
+	"test/fixture",
+	"test_fixtures",
+	"fixtures",
+	"testdata",
+}
+
+// --- Structures ---
+
+// Found is a manifest found in a repo tree.
+type Found struct {
+	Dir    string
+	File   string
+	Parser Parser
+}
+
+// RepoMix is the number of manifests of each ecosystem in one repo.
+type RepoMix struct {
+	Repo      string         `json:"repo"`
+	Manifests map[string]int `json:"manifests"`
+}
+
+// Composition is the technology mix of the scanned repos: the repos using
+// each ecosystem and the repos per combination of ecosystems, such as
+// "npm+pub".
+type Composition struct {
+	Ecosystems   map[string]int `json:"ecosystems"`
+	Combinations map[string]int `json:"combinations"`
+	Repos        []RepoMix      `json:"repos,omitempty"`
+}
+
+// Tracker collects the ecosystems of the manifests scanned per repo.
+type Tracker struct {
+	mu    sync.Mutex
+	repos map[string]map[string]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{repos: map[string]map[string]int{}}
+}
+
+// --- Core logic ---
+
+// Discover returns the manifests among the file paths of a repo tree below
+// root, one per directory and ecosystem, by directory and with pub first.
+// Manifests in or below the exclude directories are left out, and unless
+// examples is set those in ExampleDirs below root, whose directories are
+// returned second.
+func Discover(paths []string, root string, exclude []string, examples bool) ([]Found, []string) {
+	dirs, exampleDirs := map[string][]string{}, map[string][]string{}
+	for _, p := range paths {
+		if root != "" && !strings.HasPrefix(p, root+"/") {
+			continue
+		}
+		dir, name := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		rel := strings.TrimPrefix(strings.TrimPrefix(dir, root), "/")
+		if skipped(rel) || excluded(dir, exclude) {
+			continue
+		}
+		if !examples && isExample(rel) {
+			exampleDirs[dir] = append(exampleDirs[dir], name)
+			continue
+		}
+		dirs[dir] = append(dirs[dir], name)
+	}
+	return discovered(dirs), manifestDirs(exampleDirs)
+}
+
+// discovered picks the manifests of each directory, by directory and with
+// pub first.
+func discovered(dirs map[string][]string) []Found {
+	order := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		order = append(order, dir)
+	}
+	sort.Strings(order)
+
+	ecosystems := append([]string{Pub}, Ecosystems()...)
+	var out []Found
+	for _, dir := range order {
+		for i, eco := range ecosystems {
+			if i > 0 && eco == Pub {
+				continue
+			}
+			if file, ok := Find(parsers[eco], dirs[dir]); ok {
+				out = append(out, Found{Dir: dir, File: file, Parser: parsers[eco]})
+			}
+		}
+	}
+	return out
+}
+
+func skipped(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, part := range strings.Split(dir, "/") {
+		if SkippedDirs[part] || strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestDirs returns the directories holding a manifest, sorted.
+func manifestDirs(dirs map[string][]string) []string {
+	var out []string
+	for _, f := range discovered(dirs) {
+		if len(out) == 0 || out[len(out)-1] != f.Dir {
+			out = append(out, f.Dir)
+		}
+	}
+	return out
+}
+
+// isExample reports whether a directory, relative to the discovery root,
+// is in or below one of ExampleDirs.
+func isExample(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	parts := "/" + dir + "/"
+	for _, e := range ExampleDirs {
+		if strings.Contains(parts, "/"+e+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func excluded(dir string, exclude []string) bool {
+	for _, e := range exclude {
+		if dir == e || strings.HasPrefix(dir, e+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Add counts the manifest of a result towards its repo. Results of one
+// repo share the owner/repo part of their name.
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || r.Ecosystem == "" {
+		return
+	}
+	repo, _, _ := strings.Cut(r.Repo, ":")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.repos[repo] == nil {
+		t.repos[repo] = map[string]int{}
+	}
+	t.repos[repo][r.Ecosystem]++
+}
+
+// Report returns the composition, or nil when no ecosystem was recorded.
+func (t *Tracker) Report() *Composition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.repos) == 0 {
+		return nil
+	}
+	c := &Composition{Ecosystems: map[string]int{}, Combinations: map[string]int{}}
+	for repo, manifests := range t.repos {
+		mix := RepoMix{Repo: repo, Manifests: map[string]int{}}
+		var names []string
+		for eco, n := range manifests {
+			mix.Manifests[eco] = n
+			c.Ecosystems[eco]++
+			names = append(names, eco)
+		}
+		sort.Strings(names)
+		c.Combinations[strings.Join(names, "+")]++
+		c.Repos = append(c.Repos, mix)
+	}
+	sort.Slice(c.Repos, func(i, j int) bool { return c.Repos[i].Repo < c.Repos[j].Repo })
+	return c
+}
@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// GoParser reads go.mod. Requirements marked // indirect are not direct
+// dependencies and are left out; replaced modules count as overrides and
+// the go and toolchain directives as the environment.
+type GoParser struct{}
+
+func init() { Register(GoParser{}) }
+
+func (GoParser) Ecosystem() string { return "go" }
+func (GoParser) Files() []string   { return []string{"go.mod"} }
+func (GoParser) PURLType() string  { return "golang" }
+
+func (GoParser) URL(name string) string {
+	return "https://pkg.go.dev/" + name
+}
+
+func (GoParser) Parse(_, content string) (Manifest, error) {
+	m := Manifest{Dependencies: map[string]string{}, Environment: map[string]string{}}
+	block := ""
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; sc.Scan(); n++ {
+		line, comment, _ := strings.Cut(sc.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			if len(fields) < 2 {
+				return Manifest{}, fmt.Errorf("go.mod line %d: module without path", n)
+			}
+			m.Name = strings.Trim(fields[1], `"`)
+		case "go", "toolchain":
+			if len(fields) >= 2 {
+				m.Environment[fields[0]] = fields[1]
+			}
+		case "require":
+			if len(fields) < 3 {
+				return Manifest{}, fmt.Errorf("go.mod line %d: malformed require", n)
+			}
+			if strings.TrimSpace(comment) != "indirect" {
+				m.Dependencies[fields[1]] = fields[2]
+			}
+		case "replace":
+			if len(fields) >= 2 {
+				m.Overrides = append(m.Overrides, fields[1])
+			}
+		}
+	}
+	return m, sc.Err()
+}
@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Pub is the ecosystem of pubspec.yaml, the default.
+const Pub = "pub"
+
+// Auto selects the ecosystem of each repo from the manifests it contains.
+const Auto = "auto"
+
+// --- Structures ---
+
+// Manifest is the part of a dependency manifest the scanner reports, in
+// the same shape for every ecosystem. Dependencies and DevDependencies map
+// package names to their constraints as written in the manifest;
+// Overrides names the packages whose resolution the manifest overrides.
+// Hosted holds the hosted: blocks of pub dependencies fetched from another
+// server than pub.dev.
+type Manifest struct {
+	Name            string
+	Version         string
+	PublishTo       string
+	Dependencies    map[string]string
+	DevDependencies map[string]string
+	Overrides       []string
+	Environment     map[string]string
+	Hosted          map[string]pubspec.Hosted
+}
+
+// Parser reads the manifest of one ecosystem.
+type Parser interface {
+	// Ecosystem names the ecosystem, e.g. "pub" or "npm".
+	Ecosystem() string
+	// Files are the manifest file names the parser reads, looked up next
+	// to the entry path, in order of precedence.
+	Files() []string
+	// Parse reads the content of file, one of Files.
+	Parse(file, content string) (Manifest, error)
+	// PURLType is the package URL type of the ecosystem's packages.
+	PURLType() string
+	// URL returns the registry page of a package.
+	URL(name string) string
+}
+
+var parsers = map[string]Parser{}
+
+// --- Core logic ---
+
+// Register makes a parser available by its ecosystem name, and its
+// packages known to package URLs.
+func Register(p Parser) {
+	parsers[p.Ecosystem()] = p
+	purl.Register(p.Ecosystem(), p.PURLType(), p.URL)
+}
+
+// Lookup returns the parser for an ecosystem.
+func Lookup(ecosystem string) (Parser, error) {
+	p, ok := parsers[ecosystem]
+	if !ok {
+		return nil, fmt.Errorf("unknown ecosystem %q (want one of %s)", ecosystem, strings.Join(Ecosystems(), ", "))
+	}
+	return p, nil
+}
+
+// Ecosystems returns the registered ecosystem names, sorted.
+func Ecosystems() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Find returns the first manifest of p present in a directory listing.
+func Find(p Parser, names []string) (string, bool) {
+	present := map[string]bool{}
+	for _, name := range names {
+		present[name] = true
+	}
+	for _, file := range p.Files() {
+		if present[file] {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// Detect returns the parser and manifest for a directory listing: pub when
+// a pubspec is present, otherwise the first ecosystem by name with a
+// manifest in the listing.
+func Detect(names []string) (Parser, string, bool) {
+	if file, ok := Find(parsers[Pub], names); ok {
+		return parsers[Pub], file, true
+	}
+	for _, eco := range Ecosystems() {
+		if file, ok := Find(parsers[eco], names); ok {
+			return parsers[eco], file, true
+		}
+	}
+	return nil, "", false
+}
+
+// Others returns the manifests of ecosystems other than pub present in a
+// directory listing, ordered by ecosystem name.
+func Others(names []string) []string {
+	var out []string
+	for _, eco := range Ecosystems() {
+		if eco == Pub {
+			continue
+		}
+		if file, ok := Find(parsers[eco], names); ok {
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+// Apply records the manifest in a repo result. With mainDeps only the main
+// dependencies are kept.
+func Apply(res *stats.RepoResult, m Manifest, mainDeps bool) {
+	res.Package, res.Version, res.PublishTo = m.Name, m.Version, m.PublishTo
+	res.Environment = m.Environment
+	res.Dependencies = names(m.Dependencies)
+	res.Constraints = map[string]string{}
+	for name, c := range m.Dependencies {
+		res.Constraints[name] = c
+	}
+	defer func() { res.Hosted = hostedOf(m.Hosted, res.Constraints) }()
+	if mainDeps {
+		return
+	}
+	res.DevDependencies = names(m.DevDependencies)
+	res.DependencyOverrides = append([]string(nil), m.Overrides...)
+	sort.Strings(res.DependencyOverrides)
+	for name, c := range m.DevDependencies {
+		if _, ok := res.Constraints[name]; !ok {
+			res.Constraints[name] = c
+		}
+	}
+}
+
+// hostedOf returns the hosted: blocks of the packages a result keeps.
+func hostedOf(hosted map[string]pubspec.Hosted, kept map[string]string) map[string]pubspec.Hosted {
+	var out map[string]pubspec.Hosted
+	for name, h := range hosted {
+		if _, ok := kept[name]; !ok {
+			continue
+		}
+		if out == nil {
+			out = map[string]pubspec.Hosted{}
+		}
+		out[name] = h
+	}
+	return out
+}
+
+func names(deps map[string]string) []string {
+	out := make([]string, 0, len(deps))
+	for name := range deps {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
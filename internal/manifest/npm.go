@@ -0,0 +1,49 @@
+package manifest
+
+import "encoding/json"
+
+// NPMParser reads package.json. overrides (npm) and resolutions (Yarn)
+// count as overrides, engines as the environment.
+type NPMParser struct{}
+
+func init() { Register(NPMParser{}) }
+
+func (NPMParser) Ecosystem() string { return "npm" }
+func (NPMParser) Files() []string   { return []string{"package.json"} }
+func (NPMParser) PURLType() string  { return "npm" }
+
+func (NPMParser) URL(name string) string {
+	return "https://www.npmjs.com/package/" + name
+}
+
+func (NPMParser) Parse(_, content string) (Manifest, error) {
+	var pkg struct {
+		Name            string                     `json:"name"`
+		Version         string                     `json:"version"`
+		Private         bool                       `json:"private"`
+		Dependencies    map[string]string          `json:"dependencies"`
+		DevDependencies map[string]string          `json:"devDependencies"`
+		Overrides       map[string]json.RawMessage `json:"overrides"`
+		Resolutions     map[string]json.RawMessage `json:"resolutions"`
+		Engines         map[string]string          `json:"engines"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return Manifest{}, err
+	}
+	m := Manifest{
+		Name:            pkg.Name,
+		Version:         pkg.Version,
+		Dependencies:    pkg.Dependencies,
+		DevDependencies: pkg.DevDependencies,
+		Environment:     pkg.Engines,
+	}
+	if pkg.Private {
+		m.PublishTo = "none"
+	}
+	for _, section := range []map[string]json.RawMessage{pkg.Overrides, pkg.Resolutions} {
+		for name := range section {
+			m.Overrides = append(m.Overrides, name)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,41 @@
+package manifest
+
+import (
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+)
+
+// PubParser reads pubspec.yaml.
+type PubParser struct{}
+
+func init() { Register(PubParser{}) }
+
+func (PubParser) Ecosystem() string { return Pub }
+func (PubParser) Files() []string   { return []string{"pubspec.yaml"} }
+func (PubParser) PURLType() string  { return purl.Pub }
+
+func (PubParser) URL(name string) string {
+	return "https://pub.dev/packages/" + name
+}
+
+func (PubParser) Parse(_, content string) (Manifest, error) {
+	ps, err := pubspec.Parse(content)
+	if err != nil {
+		return Manifest{}, err
+	}
+	return FromPubspec(ps), nil
+}
+
+// FromPubspec converts a parsed pubspec, e.g. one read leniently.
+func FromPubspec(ps pubspec.Pubspec) Manifest {
+	return Manifest{
+		Name:            ps.Name,
+		Version:         ps.Version,
+		PublishTo:       ps.PublishTo,
+		Dependencies:    pubspec.Constraints(ps.Dependencies),
+		DevDependencies: pubspec.Constraints(ps.DevDependencies),
+		Overrides:       pubspec.Names(ps.DependencyOverrides),
+		Environment:     ps.EnvironmentConstraints(),
+		Hosted:          pubspec.HostedSources(ps.Dependencies, ps.DevDependencies),
+	}
+}
@@ -0,0 +1,205 @@
+package manifest
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// PythonParser reads pyproject.toml, or requirements.txt when a repo has
+// no pyproject. Requirements are converted to pub's syntax ("==1.2" is
+// exact, "~=1.2" compatible up to 2.0) and package names normalized as
+// PyPI does. The project's dependencies, or Poetry's, are the main
+// dependencies; dependency groups and Poetry's dev groups are dev
+// dependencies. Optional extras are not counted. requires-python is the
+// environment and uv's override-dependencies are the overrides.
+type PythonParser struct{}
+
+func init() { Register(PythonParser{}) }
+
+func (PythonParser) Ecosystem() string { return "pypi" }
+func (PythonParser) Files() []string   { return []string{"pyproject.toml", "requirements.txt"} }
+func (PythonParser) PURLType() string  { return "pypi" }
+
+func (PythonParser) URL(name string) string {
+	return "https://pypi.org/project/" + name + "/"
+}
+
+func (PythonParser) Parse(file, content string) (Manifest, error) {
+	if file == "requirements.txt" {
+		return requirementsTxt(content), nil
+	}
+	doc, err := parseTOML(content)
+	if err != nil {
+		return Manifest{}, err
+	}
+	m := Manifest{Dependencies: map[string]string{}, DevDependencies: map[string]string{}, Environment: map[string]string{}}
+	project := table(doc, "project")
+	m.Name, _ = project["name"].(string)
+	m.Version, _ = project["version"].(string)
+	if v, ok := project["requires-python"].(string); ok {
+		m.Environment["python"] = requirement(v, "==")
+	}
+	if classifiers, ok := project["classifiers"].([]interface{}); ok {
+		for _, c := range classifiers {
+			if c == "Private :: Do Not Upload" {
+				m.PublishTo = "none"
+			}
+		}
+	}
+	pep508List(m.Dependencies, project["dependencies"])
+	for _, group := range table(doc, "dependency-groups") {
+		pep508List(m.DevDependencies, group)
+	}
+
+	tool := table(doc, "tool")
+	poetry := table(tool, "poetry")
+	if m.Name == "" {
+		m.Name, _ = poetry["name"].(string)
+		m.Version, _ = poetry["version"].(string)
+	}
+	if poetry["package-mode"] == false {
+		m.PublishTo = "none"
+	}
+	for name, v := range table(poetry, "dependencies") {
+		if strings.EqualFold(name, "python") {
+			if s, ok := v.(string); ok {
+				m.Environment["python"] = requirement(s, "==")
+			}
+			continue
+		}
+		m.Dependencies[normalizePyPI(name)] = poetrySource(v)
+	}
+	for name, v := range table(poetry, "dev-dependencies") {
+		m.DevDependencies[normalizePyPI(name)] = poetrySource(v)
+	}
+	for _, group := range table(poetry, "group") {
+		g, _ := group.(map[string]interface{})
+		for name, v := range table(g, "dependencies") {
+			m.DevDependencies[normalizePyPI(name)] = poetrySource(v)
+		}
+	}
+	if overrides, ok := table(tool, "uv")["override-dependencies"].([]interface{}); ok {
+		for _, o := range overrides {
+			if s, ok := o.(string); ok {
+				if name, _, ok := pep508(s); ok {
+					m.Overrides = append(m.Overrides, name)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+var (
+	pep508Name = regexp.MustCompile(`^([A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?)\s*(?:\[[^\]]*\])?\s*(.*)$`)
+	pypiSep    = regexp.MustCompile(`[-_.]+`)
+)
+
+// normalizePyPI returns the normalized form of a Python package name, under
+// which PyPI treats "Foo.Bar" and "foo_bar" as the same package.
+func normalizePyPI(name string) string {
+	return pypiSep.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// pep508 parses a dependency specifier such as "requests[socks]>=2.8;
+// python_version<'3.12'". Extras and environment markers are dropped;
+// direct references are described by their source.
+func pep508(spec string) (name, constraint string, ok bool) {
+	spec, _, _ = strings.Cut(spec, ";")
+	m := pep508Name.FindStringSubmatch(strings.TrimSpace(spec))
+	if m == nil {
+		return "", "", false
+	}
+	name, rest := normalizePyPI(m[1]), strings.TrimSpace(m[2])
+	if url, ok := strings.CutPrefix(rest, "@"); ok {
+		url = strings.TrimSpace(url)
+		switch {
+		case strings.HasPrefix(url, "git+"):
+			return name, "git:" + strings.TrimPrefix(url, "git+"), true
+		case strings.HasPrefix(url, "file:"):
+			return name, "path:" + strings.TrimPrefix(strings.TrimPrefix(url, "file:"), "//"), true
+		}
+		return name, "url:" + url, true
+	}
+	if strings.ContainsAny(rest, ":/") {
+		return "", "", false
+	}
+	rest = strings.TrimSuffix(strings.TrimPrefix(rest, "("), ")")
+	return name, requirement(rest, "=="), true
+}
+
+func pep508List(out map[string]string, v interface{}) {
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			if name, c, ok := pep508(s); ok {
+				out[name] = c
+			}
+		}
+	}
+}
+
+// poetrySource describes a Poetry dependency: a requirement in Poetry's
+// syntax, where a bare version is exact, or a table with its source. Of
+// multiple constraints the first counts.
+func poetrySource(v interface{}) string {
+	switch d := v.(type) {
+	case string:
+		return requirement(d, "==")
+	case []interface{}:
+		if len(d) > 0 {
+			return poetrySource(d[0])
+		}
+	case map[string]interface{}:
+		if p, ok := d["path"].(string); ok {
+			return "path:" + p
+		}
+		if g, ok := d["git"].(string); ok {
+			for _, ref := range []string{"rev", "tag", "branch"} {
+				if r, ok := d[ref].(string); ok {
+					return "git:" + g + "@" + r
+				}
+			}
+			return "git:" + g
+		}
+		if u, ok := d["url"].(string); ok {
+			return "url:" + u
+		}
+		if s, ok := d["version"].(string); ok {
+			return requirement(s, "==")
+		}
+	}
+	return "any"
+}
+
+// requirementsTxt reads the requirements of a pip requirements file.
+// Options, includes and editable installs are skipped.
+func requirementsTxt(content string) Manifest {
+	m := Manifest{Dependencies: map[string]string{}}
+	var line string
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		text := sc.Text()
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			text = ""
+		}
+		if cont, ok := strings.CutSuffix(strings.TrimRight(text, " \t"), `\`); ok {
+			line += cont
+			continue
+		}
+		line += text
+		spec := strings.TrimSpace(line)
+		line = ""
+		if spec == "" || strings.HasPrefix(spec, "-") {
+			continue
+		}
+		if name, c, ok := pep508(spec); ok {
+			m.Dependencies[name] = c
+		}
+	}
+	return m
+}
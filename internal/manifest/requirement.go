@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// requirement converts a version requirement of another ecosystem to pub's
+// constraint syntax, so the version analyses apply to it unchanged.
+// Comparators are separated by commas; bare is the operator a version
+// without one means: "^" in Cargo, "==" in Python. Exclusions (!=) cannot
+// be expressed and are dropped, release segments beyond the patch version
+// are cut off.
+func requirement(req, bare string) string {
+	var bounds []string
+	exact, caret := "", ""
+	comparators := 0
+	for _, c := range strings.Split(req, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" || c == "*" {
+			continue
+		}
+		op := ""
+		for _, o := range []string{"===", "~=", "==", "!=", ">=", "<=", "^", "~", "=", ">", "<"} {
+			if strings.HasPrefix(c, o) {
+				op = o
+				break
+			}
+		}
+		v := strings.TrimSpace(c[len(op):])
+		if op == "" {
+			op = bare
+		}
+		if op == "!=" || v == "*" {
+			continue
+		}
+		comparators++
+		parts, wildcard := release(v)
+		if parts == nil {
+			// Not a numeric version, e.g. a pre-release in Python's syntax:
+			// kept as written.
+			bounds = append(bounds, c)
+			continue
+		}
+		lower := pad(parts)
+		switch {
+		case wildcard && (op == "==" || op == "=" || op == "^" || op == "~"):
+			bounds = append(bounds, ">="+lower, "<"+bump(parts, len(parts)-1))
+		case op == "^":
+			k := len(parts) - 1
+			for i, p := range parts {
+				if p != 0 {
+					k = i
+					break
+				}
+			}
+			upper := bump(parts, k)
+			if upper == nextBreaking(parts) {
+				caret = "^" + lower
+			}
+			bounds = append(bounds, ">="+lower, "<"+upper)
+		case op == "~":
+			bounds = append(bounds, ">="+lower, "<"+bump(parts, min(1, len(parts)-1)))
+		case op == "~=":
+			bounds = append(bounds, ">="+lower, "<"+bump(parts, max(0, len(parts)-2)))
+		case op == "==" || op == "=" || op == "===":
+			exact = lower
+			bounds = append(bounds, ">="+lower, "<="+lower)
+		default:
+			bounds = append(bounds, op+lower)
+		}
+	}
+	switch {
+	case comparators == 0:
+		return "any"
+	case comparators == 1 && exact != "":
+		return exact
+	case comparators == 1 && caret != "":
+		return caret
+	}
+	return strings.Join(bounds, " ")
+}
+
+// release returns the numeric release segments of a version, at most three,
+// and whether the version ends in a wildcard such as 1.2.*. It returns nil
+// for versions that are not numeric.
+func release(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "+-"); i >= 0 {
+		v = v[:i]
+	}
+	wildcard := strings.HasSuffix(v, ".*") || strings.HasSuffix(v, ".x")
+	if wildcard {
+		v = v[:len(v)-2]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts[:min(len(parts), 3)], wildcard
+}
+
+// pad writes release segments as a full major.minor.patch version.
+func pad(parts []int) string {
+	full := [3]int{}
+	copy(full[:], parts)
+	return strconv.Itoa(full[0]) + "." + strconv.Itoa(full[1]) + "." + strconv.Itoa(full[2])
+}
+
+// bump increments segment k and zeroes the ones after it.
+func bump(parts []int, k int) string {
+	next := append([]int(nil), parts[:k+1]...)
+	next[k]++
+	return pad(next)
+}
+
+// nextBreaking is the upper bound of pub's caret constraint on parts.
+func nextBreaking(parts []int) string {
+	if parts[0] == 0 {
+		return bump(append(append([]int(nil), parts...), 0), 1)
+	}
+	return bump(parts, 0)
+}
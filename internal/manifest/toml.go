@@ -0,0 +1,360 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlParser reads the subset of TOML dependency manifests use: tables,
+// arrays of tables, dotted and quoted keys, strings, integers, floats,
+// booleans, arrays and inline tables. Dates and times are kept as strings
+// and must not separate the time with a space.
+type tomlParser struct {
+	s string
+	i int
+}
+
+// parseTOML decodes a TOML document into nested maps.
+func parseTOML(content string) (map[string]interface{}, error) {
+	p := &tomlParser{s: content}
+	root := map[string]interface{}{}
+	current := root
+	for {
+		p.skipBlank(true)
+		if p.i >= len(p.s) {
+			return root, nil
+		}
+		switch {
+		case strings.HasPrefix(p.s[p.i:], "[["):
+			p.i += 2
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]]"); err != nil {
+				return nil, err
+			}
+			parent, err := p.table(root, key[:len(key)-1])
+			if err != nil {
+				return nil, err
+			}
+			last := key[len(key)-1]
+			list, ok := parent[last].([]interface{})
+			if _, exists := parent[last]; exists && !ok {
+				return nil, p.errorf("key %s is not an array of tables", strings.Join(key, "."))
+			}
+			current = map[string]interface{}{}
+			parent[last] = append(list, current)
+		case p.s[p.i] == '[':
+			p.i++
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if current, err = p.table(root, key); err != nil {
+				return nil, err
+			}
+		default:
+			if err := p.keyValue(current); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.s[:min(p.i, len(p.s))], "\n") + 1
+	return fmt.Errorf("toml line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips spaces and comments, and newlines when lines is set.
+func (p *tomlParser) skipBlank(lines bool) {
+	for p.i < len(p.s) {
+		switch c := p.s[p.i]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.i++
+		case c == '\n' && lines:
+			p.i++
+		case c == '#':
+			for p.i < len(p.s) && p.s[p.i] != '\n' {
+				p.i++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expect(tok string) error {
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.s[p.i:], tok) {
+		return p.errorf("expected %q", tok)
+	}
+	p.i += len(tok)
+	return nil
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	if p.i < len(p.s) && p.s[p.i] != '\n' {
+		return p.errorf("unexpected %q after value", p.s[p.i])
+	}
+	return nil
+}
+
+// table returns the table at key below root, creating missing ones and
+// descending into the last element of arrays of tables.
+func (p *tomlParser) table(root map[string]interface{}, key []string) (map[string]interface{}, error) {
+	t := root
+	for _, k := range key {
+		switch v := t[k].(type) {
+		case nil:
+			m := map[string]interface{}{}
+			t[k] = m
+			t = m
+		case map[string]interface{}:
+			t = v
+		case []interface{}:
+			m, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("key %s is not a table", k)
+			}
+			t = m
+		default:
+			return nil, p.errorf("key %s is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+// key reads a dotted key of bare and quoted parts.
+func (p *tomlParser) key() ([]string, error) {
+	var key []string
+	for {
+		p.skipBlank(false)
+		if p.i >= len(p.s) {
+			return nil, p.errorf("expected a key")
+		}
+		switch p.s[p.i] {
+		case '"', '\'':
+			part, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = append(key, part)
+		default:
+			start := p.i
+			for p.i < len(p.s) && isBareKey(p.s[p.i]) {
+				p.i++
+			}
+			if p.i == start {
+				return nil, p.errorf("expected a key")
+			}
+			key = append(key, p.s[start:p.i])
+		}
+		p.skipBlank(false)
+		if p.i >= len(p.s) || p.s[p.i] != '.' {
+			return key, nil
+		}
+		p.i++
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) keyValue(t map[string]interface{}) error {
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(t, key[:len(key)-1])
+	if err != nil {
+		return err
+	}
+	last := key[len(key)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("duplicate key %s", strings.Join(key, "."))
+	}
+	parent[last] = v
+	return nil
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	p.skipBlank(false)
+	if p.i >= len(p.s) {
+		return nil, p.errorf("expected a value")
+	}
+	switch p.s[p.i] {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		p.i++
+		list := []interface{}{}
+		for {
+			p.skipBlank(true)
+			if p.i < len(p.s) && p.s[p.i] == ']' {
+				p.i++
+				return list, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			p.skipBlank(true)
+			if p.i < len(p.s) && p.s[p.i] == ',' {
+				p.i++
+			} else if p.i >= len(p.s) || p.s[p.i] != ']' {
+				return nil, p.errorf("expected ',' or ']' in array")
+			}
+		}
+	case '{':
+		p.i++
+		t := map[string]interface{}{}
+		p.skipBlank(false)
+		if p.i < len(p.s) && p.s[p.i] == '}' {
+			p.i++
+			return t, nil
+		}
+		for {
+			if err := p.keyValue(t); err != nil {
+				return nil, err
+			}
+			p.skipBlank(false)
+			if p.i < len(p.s) && p.s[p.i] == '}' {
+				p.i++
+				return t, nil
+			}
+			if p.i >= len(p.s) || p.s[p.i] != ',' {
+				return nil, p.errorf("expected ',' or '}' in inline table")
+			}
+			p.i++
+		}
+	}
+	start := p.i
+	for p.i < len(p.s) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[p.i])) {
+		p.i++
+	}
+	tok := p.s[start:p.i]
+	switch tok {
+	case "":
+		return nil, p.errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	clean := strings.ReplaceAll(tok, "_", "")
+	if n, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, nil
+	}
+	if tok[0] >= '0' && tok[0] <= '9' || tok == "inf" || tok == "nan" || tok[0] == '+' || tok[0] == '-' {
+		return tok, nil
+	}
+	return nil, p.errorf("invalid value %q", tok)
+}
+
+// str reads a basic or literal string, single or multi-line.
+func (p *tomlParser) str() (string, error) {
+	q := p.s[p.i]
+	delim := string(q)
+	if strings.HasPrefix(p.s[p.i:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	p.i += len(delim)
+	multi := len(delim) == 3
+	if multi {
+		if strings.HasPrefix(p.s[p.i:], "\r\n") {
+			p.i += 2
+		} else if p.i < len(p.s) && p.s[p.i] == '\n' {
+			p.i++
+		}
+	}
+	var b strings.Builder
+	for {
+		if p.i >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.s[p.i:], delim) {
+			p.i += len(delim)
+			// Up to two quotes may end a multi-line string's content.
+			for n := 0; multi && n < 2 && p.i < len(p.s) && p.s[p.i] == q; n++ {
+				b.WriteByte(q)
+				p.i++
+			}
+			return b.String(), nil
+		}
+		c := p.s[p.i]
+		if c == '\n' && !multi {
+			return "", p.errorf("unterminated string")
+		}
+		if c != '\\' || q == '\'' {
+			b.WriteByte(c)
+			p.i++
+			continue
+		}
+		p.i++
+		if p.i >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+		switch e := p.s[p.i]; e {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(e)
+		case 'u', 'U':
+			n := 4
+			if e == 'U' {
+				n = 8
+			}
+			if p.i+n >= len(p.s) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(p.s[p.i+1:p.i+1+n], 16, 32)
+			if err != nil {
+				return "", p.errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			p.i += n
+		case ' ', '\t', '\r', '\n':
+			if !multi {
+				return "", p.errorf("invalid escape")
+			}
+			// A line-ending backslash trims the following whitespace.
+			for p.i < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.i])) {
+				p.i++
+			}
+			continue
+		default:
+			return "", p.errorf("invalid escape \\%c", e)
+		}
+		p.i++
+	}
+}
@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tomlTable = map[string]interface{}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want tomlTable
+	}{
+		{"empty", "# nothing\n\n", tomlTable{}},
+		{"key values", "name = \"app\" # comment\nversion = '0.1.0'\nedition = 2021\nratio = 0.5\npublish = false\nbig = 1_000\nhex = 0x10\n",
+			tomlTable{"name": "app", "version": "0.1.0", "edition": int64(2021), "ratio": 0.5, "publish": false, "big": int64(1000), "hex": int64(16)}},
+		{"tables", "[package]\nname = \"app\"\n\n[dependencies]\nserde = \"1.0\"\n",
+			tomlTable{"package": tomlTable{"name": "app"}, "dependencies": tomlTable{"serde": "1.0"}}},
+		{"dotted and quoted keys", "[tool.poetry.dependencies]\npython = \"^3.11\"\n\"my.pkg\" = \"*\"\nsite.url = 'x'\n",
+			tomlTable{"tool": tomlTable{"poetry": tomlTable{"dependencies": tomlTable{"python": "^3.11", "my.pkg": "*", "site": tomlTable{"url": "x"}}}}}},
+		{"inline tables and arrays", "serde = { version = \"1.0\", features = [\"derive\", \"std\",] }\nempty = {}\n",
+			tomlTable{"serde": tomlTable{"version": "1.0", "features": []interface{}{"derive", "std"}}, "empty": tomlTable{}}},
+		{"multi-line array", "deps = [\n  \"a\", # first\n  \"b\"\n]\n", tomlTable{"deps": []interface{}{"a", "b"}}},
+		{"arrays of tables", "[[bin]]\nname = \"a\"\n[[bin]]\nname = \"b\"\n",
+			tomlTable{"bin": []interface{}{tomlTable{"name": "a"}, tomlTable{"name": "b"}}}},
+		{"table below array of tables", "[[workspace.member]]\nname = \"a\"\n[workspace.member.deps]\nx = 1\n",
+			tomlTable{"workspace": tomlTable{"member": []interface{}{tomlTable{"name": "a", "deps": tomlTable{"x": int64(1)}}}}}},
+		{"escapes", `s = "tab\tquote\" \u00e9"` + "\n" + `lit = 'C:\path'` + "\n",
+			tomlTable{"s": "tab\tquote\" é", "lit": `C:\path`}},
+		{"multi-line strings", "a = \"\"\"\nline one\nline two\"\"\"\nb = '''\nraw \\n'''\nc = \"\"\"trimmed \\\n     here\"\"\"\n",
+			tomlTable{"a": "line one\nline two", "b": "raw \\n", "c": "trimmed here"}},
+		{"dates kept", "released = 2024-01-02T03:04:05Z\n", tomlTable{"released": "2024-01-02T03:04:05Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		doc     string
+		wantErr string
+	}{
+		{"name = \"app", "toml line 1: unterminated string"},
+		{"name = \"app\nx = 1", "toml line 1: unterminated string"},
+		{"[package\nname = 1", `expected "]"`},
+		{"a = 1 2", "unexpected '2' after value"},
+		{"a = ", "expected a value"},
+		{"a = yes", `invalid value "yes"`},
+		{"a = [1 2]", "expected ',' or ']' in array"},
+		{"a = {b = 1 c = 2}", "expected ',' or '}' in inline table"},
+		{`a = "\q"`, `invalid escape \q`},
+		{`a = "\u12"`, "invalid unicode escape"},
+		{"a = 1\n[[a]]", "key a is not an array of tables"},
+		{"x = 1\n\n\ny = \"", "toml line 4"},
+		{"a = 1\na = 2", "duplicate key a"},
+		{"a = 1\n[a.b]", "key a is not a table"},
+		{"= 1", "expected a key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc, func(t *testing.T) {
+			_, err := parseTOML(tt.doc)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package native
+
+import (
+	"bufio"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Platforms of native dependencies.
+const (
+	Android = "android"
+	IOS     = "ios"
+)
+
+// Files native dependencies are read from, relative to the app, in order
+// of precedence per platform.
+var (
+	GradleFiles = []string{"android/app/build.gradle", "android/app/build.gradle.kts"}
+	PodFiles    = []string{"ios/Podfile.lock", "ios/Podfile"}
+)
+
+// --- Structures ---
+
+// Usage is a native dependency with the repos using it and the versions
+// they use.
+type Usage struct {
+	Platform string         `json:"platform"`
+	Name     string         `json:"name"`
+	Count    int            `json:"count"`
+	Versions map[string]int `json:"versions,omitempty"`
+}
+
+// Report is the native dependency inventory of the Flutter apps scanned.
+type Report struct {
+	Repos        map[string]int `json:"repos"`
+	Dependencies []Usage        `json:"dependencies"`
+}
+
+// Tracker collects the native dependencies recorded in repo results.
+type Tracker struct {
+	mu    sync.Mutex
+	repos map[string]int
+	usage map[string]*Usage
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{repos: map[string]int{}, usage: map[string]*Usage{}}
+}
+
+var (
+	// gradleDep matches configurations such as implementation 'g:a:v' and
+	// api("g:a:v") inside a dependencies block.
+	gradleDep = regexp.MustCompile(`^\s*(implementation|api|compileOnly|runtimeOnly|kapt|ksp|annotationProcessor|coreLibraryDesugaring|\w+Implementation)\s*\(?\s*["']([^"':]+):([^"':]+)(?::([^"']+))?["']`)
+	podLock   = regexp.MustCompile(`^  - "?([^ "(]+) \(([^)]+)\)"?:?$`)
+	podfile   = regexp.MustCompile(`^\s*pod\s+['"]([^'"]+)['"](?:\s*,\s*['"]([^'"]+)['"])?`)
+)
+
+// --- Core logic ---
+
+// ParseGradle returns the Maven coordinates declared as "group:artifact"
+// in a build.gradle or build.gradle.kts, with their versions. Dependencies
+// on projects, files, platforms and version catalogs are not listed; test
+// configurations are marked Test.
+func ParseGradle(content string) []stats.NativeDep {
+	var out []stats.NativeDep
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		if m := gradleDep.FindStringSubmatch(sc.Text()); m != nil {
+			test := strings.HasPrefix(m[1], "test") || strings.HasPrefix(m[1], "androidTest")
+			out = append(out, stats.NativeDep{Platform: Android, Name: m[2] + ":" + m[3], Version: m[4], Test: test})
+		}
+	}
+	return out
+}
+
+// ParsePods returns the pods of a Podfile.lock with their resolved
+// versions, or of a Podfile with their requirements.
+func ParsePods(file, content string) []stats.NativeDep {
+	var out []stats.NativeDep
+	lock := strings.HasSuffix(file, ".lock")
+	inPods := false
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line := sc.Text()
+		if !lock {
+			if m := podfile.FindStringSubmatch(line); m != nil {
+				out = append(out, stats.NativeDep{Platform: IOS, Name: m[1], Version: m[2]})
+			}
+			continue
+		}
+		switch {
+		case line == "PODS:":
+			inPods = true
+		case inPods && line != "" && !strings.HasPrefix(line, " "):
+			inPods = false
+		case inPods:
+			if m := podLock.FindStringSubmatch(line); m != nil {
+				out = append(out, stats.NativeDep{Platform: IOS, Name: m[1], Version: m[2]})
+			}
+		}
+	}
+	return out
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || len(r.Native) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	platforms := map[string]bool{}
+	seen := map[string]bool{}
+	for _, d := range r.Native {
+		platforms[d.Platform] = true
+		key := d.Platform + " " + d.Name
+		u := t.usage[key]
+		if u == nil {
+			u = &Usage{Platform: d.Platform, Name: d.Name, Versions: map[string]int{}}
+			t.usage[key] = u
+		}
+		if !seen[key] {
+			seen[key] = true
+			u.Count++
+		}
+		if d.Version != "" {
+			u.Versions[d.Version]++
+		}
+	}
+	for p := range platforms {
+		t.repos[p]++
+	}
+}
+
+// Report returns the inventory, most used first, or nil when no native
+// dependencies were found.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.usage) == 0 {
+		return nil
+	}
+	rep := &Report{Repos: map[string]int{}}
+	for p, n := range t.repos {
+		rep.Repos[p] = n
+	}
+	for _, u := range t.usage {
+		c := *u
+		c.Versions = map[string]int{}
+		for v, n := range u.Versions {
+			c.Versions[v] = n
+		}
+		rep.Dependencies = append(rep.Dependencies, c)
+	}
+	sort.Slice(rep.Dependencies, func(i, j int) bool {
+		a, b := rep.Dependencies[i], rep.Dependencies[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		return a.Name < b.Name
+	})
+	return rep
+}
@@ -0,0 +1,132 @@
+package osv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+)
+
+const (
+	DefaultBaseURL = "https://api.osv.dev"
+
+	// batchSize is the maximum number of queries OSV accepts per request.
+	batchSize = 1000
+)
+
+// Ecosystems maps package URL types to OSV ecosystems.
+var Ecosystems = map[string]string{
+	purl.Pub: "Pub",
+	"npm":    "npm",
+	"golang": "Go",
+	"cargo":  "crates.io",
+	"pypi":   "PyPI",
+}
+
+// --- Structures ---
+
+// Query is a package, by its package URL, at a version.
+type Query struct {
+	PURL    string
+	Version string
+}
+
+type batchQuery struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Version string `json:"version"`
+}
+
+type batchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+// Client queries the OSV vulnerability database.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{HTTP: httpClient, BaseURL: DefaultBaseURL}
+}
+
+// --- Core logic ---
+
+// QueryBatch returns the advisory IDs affecting each queried package version,
+// keyed by its versioned package URL. Versions without advisories and
+// packages of ecosystems OSV does not know are omitted.
+func (c *Client) QueryBatch(ctx context.Context, queries []Query) (map[string][]string, error) {
+	out := map[string][]string{}
+	var known []Query
+	for _, q := range queries {
+		if typ, _ := purl.Split(q.PURL); Ecosystems[typ] != "" {
+			known = append(known, q)
+		}
+	}
+	// Sorted, the batches are the same from run to run, so cached
+	// responses can answer them.
+	sort.Slice(known, func(i, j int) bool {
+		if known[i].PURL != known[j].PURL {
+			return known[i].PURL < known[j].PURL
+		}
+		return known[i].Version < known[j].Version
+	})
+	queries = known
+	for start := 0; start < len(queries); start += batchSize {
+		chunk := queries[start:min(start+batchSize, len(queries))]
+
+		body := struct {
+			Queries []batchQuery `json:"queries"`
+		}{}
+		for _, q := range chunk {
+			var bq batchQuery
+			typ, name := purl.Split(q.PURL)
+			bq.Package.Name = name
+			bq.Package.Ecosystem = Ecosystems[typ]
+			bq.Version = q.Version
+			body.Queries = append(body.Queries, bq)
+		}
+		data, _ := json.Marshal(body)
+
+		req, _ := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/v1/querybatch", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			return nil, fmt.Errorf("OSV query failed: %s (%s)", resp.Status, string(msg))
+		}
+		var br batchResponse
+		err = json.NewDecoder(resp.Body).Decode(&br)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, r := range br.Results {
+			if i >= len(chunk) || len(r.Vulns) == 0 {
+				continue
+			}
+			key := purl.Versioned(chunk[i].PURL, chunk[i].Version)
+			for _, v := range r.Vulns {
+				out[key] = append(out[key], v.ID)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,308 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// --- Structures ---
+
+// Type is the type of a column.
+type Type int
+
+const (
+	// String is a UTF-8 byte array.
+	String Type = iota
+	// Int64 is a signed 64-bit integer.
+	Int64
+	// Date is a calendar date, written as days since 1970-01-01.
+	Date
+)
+
+// Column describes one column of a flat schema. Optional columns take nil
+// values.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Writer writes a Parquet file with a flat schema: uncompressed,
+// PLAIN-encoded pages, one per column and row group. Rows are buffered
+// until RowGroupSize of them are collected.
+type Writer struct {
+	// RowGroupSize is the number of rows per row group.
+	RowGroupSize int
+
+	w       *countingWriter
+	columns []Column
+	values  [][]interface{}
+	groups  []rowGroup
+	rows    int64
+	err     error
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type chunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type rowGroup struct {
+	chunks []chunk
+	rows   int64
+	size   int64
+}
+
+// Physical types, encodings and other enum values of parquet.thrift.
+const (
+	physInt32     = 1
+	physInt64     = 2
+	physByteArray = 6
+
+	required = 0
+	optional = 1
+
+	convertedUTF8 = 0
+	convertedDate = 6
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageData = 0
+)
+
+var magic = []byte("PAR1")
+
+func (c Column) physical() int32 {
+	switch c.Type {
+	case Int64:
+		return physInt64
+	case Date:
+		return physInt32
+	}
+	return physByteArray
+}
+
+// --- Core logic ---
+
+// NewWriter starts a Parquet file with the given columns on w.
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	pw := &Writer{RowGroupSize: 100000, w: &countingWriter{w: w}, columns: columns, values: make([][]interface{}, len(columns))}
+	if _, err := pw.w.Write(magic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write adds a row with one value per column: a string, an int64 or a
+// time.Time for dates, or nil in optional columns.
+func (pw *Writer) Write(row []interface{}) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(pw.columns))
+	}
+	for i, v := range row {
+		c := pw.columns[i]
+		ok := v == nil && c.Optional
+		switch v.(type) {
+		case string:
+			ok = ok || c.Type == String
+		case int64:
+			ok = ok || c.Type == Int64
+		case time.Time:
+			ok = ok || c.Type == Date
+		}
+		if !ok {
+			return fmt.Errorf("parquet: invalid value %v for column %s", v, c.Name)
+		}
+	}
+	for i, v := range row {
+		pw.values[i] = append(pw.values[i], v)
+	}
+	if len(pw.values[0]) >= pw.RowGroupSize {
+		pw.err = pw.flush()
+	}
+	return pw.err
+}
+
+// Close writes the buffered rows and the footer. It does not close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(pw.values[0]) > 0 {
+		if err := pw.flush(); err != nil {
+			return err
+		}
+	}
+	footer := pw.footer()
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(footer)))
+	for _, b := range [][]byte{footer, size[:], magic} {
+		if _, err := pw.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *Writer) flush() error {
+	rows := int64(len(pw.values[0]))
+	g := rowGroup{rows: rows}
+	for i, c := range pw.columns {
+		data := pw.page(c, pw.values[i])
+		var h thrift
+		h.begin(0)
+		h.i32(1, pageData)
+		h.i32(2, int32(len(data)))
+		h.i32(3, int32(len(data)))
+		h.begin(5)
+		h.i32(1, int32(rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.end()
+		h.end()
+		ch := chunk{offset: pw.w.n, size: int64(h.buf.Len() + len(data)), values: rows}
+		if _, err := pw.w.Write(h.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(data); err != nil {
+			return err
+		}
+		g.chunks = append(g.chunks, ch)
+		g.size += ch.size
+		pw.values[i] = pw.values[i][:0]
+	}
+	pw.groups = append(pw.groups, g)
+	pw.rows += rows
+	return nil
+}
+
+// page encodes the values of a column chunk: definition levels for
+// optional columns, then the non-null values.
+func (pw *Writer) page(c Column, values []interface{}) []byte {
+	var out []byte
+	if c.Optional {
+		levels := rleLevels(values)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(levels)))
+		out = append(out, levels...)
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			out = binary.LittleEndian.AppendUint32(out, uint32(len(v)))
+			out = append(out, v...)
+		case int64:
+			out = binary.LittleEndian.AppendUint64(out, uint64(v))
+		case time.Time:
+			days := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+			out = binary.LittleEndian.AppendUint32(out, uint32(int32(days)))
+		}
+	}
+	return out
+}
+
+// rleLevels encodes the definition levels of an optional column (1 for a
+// value, 0 for null) as runs of the RLE/bit-packing hybrid with bit width 1.
+func rleLevels(values []interface{}) []byte {
+	var out []byte
+	for i := 0; i < len(values); {
+		level := values[i] != nil
+		n := 1
+		for i+n < len(values) && (values[i+n] != nil) == level {
+			n++
+		}
+		out = binary.AppendUvarint(out, uint64(n)<<1)
+		if level {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i += n
+	}
+	return out
+}
+
+// footer encodes the FileMetaData.
+func (pw *Writer) footer() []byte {
+	var t thrift
+	t.begin(0)
+	t.i32(1, 1)
+	t.list(2, tStruct, len(pw.columns)+1)
+	t.begin(0)
+	t.str(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.end()
+	for _, c := range pw.columns {
+		converted := int32(convertedUTF8)
+		switch c.Type {
+		case Int64:
+			converted = -1
+		case Date:
+			converted = convertedDate
+		}
+		repetition := int32(required)
+		if c.Optional {
+			repetition = optional
+		}
+		t.begin(0)
+		t.i32(1, c.physical())
+		t.i32(3, repetition)
+		t.str(4, c.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.end()
+	}
+	t.i64(3, pw.rows)
+	t.list(4, tStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		t.begin(0)
+		t.list(1, tStruct, len(g.chunks))
+		for i, ch := range g.chunks {
+			c := pw.columns[i]
+			t.begin(0)
+			t.i64(2, ch.offset)
+			t.begin(3)
+			t.i32(1, c.physical())
+			t.list(2, tI32, 2)
+			t.varint(encodingPlain)
+			t.varint(encodingRLE)
+			t.list(3, tBinary, 1)
+			t.uvarint(uint64(len(c.Name)))
+			t.buf.WriteString(c.Name)
+			t.i32(4, 0)
+			t.i64(5, ch.values)
+			t.i64(6, ch.size)
+			t.i64(7, ch.size)
+			t.i64(9, ch.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.str(6, "pubscan")
+	t.end()
+	return t.buf.Bytes()
+}
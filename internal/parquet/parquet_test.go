@@ -0,0 +1,91 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testColumns = []Column{
+	{Name: "date", Type: Date},
+	{Name: "package", Type: String},
+	{Name: "count", Type: Int64},
+	{Name: "version", Type: String, Optional: true},
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   [][]interface{}
+		groups int
+	}{
+		{"empty", nil, 0},
+		{"one group", [][]interface{}{
+			{time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), "http", int64(3), "1.2.0"},
+			{time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), "dio", int64(1), nil},
+		}, 1},
+		{"several groups", [][]interface{}{
+			{time.Now(), "a", int64(1), nil}, {time.Now(), "b", int64(2), nil}, {time.Now(), "c", int64(3), nil},
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			pw, err := NewWriter(&buf, testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pw.RowGroupSize = 2
+			for _, row := range tt.rows {
+				if err := pw.Write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+			if !bytes.HasPrefix(data, magic) || !bytes.HasSuffix(data, magic) {
+				t.Fatalf("file not framed by %s", magic)
+			}
+			size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+			if size <= 0 || size > len(data)-12 {
+				t.Fatalf("footer size %d of a %d byte file", size, len(data))
+			}
+			footer := data[len(data)-8-size : len(data)-8]
+			for _, c := range testColumns {
+				if !bytes.Contains(footer, []byte(c.Name)) {
+					t.Errorf("footer lacks column %s", c.Name)
+				}
+			}
+			if len(pw.groups) != tt.groups {
+				t.Errorf("%d row groups, want %d", len(pw.groups), tt.groups)
+			}
+		})
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []interface{}
+		wantErr string
+	}{
+		{"too few values", []interface{}{time.Now(), "http"}, "row has 2 values, want 4"},
+		{"wrong type", []interface{}{time.Now(), "http", 3, nil}, "invalid value 3 for column count"},
+		{"nil in required column", []interface{}{time.Now(), nil, int64(3), nil}, "invalid value <nil> for column package"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pw, err := NewWriter(&bytes.Buffer{}, testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pw.Write(tt.row); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package parquet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestThrift(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*thrift)
+		want  []byte
+	}{
+		{"i32", func(t *thrift) { t.i32(1, 5) }, []byte{0x15, 0x0a}},
+		{"negative i64", func(t *thrift) { t.i64(2, -1) }, []byte{0x26, 0x01}},
+		{"multi-byte varint", func(t *thrift) { t.i64(1, 300) }, []byte{0x16, 0xd8, 0x04}},
+		{"string", func(t *thrift) { t.str(1, "ab") }, []byte{0x18, 0x02, 'a', 'b'}},
+		{"field delta", func(t *thrift) { t.i32(1, 1); t.i32(3, 1) }, []byte{0x15, 0x02, 0x25, 0x02}},
+		{"long field jump", func(t *thrift) { t.i32(20, 1) }, []byte{0x05, 0x28, 0x02}},
+		{"short list", func(t *thrift) { t.list(1, tI32, 3) }, []byte{0x19, 0x35}},
+		{"long list", func(t *thrift) { t.list(1, tStruct, 20) }, []byte{0x19, 0xfc, 0x14}},
+		{"nested struct", func(t *thrift) {
+			t.begin(1)
+			t.i32(1, 1)
+			t.end()
+			t.i32(2, 3)
+		}, []byte{0x1c, 0x15, 0x02, 0x00, 0x15, 0x06}},
+		{"struct in list", func(t *thrift) {
+			t.list(1, tStruct, 1)
+			t.begin(0)
+			t.i32(1, 7)
+			t.end()
+		}, []byte{0x19, 0x1c, 0x15, 0x0e, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var th thrift
+			tt.write(&th)
+			if got := th.buf.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("encoded % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
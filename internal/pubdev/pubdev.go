@@ -0,0 +1,148 @@
+package pubdev
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/redact"
+)
+
+const DefaultBaseURL = "https://pub.dev"
+
+// ErrNotFound is returned for packages that are not hosted on the server,
+// e.g. sdk packages or private packages.
+var ErrNotFound = errors.New("package not found")
+
+// --- Structures ---
+
+type Release struct {
+	Version       string                 `json:"version"`
+	Pubspec       map[string]interface{} `json:"pubspec"`
+	ArchiveURL    string                 `json:"archive_url"`
+	ArchiveSHA256 string                 `json:"archive_sha256"`
+	Published     time.Time              `json:"published"`
+}
+
+type Package struct {
+	Name     string    `json:"name"`
+	Latest   Release   `json:"latest"`
+	Versions []Release `json:"versions"`
+}
+
+// Client talks to the pub.dev package API (or a compatible pub server).
+// Token is sent as a bearer token when set, for private servers.
+type Client struct {
+	HTTP    *http.Client
+	BaseURL string
+	Token   string
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{HTTP: httpClient, BaseURL: DefaultBaseURL}
+}
+
+// --- Core logic ---
+
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	req.Header.Set("Accept", "application/vnd.pub.v2+json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("pub.dev request %s failed: %s (%s)", path, resp.Status, redact.Snippet(resp.Body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Package returns the package listing with all published versions.
+func (c *Client) Package(ctx context.Context, name string) (*Package, error) {
+	var p Package
+	if err := c.getJSON(ctx, "/api/packages/"+url.PathEscape(name), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Publisher returns the verified publisher of a package, or "" if it is
+// published by an individual account.
+func (c *Client) Publisher(ctx context.Context, name string) (string, error) {
+	var p struct {
+		PublisherID string `json:"publisherId"`
+	}
+	if err := c.getJSON(ctx, "/api/packages/"+url.PathEscape(name)+"/publisher", &p); err != nil {
+		return "", err
+	}
+	return p.PublisherID, nil
+}
+
+// Funding returns the funding URLs declared in the release's pubspec.
+func (r Release) Funding() []string {
+	list, _ := r.Pubspec["funding"].([]interface{})
+	var urls []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			urls = append(urls, s)
+		}
+	}
+	return urls
+}
+
+type Options struct {
+	IsDiscontinued bool   `json:"isDiscontinued"`
+	ReplacedBy     string `json:"replacedBy"`
+	IsUnlisted     bool   `json:"isUnlisted"`
+}
+
+// Options returns the discontinued/unlisted status of a package.
+func (c *Client) Options(ctx context.Context, name string) (*Options, error) {
+	var o Options
+	if err := c.getJSON(ctx, "/api/packages/"+url.PathEscape(name)+"/options", &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+type Score struct {
+	GrantedPoints   int      `json:"grantedPoints"`
+	MaxPoints       int      `json:"maxPoints"`
+	LikeCount       int      `json:"likeCount"`
+	PopularityScore float64  `json:"popularityScore"`
+	Tags            []string `json:"tags"`
+}
+
+// Score returns the pub.dev score and analysis tags of a package.
+func (c *Client) Score(ctx context.Context, name string) (*Score, error) {
+	var s Score
+	if err := c.getJSON(ctx, "/api/packages/"+url.PathEscape(name)+"/score", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Licenses returns the SPDX identifiers from the score's license: tags.
+func (s Score) Licenses() []string {
+	var out []string
+	for _, t := range s.Tags {
+		if l, ok := strings.CutPrefix(t, "license:"); ok && l != "fsf-libre" && l != "osi-approved" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+package publish
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Kinds of package, from where publish_to points.
+const (
+	// KindPrivate packages set publish_to: none and cannot be published.
+	KindPrivate = "private"
+	// KindInternal packages are published to a private package server.
+	KindInternal = "internal"
+	// KindPublic packages have no publish_to and go to pub.dev.
+	KindPublic = "public"
+)
+
+// Statuses of a VersionCheck.
+const (
+	StatusPublished    = "published"
+	StatusUnpublished  = "unpublished"
+	StatusBehind       = "behind"
+	StatusNotPublished = "not_published"
+	StatusError        = "error"
+)
+
+// Metadata items pub.dev scores or shows, as named in MetadataCheck.Missing.
+const (
+	ItemDescription  = "description"
+	ItemTopics       = "topics"
+	ItemRepository   = "repository"
+	ItemIssueTracker = "issue_tracker"
+	ItemScreenshots  = "screenshots"
+	ItemExample      = "example"
+)
+
+// Items lists the metadata items in the order they are checked.
+var Items = []string{ItemDescription, ItemTopics, ItemRepository, ItemIssueTracker, ItemScreenshots, ItemExample}
+
+// Limits pub.dev applies to descriptions and topics.
+const (
+	MinDescription = 60
+	MaxDescription = 180
+	MaxTopics      = 5
+)
+
+var topicPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}[a-z0-9]$`)
+
+// --- Structures ---
+
+// Package is the publishing metadata of one repo's pubspec.
+type Package struct {
+	Repo      string `json:"repo"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	PublishTo string `json:"publish_to,omitempty"`
+	Kind      string `json:"kind"`
+}
+
+// Report is the inventory of packages by how they can be published.
+// Unguarded lists the public ones that look like applications and are
+// probably missing publish_to: none.
+type Report struct {
+	Counts    map[string]int `json:"counts"`
+	Internal  []Package      `json:"internal,omitempty"`
+	Public    []Package      `json:"public,omitempty"`
+	Unguarded []Package      `json:"unguarded,omitempty"`
+
+	// Versions compares the internal packages with their servers, when
+	// --check-published is set.
+	Versions []VersionCheck `json:"versions,omitempty"`
+
+	// Metadata is the pub.dev metadata completeness of the internal and
+	// public packages, when --package-metadata is set.
+	Metadata *MetadataReport `json:"metadata,omitempty"`
+}
+
+// VersionCheck is the declared version of an internal package against the
+// latest version on its server.
+type VersionCheck struct {
+	Repo      string `json:"repo"`
+	Name      string `json:"name"`
+	Declared  string `json:"declared,omitempty"`
+	Published string `json:"published,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MetadataCheck is the metadata completeness of one package: the share of
+// Items present and valid, and the ones missing or invalid.
+type MetadataCheck struct {
+	Repo         string   `json:"repo"`
+	Name         string   `json:"name"`
+	Kind         string   `json:"kind"`
+	Completeness float64  `json:"completeness"`
+	Missing      []string `json:"missing,omitempty"`
+	Problems     []string `json:"problems,omitempty"`
+}
+
+// MetadataReport is the metadata completeness of the publishable packages.
+// Missing counts the packages lacking each item.
+type MetadataReport struct {
+	Packages []MetadataCheck `json:"packages"`
+	Missing  map[string]int  `json:"missing"`
+}
+
+// Tracker collects the publishing metadata of scanned repos.
+type Tracker struct {
+	mu       sync.Mutex
+	counts   map[string]int
+	packages []Package
+	apps     map[string]bool
+	metadata []MetadataCheck
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{counts: map[string]int{}, apps: map[string]bool{}}
+}
+
+// --- Core logic ---
+
+// Compare returns the status of a declared version against the latest
+// published one; an empty published version means the package is not on the
+// server. A declared version ahead of the server has unpublished changes.
+func Compare(declared, published string) string {
+	if published == "" {
+		return StatusNotPublished
+	}
+	d, err1 := semver.ParseVersion(declared)
+	p, err2 := semver.ParseVersion(published)
+	if err1 != nil || err2 != nil {
+		if declared == published {
+			return StatusPublished
+		}
+		return StatusError
+	}
+	switch c := d.Compare(p); {
+	case c > 0:
+		return StatusUnpublished
+	case c < 0:
+		return StatusBehind
+	}
+	return StatusPublished
+}
+
+// KindOf classifies a publish_to value.
+func KindOf(publishTo string) string {
+	switch strings.TrimSpace(publishTo) {
+	case "none":
+		return KindPrivate
+	case "":
+		return KindPublic
+	default:
+		return KindInternal
+	}
+}
+
+// LooksLikeApp reports whether r is probably an application rather than a
+// package: its version carries a build number, as flutter create sets up
+// for apps, e.g. 1.0.0+1.
+func LooksLikeApp(r stats.RepoResult) bool {
+	return strings.Contains(r.Version, "+")
+}
+
+// CheckMetadata scores the pub.dev metadata of r's package. Invalid items
+// count as missing and are explained in Problems.
+func CheckMetadata(r stats.RepoResult) MetadataCheck {
+	m := r.Metadata
+	c := MetadataCheck{Repo: r.Repo, Name: r.Package, Kind: KindOf(r.PublishTo)}
+	missing := func(item, problem string) {
+		c.Missing = append(c.Missing, item)
+		if problem != "" {
+			c.Problems = append(c.Problems, problem)
+		}
+	}
+
+	switch n := len(m.Description); {
+	case n == 0:
+		missing(ItemDescription, "")
+	case n < MinDescription:
+		missing(ItemDescription, fmt.Sprintf("description has %d characters, fewer than %d", n, MinDescription))
+	case n > MaxDescription:
+		missing(ItemDescription, fmt.Sprintf("description has %d characters, more than %d", n, MaxDescription))
+	}
+
+	var invalid []string
+	for _, topic := range m.Topics {
+		if !topicPattern.MatchString(topic) || strings.Contains(topic, "--") {
+			invalid = append(invalid, topic)
+		}
+	}
+	switch {
+	case len(m.Topics) == 0:
+		missing(ItemTopics, "")
+	case len(m.Topics) > MaxTopics:
+		missing(ItemTopics, fmt.Sprintf("%d topics, more than %d", len(m.Topics), MaxTopics))
+	case len(invalid) > 0:
+		missing(ItemTopics, "invalid topics: "+strings.Join(invalid, ", "))
+	}
+
+	if m.Repository == "" && m.Homepage == "" {
+		missing(ItemRepository, "")
+	}
+	if m.IssueTracker == "" {
+		missing(ItemIssueTracker, "")
+	}
+	if len(m.Screenshots) == 0 {
+		missing(ItemScreenshots, "")
+	}
+	if !m.HasExample {
+		missing(ItemExample, "")
+	}
+	c.Completeness = float64(len(Items)-len(c.Missing)) / float64(len(Items))
+	return c
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || r.Package == "" {
+		return
+	}
+	kind := KindOf(r.PublishTo)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[kind]++
+	if kind == KindPrivate {
+		return
+	}
+	if r.Metadata != nil {
+		t.metadata = append(t.metadata, CheckMetadata(r))
+	}
+	t.packages = append(t.packages, Package{Repo: r.Repo, Name: r.Package, Version: r.Version, PublishTo: r.PublishTo, Kind: kind})
+	if kind == KindPublic && LooksLikeApp(r) {
+		t.apps[r.Repo] = true
+	}
+}
+
+// Report returns the inventory, or nil when no pubspec declared a name.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.counts) == 0 {
+		return nil
+	}
+	rep := &Report{Counts: map[string]int{}}
+	for k, v := range t.counts {
+		rep.Counts[k] = v
+	}
+	sort.Slice(t.packages, func(i, j int) bool { return t.packages[i].Repo < t.packages[j].Repo })
+	for _, p := range t.packages {
+		switch {
+		case p.Kind == KindInternal:
+			rep.Internal = append(rep.Internal, p)
+		case t.apps[p.Repo]:
+			rep.Unguarded = append(rep.Unguarded, p)
+		default:
+			rep.Public = append(rep.Public, p)
+		}
+	}
+	if len(t.metadata) > 0 {
+		rep.Metadata = &MetadataReport{Missing: map[string]int{}}
+		sort.Slice(t.metadata, func(i, j int) bool {
+			if t.metadata[i].Completeness != t.metadata[j].Completeness {
+				return t.metadata[i].Completeness < t.metadata[j].Completeness
+			}
+			return t.metadata[i].Repo < t.metadata[j].Repo
+		})
+		for _, c := range t.metadata {
+			rep.Metadata.Packages = append(rep.Metadata.Packages, c)
+			for _, item := range c.Missing {
+				rep.Metadata.Missing[item]++
+			}
+		}
+	}
+	return rep
+}
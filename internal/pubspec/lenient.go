@@ -0,0 +1,111 @@
+package pubspec
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseLenient decodes a slightly broken pubspec as far as possible. Tab
+// indentation is replaced, the last of duplicate keys wins, and top-level
+// sections that still fail to parse are skipped. The warnings say what was
+// repaired or dropped.
+func ParseLenient(content string) (Pubspec, []string) {
+	var warnings []string
+	content, tabs := untab(content)
+	if tabs > 0 {
+		warnings = append(warnings, fmt.Sprintf("replaced tab indentation on %d lines", tabs))
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err == nil && len(root.Content) > 0 {
+		warnings = append(warnings, dedupe(root.Content[0], "")...)
+		var ps Pubspec
+		if err := root.Content[0].Decode(&ps); err == nil {
+			return ps, warnings
+		}
+	}
+
+	// Decode each top-level section on its own, so one broken section does
+	// not lose the others.
+	var ps Pubspec
+	for _, block := range topLevelBlocks(content) {
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(block.text), &node); err != nil || len(node.Content) == 0 {
+			warnings = append(warnings, fmt.Sprintf("line %d: skipped unparsable section %q", block.line, block.key))
+			continue
+		}
+		warnings = append(warnings, dedupe(node.Content[0], "")...)
+		if err := node.Content[0].Decode(&ps); err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: skipped invalid section %q", block.line, block.key))
+		}
+	}
+	return ps, warnings
+}
+
+// untab replaces tabs in the indentation of every line with two spaces.
+func untab(content string) (string, int) {
+	lines := strings.Split(content, "\n")
+	n := 0
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(trimmed)]
+		if strings.Contains(indent, "\t") {
+			lines[i] = strings.ReplaceAll(indent, "\t", "  ") + trimmed
+			n++
+		}
+	}
+	return strings.Join(lines, "\n"), n
+}
+
+// dedupe removes all but the last of duplicate keys in every mapping below
+// n, and returns a warning per removed key.
+func dedupe(n *yaml.Node, path string) []string {
+	var warnings []string
+	if n.Kind == yaml.MappingNode {
+		last := map[string]int{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			last[n.Content[i].Value] = i
+		}
+		kept := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if last[k.Value] != i {
+				warnings = append(warnings, fmt.Sprintf("line %d: duplicate key %q, using the last one", k.Line, path+k.Value))
+				continue
+			}
+			kept = append(kept, k, v)
+		}
+		n.Content = kept
+	}
+	for i, c := range n.Content {
+		prefix := path
+		if n.Kind == yaml.MappingNode && i%2 == 1 {
+			prefix = path + n.Content[i-1].Value + "."
+		}
+		warnings = append(warnings, dedupe(c, prefix)...)
+	}
+	return warnings
+}
+
+type block struct {
+	key  string
+	line int
+	text string
+}
+
+// topLevelBlocks splits content at lines starting with a key, keeping the
+// comments and indented lines below each key with it.
+func topLevelBlocks(content string) []block {
+	var out []block
+	for i, line := range strings.Split(content, "\n") {
+		starts := line != "" && line[0] != ' ' && line[0] != '#' && line != "---"
+		if starts || len(out) == 0 {
+			key, _, _ := strings.Cut(line, ":")
+			out = append(out, block{key: strings.TrimSpace(key), line: i + 1})
+		}
+		out[len(out)-1].text += line + "\n"
+	}
+	return out
+}
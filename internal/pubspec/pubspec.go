@@ -0,0 +1,244 @@
+package pubspec
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Pubspec struct {
+	Name                string                 `yaml:"name"`
+	Version             string                 `yaml:"version"`
+	PublishTo           string                 `yaml:"publish_to"`
+	Dependencies        map[string]interface{} `yaml:"dependencies"`
+	DevDependencies     map[string]interface{} `yaml:"dev_dependencies"`
+	DependencyOverrides map[string]interface{} `yaml:"dependency_overrides"`
+	Environment         map[string]interface{} `yaml:"environment"`
+}
+
+// Screenshot is an entry of the screenshots field shown on pub.dev.
+type Screenshot struct {
+	Description string `json:"description"`
+	Path        string `json:"path"`
+}
+
+// Metadata is the pub.dev-facing metadata of a package. HasExample is set by
+// the scanner, which looks for an example directory.
+type Metadata struct {
+	Description   string       `json:"description,omitempty"`
+	Homepage      string       `json:"homepage,omitempty"`
+	Repository    string       `json:"repository,omitempty"`
+	IssueTracker  string       `json:"issue_tracker,omitempty"`
+	Documentation string       `json:"documentation,omitempty"`
+	Topics        []string     `json:"topics,omitempty"`
+	Funding       []string     `json:"funding,omitempty"`
+	Screenshots   []Screenshot `json:"screenshots,omitempty"`
+	HasExample    bool         `json:"has_example"`
+}
+
+// ParseMetadata returns the pub.dev-facing fields of a pubspec. Fields of
+// an unexpected shape are left empty rather than failing, since they do not
+// affect dependency resolution.
+func ParseMetadata(content string) *Metadata {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &raw); err != nil {
+		return nil
+	}
+	str := func(key string) string {
+		s, _ := raw[key].(string)
+		return strings.TrimSpace(s)
+	}
+	strs := func(key string) []string {
+		list, _ := raw[key].([]interface{})
+		var out []string
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	m := &Metadata{
+		Description:   str("description"),
+		Homepage:      str("homepage"),
+		Repository:    str("repository"),
+		IssueTracker:  str("issue_tracker"),
+		Documentation: str("documentation"),
+		Topics:        strs("topics"),
+		Funding:       strs("funding"),
+	}
+	list, _ := raw["screenshots"].([]interface{})
+	for _, v := range list {
+		if e, ok := v.(map[string]interface{}); ok {
+			desc, _ := e["description"].(string)
+			path, _ := e["path"].(string)
+			m.Screenshots = append(m.Screenshots, Screenshot{Description: desc, Path: path})
+		}
+	}
+	return m
+}
+
+// Parse decodes a pubspec. Anchors and merge keys are resolved; duplicate
+// keys and malformed YAML are errors.
+func Parse(content string) (Pubspec, error) {
+	var ps Pubspec
+	if err := yaml.Unmarshal([]byte(content), &ps); err != nil {
+		return Pubspec{}, fmt.Errorf("invalid pubspec.yaml: %w", err)
+	}
+	return ps, nil
+}
+
+// Names returns the sorted package names declared in a dependency section.
+func Names(section map[string]interface{}) []string {
+	names := make([]string, 0, len(section))
+	for k := range section {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Constraint describes how a dependency is declared: the version constraint
+// for hosted packages, or the source for sdk, path and git dependencies.
+func Constraint(v interface{}) string {
+	switch d := v.(type) {
+	case nil:
+		return "any"
+	case string:
+		return d
+	case map[string]interface{}:
+		if sdk, ok := d["sdk"]; ok {
+			return fmt.Sprintf("sdk:%v", sdk)
+		}
+		if p, ok := d["path"]; ok {
+			return fmt.Sprintf("path:%v", p)
+		}
+		if g, ok := d["git"]; ok {
+			switch g := g.(type) {
+			case string:
+				return "git:" + g
+			case map[string]interface{}:
+				s := fmt.Sprintf("git:%v", g["url"])
+				if ref, ok := g["ref"]; ok {
+					s += fmt.Sprintf("@%v", ref)
+				}
+				return s
+			}
+			return "git"
+		}
+		if version, ok := d["version"]; ok {
+			return fmt.Sprint(version)
+		}
+		return "any"
+	default:
+		return fmt.Sprint(d)
+	}
+}
+
+// GitSource returns the url, ref and path of a git dependency.
+func GitSource(v interface{}) (url, ref, path string, ok bool) {
+	d, isMap := v.(map[string]interface{})
+	if !isMap {
+		return "", "", "", false
+	}
+	switch g := d["git"].(type) {
+	case string:
+		return g, "", "", true
+	case map[string]interface{}:
+		url = fmt.Sprint(g["url"])
+		if r, has := g["ref"]; has {
+			ref = fmt.Sprint(r)
+		}
+		if p, has := g["path"]; has {
+			path = fmt.Sprint(p)
+		}
+		return url, ref, path, true
+	}
+	return "", "", "", false
+}
+
+// Hosted is the hosted: block of a dependency: the package server it is
+// fetched from, and its name there when the block gives one.
+type Hosted struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+}
+
+// HostedSource returns the hosted: block of a dependency, in the mapping
+// form with name and url or the short form naming only the url.
+// Credentials in the url are dropped.
+func HostedSource(v interface{}) (Hosted, bool) {
+	d, isMap := v.(map[string]interface{})
+	if !isMap {
+		return Hosted{}, false
+	}
+	var out Hosted
+	switch h := d["hosted"].(type) {
+	case string:
+		out.URL = h
+	case map[string]interface{}:
+		if n, has := h["name"]; has && n != nil {
+			out.Name = fmt.Sprint(n)
+		}
+		if u, has := h["url"]; has && u != nil {
+			out.URL = fmt.Sprint(u)
+		}
+	}
+	if u, err := url.Parse(out.URL); err == nil && u.User != nil {
+		u.User = nil
+		out.URL = u.String()
+	}
+	return out, out.URL != ""
+}
+
+// HostedSources maps the packages of the given sections declared with a
+// hosted: block to it. Earlier sections take precedence.
+func HostedSources(sections ...map[string]interface{}) map[string]Hosted {
+	out := map[string]Hosted{}
+	for _, section := range sections {
+		for name, v := range section {
+			if _, ok := out[name]; ok {
+				continue
+			}
+			if h, ok := HostedSource(v); ok {
+				out[name] = h
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Constraints maps every package of the given sections to its constraint.
+// Earlier sections take precedence when a package is declared twice.
+func Constraints(sections ...map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	for _, section := range sections {
+		for name, v := range section {
+			if _, ok := out[name]; !ok {
+				out[name] = Constraint(v)
+			}
+		}
+	}
+	return out
+}
+
+// EnvironmentConstraints returns the sdk and flutter constraints of the
+// environment section.
+func (ps Pubspec) EnvironmentConstraints() map[string]string {
+	if len(ps.Environment) == 0 {
+		return nil
+	}
+	out := map[string]string{}
+	for k, v := range ps.Environment {
+		if v != nil {
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
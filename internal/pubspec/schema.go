@@ -0,0 +1,123 @@
+package pubspec
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+)
+
+// Kinds of Violation.
+const (
+	ViolationUnknownKey        = "unknown-key"
+	ViolationDeprecatedKey     = "deprecated-key"
+	ViolationInvalidName       = "invalid-name"
+	ViolationInvalidVersion    = "invalid-version"
+	ViolationInvalidConstraint = "invalid-constraint"
+)
+
+// Violation is a pubspec field that does not match the pubspec schema.
+type Violation struct {
+	Kind    string `json:"kind"`
+	Field   string `json:"field"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// topLevelKeys are the fields of the published pubspec schema. Tools such as
+// flutter_launcher_icons read their own keys; those count as unknown.
+var topLevelKeys = map[string]bool{
+	"name": true, "version": true, "description": true, "homepage": true,
+	"repository": true, "issue_tracker": true, "documentation": true,
+	"dependencies": true, "dev_dependencies": true, "dependency_overrides": true,
+	"environment": true, "executables": true, "platforms": true,
+	"publish_to": true, "funding": true, "false_secrets": true,
+	"screenshots": true, "topics": true, "ignored_advisories": true,
+	"flutter": true, "workspace": true, "resolution": true, "hooks": true,
+}
+
+var deprecatedKeys = map[string]string{
+	"author":       "author is no longer used by pub",
+	"authors":      "authors is no longer used by pub",
+	"transformers": "transformers were removed in Dart 2",
+	"web":          "web is no longer used by pub",
+}
+
+// namePattern is pub's package name rule: a lowercase Dart identifier.
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+var reservedWords = map[string]bool{
+	"assert": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "default": true, "do": true, "else": true,
+	"enum": true, "extends": true, "false": true, "final": true, "finally": true,
+	"for": true, "if": true, "in": true, "is": true, "new": true, "null": true,
+	"rethrow": true, "return": true, "super": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "var": true, "void": true,
+	"while": true, "with": true,
+}
+
+// Validate checks a pubspec against the pubspec schema: unknown and
+// deprecated top-level keys, the name and version formats, and the syntax of
+// hosted and environment constraints. Content that is not valid YAML yields
+// no violations; Parse reports it.
+func Validate(content string) []Violation {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	var out []Violation
+	doc := root.Content[0]
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		k, v := doc.Content[i], doc.Content[i+1]
+		switch key := k.Value; {
+		case deprecatedKeys[key] != "":
+			out = append(out, Violation{Kind: ViolationDeprecatedKey, Field: key, Line: k.Line, Message: deprecatedKeys[key]})
+		case !topLevelKeys[key]:
+			out = append(out, Violation{Kind: ViolationUnknownKey, Field: key, Line: k.Line, Message: fmt.Sprintf("unknown top-level key %q", key)})
+		case key == "name":
+			if !namePattern.MatchString(v.Value) || reservedWords[v.Value] {
+				out = append(out, Violation{Kind: ViolationInvalidName, Field: key, Line: v.Line,
+					Message: fmt.Sprintf("package name %q must be a lowercase Dart identifier", v.Value)})
+			}
+		case key == "version":
+			if _, err := semver.ParseVersion(v.Value); err != nil {
+				out = append(out, Violation{Kind: ViolationInvalidVersion, Field: key, Line: v.Line,
+					Message: fmt.Sprintf("version %q is not a semantic version", v.Value)})
+			}
+		case key == "dependencies", key == "dev_dependencies", key == "dependency_overrides", key == "environment":
+			out = append(out, constraintViolations(key, v)...)
+		}
+	}
+	return out
+}
+
+// constraintViolations checks the hosted constraints of a dependency or
+// environment section. Sources such as git and path have none.
+func constraintViolations(section string, n *yaml.Node) []Violation {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var out []Violation
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		name, v := n.Content[i].Value, n.Content[i+1]
+		c := v
+		if v.Kind == yaml.MappingNode {
+			c = nil
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				if v.Content[j].Value == "version" {
+					c = v.Content[j+1]
+				}
+			}
+		}
+		if c == nil || c.Kind != yaml.ScalarNode || c.Tag == "!!null" {
+			continue
+		}
+		if _, err := semver.ParseConstraint(c.Value); err != nil {
+			out = append(out, Violation{Kind: ViolationInvalidConstraint, Field: section + "." + name, Line: c.Line,
+				Message: fmt.Sprintf("invalid constraint %q on %s", c.Value, name)})
+		}
+	}
+	return out
+}
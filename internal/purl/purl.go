@@ -0,0 +1,100 @@
+package purl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Pub is the package URL type of pub packages, the ecosystem of results
+// without one.
+const Pub = "pub"
+
+// --- Structures ---
+
+type ecosystem struct {
+	typ string
+	url func(name string) string
+}
+
+var ecosystems = map[string]ecosystem{}
+
+// --- Core logic ---
+
+// Register maps an ecosystem to its package URL type and the function
+// returning the registry page of a package.
+func Register(name, typ string, page func(name string) string) {
+	ecosystems[name] = ecosystem{typ: typ, url: page}
+}
+
+// Type returns the package URL type of an ecosystem, pub for "".
+func Type(eco string) string {
+	if eco == "" {
+		return Pub
+	}
+	if e, ok := ecosystems[eco]; ok {
+		return e.typ
+	}
+	return eco
+}
+
+// For returns the package URL of a package, e.g. pkg:pub/http or
+// pkg:npm/%40babel/core, without a version.
+func For(eco, name string) string {
+	escaped := strings.ReplaceAll(url.PathEscape(name), "%2F", "/")
+	if rest, ok := strings.CutPrefix(escaped, "@"); ok {
+		escaped = "%40" + rest
+	}
+	return "pkg:" + Type(eco) + "/" + escaped
+}
+
+// Versioned appends a version to a package URL.
+func Versioned(p, version string) string {
+	if version == "" {
+		return p
+	}
+	return p + "@" + url.PathEscape(version)
+}
+
+// Split returns the type and name of a package URL, or "" for both when p
+// is not one.
+func Split(p string) (typ, name string) {
+	rest, ok := strings.CutPrefix(p, "pkg:")
+	if !ok {
+		return "", ""
+	}
+	typ, name, _ = strings.Cut(rest, "/")
+	if n, err := url.PathUnescape(name); err == nil {
+		name = n
+	}
+	return typ, name
+}
+
+// Name returns the package name of a package URL, or p unchanged when it
+// is not one, e.g. a name from a report written before package URLs.
+func Name(p string) string {
+	if _, name := Split(p); name != "" {
+		return name
+	}
+	return p
+}
+
+// URL returns the registry page of the package a package URL names, or ""
+// for unknown types.
+func URL(p string) string {
+	typ, name := Split(p)
+	for _, e := range ecosystems {
+		if e.typ == typ && e.url != nil {
+			return e.url(name)
+		}
+	}
+	return ""
+}
+
+// Key returns the package URL of a package identified by either its URL
+// or, as in reports written before package URLs, its pub package name.
+func Key(p string) string {
+	if strings.HasPrefix(p, "pkg:") {
+		return p
+	}
+	return For("", p)
+}
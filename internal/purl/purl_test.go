@@ -0,0 +1,87 @@
+package purl
+
+import "testing"
+
+func init() {
+	Register("python", "pypi", func(name string) string { return "https://pypi.org/project/" + name })
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		eco, name string
+		want      string
+	}{
+		{"", "http", "pkg:pub/http"},
+		{"pub", "http", "pkg:pub/http"},
+		{"python", "requests", "pkg:pypi/requests"},
+		{"npm", "@babel/core", "pkg:npm/%40babel/core"},
+		{"npm", "left pad", "pkg:npm/left%20pad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := For(tt.eco, tt.name); got != tt.want {
+				t.Errorf("For = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in, typ, name string
+	}{
+		{"pkg:pub/http", "pub", "http"},
+		{"pkg:npm/%40babel/core", "npm", "@babel/core"},
+		{"pkg:npm/%zz", "npm", "%zz"},
+		{"http", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			typ, name := Split(tt.in)
+			if typ != tt.typ || name != tt.name {
+				t.Errorf("Split = %q, %q, want %q, %q", typ, name, tt.typ, tt.name)
+			}
+		})
+	}
+}
+
+func TestNameKeyURL(t *testing.T) {
+	tests := []struct {
+		in, name, key, url string
+	}{
+		{"pkg:pub/http", "http", "pkg:pub/http", ""},
+		{"http", "http", "pkg:pub/http", ""},
+		{"pkg:pypi/requests", "requests", "pkg:pypi/requests", "https://pypi.org/project/requests"},
+		{"pkg:cargo/serde", "serde", "pkg:cargo/serde", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := Name(tt.in); got != tt.name {
+				t.Errorf("Name = %q, want %q", got, tt.name)
+			}
+			if got := Key(tt.in); got != tt.key {
+				t.Errorf("Key = %q, want %q", got, tt.key)
+			}
+			if got := URL(tt.in); got != tt.url {
+				t.Errorf("URL = %q, want %q", got, tt.url)
+			}
+		})
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	tests := []struct {
+		p, version, want string
+	}{
+		{"pkg:pub/http", "", "pkg:pub/http"},
+		{"pkg:pub/http", "1.2.0", "pkg:pub/http@1.2.0"},
+		{"pkg:npm/x", "1.0.0 beta", "pkg:npm/x@1.0.0%20beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Versioned(tt.p, tt.version); got != tt.want {
+				t.Errorf("Versioned = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package quota
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Structures ---
+
+// ProviderUsage is the API consumption of one provider. Limit, Remaining and
+// Reset are the rate limit headers of its latest response, when it sends
+// them.
+type ProviderUsage struct {
+	Provider    string     `json:"provider"`
+	Requests    int        `json:"requests"`
+	Errors      int        `json:"errors,omitempty"`
+	RateLimited int        `json:"rate_limited,omitempty"`
+	Limit       int        `json:"limit,omitempty"`
+	Remaining   *int       `json:"remaining,omitempty"`
+	Reset       *time.Time `json:"reset,omitempty"`
+}
+
+// CacheUsage is the hit rate of one lookup cache.
+type CacheUsage struct {
+	Cache   string  `json:"cache"`
+	Hits    int     `json:"hits"`
+	Misses  int     `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// Usage is the API consumption of a scan.
+type Usage struct {
+	Providers []ProviderUsage `json:"providers"`
+	Caches    []CacheUsage    `json:"caches,omitempty"`
+}
+
+// Meter counts the requests sent through its transports per provider.
+// Requests to hosts that were not registered are counted under the host.
+type Meter struct {
+	mu        sync.Mutex
+	hosts     map[string]string
+	providers map[string]*ProviderUsage
+	caches    map[string]*CacheUsage
+}
+
+func NewMeter() *Meter {
+	return &Meter{hosts: map[string]string{}, providers: map[string]*ProviderUsage{}, caches: map[string]*CacheUsage{}}
+}
+
+type transport struct {
+	m    *Meter
+	base http.RoundTripper
+}
+
+// --- Core logic ---
+
+// Register attributes requests to the host of baseURL to provider.
+func (m *Meter) Register(provider, baseURL string) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hosts[strings.ToLower(u.Host)] = provider
+}
+
+// Wrap returns a transport that sends requests through base and counts
+// them.
+func (m *Meter) Wrap(base http.RoundTripper) http.RoundTripper {
+	return &transport{m: m, base: base}
+}
+
+// Cache records the hits and misses of a lookup cache, adding to earlier
+// records of the same cache. Unused caches are left out.
+func (m *Meter) Cache(name string, hits, misses int) {
+	if hits+misses == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.caches[name]
+	if c == nil {
+		c = &CacheUsage{Cache: name}
+		m.caches[name] = c
+	}
+	c.Hits += hits
+	c.Misses += misses
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	t.m.record(strings.ToLower(req.URL.Host), resp, err)
+	return resp, err
+}
+
+func (m *Meter) record(host string, resp *http.Response, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := m.hosts[host]
+	if name == "" {
+		name = host
+	}
+	p := m.providers[name]
+	if p == nil {
+		p = &ProviderUsage{Provider: name}
+		m.providers[name] = p
+	}
+	p.Requests++
+	if err != nil {
+		p.Errors++
+		return
+	}
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0") {
+		p.RateLimited++
+	} else if resp.StatusCode >= 500 {
+		p.Errors++
+	}
+	if v, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		p.Limit = v
+	}
+	if v, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		p.Remaining = &v
+	}
+	if v, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset := time.Unix(v, 0).UTC()
+		p.Reset = &reset
+	}
+}
+
+// Usage returns the consumption so far, or nil when nothing was requested.
+func (m *Meter) Usage() *Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.providers) == 0 && len(m.caches) == 0 {
+		return nil
+	}
+	u := &Usage{}
+	for _, p := range m.providers {
+		u.Providers = append(u.Providers, *p)
+	}
+	sort.Slice(u.Providers, func(i, j int) bool { return u.Providers[i].Provider < u.Providers[j].Provider })
+	for _, c := range m.caches {
+		cu := *c
+		if total := c.Hits + c.Misses; total > 0 {
+			cu.HitRate = float64(c.Hits) / float64(total)
+		}
+		u.Caches = append(u.Caches, cu)
+	}
+	sort.Slice(u.Caches, func(i, j int) bool { return u.Caches[i].Cache < u.Caches[j].Cache })
+	return u
+}
+
+// Sum adds up the usage of several runs, e.g. of merged shards. Rate limit
+// states are the tightest seen. Nil usages are skipped; the sum of none is
+// nil.
+func Sum(usages ...*Usage) *Usage {
+	providers := map[string]*ProviderUsage{}
+	caches := map[string]*CacheUsage{}
+	seen := false
+	for _, u := range usages {
+		if u == nil {
+			continue
+		}
+		seen = true
+		for _, p := range u.Providers {
+			s := providers[p.Provider]
+			if s == nil {
+				s = &ProviderUsage{Provider: p.Provider}
+				providers[p.Provider] = s
+			}
+			s.Requests += p.Requests
+			s.Errors += p.Errors
+			s.RateLimited += p.RateLimited
+			if p.Limit > s.Limit {
+				s.Limit = p.Limit
+			}
+			if p.Remaining != nil && (s.Remaining == nil || *p.Remaining < *s.Remaining) {
+				v := *p.Remaining
+				s.Remaining = &v
+			}
+			if p.Reset != nil && (s.Reset == nil || p.Reset.After(*s.Reset)) {
+				v := *p.Reset
+				s.Reset = &v
+			}
+		}
+		for _, c := range u.Caches {
+			s := caches[c.Cache]
+			if s == nil {
+				s = &CacheUsage{Cache: c.Cache}
+				caches[c.Cache] = s
+			}
+			s.Hits += c.Hits
+			s.Misses += c.Misses
+		}
+	}
+	if !seen {
+		return nil
+	}
+	m := &Meter{providers: providers, caches: caches}
+	return m.Usage()
+}
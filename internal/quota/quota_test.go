@@ -0,0 +1,37 @@
+package quota
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSum(t *testing.T) {
+	one, five := 1, 5
+	early, late := time.Unix(100, 0).UTC(), time.Unix(200, 0).UTC()
+	tests := []struct {
+		name   string
+		usages []*Usage
+		want   *Usage
+	}{
+		{"none", nil, nil},
+		{"nil usages", []*Usage{nil, nil}, nil},
+		{"summed", []*Usage{
+			{Providers: []ProviderUsage{{Provider: "github", Requests: 3, Errors: 1, Limit: 5000, Remaining: &five, Reset: &early}},
+				Caches: []CacheUsage{{Cache: "pub.dev", Hits: 1, Misses: 1}}},
+			nil,
+			{Providers: []ProviderUsage{{Provider: "github", Requests: 2, RateLimited: 1, Limit: 5000, Remaining: &one, Reset: &late}, {Provider: "osv", Requests: 1}},
+				Caches: []CacheUsage{{Cache: "pub.dev", Hits: 2}}},
+		}, &Usage{
+			Providers: []ProviderUsage{{Provider: "github", Requests: 5, Errors: 1, RateLimited: 1, Limit: 5000, Remaining: &one, Reset: &late}, {Provider: "osv", Requests: 1}},
+			Caches:    []CacheUsage{{Cache: "pub.dev", Hits: 3, Misses: 1, HitRate: 0.75}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.usages...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sum = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
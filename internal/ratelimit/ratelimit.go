@@ -0,0 +1,241 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for retrying rate-limited requests.
+const (
+	DefaultMaxRetries = 3
+	DefaultMaxWait    = 2 * time.Minute
+
+	// backoff is the first delay for 429 responses without Retry-After.
+	backoff = time.Second
+)
+
+// --- Structures ---
+
+// Bucket is a token bucket: up to burst requests at once, refilled at rate
+// requests per second. A zero rate does not limit, but Pause still holds
+// requests back.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time
+}
+
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Limiter throttles requests per host and retries requests the host
+// rejected as rate limited, after the delay its Retry-After asks for.
+// While a host's delay runs, every request to it waits.
+type Limiter struct {
+	// MaxRetries bounds the retries of one request; MaxWait is the longest
+	// Retry-After honored; longer ones return the response as is.
+	MaxRetries int
+	MaxWait    time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+func New() *Limiter {
+	return &Limiter{MaxRetries: DefaultMaxRetries, MaxWait: DefaultMaxWait, buckets: map[string]*Bucket{}}
+}
+
+// transport sends requests through http.DefaultTransport.
+type transport struct {
+	l       *Limiter
+	timeout time.Duration
+}
+
+// cancelBody releases the attempt's timeout when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// --- Core logic ---
+
+// Wait blocks until a request may be sent.
+func (b *Bucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		switch {
+		case now.Before(b.until):
+			wait = b.until.Sub(now)
+		case b.rate <= 0:
+			b.mu.Unlock()
+			return nil
+		default:
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens--
+				b.mu.Unlock()
+				return nil
+			}
+			wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Pause holds requests back for d.
+func (b *Bucket) Pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.until) {
+		b.until = until
+	}
+}
+
+// Limit allows rate requests per second, in bursts of up to burst, to the
+// host of baseURL. A rate of 0 removes the limit.
+func (l *Limiter) Limit(baseURL string, rate float64, burst int) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets[strings.ToLower(u.Host)] = NewBucket(rate, burst)
+}
+
+func (l *Limiter) bucket(host string) *Bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[host]
+	if b == nil {
+		b = NewBucket(0, 1)
+		l.buckets[host] = b
+	}
+	return b
+}
+
+// Client returns an HTTP client whose requests stay within the limits.
+// Unlike http.Client.Timeout, timeout applies to each attempt, so waiting
+// for the limiter or a Retry-After does not make requests time out.
+func (l *Limiter) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &transport{l: l, timeout: timeout}}
+}
+
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	base := http.DefaultTransport
+	if t.timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelBody{resp.Body, cancel}
+	return resp, nil
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	b := t.l.bucket(host)
+	for attempt := 0; ; attempt++ {
+		if err := b.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := t.attempt(req)
+		if err != nil {
+			return nil, err
+		}
+		delay, limited := RetryDelay(resp, attempt, time.Now())
+		if !limited || attempt >= t.l.MaxRetries || delay > t.l.MaxWait {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		fmt.Printf("⏳ %s is rate limiting requests, retrying in %s\n", host, delay.Round(time.Second))
+		b.Pause(delay)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// RetryDelay reports whether resp rejected the request as rate limited and
+// how long to wait before retrying. It honors Retry-After on 429, 503 and
+// GitHub's secondary rate limit 403s, and backs off exponentially on 429s
+// without it.
+func RetryDelay(resp *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	after, hasAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		if hasAfter {
+			return after, true
+		}
+		return backoff << attempt, true
+	case http.StatusServiceUnavailable, http.StatusForbidden:
+		return after, hasAfter
+	}
+	return 0, false
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	date := func(d time.Duration) string { return now.Add(d).Format(http.TimeFormat) }
+	tests := []struct {
+		name        string
+		status      int
+		retryAfter  string
+		attempt     int
+		want        time.Duration
+		wantLimited bool
+	}{
+		{"429 with seconds", 429, "30", 0, 30 * time.Second, true},
+		{"429 with date", 429, date(90 * time.Second), 0, 90 * time.Second, true},
+		{"429 with past date", 429, date(-time.Minute), 0, 0, true},
+		{"429 without header", 429, "", 0, time.Second, true},
+		{"429 backs off", 429, "", 3, 8 * time.Second, true},
+		{"429 with garbage", 429, "soon", 1, 2 * time.Second, true},
+		{"503 with seconds", 503, " 5 ", 0, 5 * time.Second, true},
+		{"503 without header", 503, "", 0, 0, false},
+		{"secondary rate limit", 403, "60", 0, time.Minute, true},
+		{"plain 403", 403, "", 0, 0, false},
+		{"negative seconds", 503, "-1", 0, 0, false},
+		{"ok", 200, "30", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			got, limited := RetryDelay(resp, tt.attempt, now)
+			if got != tt.want || limited != tt.wantLimited {
+				t.Errorf("RetryDelay = %s, %v, want %s, %v", got, limited, tt.want, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		rejections int
+		retryAfter string
+		maxRetries int
+		maxWait    time.Duration
+		wantCalls  int32
+		wantStatus int
+	}{
+		{"retried", 2, "0", 3, time.Minute, 3, 200},
+		{"out of retries", 5, "0", 2, time.Minute, 3, 429},
+		{"wait too long", 1, "3600", 3, time.Minute, 1, 429},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= int32(tt.rejections) {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			l := New()
+			l.MaxRetries, l.MaxWait = tt.maxRetries, tt.maxWait
+			resp, err := l.Client(time.Second).Post(srv.URL, "text/plain", strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || calls.Load() != tt.wantCalls {
+				t.Errorf("status %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		bucket  func() *Bucket
+		calls   int
+		wantErr bool
+	}{
+		{"within burst", func() *Bucket { return NewBucket(0.01, 3) }, 3, false},
+		{"over burst", func() *Bucket { return NewBucket(0.01, 3) }, 4, true},
+		{"unlimited", func() *Bucket { return NewBucket(0, 1) }, 100, false},
+		{"paused", func() *Bucket {
+			b := NewBucket(0, 1)
+			b.Pause(time.Hour)
+			return b
+		}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			b := tt.bucket()
+			var err error
+			for i := 0; i < tt.calls && err == nil; i++ {
+				err = b.Wait(ctx)
+			}
+			if got := errors.Is(err, context.DeadlineExceeded); got != tt.wantErr {
+				t.Errorf("error %v, want blocked %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package redact
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every redacted value.
+const Mask = "[REDACTED]"
+
+// secretVars match the names of environment variables holding credentials.
+var secretVars = regexp.MustCompile(`(?i)(TOKEN|KEY|SECRET|SALT|PASSWORD|AUTH)`)
+
+// patterns catch credentials that were never registered, such as tokens
+// quoted back in response bodies. The groups around the credential are kept.
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`()\b(?:gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`),
+	regexp.MustCompile(`(?i)((?:authorization|x-api-key|proxy-authorization)\s*[:=]\s*)(?:(?:bearer|token|basic)\s+)?[^\s,;"'\[\]]+`),
+	regexp.MustCompile(`(?i)(\b(?:bearer|token|basic)\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`(?i)([?&](?:access_token|token|api_key|apikey|key|sig|signature|password)=)[^&\s"']+`),
+	regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+(@)`),
+}
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// --- Core logic ---
+
+// Add registers values that must never be printed. Short values are
+// ignored, they would mask unrelated text.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) >= 6 {
+			secrets = append(secrets, v)
+		}
+	}
+	// Longest first, so a secret containing another is masked whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// FromEnv registers the values of every environment variable whose name
+// suggests a credential, e.g. GITHUB_TOKEN or DTRACK_API_KEY.
+func FromEnv() {
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok && secretVars.MatchString(name) {
+			Add(value)
+			// Header values such as "Authorization: Bearer x" also leak
+			// without their name.
+			if _, v, ok := strings.Cut(value, ":"); ok {
+				Add(v)
+			}
+		}
+	}
+}
+
+// String masks registered secrets and credential-looking substrings of s.
+func String(s string) string {
+	mu.RLock()
+	for _, v := range secrets {
+		s = strings.ReplaceAll(s, v, Mask)
+	}
+	mu.RUnlock()
+	for _, re := range patterns {
+		s = re.ReplaceAllString(s, "${1}"+Mask+"${2}")
+	}
+	return s
+}
+
+// Stdout routes os.Stdout through String line by line. The returned
+// function restores it after writing any pending output; it is safe to call
+// more than once.
+func Stdout() func() {
+	return route(&os.Stdout)
+}
+
+// Stderr routes os.Stderr through String like Stdout.
+func Stderr() func() {
+	return route(&os.Stderr)
+}
+
+// route replaces *f with a pipe whose lines are written to the original
+// file redacted.
+func route(f **os.File) func() {
+	orig := *f
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	*f = w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		copyLines(orig, r)
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			*f = orig
+			w.Close()
+			<-done
+		})
+	}
+}
+
+func copyLines(dst io.Writer, src io.Reader) {
+	br := bufio.NewReaderSize(src, 64*1024)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			io.WriteString(dst, String(line))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// snippetLen is the most of a response body Snippet returns.
+const snippetLen = 200
+
+// Snippet returns the start of a response body for an error message: at
+// most 200 bytes on one line, with credentials masked.
+func Snippet(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, snippetLen+1))
+	s := strings.Join(strings.Fields(string(data)), " ")
+	if len(data) > snippetLen {
+		s = strings.ToValidUTF8(s[:min(len(s), snippetLen)], "") + "…"
+	}
+	return String(s)
+}
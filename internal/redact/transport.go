@@ -0,0 +1,69 @@
+package redact
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sensitiveHeaders are logged by name only.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true, "Proxy-Authorization": true, "Cookie": true,
+	"Set-Cookie": true, "X-Api-Key": true,
+}
+
+// Transport masks credentials in transport errors and, with Debug, logs
+// sanitized request and response metadata. Bodies are never logged.
+type Transport struct {
+	Base  http.RoundTripper
+	Debug bool
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	if t.Debug {
+		fmt.Printf("→ %s %s %s\n", req.Method, String(req.URL.String()), headers(req.Header))
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		if t.Debug {
+			fmt.Printf("← %s %s failed after %s: %s\n", req.Method, String(req.URL.String()), time.Since(start).Round(time.Millisecond), String(err.Error()))
+		}
+		return nil, redactedError{err}
+	}
+	if t.Debug {
+		fmt.Printf("← %s %s %s in %s, %d bytes %s\n", req.Method, String(req.URL.String()), resp.Status,
+			time.Since(start).Round(time.Millisecond), resp.ContentLength, headers(resp.Header))
+	}
+	return resp, nil
+}
+
+// redactedError masks the message of err and keeps it for errors.Is.
+type redactedError struct{ err error }
+
+func (e redactedError) Error() string { return String(e.err.Error()) }
+func (e redactedError) Unwrap() error { return e.err }
+
+// headers formats h with the values of sensitive headers masked.
+func headers(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		v := strings.Join(h[name], ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			v = Mask
+		}
+		parts = append(parts, name+"="+String(v))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
@@ -0,0 +1,182 @@
+package remediation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Item is one piece of migration work: a rule broken by a package (or by
+// the repos themselves, for rules without a package) across repos. The
+// blast radius is the number of repos affected fleet-wide; in a team plan
+// Repos holds only the team's repos.
+type Item struct {
+	Priority    int      `json:"priority"`
+	Package     string   `json:"package,omitempty"`
+	Rule        string   `json:"rule"`
+	Severity    string   `json:"severity"`
+	BlastRadius int      `json:"blast_radius"`
+	Repos       []string `json:"repos"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// TeamPlan is the work of one owning team, in plan priority.
+type TeamPlan struct {
+	Team  string `json:"team"`
+	Items []Item `json:"items"`
+}
+
+// Plan groups findings into work items, most urgent first, and splits
+// them by team so every team can be ticketed separately.
+type Plan struct {
+	Items []Item     `json:"items"`
+	Teams []TeamPlan `json:"teams"`
+}
+
+// --- Core logic ---
+
+// Build groups list by package and rule. Items are ordered by severity,
+// then blast radius. owners maps repos to their CODEOWNERS teams; repos
+// without any are attributed to stats.Unowned.
+func Build(list []findings.Finding, owners map[string][]string) Plan {
+	type key struct{ pkg, rule string }
+	items := map[key]*Item{}
+	for _, f := range list {
+		pkg := f.PURL
+		if pkg == "" {
+			pkg = f.Package
+		}
+		k := key{pkg, f.Rule}
+		it := items[k]
+		if it == nil {
+			it = &Item{Package: f.Package, Rule: f.Rule, Severity: f.Severity, Remediation: f.Remediation}
+			items[k] = it
+		}
+		if findings.Rank(f.Severity) > findings.Rank(it.Severity) {
+			it.Severity = f.Severity
+		}
+		it.Repos = append(it.Repos, f.Repo)
+	}
+
+	var p Plan
+	for _, it := range items {
+		it.Repos = unique(it.Repos)
+		it.BlastRadius = len(it.Repos)
+		p.Items = append(p.Items, *it)
+	}
+	sort.Slice(p.Items, func(i, j int) bool {
+		a, b := p.Items[i], p.Items[j]
+		if ra, rb := findings.Rank(a.Severity), findings.Rank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.BlastRadius != b.BlastRadius {
+			return a.BlastRadius > b.BlastRadius
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Rule < b.Rule
+	})
+
+	teams := map[string]*TeamPlan{}
+	for i := range p.Items {
+		p.Items[i].Priority = i + 1
+		byTeam := map[string][]string{}
+		for _, repo := range p.Items[i].Repos {
+			repoTeams := owners[repo]
+			if len(repoTeams) == 0 {
+				repoTeams = []string{stats.Unowned}
+			}
+			for _, t := range repoTeams {
+				byTeam[t] = append(byTeam[t], repo)
+			}
+		}
+		for t, repos := range byTeam {
+			tp := teams[t]
+			if tp == nil {
+				tp = &TeamPlan{Team: t}
+				teams[t] = tp
+			}
+			it := p.Items[i]
+			it.Repos = repos
+			tp.Items = append(tp.Items, it)
+		}
+	}
+	for _, tp := range teams {
+		p.Teams = append(p.Teams, *tp)
+	}
+	sort.Slice(p.Teams, func(i, j int) bool {
+		if (p.Teams[i].Team == stats.Unowned) != (p.Teams[j].Team == stats.Unowned) {
+			return p.Teams[j].Team == stats.Unowned
+		}
+		return p.Teams[i].Team < p.Teams[j].Team
+	})
+	return p
+}
+
+// WriteMarkdown renders the plan as a Markdown document: all items, then a
+// section per team with the repos to change.
+func WriteMarkdown(w io.Writer, p Plan) error {
+	var b strings.Builder
+	b.WriteString("# Remediation Plan\n\n")
+	if len(p.Items) == 0 {
+		b.WriteString("No findings.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString("| Priority | Package | Rule | Severity | Repos | Remediation |\n")
+	b.WriteString("|---:|---|---|---|---:|---|\n")
+	for _, it := range p.Items {
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %d | %s |\n", it.Priority, cell(it.Package), it.Rule, it.Severity, it.BlastRadius, cell(it.Remediation))
+	}
+	for _, tp := range p.Teams {
+		fmt.Fprintf(&b, "\n## %s\n\n", tp.Team)
+		b.WriteString("| Priority | Package | Rule | Severity | Blast radius | Team repos | Remediation |\n")
+		b.WriteString("|---:|---|---|---|---:|---|---|\n")
+		for _, it := range tp.Items {
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %d | %s | %s |\n", it.Priority, cell(it.Package), it.Rule, it.Severity, it.BlastRadius, strings.Join(it.Repos, ", "), cell(it.Remediation))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes one row per team and item, ready to import as tickets.
+func WriteCSV(w io.Writer, p Plan) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"team", "priority", "package", "rule", "severity", "blast_radius", "team_repos", "remediation"})
+	for _, tp := range p.Teams {
+		for _, it := range tp.Items {
+			cw.Write([]string{tp.Team, strconv.Itoa(it.Priority), it.Package, it.Rule, it.Severity,
+				strconv.Itoa(it.BlastRadius), strings.Join(it.Repos, ";"), it.Remediation})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func cell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func unique(list []string) []string {
+	sort.Strings(list)
+	out := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package repolist
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Overlap is a repo listed more than once, by the same input or several.
+// Inputs are where it was listed, as "input:line" when the line is known.
+type Overlap struct {
+	Repo   string   `json:"repo"`
+	Inputs []string `json:"inputs,omitempty"`
+}
+
+// Dedup drops entries naming a repo and path that an earlier entry of any
+// input already named, and remembers where both were listed. Names compare
+// normalized, so entries should come from Read or Normalize. It keeps every
+// entry ID it was given, so its memory grows with the fleet.
+type Dedup struct {
+	mu       sync.Mutex
+	first    map[string]string // entry ID -> where it was first listed
+	overlaps map[string][]string
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{first: map[string]string{}, overlaps: map[string][]string{}}
+}
+
+// Add records the entry e of input and reports whether it duplicates an
+// earlier one, and where that one was listed.
+func (d *Dedup) Add(input string, e Entry) (string, bool) {
+	at := input
+	if e.Line > 0 {
+		at = fmt.Sprintf("%s:%d", input, e.Line)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	first, dup := d.first[e.ID()]
+	if !dup {
+		d.first[e.ID()] = at
+		return "", false
+	}
+	if len(d.overlaps[e.ID()]) == 0 {
+		d.overlaps[e.ID()] = []string{first}
+	}
+	d.overlaps[e.ID()] = append(d.overlaps[e.ID()], at)
+	return first, true
+}
+
+// Overlaps returns the repos listed more than once, sorted by repo.
+func (d *Dedup) Overlaps() []Overlap {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Overlap, 0, len(d.overlaps))
+	for id, inputs := range d.overlaps {
+		out = append(out, Overlap{Repo: id, Inputs: inputs})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Repo < out[j].Repo })
+	return out
+}
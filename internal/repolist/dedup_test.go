@@ -0,0 +1,40 @@
+package repolist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	type add struct {
+		input string
+		entry Entry
+	}
+	tests := []struct {
+		name         string
+		adds         []add
+		wantDups     []string
+		wantOverlaps []Overlap
+	}{
+		{"distinct", []add{{"a.txt", Entry{Name: "acme/app", Line: 1}}, {"b.txt", Entry{Name: "acme/web", Line: 1}}}, []string{"", ""}, []Overlap{}},
+		{"across inputs", []add{{"a.txt", Entry{Name: "acme/app", Line: 3}}, {"b.txt", Entry{Name: "acme/app", Line: 7}}},
+			[]string{"", "a.txt:3"}, []Overlap{{Repo: "acme/app", Inputs: []string{"a.txt:3", "b.txt:7"}}}},
+		{"stdin without lines", []add{{"a.txt", Entry{Name: "acme/app", Line: 1}}, {"-", Entry{Name: "acme/app"}}, {"-", Entry{Name: "acme/app"}}},
+			[]string{"", "a.txt:1", "a.txt:1"}, []Overlap{{Repo: "acme/app", Inputs: []string{"a.txt:1", "-", "-"}}}},
+		{"other path", []add{{"a.txt", Entry{Name: "acme/app", Line: 1}}, {"a.txt", Entry{Name: "acme/app", Path: "pkg/a", Line: 2}}}, []string{"", ""}, []Overlap{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDedup()
+			for i, a := range tt.adds {
+				first, dup := d.Add(a.input, a.entry)
+				if first != tt.wantDups[i] || dup != (tt.wantDups[i] != "") {
+					t.Errorf("entry %d: first %q, dup %v, want %q", i, first, dup, tt.wantDups[i])
+				}
+			}
+			if got := d.Overlaps(); !reflect.DeepEqual(got, tt.wantOverlaps) {
+				t.Errorf("overlaps %+v, want %+v", got, tt.wantOverlaps)
+			}
+		})
+	}
+}
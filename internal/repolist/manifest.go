@@ -0,0 +1,151 @@
+package repolist
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ProviderGitHub = "github"
+
+// Providers are the repo hosts a manifest may name.
+var Providers = []string{ProviderGitHub}
+
+// manifestEntry is one repo of a JSON or YAML manifest. An entry may also be
+// a plain "owner/repo" string.
+type manifestEntry struct {
+	Repo        string            `yaml:"repo"`
+	Provider    string            `yaml:"provider"`
+	Ref         string            `yaml:"ref"`
+	Subdirs     []string          `yaml:"subdirs"`
+	Exclude     []string          `yaml:"exclude"`
+	Labels      map[string]string `yaml:"labels"`
+	Weight      float64           `yaml:"weight"`
+	Credentials string            `yaml:"credentials"`
+}
+
+var manifestFields = map[string]bool{
+	"repo": true, "provider": true, "ref": true, "subdirs": true, "exclude": true,
+	"labels": true, "weight": true, "credentials": true,
+}
+
+// readManifest reads a YAML or JSON manifest: a list of entries, either at
+// the top level or under a "repos" key. Every subdirectory of an entry is
+// emitted as an entry of its own.
+func readManifest(r io.Reader, emit func(Entry) error, problem func(int, error) error) error {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	list := doc.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = nil
+		for i := 0; i+1 < len(doc.Content[0].Content); i += 2 {
+			if doc.Content[0].Content[i].Value == "repos" {
+				list = doc.Content[0].Content[i+1]
+			}
+		}
+		if list == nil {
+			return fmt.Errorf("manifest has no repos list")
+		}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: expected a list of repos", list.Line)
+	}
+
+	for _, item := range list.Content {
+		entries, err := manifestEntries(item)
+		if err != nil {
+			if err := problem(item.Line, err); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, e := range entries {
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// manifestEntries decodes and checks one manifest item.
+func manifestEntries(item *yaml.Node) ([]Entry, error) {
+	var m manifestEntry
+	switch item.Kind {
+	case yaml.ScalarNode:
+		m.Repo = item.Value
+	case yaml.MappingNode:
+		for i := 0; i < len(item.Content); i += 2 {
+			if key := item.Content[i].Value; !manifestFields[key] {
+				return nil, fmt.Errorf("unknown field %q", key)
+			}
+		}
+		if err := item.Decode(&m); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected a repo name or mapping")
+	}
+
+	if m.Repo == "" {
+		return nil, fmt.Errorf("missing repo")
+	}
+	if m.Provider != "" && !knownProvider(m.Provider) {
+		return nil, fmt.Errorf("unknown provider %q", m.Provider)
+	}
+	if m.Weight < 0 {
+		return nil, fmt.Errorf("negative weight %v", m.Weight)
+	}
+
+	name, dir := SplitPath(m.Repo)
+	if dir != "" && len(m.Subdirs) > 0 {
+		return nil, fmt.Errorf("repo %q names a subdirectory and subdirs are given", m.Repo)
+	}
+	base := Entry{
+		Name:        name,
+		Path:        dir,
+		Weight:      m.Weight,
+		Provider:    m.Provider,
+		Ref:         m.Ref,
+		Labels:      m.Labels,
+		Credentials: m.Credentials,
+		Line:        item.Line,
+	}
+	dirs := m.Subdirs
+	if len(dirs) == 0 {
+		dirs = []string{base.Path}
+	}
+	out := make([]Entry, 0, len(dirs))
+	for _, dir := range dirs {
+		e := base
+		if len(m.Subdirs) > 0 {
+			var ok bool
+			if e.Path, ok = cleanDir(dir); !ok {
+				return nil, fmt.Errorf("invalid subdirectory %q", dir)
+			}
+		}
+		if len(m.Exclude) > 0 {
+			var err error
+			if e.Exclude, err = excludeDirs(&e, m.Exclude); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func knownProvider(p string) bool {
+	for _, known := range Providers {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package repolist
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// IsURL reports whether a repos path is an http(s) URL.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Stdin is the repos path that reads the list from standard input.
+const Stdin = "-"
+
+// Open opens a repos list from a local path, an http(s) URL or Stdin.
+// header is an optional "Name: value" request header for URLs, e.g. an
+// Authorization header; a value without a name is sent as Authorization.
+func Open(ctx context.Context, client *http.Client, path, header string) (io.ReadCloser, error) {
+	if path == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+	if !IsURL(path) {
+		return os.Open(path)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header != "" {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.ContainsAny(name, " \t") {
+			name, value = "Authorization", header
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		// The URL may carry a token in its query, so only the status is shown.
+		return nil, fmt.Errorf("failed to fetch repos list (%s)", resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,363 @@
+package repolist
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// csvColumns are the header names tried, in order, when no column is given.
+// They cover the usual GH Archive / BigQuery export shapes.
+var csvColumns = []string{"repo_name", "repo.name", "full_name", "repo", "name", "repo_url", "repo.url", "url"}
+
+// Entry is one repository of a repos list. Weight is 0 unless the list
+// gives one; the other options come from manifests only.
+type Entry struct {
+	Name   string
+	Weight float64
+
+	// Provider hosts the repo; empty means GitHub.
+	Provider string
+	// Ref is the branch, tag or commit to scan instead of the most recently
+	// updated branch.
+	Ref string
+	// Path is the directory of the pubspec.yaml within the repo.
+	Path string
+	// Exclude are directories, relative to the repo root, whose manifests
+	// and sources are not scanned.
+	Exclude []string
+	// Labels are free-form key/value pairs carried into the results.
+	Labels map[string]string
+	// Credentials names the environment variable holding the token for
+	// this repo instead of GITHUB_TOKEN.
+	Credentials string
+
+	// Line is the line of the entry in the list, when known, and Input
+	// names the list when several are combined.
+	Line  int
+	Input string
+}
+
+// ID names the entry in results: the repo, followed by ":path" when a
+// subdirectory is scanned, e.g. owner/repo:apps/mobile.
+func (e Entry) ID() string {
+	if e.Path == "" {
+		return e.Name
+	}
+	return e.Name + ":" + e.Path
+}
+
+// Excludes reports whether the repo path p is in an excluded directory.
+func (e Entry) Excludes(p string) bool {
+	for _, dir := range e.Exclude {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Options select the CSV columns to read. Empty Column auto-detects the
+// repository column; empty WeightColumn reads no weights.
+type Options struct {
+	Column       string
+	WeightColumn string
+}
+
+// DetectFormat picks the input format from the file extension unless one is
+// given explicitly.
+func DetectFormat(path, format string) string {
+	if format != "" {
+		return format
+	}
+	if IsURL(path) {
+		path, _, _ = strings.Cut(path, "?")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatText
+}
+
+// Read streams repository entries from r and calls emit for each one.
+// Reading stops at the first error returned by emit.
+func Read(r io.Reader, format string, opts Options, emit func(Entry) error) error {
+	return read(r, format, opts, emit, func(line int, err error) error {
+		return fmt.Errorf("line %d: %w", line, err)
+	})
+}
+
+// read is Read with malformed entries passed to problem, which either
+// returns an error to stop reading or nil to skip the entry.
+func read(r io.Reader, format string, opts Options, emit func(Entry) error, problem func(line int, err error) error) error {
+	switch format {
+	case FormatText:
+		return readText(r, emit, problem)
+	case FormatCSV:
+		return readCSV(r, opts, emit)
+	case FormatJSON, FormatYAML:
+		return readManifest(r, emit, problem)
+	default:
+		return fmt.Errorf("unknown repos format %q", format)
+	}
+}
+
+// readText reads whitespace-separated repositories, each optionally
+// followed by :dir to scan a subdirectory. weight=N and exclude=dir,...
+// tokens apply to the repository before them on the same line; # starts a
+// comment.
+func readText(r io.Reader, emit func(Entry) error, problem func(int, error) error) error {
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		var pending *Entry
+		for _, tok := range strings.Fields(stripComment(sc.Text())) {
+			if v, ok := strings.CutPrefix(tok, "weight="); ok {
+				w, err := strconv.ParseFloat(v, 64)
+				if err != nil || w <= 0 || pending == nil {
+					if err := problem(line, fmt.Errorf("invalid weight %q", tok)); err != nil {
+						return err
+					}
+					continue
+				}
+				pending.Weight = w
+				continue
+			}
+			if v, ok := strings.CutPrefix(tok, "exclude="); ok {
+				dirs, err := excludeDirs(pending, strings.Split(v, ","))
+				if err != nil {
+					if err := problem(line, err); err != nil {
+						return err
+					}
+					continue
+				}
+				pending.Exclude = append(pending.Exclude, dirs...)
+				continue
+			}
+			if pending != nil {
+				if err := emit(*pending); err != nil {
+					return err
+				}
+			}
+			name, dir := SplitPath(tok)
+			pending = &Entry{Name: name, Path: dir, Line: line}
+		}
+		if pending != nil {
+			if err := emit(*pending); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+func readCSV(r io.Reader, opts Options, emit func(Entry) error) error {
+	cr := csv.NewReader(bufio.NewReaderSize(r, 1<<20))
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	idx := -1
+	candidates := csvColumns
+	if opts.Column != "" {
+		candidates = []string{opts.Column}
+	}
+	for _, want := range candidates {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), want) {
+				idx = i
+				break
+			}
+		}
+		if idx >= 0 {
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("no repository column found in CSV header (tried %s)", strings.Join(candidates, ", "))
+	}
+	weightIdx := -1
+	if opts.WeightColumn != "" {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), opts.WeightColumn) {
+				weightIdx = i
+			}
+		}
+		if weightIdx < 0 {
+			return fmt.Errorf("weight column %q not found in CSV header", opts.WeightColumn)
+		}
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if idx >= len(rec) {
+			continue
+		}
+		name, dir := SplitPath(rec[idx])
+		if name == "" {
+			continue
+		}
+		line, _ := cr.FieldPos(idx)
+		e := Entry{Name: name, Path: dir, Line: line}
+		if weightIdx >= 0 && weightIdx < len(rec) {
+			if w, err := strconv.ParseFloat(strings.TrimSpace(rec[weightIdx]), 64); err == nil && w > 0 {
+				e.Weight = w
+			}
+		}
+		if err := emit(e); err != nil {
+			return err
+		}
+	}
+}
+
+// repoURLPrefixes are the URL forms found in exports and copied from the
+// browser, such as https://github.com/owner/repo or
+// https://api.github.com/repos/owner/repo.
+var repoURLPrefixes = []string{"https://api.github.com/repos/", "https://github.com/", "http://github.com/",
+	"https://www.github.com/", "ssh://git@github.com/", "git@github.com:", "github.com/"}
+
+// Normalize reduces the ways a repo is written in lists to owner/repo: URL
+// forms, a .git suffix and trailing slashes are removed, and the name is
+// lowercased, as GitHub names are case-insensitive, so one repo written
+// differently is one entry. Other values are returned for validation to
+// reject.
+func Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	for _, prefix := range repoURLPrefixes {
+		if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			s = s[len(prefix):]
+			if i := strings.IndexAny(s, "?#"); i >= 0 {
+				s = s[:i]
+			}
+			break
+		}
+	}
+	s = strings.TrimRight(s, "/")
+	s = strings.TrimRight(strings.TrimSuffix(strings.ToLower(s), ".git"), "/")
+	return s
+}
+
+// SplitPath splits a repo written with a subdirectory, such as
+// owner/repo:apps/mobile or its URL form, into the normalized repo and the
+// directory. The directory is "" when none is given.
+func SplitPath(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	rest := s
+	for _, prefix := range repoURLPrefixes {
+		if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			rest = s[len(prefix):]
+			break
+		}
+	}
+	i := strings.Index(rest, ":")
+	if i < 0 {
+		return Normalize(s), ""
+	}
+	dir, _ := cleanDir(rest[i+1:])
+	return Normalize(s[:len(s)-len(rest)+i]), dir
+}
+
+// cleanDir cleans a directory of a repo to a path relative to its root,
+// reporting false when nothing is left.
+func cleanDir(dir string) (string, bool) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	return dir, dir != ""
+}
+
+// excludeDirs resolves the excluded directories of an entry, given
+// relative to its path.
+func excludeDirs(e *Entry, dirs []string) ([]string, error) {
+	if e == nil {
+		return nil, fmt.Errorf("exclude without a repo")
+	}
+	out := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		dir, ok := cleanDir(path.Join(e.Path, d))
+		if !ok || dir == e.Path || strings.TrimSpace(d) == "" {
+			return nil, fmt.Errorf("invalid excluded directory %q", d)
+		}
+		out = append(out, dir)
+	}
+	return out, nil
+}
+
+// stripComment cuts a # comment, at the start of a line or after
+// whitespace, off a line of a text list.
+func stripComment(line string) string {
+	for i, c := range line {
+		if c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// --- Sharding ---
+
+// Shard selects a stable subset of repositories so several workers can split
+// one scan. Index is 1-based.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses the K/N form, e.g. "3/10".
+func ParseShard(s string) (Shard, error) {
+	k, n, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected K/N", s)
+	}
+	index, err1 := strconv.Atoi(strings.TrimSpace(k))
+	count, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected K/N with 1 <= K <= N", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Includes reports whether repo belongs to the shard. Assignment hashes the
+// lowercased name, so it does not depend on input order.
+func (s Shard) Includes(repo string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(repo)))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
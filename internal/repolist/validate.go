@@ -0,0 +1,59 @@
+package repolist
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+// Problem is an issue found in a repos list.
+type Problem struct {
+	Line    int    `json:"line,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// namePattern matches the owner/repo names GitHub allows.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9-]+/[A-Za-z0-9._-]+$`)
+
+// Validate reads a whole repos list and reports every malformed entry and
+// duplicate instead of stopping at the first one. It returns the valid
+// entries, without duplicates, for further checks. The error is set when the
+// list cannot be read at all.
+func Validate(r io.Reader, format string, opts Options) ([]Entry, []Problem, error) {
+	var (
+		entries  []Entry
+		problems []Problem
+		seen     = map[string]int{}
+	)
+	err := read(r, format, opts, func(e Entry) error {
+		switch line, dup := seen[e.ID()]; {
+		case !namePattern.MatchString(e.Name):
+			problems = append(problems, Problem{Line: e.Line, Repo: e.ID(), Message: fmt.Sprintf("invalid repo %q: expected owner/repo", e.Name)})
+		case dup:
+			problems = append(problems, Problem{Line: e.Line, Repo: e.ID(), Message: fmt.Sprintf("duplicate of %s on line %d", e.ID(), line)})
+		default:
+			seen[e.ID()] = e.Line
+			entries = append(entries, e)
+		}
+		return nil
+	}, func(line int, err error) error {
+		problems = append(problems, Problem{Line: line, Message: err.Error()})
+		return nil
+	})
+	SortProblems(problems)
+	return entries, problems, err
+}
+
+// SortProblems orders problems by line.
+func SortProblems(list []Problem) {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Line < list[j].Line })
+}
@@ -0,0 +1,174 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/enrich"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Weights are multiplied with the number of occurrences of each signal.
+type Weights struct {
+	Vulnerable   float64 `json:"vulnerable"`
+	Discontinued float64 `json:"discontinued"`
+	Unbounded    float64 `json:"unbounded"`
+	Stale        float64 `json:"stale"`
+	Overrides    float64 `json:"overrides"`
+}
+
+var DefaultWeights = Weights{Vulnerable: 10, Discontinued: 5, Unbounded: 2, Stale: 1, Overrides: 1}
+
+// Vuln is a dependency whose checked version has advisories.
+type Vuln struct {
+	Package    string   `json:"package"`
+	Version    string   `json:"version"`
+	Advisories []string `json:"advisories"`
+}
+
+type Signals struct {
+	Vulnerable   []Vuln   `json:"vulnerable,omitempty"`
+	Discontinued []string `json:"discontinued,omitempty"`
+	Unbounded    []string `json:"unbounded,omitempty"`
+	Stale        []string `json:"stale,omitempty"`
+	Overrides    int      `json:"overrides,omitempty"`
+}
+
+type RepoRisk struct {
+	Repo      string  `json:"repo"`
+	Ecosystem string  `json:"ecosystem,omitempty"`
+	Score     float64 `json:"score"`
+	Signals   Signals `json:"signals"`
+}
+
+// Input is the enrichment data the signals are computed from. Packages
+// holds the pub.dev data of pub packages by name, Vulns the advisories by
+// versioned package URL.
+type Input struct {
+	Packages   map[string]*enrich.PackageInfo
+	Vulns      map[string][]string
+	StaleAfter time.Duration
+	Now        time.Time
+}
+
+// --- Core logic ---
+
+// ParseWeights reads "name=value" pairs separated by commas, starting from
+// the defaults, e.g. "vulnerable=20,stale=0".
+func ParseWeights(s string) (Weights, error) {
+	w := DefaultWeights
+	if strings.TrimSpace(s) == "" {
+		return w, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if !ok || err != nil {
+			return w, fmt.Errorf("invalid risk weight %q", pair)
+		}
+		switch strings.TrimSpace(k) {
+		case "vulnerable":
+			w.Vulnerable = f
+		case "discontinued":
+			w.Discontinued = f
+		case "unbounded":
+			w.Unbounded = f
+		case "stale":
+			w.Stale = f
+		case "overrides":
+			w.Overrides = f
+		default:
+			return w, fmt.Errorf("unknown risk signal %q", k)
+		}
+	}
+	return w, nil
+}
+
+// LowerBound returns the lowest version a hosted constraint allows. It is
+// the version vulnerabilities are checked against when no lockfile is known.
+func LowerBound(constraint string) (string, bool) {
+	if strings.Contains(constraint, ":") {
+		return "", false
+	}
+	rng, err := semver.ParseConstraint(constraint)
+	if err != nil || rng.Min == nil {
+		return "", false
+	}
+	return rng.Min.String(), true
+}
+
+// Unbounded returns the sorted hosted dependencies of r whose constraint has
+// no upper bound.
+func Unbounded(r stats.RepoResult) []string {
+	var out []string
+	for name, c := range r.Constraints {
+		if strings.Contains(c, ":") {
+			continue
+		}
+		if rng, err := semver.ParseConstraint(c); err == nil && rng.Unbounded() {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Assess computes the signals and score of one repo.
+func Assess(r stats.RepoResult, in Input, w Weights) RepoRisk {
+	sig := Signals{Unbounded: Unbounded(r)}
+	names := make([]string, 0, len(r.Constraints))
+	for name := range r.Constraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := r.Constraints[name]
+		if strings.Contains(c, ":") {
+			continue
+		}
+		if v, ok := LowerBound(c); ok {
+			if ids := in.Vulns[purl.Versioned(purl.For(r.Ecosystem, name), v)]; len(ids) > 0 {
+				sig.Vulnerable = append(sig.Vulnerable, Vuln{Package: name, Version: v, Advisories: ids})
+			}
+		}
+		if purl.Type(r.Ecosystem) != purl.Pub {
+			continue
+		}
+		info := in.Packages[name]
+		if info == nil || info.NotFound {
+			continue
+		}
+		if info.Discontinued {
+			sig.Discontinued = append(sig.Discontinued, name)
+		}
+		if in.StaleAfter > 0 && !info.Published.IsZero() && in.Now.Sub(info.Published) > in.StaleAfter {
+			sig.Stale = append(sig.Stale, name)
+		}
+	}
+	sig.Overrides = len(r.DependencyOverrides)
+
+	score := w.Vulnerable*float64(len(sig.Vulnerable)) +
+		w.Discontinued*float64(len(sig.Discontinued)) +
+		w.Unbounded*float64(len(sig.Unbounded)) +
+		w.Stale*float64(len(sig.Stale)) +
+		w.Overrides*float64(sig.Overrides)
+	return RepoRisk{Repo: r.Repo, Ecosystem: r.Ecosystem, Score: score, Signals: sig}
+}
+
+// Rank sorts repos by descending score.
+func Rank(list []RepoRisk) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Repo < list[j].Repo
+	})
+}
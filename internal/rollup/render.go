@@ -0,0 +1,150 @@
+package rollup
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Formats are the output formats of Render.
+var Formats = []string{"md", "html"}
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+	"signed": func(n int) string {
+		if n > 0 {
+			return fmt.Sprintf("+%d", n)
+		}
+		return fmt.Sprint(n)
+	},
+	"score": func(f float64) string { return fmt.Sprintf("%.1f", f) },
+	"join":  strings.Join,
+}
+
+const markdownTemplate = `# Dependency Rollup {{.Quarter}}
+
+{{.Repos}} repositories, {{.Scans}} scans this quarter, compared from {{date .From}} to {{date .To}}.
+
+## New Packages Adopted
+{{if .Adopted}}
+| Package | Repos |
+|---|---:|
+{{range .Adopted}}| {{.Name}} | {{.Repos}} |
+{{end}}{{else}}
+None.
+{{end}}
+## Retired Packages
+{{if .Retired}}
+| Package | Repos before |
+|---|---:|
+{{range .Retired}}| {{.Name}} | {{.Repos}} |
+{{end}}{{else}}
+None.
+{{end}}
+## Vulnerability Trend
+{{if .RiskKnown}}
+| Scan | Vulnerable dependencies | Repos | Advisories |
+|---|---:|---:|---:|
+{{range .Vulns}}| {{date .Date}} | {{.Dependencies}} | {{.Repos}} | {{.Advisories}} |
+{{end}}
+Change over the quarter: {{signed .VulnChange}} vulnerable dependencies.
+{{else}}
+No risk data; scan with --risk to track vulnerabilities.
+{{end}}
+## Top Risk Repositories
+{{if .TopRisk}}
+| Repo | Score | Vulnerable | Discontinued | Stale |
+|---|---:|---:|---:|---:|
+{{range .TopRisk}}| {{.Repo}} | {{score .Score}} | {{len .Signals.Vulnerable}} | {{len .Signals.Discontinued}} | {{len .Signals.Stale}} |
+{{end}}{{else}}
+None.
+{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Dependency Rollup {{.Quarter}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; }
+td.n { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Dependency Rollup {{.Quarter}}</h1>
+<p>{{.Repos}} repositories, {{.Scans}} scans this quarter, compared from {{date .From}} to {{date .To}}.</p>
+
+<h2>New Packages Adopted</h2>
+{{if .Adopted}}<table>
+<tr><th>Package</th><th>Repos</th></tr>
+{{range .Adopted}}<tr><td>{{.Name}}</td><td class="n">{{.Repos}}</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>
+{{end}}
+<h2>Retired Packages</h2>
+{{if .Retired}}<table>
+<tr><th>Package</th><th>Repos before</th></tr>
+{{range .Retired}}<tr><td>{{.Name}}</td><td class="n">{{.Repos}}</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>
+{{end}}
+<h2>Vulnerability Trend</h2>
+{{if .RiskKnown}}<table>
+<tr><th>Scan</th><th>Vulnerable dependencies</th><th>Repos</th><th>Advisories</th></tr>
+{{range .Vulns}}<tr><td>{{date .Date}}</td><td class="n">{{.Dependencies}}</td><td class="n">{{.Repos}}</td><td class="n">{{.Advisories}}</td></tr>
+{{end}}</table>
+<p>Change over the quarter: {{signed .VulnChange}} vulnerable dependencies.</p>
+{{else}}<p>No risk data; scan with --risk to track vulnerabilities.</p>
+{{end}}
+<h2>Top Risk Repositories</h2>
+{{if .TopRisk}}<table>
+<tr><th>Repo</th><th>Score</th><th>Vulnerable</th><th>Discontinued</th><th>Stale</th></tr>
+{{range .TopRisk}}<tr><td>{{.Repo}}</td><td class="n">{{score .Score}}</td><td class="n">{{len .Signals.Vulnerable}}</td><td class="n">{{len .Signals.Discontinued}}</td><td class="n">{{len .Signals.Stale}}</td></tr>
+{{end}}</table>
+{{else}}<p>None.</p>
+{{end}}</body>
+</html>
+`
+
+// executor is what text and html templates have in common.
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// Render writes r in format, "md" or "html", with the built-in template or
+// the one at tmplPath. Templates ending in .html or .htm are parsed as
+// HTML templates, so values are escaped; others as text.
+func Render(w io.Writer, r Rollup, format, tmplPath string) error {
+	var t executor
+	var err error
+	switch {
+	case tmplPath != "":
+		data, rerr := os.ReadFile(tmplPath)
+		if rerr != nil {
+			return rerr
+		}
+		name := filepath.Base(tmplPath)
+		if ext := strings.ToLower(filepath.Ext(tmplPath)); ext == ".html" || ext == ".htm" {
+			t, err = htmltemplate.New(name).Funcs(funcs).Parse(string(data))
+		} else {
+			t, err = template.New(name).Funcs(funcs).Parse(string(data))
+		}
+	case format == "md":
+		t, err = template.New("md").Funcs(funcs).Parse(markdownTemplate)
+	case format == "html":
+		t, err = htmltemplate.New("html").Funcs(funcs).Parse(htmlTemplate)
+	default:
+		return fmt.Errorf("unknown format %q: want %s", format, strings.Join(Formats, " or "))
+	}
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, r)
+}
@@ -0,0 +1,178 @@
+package rollup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Snapshot is what a rollup needs from one stored report: when it was
+// scanned, the packages in use and the risk of every repo.
+type Snapshot struct {
+	Date     time.Time
+	Repos    int
+	Packages []stats.PackageStat
+	Risk     []risk.RepoRisk
+}
+
+// PackageChange is a package adopted or retired during the quarter, with
+// the number of repos using it at the end or at the start.
+type PackageChange struct {
+	Name  string `json:"name"`
+	PURL  string `json:"purl,omitempty"`
+	Repos int    `json:"repos"`
+}
+
+// VulnPoint counts the vulnerable dependencies of one scan.
+type VulnPoint struct {
+	Date         time.Time `json:"date"`
+	Dependencies int       `json:"dependencies"`
+	Repos        int       `json:"repos"`
+	Advisories   int       `json:"advisories"`
+}
+
+// Rollup summarizes a quarter of stored reports: the last scan of the
+// quarter compared with the one it started from.
+type Rollup struct {
+	Quarter   string          `json:"quarter"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Scans     int             `json:"scans"`
+	Repos     int             `json:"repos"`
+	Adopted   []PackageChange `json:"adopted"`
+	Retired   []PackageChange `json:"retired"`
+	Vulns     []VulnPoint     `json:"vulnerability_trend"`
+	TopRisk   []risk.RepoRisk `json:"top_risk"`
+	RiskKnown bool            `json:"risk_known"`
+}
+
+// --- Core logic ---
+
+// ParseQuarter reads "2026Q3" or "2026-Q3" and returns the first day of
+// the quarter and of the next one, in UTC.
+func ParseQuarter(s string) (time.Time, time.Time, error) {
+	year, q, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(s)), "Q")
+	year = strings.TrimSuffix(year, "-")
+	y, yerr := strconv.Atoi(year)
+	n, qerr := strconv.Atoi(q)
+	if !ok || yerr != nil || qerr != nil || n < 1 || n > 4 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid quarter %q: want e.g. 2026Q3", s)
+	}
+	start := time.Date(y, time.Month(3*(n-1)+1), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 3, 0), nil
+}
+
+// QuarterOf names the quarter t falls in, e.g. "2026Q3".
+func QuarterOf(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%dQ%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+// Build rolls up the snapshots of quarter. The quarter is compared against
+// the last snapshot before it, or its first one when there is none, and
+// lists up to top repos by risk.
+func Build(snaps []Snapshot, quarter string, top int) (Rollup, error) {
+	start, end, err := ParseQuarter(quarter)
+	if err != nil {
+		return Rollup{}, err
+	}
+	sorted := append([]Snapshot(nil), snaps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var before *Snapshot
+	var in []Snapshot
+	for i, s := range sorted {
+		switch {
+		case s.Date.Before(start):
+			before = &sorted[i]
+		case s.Date.Before(end):
+			in = append(in, s)
+		}
+	}
+	if len(in) == 0 {
+		return Rollup{}, fmt.Errorf("no reports from %s", QuarterOf(start))
+	}
+	series := in
+	if before != nil {
+		series = append([]Snapshot{*before}, in...)
+	}
+	first, last := series[0], series[len(series)-1]
+
+	r := Rollup{
+		Quarter: QuarterOf(start),
+		From:    first.Date,
+		To:      last.Date,
+		Scans:   len(in),
+		Repos:   last.Repos,
+		Adopted: changes(last.Packages, first.Packages),
+		Retired: changes(first.Packages, last.Packages),
+	}
+	for _, s := range series {
+		r.RiskKnown = r.RiskKnown || s.Risk != nil
+		r.Vulns = append(r.Vulns, vulnPoint(s))
+	}
+	ranked := append([]risk.RepoRisk(nil), last.Risk...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	for _, rr := range ranked {
+		if len(r.TopRisk) == top || rr.Score <= 0 {
+			break
+		}
+		r.TopRisk = append(r.TopRisk, rr)
+	}
+	return r, nil
+}
+
+// VulnChange is the change in vulnerable dependencies over the quarter.
+func (r Rollup) VulnChange() int {
+	if len(r.Vulns) == 0 {
+		return 0
+	}
+	return r.Vulns[len(r.Vulns)-1].Dependencies - r.Vulns[0].Dependencies
+}
+
+// changes lists the packages of a that are missing in b, most used first.
+func changes(a, b []stats.PackageStat) []PackageChange {
+	have := map[string]bool{}
+	for _, p := range b {
+		have[p.Key()] = true
+	}
+	out := []PackageChange{}
+	for _, p := range a {
+		if !have[p.Key()] {
+			out = append(out, PackageChange{Name: p.Name, PURL: p.PURL, Repos: p.Count})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Repos != out[j].Repos {
+			return out[i].Repos > out[j].Repos
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func vulnPoint(s Snapshot) VulnPoint {
+	p := VulnPoint{Date: s.Date}
+	advisories := map[string]bool{}
+	for _, rr := range s.Risk {
+		if len(rr.Signals.Vulnerable) == 0 {
+			continue
+		}
+		p.Repos++
+		p.Dependencies += len(rr.Signals.Vulnerable)
+		for _, v := range rr.Signals.Vulnerable {
+			for _, a := range v.Advisories {
+				advisories[a] = true
+			}
+		}
+	}
+	p.Advisories = len(advisories)
+	return p
+}
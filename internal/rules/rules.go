@@ -0,0 +1,225 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/cel"
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Rule is a governance rule written as a CEL expression over the per-repo
+// model. Expr must hold for every repo; When limits the repos it applies
+// to. Package attributes the finding to a package, if any.
+type Rule struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+	Severity    string `yaml:"severity"`
+	When        string `yaml:"when"`
+	Expr        string `yaml:"expr"`
+	Package     string `yaml:"package"`
+	Remediation string `yaml:"remediation"`
+
+	when *cel.Program
+	expr *cel.Program
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Set is a loaded rules file. Evaluation errors are counted per rule
+// rather than failing the scan, since a rule may not fit every repo.
+type Set struct {
+	Rules []Rule
+
+	mu     sync.Mutex
+	errors map[string]*EvalError
+}
+
+// EvalError is the first error of a rule and the number of repos it
+// failed on.
+type EvalError struct {
+	Rule  string
+	Repo  string
+	Err   error
+	Repos int
+}
+
+// --- Core logic ---
+
+// Load reads and compiles a rules file.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rf rulesFile
+	if err := yaml.Unmarshal(data, &rf); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for i := range rf.Rules {
+		r := &rf.Rules[i]
+		if r.ID == "" || r.Expr == "" {
+			return nil, fmt.Errorf("rule %d: id and expr are required", i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", r.ID)
+		}
+		seen[r.ID] = true
+		if r.Severity == "" {
+			r.Severity = findings.SeverityMedium
+		}
+		if !findings.ValidSeverity(r.Severity) {
+			return nil, fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+		}
+		if r.expr, err = compile(r.Expr); err != nil {
+			return nil, fmt.Errorf("rule %s: expr: %v", r.ID, err)
+		}
+		if r.When != "" {
+			if r.when, err = compile(r.When); err != nil {
+				return nil, fmt.Errorf("rule %s: when: %v", r.ID, err)
+			}
+		}
+	}
+	return &Set{Rules: rf.Rules, errors: map[string]*EvalError{}}, nil
+}
+
+// compile compiles an expression and checks that it only refers to the
+// variables of the Model, so a misspelled name fails the load instead of
+// every evaluation.
+func compile(src string) (*cel.Program, error) {
+	p, err := cel.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	declared := Model(stats.RepoResult{})
+	for _, name := range p.Variables() {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("undeclared reference to %q", name)
+		}
+	}
+	return p, nil
+}
+
+// Model is the per-repo dependency model rules are evaluated against.
+// Every field is present, so rules need no has() checks.
+func Model(r stats.RepoResult) map[string]interface{} {
+	list := func(items []string) []interface{} {
+		out := make([]interface{}, len(items))
+		for i, s := range items {
+			out[i] = s
+		}
+		return out
+	}
+	dict := func(m map[string]string) map[string]interface{} {
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[k] = v
+		}
+		return out
+	}
+	ecosystem := r.Ecosystem
+	if ecosystem == "" {
+		ecosystem = "pub"
+	}
+	return map[string]interface{}{
+		"repo":                 r.Repo,
+		"owner":                strings.SplitN(r.Repo, "/", 2)[0],
+		"branch":               r.Branch,
+		"ecosystem":            ecosystem,
+		"owners":               list(r.Owners),
+		"package":              r.Package,
+		"version":              r.Version,
+		"publish_to":           r.PublishTo,
+		"dependencies":         list(r.Dependencies),
+		"dev_dependencies":     list(r.DevDependencies),
+		"dependency_overrides": list(r.DependencyOverrides),
+		"constraints":          dict(r.Constraints),
+		"environment":          dict(r.Environment),
+		"locked":               dict(r.Locked),
+		"lock_missing":         r.LockMissing,
+		"locked_sha256":        dict(r.LockedSHA256),
+		"flutter_pin":          r.FlutterPin,
+	}
+}
+
+// Check evaluates every rule against r and returns a finding for each rule
+// it breaks. It is a findings.RepoCheck. Repos that failed to scan are not
+// checked.
+func (s *Set) Check(r stats.RepoResult) []findings.Finding {
+	if r.Error != "" {
+		return nil
+	}
+	model := Model(r)
+	var out []findings.Finding
+	for _, rule := range s.Rules {
+		if rule.when != nil {
+			applies, err := rule.when.EvalBool(model)
+			if err != nil {
+				s.fail(rule.ID, r.Repo, err)
+				continue
+			}
+			if !applies {
+				continue
+			}
+		}
+		ok, err := rule.expr.EvalBool(model)
+		if err != nil {
+			s.fail(rule.ID, r.Repo, err)
+			continue
+		}
+		if ok {
+			continue
+		}
+		msg := rule.Description
+		if msg == "" {
+			msg = "violates " + rule.ID
+		}
+		out = append(out, findings.Finding{
+			Rule:        findings.CustomRule(rule.ID),
+			Severity:    rule.Severity,
+			Repo:        r.Repo,
+			Package:     rule.Package,
+			Message:     msg,
+			Evidence:    "expr is false: " + rule.Expr,
+			Remediation: rule.Remediation,
+		})
+	}
+	for i := range out {
+		out[i].ID = out[i].Fingerprint()
+	}
+	return out
+}
+
+func (s *Set) fail(rule, repo string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.errors[rule]
+	if e == nil {
+		e = &EvalError{Rule: rule, Repo: repo, Err: err}
+		s.errors[rule] = e
+	}
+	e.Repos++
+}
+
+// Errors returns the evaluation errors by rule.
+func (s *Set) Errors() []EvalError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]EvalError, 0, len(s.errors))
+	for _, e := range s.errors {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Rule < out[j].Rule })
+	return out
+}
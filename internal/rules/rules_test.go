@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pgithub.com/plasmatrip/pubscan/internal/findings"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr string
+	}{
+		{"valid", "rules:\n  - id: no-analytics\n    when: ecosystem == 'pub'\n    expr: '!(\"firebase_analytics\" in dependencies)'\n", 1, ""},
+		{"macro variable", "rules:\n  - id: pinned\n    expr: dependencies.all(d, d in constraints)\n", 1, ""},
+		{"no rules", "rules: []\n", 0, ""},
+		{"no id", "rules:\n  - expr: 'true'\n", 0, "rule 1: id and expr are required"},
+		{"no expr", "rules:\n  - id: empty\n", 0, "rule 1: id and expr are required"},
+		{"duplicate id", "rules:\n  - id: a\n    expr: 'true'\n  - id: a\n    expr: 'false'\n", 0, "rule a: duplicate id"},
+		{"unknown severity", "rules:\n  - id: a\n    severity: fatal\n    expr: 'true'\n", 0, `rule a: unknown severity "fatal"`},
+		{"syntax error", "rules:\n  - id: a\n    expr: 'size(dependencies) >'\n", 0, "rule a: expr: unexpected end of expression"},
+		{"misspelled variable", "rules:\n  - id: a\n    expr: dependncies.size() > 0\n", 0, `rule a: expr: undeclared reference to "dependncies"`},
+		{"misspelled in when", "rules:\n  - id: a\n    when: ecosytem == 'pub'\n    expr: 'true'\n", 0, `rule a: when: undeclared reference to "ecosytem"`},
+		{"not yaml", "rules: [\n", 0, "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			set, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(set.Rules) != tt.want {
+				t.Errorf("%d rules, want %d", len(set.Rules), tt.want)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `rules:
+  - id: no-analytics
+    severity: high
+    description: analytics are not allowed
+    expr: '!("firebase_analytics" in dependencies)'
+  - id: apps-only
+    when: publish_to == 'none'
+    expr: size(dependency_overrides) == 0
+  - id: locked
+    expr: locked.http == '1.2.0'
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		repo       stats.RepoResult
+		want       []string
+		wantErrors int
+	}{
+		{"passes", stats.RepoResult{Repo: "acme/app", Locked: map[string]string{"http": "1.2.0"}}, nil, 0},
+		{"breaks rules", stats.RepoResult{Repo: "acme/app", PublishTo: "none", Dependencies: []string{"firebase_analytics"},
+			DependencyOverrides: []string{"meta"}, Locked: map[string]string{"http": "1.2.0"}},
+			[]string{findings.CustomRule("no-analytics"), findings.CustomRule("apps-only")}, 0},
+		{"evaluation error", stats.RepoResult{Repo: "acme/app"}, nil, 1},
+		{"failed scan", stats.RepoResult{Repo: "acme/app", Error: "not found", Dependencies: []string{"firebase_analytics"}}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set.errors = map[string]*EvalError{}
+			var got []string
+			for _, f := range set.Check(tt.repo) {
+				got = append(got, f.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("findings %v, want %v", got, tt.want)
+			}
+			if errs := set.Errors(); len(errs) != tt.wantErrors {
+				t.Errorf("errors %+v, want %d", errs, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+	"pgithub.com/plasmatrip/pubscan/internal/risk"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// BOM is a CycloneDX 1.5 document.
+type BOM struct {
+	BOMFormat    string      `json:"bomFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	SerialNumber string      `json:"serialNumber"`
+	Version      int         `json:"version"`
+	Metadata     Metadata    `json:"metadata"`
+	Components   []Component `json:"components"`
+}
+
+type Metadata struct {
+	Timestamp string    `json:"timestamp"`
+	Tools     []Tool    `json:"tools"`
+	Component Component `json:"component"`
+}
+
+type Tool struct {
+	Name string `json:"name"`
+}
+
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Component struct {
+	Type       string     `json:"type"`
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	PURL       string     `json:"purl,omitempty"`
+	Scope      string     `json:"scope,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// --- Core logic ---
+
+// Build describes a repo and its declared dependencies. Without a lockfile
+// the version of a component is the lowest version its constraint allows;
+// the constraint itself is kept as the pubscan:constraint property. Dev
+// dependencies are scoped "excluded" as they do not ship with the app.
+func Build(r stats.RepoResult, now time.Time) BOM {
+	bom := BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Tools:     []Tool{{Name: "pubscan"}},
+			Component: Component{Type: "application", Name: r.Repo, Version: r.Branch},
+		},
+		Components: []Component{},
+	}
+
+	add := func(names []string, scope string) {
+		for _, name := range names {
+			c := r.Constraints[name]
+			if strings.HasPrefix(c, "sdk:") {
+				continue
+			}
+			comp := Component{Type: "library", Name: name, Scope: scope}
+			comp.PURL = purl.For(r.Ecosystem, name)
+			if v, ok := risk.LowerBound(c); ok {
+				comp.Version = v
+				comp.PURL = purl.Versioned(comp.PURL, v)
+			}
+			comp.BOMRef = comp.PURL
+			if c != "" {
+				comp.Properties = []Property{{Name: "pubscan:constraint", Value: c}}
+			}
+			bom.Components = append(bom.Components, comp)
+		}
+	}
+	add(r.Dependencies, "required")
+	add(r.DevDependencies, "excluded")
+
+	// Native dependencies found with --native ship with the app as well.
+	for _, d := range r.Native {
+		comp := Component{Type: "library", Name: d.Name, Version: d.Version, Scope: "required"}
+		if d.Test {
+			comp.Scope = "excluded"
+		}
+		name, subspec, _ := strings.Cut(d.Name, "/")
+		switch d.Platform {
+		case "android":
+			comp.PURL = "pkg:maven/" + strings.Replace(d.Name, ":", "/", 1)
+		case "ios":
+			comp.PURL = "pkg:cocoapods/" + name
+		}
+		if d.Version != "" && !strings.ContainsAny(d.Version, " <>=~") {
+			comp.PURL += "@" + d.Version
+		}
+		if d.Platform == "ios" && subspec != "" {
+			comp.PURL += "#" + subspec
+		}
+		comp.BOMRef = comp.PURL
+		comp.Properties = []Property{{Name: "pubscan:platform", Value: d.Platform}}
+		bom.Components = append(bom.Components, comp)
+	}
+	return bom
+}
+
+func uuid() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// FileName is the SBOM file name for a repo, e.g. acme_app.cdx.json.
+func FileName(repo string) string {
+	return strings.ReplaceAll(repo, "/", "_") + ".cdx.json"
+}
@@ -0,0 +1,288 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- Structures ---
+
+// Version is a semantic version as used by pub.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+	Build               string
+}
+
+// Range is a version interval. A nil bound is open.
+type Range struct {
+	Min, Max               *Version
+	IncludeMin, IncludeMax bool
+}
+
+// --- Versions ---
+
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	var v Version
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build, s = s[i+1:], s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Pre, s = s[i+1:], s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1. Pre-release versions sort before the release;
+// build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return comparePre(v.Pre, o.Pre)
+}
+
+// comparePre orders pre-releases by their dot-separated identifiers as
+// semver section 11 does: numeric identifiers numerically and before
+// alphanumeric ones, and a shorter list of equal identifiers first, so
+// beta.2 < beta.10 < beta.rc.
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aNum := numeric(as[i])
+		bn, bNum := numeric(bs[i])
+		switch {
+		case aNum && bNum:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aNum:
+			return -1
+		case bNum:
+			return 1
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+// numeric returns the value of a pre-release identifier made of digits.
+func numeric(id string) (int, bool) {
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(id)
+	return n, err == nil
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// sameRelease reports whether v and o differ at most in pre-release and
+// build.
+func (v Version) sameRelease(o Version) bool {
+	return v.Major == o.Major && v.Minor == o.Minor && v.Patch == o.Patch
+}
+
+// MajorKey identifies a compatibility line: "2.x" for 2.3.1, and "0.13.x"
+// for 0.13.4 since pub treats minor bumps of 0.x versions as breaking.
+func (v Version) MajorKey() string {
+	if v.Major == 0 {
+		return fmt.Sprintf("0.%d.x", v.Minor)
+	}
+	return fmt.Sprintf("%d.x", v.Major)
+}
+
+// NextBreaking is the first version incompatible with v under caret rules.
+func (v Version) NextBreaking() Version {
+	if v.Major == 0 {
+		return Version{Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major + 1}
+}
+
+// --- Constraints ---
+
+// ParseConstraint parses a pub version constraint such as "^1.2.0",
+// ">=1.0.0 <3.0.0", "1.2.3" or "any".
+func ParseConstraint(s string) (Range, error) {
+	s = strings.Trim(strings.TrimSpace(s), `"'`)
+	if s == "" || s == "any" {
+		return Range{}, nil
+	}
+	if strings.HasPrefix(s, "^") {
+		v, err := ParseVersion(s[1:])
+		if err != nil {
+			return Range{}, err
+		}
+		next := v.NextBreaking()
+		return Range{Min: &v, IncludeMin: true, Max: &next}, nil
+	}
+
+	var r Range
+	fields := strings.Fields(s)
+	if len(fields) == 1 && !strings.ContainsAny(fields[0], "<>=") {
+		v, err := ParseVersion(fields[0])
+		if err != nil {
+			return Range{}, err
+		}
+		return Range{Min: &v, Max: &v, IncludeMin: true, IncludeMax: true}, nil
+	}
+	for _, f := range fields {
+		rest := strings.TrimLeft(f, "<>=")
+		op := f[:len(f)-len(rest)]
+		if rest == "" {
+			return Range{}, fmt.Errorf("invalid constraint %q", s)
+		}
+		v, err := ParseVersion(rest)
+		if err != nil {
+			return Range{}, err
+		}
+		switch op {
+		case ">=":
+			r.Min, r.IncludeMin = &v, true
+		case ">":
+			r.Min, r.IncludeMin = &v, false
+		case "<=":
+			r.Max, r.IncludeMax = &v, true
+		case "<":
+			r.Max, r.IncludeMax = &v, false
+		default:
+			return Range{}, fmt.Errorf("invalid constraint %q", s)
+		}
+	}
+	return r, nil
+}
+
+// Allows reports whether v satisfies the range. Like pub, an exclusive
+// upper bound that is a release also excludes its pre-releases, so ^1.0.0
+// does not allow 2.0.0-dev.1, unless the lower bound is a pre-release of
+// that same release.
+func (r Range) Allows(v Version) bool {
+	if r.Min != nil {
+		c := v.Compare(*r.Min)
+		if c < 0 || (c == 0 && !r.IncludeMin) {
+			return false
+		}
+	}
+	if r.Max != nil {
+		c := v.Compare(*r.Max)
+		if c > 0 || (c == 0 && !r.IncludeMax) {
+			return false
+		}
+		if !r.IncludeMax && r.Max.Pre == "" && v.Pre != "" && v.sameRelease(*r.Max) &&
+			!(r.Min != nil && r.Min.Pre != "" && r.Min.sameRelease(*r.Max)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersects reports whether some version satisfies both ranges.
+func (r Range) Intersects(o Range) bool {
+	lo, loIncl := r.Min, r.IncludeMin
+	if o.Min != nil && (lo == nil || o.Min.Compare(*lo) > 0 || o.Min.Compare(*lo) == 0 && !o.IncludeMin) {
+		lo, loIncl = o.Min, o.IncludeMin
+	}
+	hi, hiIncl := r.Max, r.IncludeMax
+	if o.Max != nil && (hi == nil || o.Max.Compare(*hi) < 0 || o.Max.Compare(*hi) == 0 && !o.IncludeMax) {
+		hi, hiIncl = o.Max, o.IncludeMax
+	}
+	if lo == nil || hi == nil {
+		return true
+	}
+	c := lo.Compare(*hi)
+	return c < 0 || c == 0 && loIncl && hiIncl
+}
+
+// IsAny reports whether the range has no bounds at all.
+func (r Range) IsAny() bool {
+	return r.Min == nil && r.Max == nil
+}
+
+// Unbounded reports whether the range has no upper bound, so any future
+// breaking release is accepted.
+func (r Range) Unbounded() bool {
+	return r.Max == nil
+}
+
+// MajorKey returns the compatibility line the lower bound of the range is
+// on, or "" when the range has no lower bound.
+func (r Range) MajorKey() string {
+	if r.Min == nil {
+		return ""
+	}
+	return r.Min.MajorKey()
+}
+
+func (r Range) String() string {
+	if r.IsAny() {
+		return "any"
+	}
+	if r.Min != nil && r.Max != nil && r.IncludeMin && r.IncludeMax && r.Min.Compare(*r.Max) == 0 {
+		return r.Min.String()
+	}
+	var parts []string
+	if r.Min != nil {
+		op := ">"
+		if r.IncludeMin {
+			op = ">="
+		}
+		parts = append(parts, op+r.Min.String())
+	}
+	if r.Max != nil {
+		op := "<"
+		if r.IncludeMax {
+			op = "<="
+		}
+		parts = append(parts, op+r.Max.String())
+	}
+	return strings.Join(parts, " ")
+}
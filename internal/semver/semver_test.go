@@ -0,0 +1,170 @@
+package semver
+
+import "testing"
+
+func mustVersion(t *testing.T, s string) Version {
+	t.Helper()
+	v, err := ParseVersion(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Version
+		wantErr bool
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, false},
+		{" 0.13.4 ", Version{Minor: 13, Patch: 4}, false},
+		{"2.0.0-dev.1", Version{Major: 2, Pre: "dev.1"}, false},
+		{"1.0.0-beta+build.5", Version{Major: 1, Pre: "beta", Build: "build.5"}, false},
+		{"1.0.0+hotfix-2", Version{Major: 1, Build: "hotfix-2"}, false},
+		{"1.2", Version{}, true},
+		{"1.x.0", Version{}, true},
+		{"-1.0.0", Version{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseVersion(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0+build.2", "1.0.0+build.1", 0},
+		{"1.0.0-beta.9", "1.0.0-beta.10", -1},
+		{"1.0.0-beta.10", "1.0.0-beta.9", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0-dev.02", "1.0.0-dev.2", 0},
+		{"1.0.0--1", "1.0.0-1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := mustVersion(t, tt.a), mustVersion(t, tt.b)
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare = %d, want %d", got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("reverse Compare = %d, want %d", got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"any", "3.0.0-dev.1", true},
+		{"^1.2.0", "1.2.0", true},
+		{"^1.2.0", "1.9.9", true},
+		{"^1.2.0", "1.1.9", false},
+		{"^1.2.0", "2.0.0", false},
+		{"^1.2.0", "2.0.0-dev.1", false},
+		{"^1.2.0", "1.3.0-beta", true},
+		{"^0.13.0", "0.14.0-dev", false},
+		{"^0.13.0", "0.13.9", true},
+		{">=1.0.0 <2.0.0", "2.0.0-nullsafety.0", false},
+		{">=2.0.0-dev.1 <2.0.0", "2.0.0-dev.5", true},
+		{">=1.0.0 <=2.0.0", "2.0.0-dev.1", true},
+		{">=1.0.0 <2.0.0-dev.3", "2.0.0-dev.2", true},
+		{">=1.0.0 <2.0.0-dev.3", "2.0.0-dev.3", false},
+		{">1.0.0", "1.0.0", false},
+		{">1.0.0", "1.0.1-beta", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" allows "+tt.version, func(t *testing.T) {
+			r, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Allows(mustVersion(t, tt.version)); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, c := range []string{"^1.2", ">=", "~>1.0.0", ">=1.0.0 <2"} {
+		t.Run(c, func(t *testing.T) {
+			if _, err := ParseConstraint(c); err == nil {
+				t.Errorf("no error")
+			}
+		})
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"^1.0.0", "^1.5.0", true},
+		{"^1.0.0", "^2.0.0", false},
+		{">=1.0.0 <2.0.0", ">=2.0.0", false},
+		{">=1.0.0 <=2.0.0", ">=2.0.0", true},
+		{"any", "^3.0.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" and "+tt.b, func(t *testing.T) {
+			a, err := ParseConstraint(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseConstraint(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Intersects(b); got != tt.want {
+				t.Errorf("Intersects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMajorKey(t *testing.T) {
+	tests := []struct {
+		version, key, next string
+	}{
+		{"2.3.1", "2.x", "3.0.0"},
+		{"0.13.4", "0.13.x", "0.14.0"},
+		{"0.0.3", "0.0.x", "0.1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			v := mustVersion(t, tt.version)
+			if got := v.MajorKey(); got != tt.key {
+				t.Errorf("MajorKey = %q, want %q", got, tt.key)
+			}
+			if got := v.NextBreaking().String(); got != tt.next {
+				t.Errorf("NextBreaking = %q, want %q", got, tt.next)
+			}
+		})
+	}
+}
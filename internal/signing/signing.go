@@ -0,0 +1,78 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- Structures ---
+
+// Signer creates detached signatures with an external signing tool.
+type Signer struct {
+	// Tool is "cosign" or "minisign".
+	Tool string
+	// Key is the path of the private key.
+	Key string
+	// Password unlocks a minisign key; cosign reads COSIGN_PASSWORD itself.
+	Password string
+}
+
+// Tools lists the supported signing tools.
+var Tools = []string{"cosign", "minisign"}
+
+// --- Core logic ---
+
+// Checksum writes path.sha256 in the format of sha256sum, so that
+// `sha256sum -c report.json.sha256` verifies the report.
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	out := path + ".sha256"
+	line := hex.EncodeToString(h.Sum(nil)) + "  " + filepath.Base(path) + "\n"
+	return out, os.WriteFile(out, []byte(line), 0644)
+}
+
+// Sign writes a detached signature of path: path.sig for cosign, which
+// `cosign verify-blob --key cosign.pub --signature report.json.sig
+// report.json` checks, or path.minisig for minisign (`minisign -V`).
+func (s Signer) Sign(ctx context.Context, path string) (string, error) {
+	var cmd *exec.Cmd
+	var out string
+	switch s.Tool {
+	case "cosign":
+		out = path + ".sig"
+		cmd = exec.CommandContext(ctx, "cosign", "sign-blob", "--yes", "--tlog-upload=false", "--key", s.Key, "--output-signature", out, path)
+	case "minisign":
+		out = path + ".minisig"
+		cmd = exec.CommandContext(ctx, "minisign", "-S", "-s", s.Key, "-m", path, "-x", out)
+		if s.Password != "" {
+			cmd.Stdin = strings.NewReader(s.Password + "\n")
+		}
+	default:
+		return "", fmt.Errorf("unknown signing tool %q (want %s)", s.Tool, strings.Join(Tools, " or "))
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", s.Tool, err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", s.Tool, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package simulate
+
+import (
+	"fmt"
+	"sort"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Statuses of a repo in a simulation, from best to worst.
+const (
+	Accepts   = "accepts"
+	NeedsBump = "needs_bump"
+	Conflict  = "conflict"
+	NotHosted = "not_hosted"
+)
+
+// --- Structures ---
+
+// Target is the version an upgrade campaign moves a package to, with the
+// dependency and environment constraints of its pubspec when it is
+// published.
+type Target struct {
+	Package      string
+	Version      semver.Version
+	Dependencies map[string]string
+	Environment  map[string]string
+}
+
+// Index looks up published packages.
+type Index interface {
+	// Requires returns the dependency constraints of name at version.
+	Requires(name, version string) map[string]string
+	// Newest returns the newest version of name allowed by r, or "".
+	Newest(name string, r semver.Range) string
+}
+
+// Clash is a constraint that rules out the target version as the repo is
+// today: Source at its current version requires Package within Requires,
+// but the repo has Have.
+type Clash struct {
+	Source   string `json:"source"`
+	Package  string `json:"package"`
+	Requires string `json:"requires"`
+	Have     string `json:"have,omitempty"`
+}
+
+// Repo is the outcome for one repo using the package. Suggested is the
+// constraint to move to when the declared one excludes the target.
+type Repo struct {
+	Repo       string  `json:"repo"`
+	Status     string  `json:"status"`
+	Constraint string  `json:"constraint"`
+	Locked     string  `json:"locked,omitempty"`
+	Suggested  string  `json:"suggested,omitempty"`
+	Clashes    []Clash `json:"clashes,omitempty"`
+}
+
+// Report is the outcome of a simulation across the fleet. Published is
+// false when the target version is not on the package server yet, so only
+// the declared constraints could be checked.
+type Report struct {
+	Package   string         `json:"package"`
+	Version   string         `json:"version"`
+	Published bool           `json:"published"`
+	Counts    map[string]int `json:"counts"`
+	Repos     []Repo         `json:"repos"`
+}
+
+// --- Core logic ---
+
+func (c Clash) String() string {
+	s := fmt.Sprintf("%s requires %s %s", c.Source, c.Package, c.Requires)
+	if c.Have != "" {
+		s += ", repo has " + c.Have
+	}
+	return s
+}
+
+// Check simulates moving r to the target version. It returns false for
+// repos that do not depend on the package. Sibling packages are taken at
+// their locked version, or at the newest version their constraint allows
+// when the repo has no lockfile.
+func Check(r stats.RepoResult, t Target, idx Index) (Repo, bool) {
+	declared, ok := r.Constraints[t.Package]
+	if !ok || r.Error != "" {
+		return Repo{}, false
+	}
+	out := Repo{Repo: r.Repo, Constraint: declared, Locked: r.Locked[t.Package]}
+	rng, err := semver.ParseConstraint(declared)
+	if err != nil {
+		out.Status = NotHosted
+		return out, true
+	}
+	out.Status = Accepts
+	if !rng.Allows(t.Version) {
+		out.Status = NeedsBump
+		out.Suggested = "^" + t.Version.String()
+	}
+
+	siblings := map[string]string{}
+	for name, v := range r.Locked {
+		siblings[name] = v
+	}
+	for name, c := range r.Constraints {
+		if _, locked := siblings[name]; locked {
+			continue
+		}
+		if sr, err := semver.ParseConstraint(c); err == nil {
+			if v := idx.Newest(name, sr); v != "" {
+				siblings[name] = v
+			}
+		}
+	}
+	for name, v := range siblings {
+		if name == t.Package {
+			continue
+		}
+		req, ok := idx.Requires(name, v)[t.Package]
+		if !ok {
+			continue
+		}
+		if rr, err := semver.ParseConstraint(req); err == nil && !rr.Allows(t.Version) {
+			out.Clashes = append(out.Clashes, Clash{Source: name + " " + v, Package: t.Package, Requires: req})
+		}
+	}
+
+	source := t.Package + " " + t.Version.String()
+	for name, req := range t.Dependencies {
+		if have, ok := r.Constraints[name]; ok && !compatible(have, req) {
+			out.Clashes = append(out.Clashes, Clash{Source: source, Package: name, Requires: req, Have: have})
+		}
+	}
+	for name, req := range t.Environment {
+		if have, ok := r.Environment[name]; ok && !compatible(have, req) {
+			out.Clashes = append(out.Clashes, Clash{Source: source, Package: name, Requires: req, Have: have})
+		}
+	}
+	if len(out.Clashes) > 0 {
+		out.Status = Conflict
+		sort.Slice(out.Clashes, func(i, j int) bool {
+			if out.Clashes[i].Source != out.Clashes[j].Source {
+				return out.Clashes[i].Source < out.Clashes[j].Source
+			}
+			return out.Clashes[i].Package < out.Clashes[j].Package
+		})
+	}
+	return out, true
+}
+
+// compatible reports whether two constraints can both be met. Constraints
+// that are not version ranges, e.g. git or path ones, are not judged.
+func compatible(a, b string) bool {
+	ra, err := semver.ParseConstraint(a)
+	if err != nil {
+		return true
+	}
+	rb, err := semver.ParseConstraint(b)
+	if err != nil {
+		return true
+	}
+	return ra.Intersects(rb)
+}
+
+// Build collects the repo outcomes into a report sorted by status, worst
+// first, then by repo.
+func Build(t Target, published bool, repos []Repo) Report {
+	rank := map[string]int{Conflict: 0, NeedsBump: 1, NotHosted: 2, Accepts: 3}
+	sort.Slice(repos, func(i, j int) bool {
+		if rank[repos[i].Status] != rank[repos[j].Status] {
+			return rank[repos[i].Status] < rank[repos[j].Status]
+		}
+		return repos[i].Repo < repos[j].Repo
+	})
+	rep := Report{Package: t.Package, Version: t.Version.String(), Published: published, Counts: map[string]int{}, Repos: repos}
+	for _, r := range repos {
+		rep.Counts[r.Status]++
+	}
+	if rep.Repos == nil {
+		rep.Repos = []Repo{}
+	}
+	return rep
+}
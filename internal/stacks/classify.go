@@ -0,0 +1,165 @@
+package stacks
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Category groups alternative stacks for one architectural concern, such as
+// state management or routing.
+type Category struct {
+	Name   string
+	Stacks []Stack
+}
+
+// RepoStacks is the architecture of one repo: the stacks it uses per
+// category, and their combination as a single label.
+type RepoStacks struct {
+	Repo   string              `json:"repo"`
+	Stacks map[string][]string `json:"stacks"`
+	Label  string              `json:"label"`
+}
+
+type CategoryUsage struct {
+	Name string `json:"name"`
+	// Count is the number of repos using any stack of the category, None
+	// the number using none.
+	Count  int     `json:"count"`
+	None   int     `json:"none"`
+	Stacks []Usage `json:"stacks"`
+}
+
+// StackReport is the dedicated stack report section.
+type StackReport struct {
+	Categories   []CategoryUsage `json:"categories"`
+	Combinations []Usage         `json:"combinations"`
+	Repos        []RepoStacks    `json:"repos,omitempty"`
+}
+
+// Classifier assigns repos to the stacks of each category. With CountOnly
+// set it keeps counts but no repo names.
+type Classifier struct {
+	mu         sync.Mutex
+	CountOnly  bool
+	categories []Category
+	usage      map[string]map[string]*Usage
+	count      map[string]int
+	none       map[string]int
+	combos     map[string]*Usage
+	repos      []RepoStacks
+}
+
+func NewClassifier(categories []Category) *Classifier {
+	c := &Classifier{
+		categories: categories,
+		usage:      map[string]map[string]*Usage{},
+		count:      map[string]int{},
+		none:       map[string]int{},
+		combos:     map[string]*Usage{},
+	}
+	for _, cat := range categories {
+		c.usage[cat.Name] = map[string]*Usage{}
+	}
+	return c
+}
+
+// Classify returns the stacks r uses per category. Only main dependencies
+// count, so test-only packages do not define the architecture. Categories
+// without a match are left out.
+func Classify(r stats.RepoResult, categories []Category) map[string][]string {
+	r.DevDependencies = nil
+	out := map[string][]string{}
+	for _, cat := range categories {
+		if matched := Match(r, cat.Stacks); len(matched) > 0 {
+			out[cat.Name] = matched
+		}
+	}
+	return out
+}
+
+// label joins the stacks of every category in category order, e.g.
+// "bloc+get_it+dio+go_router".
+func label(categories []Category, byCat map[string][]string) string {
+	var parts []string
+	for _, cat := range categories {
+		parts = append(parts, byCat[cat.Name]...)
+	}
+	if len(parts) == 0 {
+		return "(none)"
+	}
+	return strings.Join(parts, "+")
+}
+
+func (c *Classifier) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	byCat := Classify(r, c.categories)
+	lbl := label(c.categories, byCat)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cat := range c.categories {
+		names := byCat[cat.Name]
+		if len(names) == 0 {
+			c.none[cat.Name]++
+		} else {
+			c.count[cat.Name]++
+		}
+		for _, name := range names {
+			u := c.usage[cat.Name][name]
+			if u == nil {
+				u = &Usage{Name: name}
+				c.usage[cat.Name][name] = u
+			}
+			c.add(u, r.Repo)
+		}
+	}
+	combo := c.combos[lbl]
+	if combo == nil {
+		combo = &Usage{Name: lbl}
+		c.combos[lbl] = combo
+	}
+	c.add(combo, r.Repo)
+	if !c.CountOnly {
+		c.repos = append(c.repos, RepoStacks{Repo: r.Repo, Stacks: byCat, Label: lbl})
+	}
+}
+
+func (c *Classifier) add(u *Usage, repo string) {
+	u.Count++
+	if !c.CountOnly {
+		u.Repos = append(u.Repos, repo)
+	}
+}
+
+// Report returns the stack report, or nil when no repo was classified.
+func (c *Classifier) Report() *StackReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.combos) == 0 {
+		return nil
+	}
+	rep := &StackReport{}
+	for _, cat := range c.categories {
+		cu := CategoryUsage{Name: cat.Name, Count: c.count[cat.Name], None: c.none[cat.Name]}
+		for _, u := range c.usage[cat.Name] {
+			cu.Stacks = append(cu.Stacks, copyUsage(u))
+		}
+		sortUsage(cu.Stacks)
+		rep.Categories = append(rep.Categories, cu)
+	}
+	for _, u := range c.combos {
+		// Repos are listed per repo below; keep combinations short.
+		combo := *u
+		combo.Repos = nil
+		rep.Combinations = append(rep.Combinations, combo)
+	}
+	sortUsage(rep.Combinations)
+	rep.Repos = append(rep.Repos, c.repos...)
+	sort.Slice(rep.Repos, func(i, j int) bool { return rep.Repos[i].Repo < rep.Repos[j].Repo })
+	return rep
+}
@@ -0,0 +1,167 @@
+package stacks
+
+import (
+	"sort"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// --- Structures ---
+
+// Stack is a recognized combination of packages. A repo uses it when it
+// depends on all of Requires and on at least one of AnyOf.
+type Stack struct {
+	Name     string
+	Requires []string
+	AnyOf    []string
+}
+
+// Codegen lists the code-generation stacks built on build_runner.
+var Codegen = []Stack{
+	{Name: "freezed", Requires: []string{"build_runner"}, AnyOf: []string{"freezed"}},
+	{Name: "json_serializable", Requires: []string{"build_runner"}, AnyOf: []string{"json_serializable"}},
+	{Name: "retrofit", Requires: []string{"build_runner"}, AnyOf: []string{"retrofit_generator"}},
+	{Name: "injectable", Requires: []string{"build_runner"}, AnyOf: []string{"injectable_generator"}},
+	{Name: "auto_route", Requires: []string{"build_runner"}, AnyOf: []string{"auto_route_generator"}},
+	{Name: "drift", Requires: []string{"build_runner"}, AnyOf: []string{"drift_dev"}},
+	{Name: "hive", Requires: []string{"build_runner"}, AnyOf: []string{"hive_generator", "hive_ce_generator"}},
+	{Name: "mockito", Requires: []string{"build_runner", "mockito"}},
+	{Name: "riverpod_generator", Requires: []string{"build_runner"}, AnyOf: []string{"riverpod_generator"}},
+	{Name: "go_router_builder", Requires: []string{"build_runner"}, AnyOf: []string{"go_router_builder"}},
+}
+
+type Usage struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Repos []string `json:"repos,omitempty"`
+}
+
+// CodegenReport shows how many repos run build_runner and which stacks
+// they generate code for. Other lists build_runner repos matching none.
+type CodegenReport struct {
+	BuildRunner int     `json:"build_runner"`
+	Stacks      []Usage `json:"stacks"`
+	Other       Usage   `json:"other"`
+}
+
+// CodegenTracker detects codegen stacks in repo results. With CountOnly set
+// it keeps counts but no repo names.
+type CodegenTracker struct {
+	mu          sync.Mutex
+	CountOnly   bool
+	buildRunner int
+	stacks      map[string]*Usage
+	other       Usage
+}
+
+func NewCodegenTracker() *CodegenTracker {
+	return &CodegenTracker{stacks: map[string]*Usage{}, other: Usage{Name: "(other)"}}
+}
+
+// --- Core logic ---
+
+// Match returns the names of the stacks r uses, in definition order.
+func Match(r stats.RepoResult, defs []Stack) []string {
+	has := map[string]bool{}
+	for _, list := range [][]string{r.Dependencies, r.DevDependencies} {
+		for _, name := range list {
+			has[name] = true
+		}
+	}
+	var out []string
+	for _, s := range defs {
+		if matches(s, has) {
+			out = append(out, s.Name)
+		}
+	}
+	return out
+}
+
+func matches(s Stack, has map[string]bool) bool {
+	for _, p := range s.Requires {
+		if !has[p] {
+			return false
+		}
+	}
+	if len(s.AnyOf) == 0 {
+		return true
+	}
+	for _, p := range s.AnyOf {
+		if has[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *CodegenTracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	usesBuildRunner := false
+	for _, list := range [][]string{r.Dependencies, r.DevDependencies} {
+		for _, name := range list {
+			if name == "build_runner" {
+				usesBuildRunner = true
+			}
+		}
+	}
+	if !usesBuildRunner {
+		return
+	}
+	matched := Match(r, Codegen)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buildRunner++
+	if len(matched) == 0 {
+		t.add(&t.other, r.Repo)
+	}
+	for _, name := range matched {
+		u := t.stacks[name]
+		if u == nil {
+			u = &Usage{Name: name}
+			t.stacks[name] = u
+		}
+		t.add(u, r.Repo)
+	}
+}
+
+func (t *CodegenTracker) add(u *Usage, repo string) {
+	u.Count++
+	if !t.CountOnly {
+		u.Repos = append(u.Repos, repo)
+	}
+}
+
+// Report returns the stacks by usage, or nil when no repo uses build_runner.
+func (t *CodegenTracker) Report() *CodegenReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.buildRunner == 0 {
+		return nil
+	}
+	rep := &CodegenReport{BuildRunner: t.buildRunner, Other: copyUsage(&t.other)}
+	for _, u := range t.stacks {
+		rep.Stacks = append(rep.Stacks, copyUsage(u))
+	}
+	sortUsage(rep.Stacks)
+	return rep
+}
+
+func copyUsage(u *Usage) Usage {
+	out := *u
+	out.Repos = append([]string(nil), u.Repos...)
+	sort.Strings(out.Repos)
+	return out
+}
+
+func sortUsage(list []Usage) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+}
@@ -0,0 +1,116 @@
+package stacks
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed taxonomy.yaml
+var defaultTaxonomy []byte
+
+type taxonomyFile struct {
+	Categories []struct {
+		Name   string `yaml:"name"`
+		Stacks []struct {
+			Name     string   `yaml:"name"`
+			Packages []string `yaml:"packages"`
+		} `yaml:"stacks"`
+		// Packages are shorthand for one stack per package.
+		Packages []string `yaml:"packages"`
+	} `yaml:"categories"`
+}
+
+// Taxonomy maps packages to stacks and categories.
+type Taxonomy struct {
+	Categories []Category
+}
+
+// --- Core logic ---
+
+// DefaultTaxonomy returns the built-in taxonomy.
+func DefaultTaxonomy() Taxonomy {
+	t, err := parseTaxonomy(Taxonomy{}, defaultTaxonomy)
+	if err != nil {
+		panic("stacks: invalid built-in taxonomy: " + err.Error())
+	}
+	return t
+}
+
+// LoadTaxonomy extends the built-in taxonomy with a user file. Stacks are
+// added to their category, replacing a built-in stack of the same name, and
+// unknown categories are appended.
+func LoadTaxonomy(path string) (Taxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Taxonomy{}, err
+	}
+	return parseTaxonomy(DefaultTaxonomy(), data)
+}
+
+func parseTaxonomy(base Taxonomy, data []byte) (Taxonomy, error) {
+	var tf taxonomyFile
+	if err := yaml.Unmarshal(data, &tf); err != nil {
+		return Taxonomy{}, err
+	}
+	t := Taxonomy{Categories: append([]Category(nil), base.Categories...)}
+	for i, c := range tf.Categories {
+		if c.Name == "" {
+			return Taxonomy{}, fmt.Errorf("category %d: name is required", i+1)
+		}
+		var defs []Stack
+		for _, s := range c.Stacks {
+			if s.Name == "" || len(s.Packages) == 0 {
+				return Taxonomy{}, fmt.Errorf("category %s: every stack needs a name and packages", c.Name)
+			}
+			defs = append(defs, Stack{Name: s.Name, AnyOf: s.Packages})
+		}
+		for _, p := range c.Packages {
+			defs = append(defs, Stack{Name: p, AnyOf: []string{p}})
+		}
+		t.extend(c.Name, defs)
+	}
+	return t, nil
+}
+
+func (t *Taxonomy) extend(category string, defs []Stack) {
+	idx := -1
+	for i, c := range t.Categories {
+		if c.Name == category {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		t.Categories = append(t.Categories, Category{Name: category})
+		idx = len(t.Categories) - 1
+	}
+	cat := &t.Categories[idx]
+	list := append([]Stack(nil), cat.Stacks...)
+next:
+	for _, d := range defs {
+		for i, s := range list {
+			if s.Name == d.Name {
+				list[i] = d
+				continue next
+			}
+		}
+		list = append(list, d)
+	}
+	cat.Stacks = list
+}
+
+// CategoryOf returns the first category listing the package, or "".
+func (t Taxonomy) CategoryOf(pkg string) string {
+	for _, c := range t.Categories {
+		for _, s := range c.Stacks {
+			for _, p := range append(s.Requires, s.AnyOf...) {
+				if p == pkg {
+					return c.Name
+				}
+			}
+		}
+	}
+	return ""
+}
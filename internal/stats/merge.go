@@ -0,0 +1,281 @@
+package stats
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Conflict describes an inconsistency found while merging reports.
+type Conflict struct {
+	Repo   string   `json:"repo,omitempty"`
+	Inputs []string `json:"inputs"`
+	Reason string   `json:"reason"`
+}
+
+// mergedRepo is the result kept for a repo: a digest to compare the
+// results of other inputs with and, for failed scans, the result itself,
+// which a later successful scan replaces.
+type mergedRepo struct {
+	input  string
+	digest [sha256.Size]byte
+	failed *RepoResult
+}
+
+// Merger combines several partial reports (shards, per-provider runs) into
+// one. When every input carries per-repo results the counts are recomputed
+// from the de-duplicated repo set; otherwise the per-input counts are summed.
+// Results are counted as they arrive, so after SpillTo only a digest per
+// repo is held in memory.
+type Merger struct {
+	repos     map[string]*mergedRepo
+	order     []string
+	agg       *Aggregator
+	spill     io.Writer
+	sums      [4]map[string]int
+	inputs    []string
+	shards    map[string][]string
+	unsharded int
+	summaries int
+	withTeams bool
+	weighted  bool
+	weights   map[string]float64
+	conflicts []Conflict
+
+	collectors []Collector
+}
+
+func NewMerger() *Merger {
+	return &Merger{
+		repos:   map[string]*mergedRepo{},
+		sums:    [4]map[string]int{{}, {}, {}, {}},
+		shards:  map[string][]string{},
+		weights: map[string]float64{},
+	}
+}
+
+// --- Core logic ---
+
+// Use registers collectors that are re-run over the merged repo set. They
+// only see results when every input carries per-repo results.
+func (m *Merger) Use(c ...Collector) {
+	m.collectors = append(m.collectors, c...)
+}
+
+// SpillTo makes the merger write the merged per-repo results to w as NDJSON
+// instead of retaining them. It must be called before the first AddRepo.
+func (m *Merger) SpillTo(w io.Writer) {
+	m.spill = w
+}
+
+// AddReport records the counts and metadata of one input. detailed reports
+// whether per-repo results for this input will be passed to AddRepo. All
+// inputs must be added before their per-repo results.
+func (m *Merger) AddReport(input string, s Stats, detailed bool) {
+	m.inputs = append(m.inputs, input)
+	if !detailed {
+		m.summaries++
+	}
+	for i, list := range [][]PackageStat{s.Dependencies, s.DevDependencies, s.DependencyOverrides, s.Combined} {
+		for _, p := range list {
+			m.sums[i][p.Key()] += p.Count
+		}
+	}
+	if len(s.Teams) > 0 {
+		m.withTeams = true
+	}
+	if s.Weighted != nil {
+		m.weighted = true
+		for _, p := range s.Weighted {
+			m.weights[p.Key()] += p.Weight
+		}
+	}
+	if s.Shard == "" && len(s.Shards) == 0 {
+		m.unsharded++
+	}
+	if s.Shard != "" {
+		m.shards[s.Shard] = append(m.shards[s.Shard], input)
+	}
+	for _, sh := range s.Shards {
+		m.shards[sh] = append(m.shards[sh], input)
+	}
+}
+
+// AddRepo adds a per-repo result from input. A repo seen in several inputs
+// is kept once; differing results are reported as conflicts and the first
+// successful result wins. Manifests of different ecosystems in one
+// directory are different results.
+func (m *Merger) AddRepo(input string, r RepoResult) error {
+	if m.agg == nil {
+		m.agg = NewAggregator()
+		m.agg.TrackTeams = m.withTeams
+		if m.summaries == 0 {
+			m.agg.Use(m.collectors...)
+		}
+		if m.spill != nil {
+			m.agg.SpillTo(m.spill)
+		}
+	}
+	key := repoKey(r)
+	digest := resultDigest(r)
+	prev, ok := m.repos[key]
+	if !ok {
+		mr := &mergedRepo{input: input, digest: digest}
+		m.repos[key] = mr
+		m.order = append(m.order, key)
+		return m.keep(mr, r)
+	}
+	if prev.digest == digest {
+		return nil
+	}
+	m.conflicts = append(m.conflicts, Conflict{
+		Repo:   r.Repo,
+		Inputs: []string{prev.input, input},
+		Reason: "repo scanned with different results",
+	})
+	if prev.failed != nil && r.Error == "" {
+		*prev = mergedRepo{input: input, digest: digest}
+		return m.keep(prev, r)
+	}
+	return nil
+}
+
+// keep counts the result of mr, or holds it back while it is a failed scan
+// that another input may replace.
+func (m *Merger) keep(mr *mergedRepo, r RepoResult) error {
+	if r.Error != "" {
+		mr.failed = &r
+		return nil
+	}
+	return m.agg.Add(r)
+}
+
+// Failed reports whether the merged result of repo, a pub repo or package,
+// is a failed scan.
+func (m *Merger) Failed(repo string) bool {
+	mr := m.repos[strings.ToLower(repo)]
+	return mr != nil && mr.failed != nil
+}
+
+// Repos returns the number of merged repos.
+func (m *Merger) Repos() int {
+	return len(m.repos)
+}
+
+func repoKey(r RepoResult) string {
+	key := strings.ToLower(r.Repo)
+	if r.Ecosystem != "" && r.Ecosystem != "pub" {
+		key += " " + r.Ecosystem
+	}
+	return key
+}
+
+// resultDigest identifies a result of a repo, ignoring when its files were
+// fetched.
+func resultDigest(r RepoResult) [sha256.Size]byte {
+	data, _ := json.Marshal(withoutFetchTimes(r))
+	return sha256.Sum256(data)
+}
+
+func withoutFetchTimes(r RepoResult) RepoResult {
+	if r.Provenance == nil {
+		return r
+	}
+	p := *r.Provenance
+	p.Files = make([]FetchedFile, len(r.Provenance.Files))
+	for i, f := range r.Provenance.Files {
+		p.Files[i] = FetchedFile{Path: f.Path}
+	}
+	r.Provenance = &p
+	return r
+}
+
+// Result builds the merged report and the list of conflicts found. The
+// failed scans no input replaced are counted, and spilled, last.
+func (m *Merger) Result(minUsage int) (Stats, []Conflict, error) {
+	agg := m.agg
+	if agg == nil {
+		agg = NewAggregator()
+	}
+	for _, key := range m.order {
+		if r := m.repos[key].failed; r != nil {
+			if err := agg.Add(*r); err != nil {
+				return Stats{}, nil, err
+			}
+		}
+	}
+	out := agg.Stats(minUsage)
+	if m.summaries > 0 {
+		out = Stats{
+			Dependencies:        buildSortedList(m.sums[0], minUsage),
+			DevDependencies:     buildSortedList(m.sums[1], minUsage),
+			DependencyOverrides: buildSortedList(m.sums[2], minUsage),
+			Combined:            buildSortedList(m.sums[3], minUsage),
+			Repos:               out.Repos,
+		}
+		if m.weighted {
+			out.Weighted = buildWeightedList(m.weights, m.sums[3], minUsage)
+		}
+		if len(m.inputs) > 1 {
+			m.conflicts = append(m.conflicts, Conflict{
+				Inputs: m.inputs,
+				Reason: fmt.Sprintf("%d input(s) have no per-repo results; counts were summed and may double-count overlapping repos", m.summaries),
+			})
+		}
+	}
+
+	m.conflicts = append(m.conflicts, m.shardConflicts(&out)...)
+	out.Conflicts = m.conflicts
+	return out, m.conflicts, nil
+}
+
+// shardConflicts checks that merged shards come from one split, do not
+// overlap, and cover it completely. Incomplete merges keep their shard list.
+// Mixing in unsharded reports makes coverage meaningless, so it is skipped.
+func (m *Merger) shardConflicts(out *Stats) []Conflict {
+	if len(m.shards) == 0 || m.unsharded > 0 {
+		return nil
+	}
+	var conflicts []Conflict
+	counts := map[int]bool{}
+	covered := map[int]bool{}
+	var names []string
+	for sh, inputs := range m.shards {
+		names = append(names, sh)
+		k, n, _ := strings.Cut(sh, "/")
+		index, _ := strconv.Atoi(k)
+		count, _ := strconv.Atoi(n)
+		counts[count] = true
+		covered[index] = true
+		if len(inputs) > 1 {
+			conflicts = append(conflicts, Conflict{Inputs: inputs, Reason: "shard " + sh + " merged more than once"})
+		}
+	}
+	sort.Strings(names)
+	if len(counts) > 1 {
+		return append(conflicts, Conflict{Inputs: m.inputs, Reason: "inputs come from different shard splits: " + strings.Join(names, ", ")})
+	}
+
+	var total int
+	for n := range counts {
+		total = n
+	}
+	var missing []string
+	for i := 1; i <= total; i++ {
+		if !covered[i] {
+			missing = append(missing, strconv.Itoa(i))
+		}
+	}
+	if len(missing) > 0 {
+		out.Shards = names
+		conflicts = append(conflicts, Conflict{
+			Inputs: m.inputs,
+			Reason: fmt.Sprintf("missing shard(s) %s of %d", strings.Join(missing, ", "), total),
+		})
+	}
+	return conflicts
+}
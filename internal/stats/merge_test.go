@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// counts returns the dependency counts of s by package name.
+func counts(s Stats) map[string]int {
+	out := map[string]int{}
+	for _, p := range s.Dependencies {
+		out[p.Name] = p.Count
+	}
+	return out
+}
+
+// reasons returns the reasons of the conflicts.
+func reasons(conflicts []Conflict) []string {
+	var out []string
+	for _, c := range conflicts {
+		out = append(out, c.Reason)
+	}
+	return out
+}
+
+func TestMergerRepos(t *testing.T) {
+	app := RepoResult{Repo: "acme/app", Dependencies: []string{"http", "dio"}}
+	tests := []struct {
+		name        string
+		inputs      [][]RepoResult
+		want        map[string]int
+		wantReasons []string
+	}{
+		{"disjoint", [][]RepoResult{{app}, {{Repo: "acme/web", Dependencies: []string{"http"}}}},
+			map[string]int{"http": 2, "dio": 1}, nil},
+		{"same repo once", [][]RepoResult{{app}, {{Repo: "ACME/app", Dependencies: []string{"http", "dio"}}}},
+			map[string]int{"http": 1, "dio": 1}, []string{"repo scanned with different results"}},
+		{"identical results", [][]RepoResult{{app}, {app}},
+			map[string]int{"http": 1, "dio": 1}, nil},
+		{"fetch times ignored", [][]RepoResult{
+			{{Repo: "acme/app", Dependencies: []string{"http"}, Provenance: &Provenance{Files: []FetchedFile{{Path: "pubspec.yaml", FetchedAt: time.Unix(1, 0)}}}}},
+			{{Repo: "acme/app", Dependencies: []string{"http"}, Provenance: &Provenance{Files: []FetchedFile{{Path: "pubspec.yaml", FetchedAt: time.Unix(2, 0)}}}}},
+		}, map[string]int{"http": 1}, nil},
+		{"success wins over error", [][]RepoResult{{{Repo: "acme/app", Error: "timeout"}}, {app}},
+			map[string]int{"http": 1, "dio": 1}, []string{"repo scanned with different results"}},
+		{"first success kept", [][]RepoResult{{app}, {{Repo: "acme/app", Dependencies: []string{"meta"}}}},
+			map[string]int{"http": 1, "dio": 1}, []string{"repo scanned with different results"}},
+		{"ecosystems kept apart", [][]RepoResult{{app}, {{Repo: "acme/app", Ecosystem: "npm", Dependencies: []string{"lodash"}}}},
+			map[string]int{"http": 1, "dio": 1, "lodash": 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMerger()
+			for i, repos := range tt.inputs {
+				input := string(rune('a' + i))
+				m.AddReport(input, Stats{}, true)
+				for _, r := range repos {
+					m.AddRepo(input, r)
+				}
+			}
+			out, conflicts, err := m.Result(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := counts(out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counts %v, want %v", got, tt.want)
+			}
+			if got := reasons(conflicts); !reflect.DeepEqual(got, tt.wantReasons) {
+				t.Errorf("conflicts %q, want %q", got, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestMergerSummaries(t *testing.T) {
+	m := NewMerger()
+	m.AddReport("a.json", Stats{Dependencies: []PackageStat{{Name: "http", Count: 3}, {Name: "dio", Count: 1}}}, false)
+	m.AddReport("b.json", Stats{Dependencies: []PackageStat{{Name: "http", Count: 2}}}, true)
+	m.AddRepo("b.json", RepoResult{Repo: "acme/app", Dependencies: []string{"http"}})
+	out, conflicts, err := m.Result(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"http": 5}; !reflect.DeepEqual(counts(out), want) {
+		t.Errorf("counts %v, want %v", counts(out), want)
+	}
+	if len(out.Repos) != 1 || len(conflicts) != 1 || !strings.Contains(conflicts[0].Reason, "1 input(s) have no per-repo results") {
+		t.Errorf("repos %v, conflicts %+v", out.Repos, conflicts)
+	}
+}
+
+func TestMergerShards(t *testing.T) {
+	tests := []struct {
+		name        string
+		shards      [][]string
+		wantReasons []string
+		wantShards  []string
+	}{
+		{"complete", [][]string{{"1/3"}, {"2/3"}, {"3/3"}}, nil, nil},
+		{"complete from a merge", [][]string{{"1/3", "2/3"}, {"3/3"}}, nil, nil},
+		{"missing", [][]string{{"1/3"}, {"3/3"}}, []string{"missing shard(s) 2 of 3"}, []string{"1/3", "3/3"}},
+		{"merged twice", [][]string{{"1/2"}, {"1/2"}, {"2/2"}}, []string{"shard 1/2 merged more than once"}, nil},
+		{"different splits", [][]string{{"1/2"}, {"2/3"}}, []string{"inputs come from different shard splits: 1/2, 2/3"}, nil},
+		{"unsharded input", [][]string{{"1/2"}, nil}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMerger()
+			for i, shards := range tt.shards {
+				s := Stats{Shards: shards}
+				if len(shards) == 1 {
+					s = Stats{Shard: shards[0]}
+				}
+				m.AddReport(string(rune('a'+i)), s, true)
+			}
+			out, conflicts, err := m.Result(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reasons(conflicts); !reflect.DeepEqual(got, tt.wantReasons) {
+				t.Errorf("conflicts %q, want %q", got, tt.wantReasons)
+			}
+			if !reflect.DeepEqual(out.Shards, tt.wantShards) {
+				t.Errorf("shards %v, want %v", out.Shards, tt.wantShards)
+			}
+		})
+	}
+}
+
+func TestMergerSpill(t *testing.T) {
+	var spill strings.Builder
+	m := NewMerger()
+	m.SpillTo(&spill)
+	m.AddReport("a", Stats{}, true)
+	m.AddReport("b", Stats{}, true)
+	m.AddRepo("a", RepoResult{Repo: "acme/app", Error: "timeout"})
+	m.AddRepo("a", RepoResult{Repo: "acme/web", Error: "timeout"})
+	m.AddRepo("b", RepoResult{Repo: "acme/app", Dependencies: []string{"http"}})
+	out, _, err := m.Result(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Repos != nil {
+		t.Errorf("spilled repos kept: %v", out.Repos)
+	}
+	want := `{"repo":"acme/app"`
+	lines := strings.Split(strings.TrimSpace(spill.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], want) || !strings.Contains(lines[0], `"http"`) || !strings.Contains(lines[1], `"acme/web"`) {
+		t.Errorf("spilled %q", lines)
+	}
+	if m.Repos() != 2 || m.Failed("acme/app") || !m.Failed("ACME/web") {
+		t.Errorf("%d repos, failed app %v, web %v", m.Repos(), m.Failed("acme/app"), m.Failed("acme/web"))
+	}
+	if want := map[string]int{"http": 1}; !reflect.DeepEqual(counts(out), want) {
+		t.Errorf("counts %v, want %v", counts(out), want)
+	}
+}
@@ -0,0 +1,478 @@
+package stats
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"pgithub.com/plasmatrip/pubscan/internal/pubspec"
+	"pgithub.com/plasmatrip/pubscan/internal/purl"
+)
+
+// --- Structures ---
+
+// PackageStat is the usage of a package. Usage is counted per package URL,
+// so packages of the same name in different ecosystems are kept apart.
+type PackageStat struct {
+	Name  string `json:"name"`
+	PURL  string `json:"purl,omitempty"`
+	Count int    `json:"count"`
+	URL   string `json:"url"`
+}
+
+// Key is the package URL of the package, or the pub one of its name in
+// reports written before package URLs.
+func (p PackageStat) Key() string {
+	if p.PURL != "" {
+		return p.PURL
+	}
+	return purl.Key(p.Name)
+}
+
+// WeightedStat is the usage of a package weighted by repo importance: the
+// sum of the weights of the repos using it, in any section.
+type WeightedStat struct {
+	Name   string  `json:"name"`
+	PURL   string  `json:"purl,omitempty"`
+	Weight float64 `json:"weight"`
+	Count  int     `json:"count"`
+	URL    string  `json:"url"`
+}
+
+// Key is the package URL of the package, as for PackageStat.
+func (w WeightedStat) Key() string {
+	if w.PURL != "" {
+		return w.PURL
+	}
+	return purl.Key(w.Name)
+}
+
+// NativeDep is an Android or iOS dependency of a Flutter app: a Maven
+// "group:artifact" or a pod, with its declared or locked version. Test is
+// set for dependencies only tests use.
+type NativeDep struct {
+	Platform string `json:"platform"`
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	Test     bool   `json:"test,omitempty"`
+}
+
+// RepoResult is the per-repo outcome of a scan.
+type RepoResult struct {
+	Repo                string   `json:"repo"`
+	Branch              string   `json:"branch,omitempty"`
+	Owners              []string `json:"owners,omitempty"`
+	Weight              float64  `json:"weight,omitempty"`
+	Error               string   `json:"error,omitempty"`
+	Dependencies        []string `json:"dependencies,omitempty"`
+	DevDependencies     []string `json:"dev_dependencies,omitempty"`
+	DependencyOverrides []string `json:"dependency_overrides,omitempty"`
+
+	// Ecosystem is the manifest's ecosystem when scanned with --ecosystem;
+	// empty means pub.
+	Ecosystem string `json:"ecosystem,omitempty"`
+
+	// Package, Version and PublishTo are the pubspec's own name, version
+	// and publish_to.
+	Package   string `json:"package,omitempty"`
+	Version   string `json:"version,omitempty"`
+	PublishTo string `json:"publish_to,omitempty"`
+
+	// AuthError classifies errors caused by the token: bad_credentials,
+	// saml_sso or missing_permissions.
+	AuthError string `json:"auth_error,omitempty"`
+
+	// NotDart is set when the repo has no pubspec.yaml at the entry path:
+	// it is not a Dart project rather than a failed scan. Error still says
+	// so, keeping it out of the statistics. OtherManifests are the
+	// manifests of other ecosystems found there, with --other-manifests.
+	NotDart        bool     `json:"not_dart,omitempty"`
+	OtherManifests []string `json:"other_manifests,omitempty"`
+
+	// ParseWarnings are the parse error and repairs of a pubspec read with
+	// --lenient.
+	ParseWarnings []string `json:"parse_warnings,omitempty"`
+
+	// SchemaViolations are the pubspec schema checks that failed, when
+	// --validate-pubspec is set.
+	SchemaViolations []pubspec.Violation `json:"schema_violations,omitempty"`
+
+	// Metadata is the pub.dev-facing metadata of a publishable package,
+	// when --package-metadata is set.
+	Metadata *pubspec.Metadata `json:"metadata,omitempty"`
+
+	// Constraints maps dependencies and dev dependencies to how they are
+	// declared, e.g. "^1.2.0" or "git:https://...@main".
+	Constraints map[string]string `json:"constraints,omitempty"`
+
+	// Hosted maps the dependencies declared with a hosted: block to it,
+	// naming the package server they are fetched from.
+	Hosted map[string]pubspec.Hosted `json:"hosted,omitempty"`
+
+	// Environment holds the sdk and flutter constraints of the pubspec.
+	// FlutterPin is the Flutter version pinned by FVM or .tool-versions,
+	// when --flutter-pins is set.
+	Environment map[string]string `json:"environment,omitempty"`
+	FlutterPin  string            `json:"flutter_pin,omitempty"`
+
+	// Locked maps the packages of the repo's pubspec.lock to their resolved
+	// versions, when --lockfile is set; LockMissing marks repos that commit
+	// no lockfile.
+	Locked      map[string]string `json:"locked,omitempty"`
+	LockMissing bool              `json:"lock_missing,omitempty"`
+
+	// LockedSHA256 maps the pub.dev packages of the lockfile to the archive
+	// hash it records, when --verify-hashes is set.
+	LockedSHA256 map[string]string `json:"locked_sha256,omitempty"`
+
+	// PulledBy maps locked packages to the direct dependencies whose own
+	// dependencies reach them, and LockDepth is the longest chain from the
+	// pubspec, when --dep-graph is set.
+	PulledBy  map[string][]string `json:"pulled_by,omitempty"`
+	LockDepth int                 `json:"lock_depth,omitempty"`
+
+	// Imports counts the package imports of the Dart files under lib/ and
+	// bin/ by package, and ImportFiles the files read, when --imports is set
+	// and the package has Dart sources.
+	Imports     map[string]int `json:"imports,omitempty"`
+	ImportFiles int            `json:"import_files,omitempty"`
+
+	// Flavors are the build flavors of a Flutter app and FlavorSources where
+	// they were found, when --flavors is set.
+	Flavors       []string `json:"flavors,omitempty"`
+	FlavorSources []string `json:"flavor_sources,omitempty"`
+
+	// Native are the dependencies of the app's android/app/build.gradle and
+	// ios/Podfile.lock or Podfile, when --native is set.
+	Native []NativeDep `json:"native,omitempty"`
+
+	// CIFlutter and CIDart are the versions the repo's GitHub Actions
+	// workflows install, when --workflows is set.
+	CIFlutter []string `json:"ci_flutter,omitempty"`
+	CIDart    []string `json:"ci_dart,omitempty"`
+
+	// GitVersions maps git dependencies to the version their own pubspec
+	// declares, when --resolve-git is set.
+	GitVersions map[string]string `json:"git_versions,omitempty"`
+
+	// LintSets are the lint rule sets analysis_options.yaml includes, when
+	// --lints is set.
+	LintSets []string `json:"lint_sets,omitempty"`
+
+	// PubUpdates are the tools keeping pub dependencies up to date, or
+	// "(none)", and UpdateConfigs the tools configured at all, when
+	// --update-bots is set.
+	PubUpdates    []string `json:"pub_updates,omitempty"`
+	UpdateConfigs []string `json:"update_configs,omitempty"`
+
+	// RenamedFrom is the name the repos list gives a repo GitHub redirects
+	// to Repo, its new name; the list entry is stale.
+	RenamedFrom string `json:"renamed_from,omitempty"`
+
+	// SubmoduleOf is the repo that includes this one as a git submodule,
+	// with --submodules; the result carries that repo's labels.
+	SubmoduleOf string `json:"submodule_of,omitempty"`
+
+	// Labels are the labels of the repo's manifest entry.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Provenance records which commit the repo's files were read at.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance is what a repo was scanned at: the provider serving it, the
+// ref asked for and the commit it resolved to, and every file fetched.
+// Commit is empty when the ref could not be resolved and the files were
+// read at the ref as it was at FetchedAt.
+type Provenance struct {
+	Provider string        `json:"provider"`
+	Ref      string        `json:"ref"`
+	Commit   string        `json:"commit,omitempty"`
+	Files    []FetchedFile `json:"files,omitempty"`
+}
+
+// FetchedFile is a file read from a repo, by its path in the repo.
+type FetchedFile struct {
+	Path      string    `json:"path"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+type Stats struct {
+	Dependencies        []PackageStat `json:"dependencies"`
+	DevDependencies     []PackageStat `json:"dev_dependencies"`
+	DependencyOverrides []PackageStat `json:"dependency_overrides"`
+
+	// Combined counts every package once per repo, whichever sections it
+	// is declared in.
+	Combined []PackageStat `json:"combined"`
+
+	// Weighted is set when repos carry weights, from --weight-by or the
+	// repos file.
+	Weighted []WeightedStat `json:"weighted,omitempty"`
+
+	// Repos holds per-repo results unless they were spilled to ReposFile.
+	Repos     []RepoResult `json:"repos,omitempty"`
+	ReposFile string       `json:"repos_file,omitempty"`
+
+	// Shard is set when only part of the repos list was scanned (K/N).
+	// Shards lists the shards covered by an incomplete merge.
+	Shard  string   `json:"shard,omitempty"`
+	Shards []string `json:"shards,omitempty"`
+
+	// Teams breaks usage down by CODEOWNERS team when --codeowners is set.
+	Teams []TeamStats `json:"teams,omitempty"`
+
+	// Conflicts found when this report was produced by pubscan merge.
+	Conflicts []Conflict `json:"merge_conflicts,omitempty"`
+}
+
+// TeamStats is the dependency usage of the repos owned by one team.
+type TeamStats struct {
+	Team                string        `json:"team"`
+	Repos               []string      `json:"repos"`
+	Dependencies        []PackageStat `json:"dependencies"`
+	DevDependencies     []PackageStat `json:"dev_dependencies"`
+	DependencyOverrides []PackageStat `json:"dependency_overrides"`
+	Combined            []PackageStat `json:"combined"`
+}
+
+// Unowned is the team that repos without CODEOWNERS entries are attributed to.
+const Unowned = "(unowned)"
+
+type counter struct {
+	deps      map[string]int
+	devDeps   map[string]int
+	overrides map[string]int
+	combined  map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{
+		deps:      map[string]int{},
+		devDeps:   map[string]int{},
+		overrides: map[string]int{},
+		combined:  map[string]int{},
+	}
+}
+
+// add counts the packages of r by package URL.
+func (c *counter) add(r RepoResult) {
+	for _, k := range r.Dependencies {
+		c.deps[purl.For(r.Ecosystem, k)]++
+	}
+	for _, k := range r.DevDependencies {
+		c.devDeps[purl.For(r.Ecosystem, k)]++
+	}
+	for _, k := range r.DependencyOverrides {
+		c.overrides[purl.For(r.Ecosystem, k)]++
+	}
+	for _, k := range PackageNames(r) {
+		c.combined[purl.For(r.Ecosystem, k)]++
+	}
+}
+
+// PackageNames returns the distinct packages r declares in any section.
+func PackageNames(r RepoResult) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, list := range [][]string{r.Dependencies, r.DevDependencies, r.DependencyOverrides} {
+		for _, k := range list {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	return out
+}
+
+// Collector is an additional analysis fed with every repo result.
+type Collector interface {
+	Add(r RepoResult)
+}
+
+type teamCounter struct {
+	*counter
+	repos []string
+}
+
+// Aggregator counts package usage incrementally as repo results arrive.
+// By default per-repo results are kept for the report; after SpillTo they
+// are streamed to the given writer as NDJSON instead, so memory stays
+// bounded by the number of distinct packages rather than the fleet size.
+type Aggregator struct {
+	mu    sync.Mutex
+	total *counter
+
+	// TrackTeams attributes every successfully scanned repo to its owners.
+	TrackTeams bool
+	teams      map[string]*teamCounter
+
+	// weights sums RepoWeight per package; the weighted section is only
+	// reported once a repo carried an explicit weight.
+	weights  map[string]float64
+	weighted bool
+
+	collectors []Collector
+
+	repos []RepoResult
+	spill *json.Encoder
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		total:   newCounter(),
+		teams:   map[string]*teamCounter{},
+		weights: map[string]float64{},
+	}
+}
+
+// --- Core logic ---
+
+// Use registers collectors that receive every result passed to Add.
+func (a *Aggregator) Use(c ...Collector) {
+	a.collectors = append(a.collectors, c...)
+}
+
+// SpillTo makes the aggregator write per-repo results to w instead of
+// retaining them. It must be called before the first Add.
+func (a *Aggregator) SpillTo(w io.Writer) {
+	a.spill = json.NewEncoder(w)
+}
+
+func (a *Aggregator) Add(r RepoResult) error {
+	for _, c := range a.collectors {
+		c.Add(r)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total.add(r)
+	if r.Weight > 0 {
+		a.weighted = true
+	}
+	for _, k := range PackageNames(r) {
+		a.weights[purl.For(r.Ecosystem, k)] += RepoWeight(r)
+	}
+	if a.TrackTeams && r.Error == "" {
+		owners := r.Owners
+		if len(owners) == 0 {
+			owners = []string{Unowned}
+		}
+		for _, team := range owners {
+			tc := a.teams[team]
+			if tc == nil {
+				tc = &teamCounter{counter: newCounter()}
+				a.teams[team] = tc
+			}
+			tc.add(r)
+			tc.repos = append(tc.repos, r.Repo)
+		}
+	}
+
+	if a.spill != nil {
+		return a.spill.Encode(r)
+	}
+	a.repos = append(a.repos, r)
+	return nil
+}
+
+// Stats builds the final report, keeping packages used at least minUsage times.
+func (a *Aggregator) Stats(minUsage int) Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	repos := append([]RepoResult(nil), a.repos...)
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Repo < repos[j].Repo })
+
+	var teams []TeamStats
+	for name, tc := range a.teams {
+		teamRepos := append([]string(nil), tc.repos...)
+		sort.Strings(teamRepos)
+		teams = append(teams, TeamStats{
+			Team:                name,
+			Repos:               teamRepos,
+			Dependencies:        buildSortedList(tc.deps, minUsage),
+			DevDependencies:     buildSortedList(tc.devDeps, minUsage),
+			DependencyOverrides: buildSortedList(tc.overrides, minUsage),
+			Combined:            buildSortedList(tc.combined, minUsage),
+		})
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Team < teams[j].Team })
+
+	var weighted []WeightedStat
+	if a.weighted {
+		weighted = buildWeightedList(a.weights, a.total.combined, minUsage)
+	}
+
+	return Stats{
+		Dependencies:        buildSortedList(a.total.deps, minUsage),
+		DevDependencies:     buildSortedList(a.total.devDeps, minUsage),
+		DependencyOverrides: buildSortedList(a.total.overrides, minUsage),
+		Combined:            buildSortedList(a.total.combined, minUsage),
+		Weighted:            weighted,
+		Repos:               repos,
+		Teams:               teams,
+	}
+}
+
+// buildSortedList lists the packages of counts keyed by package URL.
+func buildSortedList(m map[string]int, minUsage int) []PackageStat {
+	var list []PackageStat
+	for k, v := range m {
+		if v >= minUsage {
+			list = append(list, PackageStat{
+				Name:  purl.Name(k),
+				PURL:  k,
+				Count: v,
+				URL:   purl.URL(k),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count < list[j].Count
+		}
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].PURL < list[j].PURL
+	})
+	return list
+}
+
+// RepoWeight is the weight of r; repos without one count once.
+func RepoWeight(r RepoResult) float64 {
+	if r.Weight > 0 {
+		return r.Weight
+	}
+	return 1
+}
+
+func buildWeightedList(weights map[string]float64, counts map[string]int, minUsage int) []WeightedStat {
+	var list []WeightedStat
+	for k, w := range weights {
+		if counts[k] >= minUsage {
+			list = append(list, WeightedStat{
+				Name:   purl.Name(k),
+				PURL:   k,
+				Weight: w,
+				Count:  counts[k],
+				URL:    purl.URL(k),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Weight != list[j].Weight {
+			return list[i].Weight < list[j].Weight
+		}
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].PURL < list[j].PURL
+	})
+	return list
+}
@@ -0,0 +1,240 @@
+package toolchain
+
+import (
+	"bufio"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"pgithub.com/plasmatrip/pubscan/internal/semver"
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// PinFiles are the files a Flutter SDK pin is read from, in order of
+// precedence: FVM 3, FVM 2 and asdf/mise.
+var PinFiles = []string{".fvmrc", ".fvm/fvm_config.json", ".tool-versions"}
+
+// --- Structures ---
+
+// Row is one combination of environment constraints and Flutter pin, with
+// the repos declaring it.
+type Row struct {
+	SDK     string   `json:"sdk,omitempty"`
+	Flutter string   `json:"flutter,omitempty"`
+	Pin     string   `json:"pin,omitempty"`
+	Count   int      `json:"count"`
+	Repos   []string `json:"repos,omitempty"`
+}
+
+// Mismatch is a repo whose pinned Flutter version is outside the flutter
+// constraint of its pubspec environment.
+type Mismatch struct {
+	Repo    string `json:"repo"`
+	Pin     string `json:"pin"`
+	Flutter string `json:"flutter"`
+}
+
+// Report is the toolchain section of a scan. CIFlutter and CIDart count
+// the versions GitHub Actions workflows install, in the Pin of each row;
+// CIMismatches are the CI Flutter versions the pubspec constraint excludes.
+type Report struct {
+	Pins         []Row      `json:"flutter_pins,omitempty"`
+	Matrix       []Row      `json:"matrix"`
+	Mismatches   []Mismatch `json:"mismatches,omitempty"`
+	CIFlutter    []Row      `json:"ci_flutter,omitempty"`
+	CIDart       []Row      `json:"ci_dart,omitempty"`
+	CIMismatches []Mismatch `json:"ci_mismatches,omitempty"`
+}
+
+// Tracker builds the environment matrix from repo results. With CountOnly
+// set it keeps counts but no repo names.
+type Tracker struct {
+	mu         sync.Mutex
+	CountOnly  bool
+	rows       map[matrixKey]*Row
+	pins       map[string]*Row
+	mismatches []Mismatch
+
+	ciFlutter    map[string]*Row
+	ciDart       map[string]*Row
+	ciMismatches []Mismatch
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{rows: map[matrixKey]*Row{}, pins: map[string]*Row{},
+		ciFlutter: map[string]*Row{}, ciDart: map[string]*Row{}}
+}
+
+type matrixKey struct {
+	sdk, flutter, pin string
+}
+
+// --- Core logic ---
+
+// ParsePin extracts the Flutter version pinned by one of PinFiles. It
+// returns "" when the file pins none.
+func ParsePin(file, content string) string {
+	switch file {
+	case ".fvmrc":
+		var cfg struct {
+			Flutter string `json:"flutter"`
+		}
+		if json.Unmarshal([]byte(content), &cfg) == nil {
+			return normalize(cfg.Flutter)
+		}
+	case ".fvm/fvm_config.json":
+		var cfg struct {
+			FlutterSdkVersion string `json:"flutterSdkVersion"`
+		}
+		if json.Unmarshal([]byte(content), &cfg) == nil {
+			return normalize(cfg.FlutterSdkVersion)
+		}
+	case ".tool-versions":
+		sc := bufio.NewScanner(strings.NewReader(content))
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) >= 2 && fields[0] == "flutter" {
+				return normalize(fields[1])
+			}
+		}
+	}
+	return ""
+}
+
+// normalize drops the channel suffix asdf adds, e.g. 3.19.0-stable.
+func normalize(v string) string {
+	return strings.TrimSuffix(strings.TrimSpace(v), "-stable")
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" {
+		return
+	}
+	key := matrixKey{sdk: r.Environment["sdk"], flutter: r.Environment["flutter"], pin: r.FlutterPin}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, v := range r.CIFlutter {
+		t.count(t.ciFlutter, v, r.Repo)
+		if key.flutter != "" && !allows(key.flutter, v) {
+			t.ciMismatches = append(t.ciMismatches, Mismatch{Repo: r.Repo, Pin: v, Flutter: key.flutter})
+		}
+	}
+	for _, v := range r.CIDart {
+		t.count(t.ciDart, v, r.Repo)
+	}
+	if len(r.Environment) == 0 && r.FlutterPin == "" {
+		return
+	}
+
+	row := t.rows[key]
+	if row == nil {
+		row = &Row{SDK: key.sdk, Flutter: key.flutter, Pin: key.pin}
+		t.rows[key] = row
+	}
+	row.Count++
+	if !t.CountOnly {
+		row.Repos = append(row.Repos, r.Repo)
+	}
+
+	if r.FlutterPin == "" {
+		return
+	}
+	t.count(t.pins, r.FlutterPin, r.Repo)
+	if key.flutter != "" && !allows(key.flutter, r.FlutterPin) {
+		t.mismatches = append(t.mismatches, Mismatch{Repo: r.Repo, Pin: r.FlutterPin, Flutter: key.flutter})
+	}
+}
+
+// count adds a repo to the row of version in rows.
+func (t *Tracker) count(rows map[string]*Row, version, repo string) {
+	row := rows[version]
+	if row == nil {
+		row = &Row{Pin: version}
+		rows[version] = row
+	}
+	row.Count++
+	if !t.CountOnly {
+		row.Repos = append(row.Repos, repo)
+	}
+}
+
+// allows reports whether the pinned version satisfies the constraint.
+// Unparseable values are not reported as mismatches.
+func allows(constraint, pin string) bool {
+	rng, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return true
+	}
+	v, err := semver.ParseVersion(pin)
+	if err != nil {
+		return true
+	}
+	return rng.Allows(v)
+}
+
+// Report returns the matrix and pins, most used first. It is empty when no
+// results carried environment, pin or CI data.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.rows) == 0 && len(t.ciFlutter) == 0 && len(t.ciDart) == 0 {
+		return nil
+	}
+	rep := &Report{
+		Pins:         sorted(t.pins),
+		Mismatches:   append([]Mismatch(nil), t.mismatches...),
+		CIFlutter:    sorted(t.ciFlutter),
+		CIDart:       sorted(t.ciDart),
+		CIMismatches: append([]Mismatch(nil), t.ciMismatches...),
+	}
+	for _, r := range t.rows {
+		rep.Matrix = append(rep.Matrix, copyRow(r))
+	}
+	sortRows(rep.Matrix)
+	sortMismatches(rep.Mismatches)
+	sortMismatches(rep.CIMismatches)
+	return rep
+}
+
+func sortMismatches(list []Mismatch) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Repo != list[j].Repo {
+			return list[i].Repo < list[j].Repo
+		}
+		return list[i].Pin < list[j].Pin
+	})
+}
+
+func sorted(m map[string]*Row) []Row {
+	var out []Row
+	for _, r := range m {
+		out = append(out, copyRow(r))
+	}
+	sortRows(out)
+	return out
+}
+
+func copyRow(r *Row) Row {
+	row := *r
+	row.Repos = append([]string(nil), r.Repos...)
+	sort.Strings(row.Repos)
+	return row
+}
+
+func sortRows(rows []Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Pin != b.Pin {
+			return a.Pin < b.Pin
+		}
+		if a.Flutter != b.Flutter {
+			return a.Flutter < b.Flutter
+		}
+		return a.SDK < b.SDK
+	})
+}
@@ -0,0 +1,125 @@
+package toolchain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkflowsDir holds the GitHub Actions workflows of a repo.
+const WorkflowsDir = ".github/workflows"
+
+// Actions that install the SDKs, and the input naming the version.
+const (
+	flutterAction = "subosito/flutter-action"
+	dartAction    = "dart-lang/setup-dart"
+)
+
+// --- Structures ---
+
+type workflow struct {
+	Jobs map[string]struct {
+		Strategy struct {
+			Matrix map[string]interface{} `yaml:"matrix"`
+		} `yaml:"strategy"`
+		Steps []struct {
+			Uses string            `yaml:"uses"`
+			With map[string]string `yaml:"with"`
+		} `yaml:"steps"`
+	} `yaml:"jobs"`
+}
+
+// CIVersions are the Flutter and Dart versions a repo's workflows install.
+// A version is a release like 3.19.0, a channel like stable when none is
+// pinned, or "(pubspec.yaml)" for versions read from a file.
+type CIVersions struct {
+	Flutter []string
+	Dart    []string
+}
+
+var matrixRef = regexp.MustCompile(`^\$\{\{\s*matrix\.([\w-]+)\s*\}\}$`)
+
+// --- Core logic ---
+
+// IsWorkflow reports whether a file in WorkflowsDir is a workflow.
+func IsWorkflow(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
+
+// ParseWorkflow returns the SDK versions the flutter-action and setup-dart
+// steps of a workflow install. Versions taken from the job matrix are
+// expanded to every value.
+func ParseWorkflow(content string) (CIVersions, error) {
+	var wf workflow
+	if err := yaml.Unmarshal([]byte(content), &wf); err != nil {
+		return CIVersions{}, err
+	}
+	var out CIVersions
+	for _, job := range wf.Jobs {
+		resolve := func(v string) []string {
+			m := matrixRef.FindStringSubmatch(strings.TrimSpace(v))
+			if m == nil {
+				return []string{normalize(v)}
+			}
+			var values []string
+			switch list := job.Strategy.Matrix[m[1]].(type) {
+			case []interface{}:
+				for _, x := range list {
+					values = append(values, normalize(fmt.Sprint(x)))
+				}
+			case nil:
+			default:
+				values = append(values, normalize(fmt.Sprint(list)))
+			}
+			if len(values) == 0 {
+				return []string{"(matrix." + m[1] + ")"}
+			}
+			return values
+		}
+		for _, step := range job.Steps {
+			action, _, _ := strings.Cut(step.Uses, "@")
+			switch action {
+			case flutterAction:
+				switch {
+				case step.With["flutter-version"] != "":
+					out.Flutter = append(out.Flutter, resolve(step.With["flutter-version"])...)
+				case step.With["flutter-version-file"] != "":
+					out.Flutter = append(out.Flutter, "("+step.With["flutter-version-file"]+")")
+				case step.With["channel"] != "":
+					out.Flutter = append(out.Flutter, resolve(step.With["channel"])...)
+				default:
+					out.Flutter = append(out.Flutter, "stable")
+				}
+			case dartAction:
+				if sdk := step.With["sdk"]; sdk != "" {
+					out.Dart = append(out.Dart, resolve(sdk)...)
+				} else {
+					out.Dart = append(out.Dart, "stable")
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Merge adds the versions of another workflow, keeping each once, sorted.
+func (v *CIVersions) Merge(o CIVersions) {
+	v.Flutter = dedupe(append(v.Flutter, o.Flutter...))
+	v.Dart = dedupe(append(v.Dart, o.Dart...))
+}
+
+func dedupe(list []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,145 @@
+package typosquat
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"sort"
+	"strings"
+)
+
+//go:embed popular.txt
+var popularList string
+
+// MinLength is the shortest popular name lookalikes are looked for; shorter
+// names are one edit away from too many legitimate packages.
+const MinLength = 4
+
+// --- Structures ---
+
+// Match is a popular package a name looks like, and why.
+type Match struct {
+	Popular string
+	Reason  string
+}
+
+// Checker flags package names that look like popular packages without
+// being one of them.
+type Checker struct {
+	popular   []string
+	isPopular map[string]bool
+	squashed  map[string]string // name without underscores -> popular name
+	allow     map[string]bool
+}
+
+// NewChecker returns a checker for the popular names; names on the
+// allowlist are never flagged.
+func NewChecker(popular, allow []string) *Checker {
+	c := &Checker{isPopular: map[string]bool{}, squashed: map[string]string{}, allow: map[string]bool{}}
+	for _, p := range popular {
+		if p = strings.TrimSpace(p); p == "" || c.isPopular[p] {
+			continue
+		}
+		c.isPopular[p] = true
+		c.popular = append(c.popular, p)
+	}
+	sort.Strings(c.popular)
+	for _, p := range c.popular {
+		if _, ok := c.squashed[squash(p)]; !ok {
+			c.squashed[squash(p)] = p
+		}
+	}
+	for _, a := range allow {
+		if a = strings.TrimSpace(a); a != "" {
+			c.allow[a] = true
+		}
+	}
+	return c
+}
+
+// --- Core logic ---
+
+// Popular returns the built-in popular pub.dev package names.
+func Popular() []string {
+	return parseList(popularList)
+}
+
+// LoadList reads package names from a file, one per line; # starts a
+// comment.
+func LoadList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseList(string(data)), nil
+}
+
+func parseList(content string) []string {
+	var out []string
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// Check returns the popular package name looks like: the same name but
+// for underscores, as http_ or flutterbloc, or one edit away from a name
+// of at least MinLength, as htpp or provder.
+func (c *Checker) Check(name string) (Match, bool) {
+	if c.isPopular[name] || c.allow[name] {
+		return Match{}, false
+	}
+	if p, ok := c.squashed[squash(name)]; ok {
+		return Match{Popular: p, Reason: "differs from " + p + " only in underscores"}, true
+	}
+	for _, p := range c.popular {
+		if len(p) < MinLength || abs(len(p)-len(name)) > 1 {
+			continue
+		}
+		if Distance(name, p) == 1 {
+			return Match{Popular: p, Reason: "one edit away from " + p}, true
+		}
+	}
+	return Match{}, false
+}
+
+// Distance is the edit distance of a and b, counting insertions,
+// deletions, substitutions and transpositions of adjacent bytes.
+func Distance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func squash(name string) string {
+	return strings.ReplaceAll(name, "_", "")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,196 @@
+package updates
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"pgithub.com/plasmatrip/pubscan/internal/stats"
+)
+
+// Tools that keep dependencies up to date.
+const (
+	ToolDependabot = "dependabot"
+	ToolRenovate   = "renovate"
+
+	// None marks repos where no tool updates pub dependencies.
+	None = "(none)"
+)
+
+// DependabotFiles and RenovateFiles are the config locations each tool
+// reads, relative to the repo root.
+var (
+	DependabotFiles = []string{".github/dependabot.yml", ".github/dependabot.yaml"}
+	RenovateFiles   = []string{"renovate.json", "renovate.json5", ".github/renovate.json", ".github/renovate.json5",
+		".renovaterc", ".renovaterc.json", ".renovaterc.json5"}
+)
+
+// --- Structures ---
+
+type dependabotConfig struct {
+	Updates []struct {
+		Ecosystem   string   `yaml:"package-ecosystem"`
+		Directory   string   `yaml:"directory"`
+		Directories []string `yaml:"directories"`
+	} `yaml:"updates"`
+}
+
+type renovateConfig struct {
+	EnabledManagers []string `json:"enabledManagers"`
+	Enabled         *bool    `json:"enabled"`
+	Pub             struct {
+		Enabled *bool `json:"enabled"`
+	} `json:"pub"`
+}
+
+// Repo is a repo without automated pub updates. Configured names the tools
+// set up for other ecosystems only. Outdated counts the dependencies whose
+// latest version the constraints exclude, when --risk looked them up.
+type Repo struct {
+	Repo         string   `json:"repo"`
+	Configured   []string `json:"configured,omitempty"`
+	Dependencies int      `json:"dependencies"`
+	Outdated     *int     `json:"outdated,omitempty"`
+}
+
+// Report is the automated dependency update coverage of the fleet. Counts
+// has the repos per tool covering pub and None. OutdatedPerRepo compares
+// the mean outdated dependencies of covered and uncovered repos.
+type Report struct {
+	Counts          map[string]int     `json:"counts"`
+	Uncovered       []Repo             `json:"uncovered,omitempty"`
+	OutdatedPerRepo map[string]float64 `json:"outdated_per_repo,omitempty"`
+}
+
+// Tracker collects the update coverage recorded in repo results.
+type Tracker struct {
+	mu        sync.Mutex
+	counts    map[string]int
+	uncovered []Repo
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{counts: map[string]int{}}
+}
+
+// --- Core logic ---
+
+// DependabotCoversPub reports whether a dependabot.yml updates the pub
+// ecosystem in dir, the pubspec's directory relative to the repo root.
+func DependabotCoversPub(content, dir string) (bool, error) {
+	var cfg dependabotConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return false, err
+	}
+	want := "/" + strings.Trim(dir, "/")
+	for _, u := range cfg.Updates {
+		if u.Ecosystem != "pub" {
+			continue
+		}
+		for _, d := range append([]string{u.Directory}, u.Directories...) {
+			if d == "" {
+				continue
+			}
+			d = path.Clean("/" + strings.Trim(d, "/"))
+			if d == want || strings.Contains(d, "*") {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+var (
+	json5Comments = regexp.MustCompile(`(?m)^\s*//.*$|/\*[\s\S]*?\*/`)
+	json5Commas   = regexp.MustCompile(`,(\s*[}\]])`)
+)
+
+// RenovateCoversPub reports whether a Renovate config updates pub
+// dependencies. Renovate's pub manager is on by default, so any config
+// covers pub unless it disables Renovate, the pub manager, or enables only
+// other managers. JSON5 configs are read after stripping comments and
+// trailing commas; ones that still do not parse are assumed to cover pub.
+func RenovateCoversPub(content string) bool {
+	var cfg renovateConfig
+	content = json5Commas.ReplaceAllString(json5Comments.ReplaceAllString(content, ""), "$1")
+	if err := json.Unmarshal([]byte(content), &cfg); err != nil {
+		return true
+	}
+	if cfg.Enabled != nil && !*cfg.Enabled {
+		return false
+	}
+	if cfg.Pub.Enabled != nil && !*cfg.Pub.Enabled {
+		return false
+	}
+	if len(cfg.EnabledManagers) == 0 {
+		return true
+	}
+	for _, m := range cfg.EnabledManagers {
+		if m == "pub" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tracker) Add(r stats.RepoResult) {
+	if r.Error != "" || len(r.PubUpdates) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tool := range r.PubUpdates {
+		t.counts[tool]++
+	}
+	if r.PubUpdates[0] == None {
+		t.uncovered = append(t.uncovered, Repo{Repo: r.Repo, Configured: r.UpdateConfigs, Dependencies: len(r.Dependencies)})
+	}
+}
+
+// Report returns the coverage, or nil when no repo was checked.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.counts) == 0 {
+		return nil
+	}
+	rep := &Report{Counts: map[string]int{}}
+	for k, v := range t.counts {
+		rep.Counts[k] = v
+	}
+	rep.Uncovered = append(rep.Uncovered, t.uncovered...)
+	sort.Slice(rep.Uncovered, func(i, j int) bool { return rep.Uncovered[i].Repo < rep.Uncovered[j].Repo })
+	return rep
+}
+
+// SetOutdated records the outdated dependencies per repo, given for every
+// checked repo, and the mean for covered and uncovered repos.
+func (rep *Report) SetOutdated(outdated map[string]int, covered map[string]bool) {
+	for i := range rep.Uncovered {
+		if n, ok := outdated[rep.Uncovered[i].Repo]; ok {
+			n := n
+			rep.Uncovered[i].Outdated = &n
+		}
+	}
+	sums, counts := map[string]int{}, map[string]int{}
+	for repo, n := range outdated {
+		group := "uncovered"
+		if covered[repo] {
+			group = "covered"
+		}
+		sums[group] += n
+		counts[group]++
+	}
+	if len(counts) == 0 {
+		return
+	}
+	rep.OutdatedPerRepo = map[string]float64{}
+	for group, n := range counts {
+		rep.OutdatedPerRepo[group] = float64(sums[group]) / float64(n)
+	}
+}